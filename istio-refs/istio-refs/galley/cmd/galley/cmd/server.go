@@ -151,6 +151,10 @@ func serverCmd() *cobra.Command {
 		"Enable the Fsnotify for watching config source files on the disk and implicit signaling on a config change. Explicit signaling will still be enabled")
 	svr.PersistentFlags().BoolVar(&serverArgs.EnableConfigAnalysis, "enableAnalysis", serverArgs.EnableConfigAnalysis,
 		"Enable config analysis service")
+	svr.PersistentFlags().StringSliceVar(&serverArgs.CustomAnalyzers, "customAnalyzers", serverArgs.CustomAnalyzers,
+		"Policy files, or directories of policy files, declaring additional analyzers written in CEL or Rego")
+	svr.PersistentFlags().StringSliceVar(&serverArgs.AnalysisNamespaces, "analysisNamespaces", serverArgs.AnalysisNamespaces,
+		"Namespaces of the resources whose status is updated with analysis messages. All namespaces if not set")
 
 	// validation webhook server config
 	_ = svr.PersistentFlags().String("validation-webhook-config-file", "", "Setting this file has no effect")
@@ -209,6 +213,8 @@ func setupAliases() {
 	viper.RegisterAlias("general.pprofPort", "pprofPort")
 	viper.RegisterAlias("general.enable_profiling", "enableProfiling")
 	viper.RegisterAlias("processing.analysis.enable", "enableAnalysis")
+	viper.RegisterAlias("processing.analysis.customAnalyzers", "customAnalyzers")
+	viper.RegisterAlias("processing.analysis.namespaces", "analysisNamespaces")
 	viper.RegisterAlias("processing.discovery.enable", "enableServiceDiscovery")
 	viper.RegisterAlias("processing.domainSuffix", "domain")
 	viper.RegisterAlias("processing.oldprocessor", "useOldProcessor")
@@ -20,11 +20,13 @@ import (
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/auth"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/deployment"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/deprecation"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/destinationrule"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/gateway"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/injection"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/policy"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/schema"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/service"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/serviceentry"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/sidecar"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/virtualservice"
 )
@@ -34,19 +36,30 @@ func All() []analysis.Analyzer {
 	analyzers := []analysis.Analyzer{
 		// Please keep this list sorted alphabetically by pkg.name for convenience
 		&annotations.K8sAnalyzer{},
+		&auth.AuthorizationPoliciesAnalyzer{},
+		&auth.AuthorizationPolicyMTLSAnalyzer{},
+		&auth.AuthorizationPolicyShadowAnalyzer{},
 		&auth.JwtAnalyzer{},
 		&auth.MTLSAnalyzer{},
 		&auth.ServiceRoleBindingAnalyzer{},
 		&auth.ServiceRoleServicesAnalyzer{},
 		&deployment.ServiceAssociationAnalyzer{},
 		&deprecation.FieldAnalyzer{},
+		&destinationrule.SubsetSelectorAnalyzer{},
+		&destinationrule.UnusedSubsetAnalyzer{},
 		&gateway.IngressGatewayPortAnalyzer{},
 		&gateway.SecretAnalyzer{},
 		&injection.Analyzer{},
 		&injection.ImageAnalyzer{},
 		&policy.DeprecatedAnalyzer{},
 		&service.PortNameAnalyzer{},
+		&serviceentry.EgressGatewayAnalyzer{},
+		&serviceentry.ExportToAnalyzer{},
+		&serviceentry.HostConflictAnalyzer{},
+		&serviceentry.RegistryConflictAnalyzer{},
+		&serviceentry.ResolutionAnalyzer{},
 		&sidecar.DefaultSelectorAnalyzer{},
+		&sidecar.EgressHostsAnalyzer{},
 		&sidecar.SelectorAnalyzer{},
 		&virtualservice.ConflictingMeshGatewayHostsAnalyzer{},
 		&virtualservice.DestinationHostAnalyzer{},
@@ -33,9 +33,11 @@ import (
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/auth"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/deployment"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/deprecation"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/destinationrule"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/gateway"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/injection"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/service"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/serviceentry"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/sidecar"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/virtualservice"
 	"istio.io/istio/galley/pkg/config/analysis/diag"
@@ -80,6 +82,37 @@ var testGrid = []testCase{
 			{msg.MisplacedAnnotation, "Namespace staging"},
 		},
 	},
+	{
+		name:       "authorizationPolicies",
+		inputFiles: []string{"testdata/authorizationpolicies.yaml"},
+		analyzer:   &auth.AuthorizationPoliciesAnalyzer{},
+		expected: []message{
+			{msg.NoMatchingWorkloadsFound, "AuthorizationPolicy ratings-other-namespace.default"},
+			{msg.UnknownAuthorizationPolicyConditionKey, "AuthorizationPolicy unknown-keys.default"},
+			{msg.UnknownAuthorizationPolicyConditionKey, "AuthorizationPolicy unknown-keys.default"},
+		},
+	},
+	{
+		name:       "authorizationPolicyMTLS",
+		inputFiles: []string{"testdata/authorizationpolicy-mtls.yaml"},
+		analyzer:   &auth.AuthorizationPolicyMTLSAnalyzer{},
+		expected: []message{
+			{msg.AuthorizationPolicyRequiresMutualTLS, "AuthorizationPolicy principals-no-sidecar.plaintext"},
+			{msg.AuthorizationPolicyRequiresMutualTLS, "AuthorizationPolicy namespaces-mtls-disabled.disabled"},
+			{msg.AuthorizationPolicyRequiresMutualTLS, "AuthorizationPolicy namespaces-mtls-disabled.disabled"},
+			{msg.AuthorizationPolicyRequiresMutualTLS, "AuthorizationPolicy principals-ratings.mixed"},
+		},
+	},
+	{
+		name:       "authorizationPolicyShadow",
+		inputFiles: []string{"testdata/authorizationpolicy-shadow.yaml"},
+		analyzer:   &auth.AuthorizationPolicyShadowAnalyzer{},
+		expected: []message{
+			{msg.AuthorizationPolicyAllowRuleShadowed, "AuthorizationPolicy allow-productpage.default"},
+			{msg.AuthorizationPolicyAllowRuleShadowed, "AuthorizationPolicy allow-productpage.default"},
+			{msg.AuthorizationPolicyAllowRuleShadowed, "AuthorizationPolicy allow-productpage.default"},
+		},
+	},
 	{
 		name:       "jwtTargetsInvalidServicePortName",
 		inputFiles: []string{"testdata/jwt-invalid-service-port-name.yaml"},
@@ -235,6 +268,25 @@ var testGrid = []testCase{
 			{msg.Deprecated, "Policy policy-with-jwt.deprecation-policy"},
 		},
 	},
+	{
+		name:       "destinationRuleSubsetSelector",
+		inputFiles: []string{"testdata/destinationrule-subsets.yaml"},
+		analyzer:   &destinationrule.SubsetSelectorAnalyzer{},
+		expected: []message{
+			{msg.DestinationRuleSubsetNoMatchingPods, "DestinationRule reviews.default"},
+			{msg.OverlappingDestinationRuleSubsets, "DestinationRule reviews.default"},
+			{msg.OverlappingDestinationRuleSubsets, "DestinationRule productpage.default"},
+		},
+	},
+	{
+		name:       "destinationRuleUnusedSubsets",
+		inputFiles: []string{"testdata/destinationrule-unused-subsets.yaml"},
+		analyzer:   &destinationrule.UnusedSubsetAnalyzer{},
+		expected: []message{
+			{msg.UnusedDestinationRuleSubset, "DestinationRule reviews.default"},
+			{msg.UnusedDestinationRuleSubset, "DestinationRule ratings.default"},
+		},
+	},
 	{
 		name:       "gatewayNoWorkload",
 		inputFiles: []string{"testdata/gateway-no-workload.yaml"},
@@ -335,6 +387,54 @@ var testGrid = []testCase{
 		analyzer:   &service.PortNameAnalyzer{},
 		expected:   []message{},
 	},
+	{
+		name:       "serviceEntryRegistryConflict",
+		inputFiles: []string{"testdata/serviceentry-registry-conflict.yaml"},
+		analyzer:   &serviceentry.RegistryConflictAnalyzer{},
+		expected: []message{
+			{msg.ServiceEntryHostConflictsWithService, "ServiceEntry reviews-fqdn.default"},
+			{msg.ServiceEntryHostConflictsWithService, "ServiceEntry ratings-other-ns.other"},
+		},
+	},
+	{
+		name:       "serviceEntryHostConflict",
+		inputFiles: []string{"testdata/serviceentry-host-conflict.yaml"},
+		analyzer:   &serviceentry.HostConflictAnalyzer{},
+		expected: []message{
+			{msg.ConflictingServiceEntryHosts, "ServiceEntry api-https.default"},
+			{msg.ConflictingServiceEntryHosts, "ServiceEntry api-tls.other"},
+			{msg.ConflictingServiceEntryHosts, "ServiceEntry www-dns.default"},
+			{msg.ConflictingServiceEntryHosts, "ServiceEntry www-static.default"},
+		},
+	},
+	{
+		name:       "serviceEntryResolution",
+		inputFiles: []string{"testdata/serviceentry-resolution.yaml"},
+		analyzer:   &serviceentry.ResolutionAnalyzer{},
+		expected: []message{
+			{msg.ServiceEntryWildcardHostDNSResolution, "ServiceEntry wildcard-dns.default"},
+			{msg.ServiceEntryTCPPortWithoutAddresses, "ServiceEntry tcp-no-addresses.default"},
+		},
+	},
+	{
+		name:       "serviceEntryExportTo",
+		inputFiles: []string{"testdata/serviceentry-exportto.yaml"},
+		analyzer:   &serviceentry.ExportToAnalyzer{},
+		expected: []message{
+			{msg.ServiceEntryNotExported, "DestinationRule api.default"},
+			{msg.ServiceEntryNotExported, "VirtualService api.default"},
+		},
+	},
+	{
+		name:       "serviceEntryEgressGateway",
+		inputFiles: []string{"testdata/serviceentry-egressgateway.yaml"},
+		analyzer:   &serviceentry.EgressGatewayAnalyzer{},
+		expected: []message{
+			{msg.IncompleteEgressGatewayRoute, "VirtualService google.default"},
+			{msg.IncompleteEgressGatewayRoute, "VirtualService bbc.default"},
+			{msg.IncompleteEgressGatewayRoute, "VirtualService bbc.default"},
+		},
+	},
 	{
 		name:       "sidecarDefaultSelector",
 		inputFiles: []string{"testdata/sidecar-default-selector.yaml"},
@@ -344,6 +444,16 @@ var testGrid = []testCase{
 			{msg.MultipleSidecarsWithoutWorkloadSelectors, "Sidecar has-conflict-1.ns2"},
 		},
 	},
+	{
+		name:       "sidecarEgressHosts",
+		inputFiles: []string{"testdata/sidecar-egress-hosts.yaml"},
+		analyzer:   &sidecar.EgressHostsAnalyzer{},
+		expected: []message{
+			{msg.HostHiddenBySidecar, "Sidecar default.frontend"},
+			{msg.HostHiddenBySidecar, "Sidecar default.frontend"},
+			{msg.HostHiddenBySidecar, "Sidecar default.frontend"},
+		},
+	},
 	{
 		name:       "sidecarSelector",
 		inputFiles: []string{"testdata/sidecar-selector.yaml"},
@@ -517,6 +627,34 @@ func TestAnalyzersInAll(t *testing.T) {
 	}
 }
 
+// Verify that local analysis (the LocalAnalysis snapshot) provides the inputs of the analyzers,
+// so that they are not silently skipped.
+func TestAnalyzersNotSkippedInLocalAnalysis(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("all", All()...), "", "istio-system", nil, true, 10*time.Second)
+	if err := sa.AddDefaultResources(); err != nil {
+		t.Fatalf("Error adding default resources: %v", err)
+	}
+	result, err := runAnalyzer(sa)
+	if err != nil {
+		t.Fatalf("Error running analysis: %v", err)
+	}
+
+	var skipped []string
+outer:
+	for _, name := range result.SkippedAnalyzers {
+		// Explicitly ignored analyzers may validate collections outside of the snapshot
+		for _, regex := range ignoreAnalyzers {
+			if match, _ := regexp.MatchString(regex, name); match {
+				continue outer
+			}
+		}
+		skipped = append(skipped, name)
+	}
+	g.Expect(skipped).To(BeEmpty(), "analyzers skipped because their inputs are not in the LocalAnalysis snapshot")
+}
+
 func TestAnalyzersHaveUniqueNames(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -543,6 +681,67 @@ func TestAnalyzersHaveDescription(t *testing.T) {
 	}
 }
 
+func TestAnalyzerFixes(t *testing.T) {
+	cases := []struct {
+		testCase
+		fixes []string
+	}{
+		{
+			testCase: testCase{
+				name:       "misplacedAnnotation",
+				inputFiles: []string{"testdata/misannotated.yaml"},
+				analyzer:   &annotations.K8sAnalyzer{},
+			},
+			fixes: []string{"Move annotation sidecar.istio.io/statsInclusionPrefixes to the pod template"},
+		},
+		{
+			testCase: testCase{
+				name:       "namespaceNotInjected",
+				inputFiles: []string{"testdata/injection.yaml"},
+				analyzer:   &injection.Analyzer{},
+			},
+			fixes: []string{"Label namespace bar with istio-injection=enabled"},
+		},
+		{
+			testCase: testCase{
+				name:       "portNameNotFollowConvention",
+				inputFiles: []string{"testdata/service-no-port-name.yaml"},
+				analyzer:   &service.PortNameAnalyzer{},
+			},
+			fixes: []string{
+				"Set the name of port 8080 to http-8080",
+				"Set the name of port 8081 to tcp-8081",
+				"Rename port foo to http-foo",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc // Capture range variable so subtests work correctly
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			sa, err := setupAnalyzerForCase(tc.testCase, nil)
+			if err != nil {
+				t.Fatalf("Error setting up analysis for testcase %s: %v", tc.name, err)
+			}
+			result, err := runAnalyzer(sa)
+			if err != nil {
+				t.Fatalf("Error running analysis on testcase %s: %v", tc.name, err)
+			}
+
+			var fixes []string
+			for _, m := range result.Messages {
+				if m.Fix != nil {
+					g.Expect(m.Fix.Patch).NotTo(BeEmpty())
+					fixes = append(fixes, m.Fix.Description)
+				}
+			}
+			g.Expect(fixes).To(ConsistOf(tc.fixes), "%v", prettyPrintMessages(result.Messages))
+		})
+	}
+}
+
 func setupAnalyzerForCase(tc testCase, cr snapshotter.CollectionReporterFn) (*local.SourceAnalyzer, error) {
 	sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("testCase", tc.analyzer), "", "istio-system", cr, true, 10*time.Second)
 
@@ -15,11 +15,16 @@
 package annotations
 
 import (
+	"fmt"
 	"strings"
 
+	appsv1 "k8s.io/api/apps/v1"
+
 	"istio.io/api/annotation"
 
 	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/collection"
@@ -95,8 +100,11 @@ outer:
 
 		attachesTo := resourceTypesAsStrings(annotationDef.Resources)
 		if !contains(attachesTo, kind) {
-			ctx.Report(collectionType,
-				msg.NewMisplacedAnnotation(r, ann, strings.Join(attachesTo, ", ")))
+			m := msg.NewMisplacedAnnotation(r, ann, strings.Join(attachesTo, ", "))
+			if kind == "Deployment" && contains(attachesTo, "Pod") {
+				m.Fix = moveToPodTemplateFix(r, ann)
+			}
+			ctx.Report(collectionType, util.AtField(m, "metadata.annotations."+ann))
 			continue
 		}
 
@@ -113,6 +121,32 @@ outer:
 
 }
 
+// moveToPodTemplateFix returns a fix moving an annotation of a Deployment to its pod template.
+func moveToPodTemplateFix(r *resource.Instance, ann string) *diag.Fix {
+	from := diag.JSONPointer("metadata", "annotations", ann)
+	fix := &diag.Fix{
+		Description: fmt.Sprintf("Move annotation %s to the pod template", ann),
+		Patch: []diag.PatchOperation{{
+			Op:   diag.PatchMove,
+			From: from,
+			Path: diag.JSONPointer("spec", "template", "metadata", "annotations", ann),
+		}},
+	}
+
+	// The annotations map of the template has to exist for the annotation to be moved to it.
+	if d := r.Message.(*appsv1.Deployment); len(d.Spec.Template.Annotations) == 0 {
+		fix.Patch = []diag.PatchOperation{
+			{Op: diag.PatchRemove, Path: from},
+			{
+				Op:    diag.PatchAdd,
+				Path:  diag.JSONPointer("spec", "template", "metadata", "annotations"),
+				Value: map[string]string{ann: r.Metadata.Annotations[ann]},
+			},
+		}
+	}
+	return fix
+}
+
 // istioAnnotation is true if the annotation is in Istio's namespace
 func istioAnnotation(ann string) bool {
 	// We document this Kubernetes annotation, we should analyze it as well
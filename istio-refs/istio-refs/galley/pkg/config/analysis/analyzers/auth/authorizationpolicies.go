@@ -0,0 +1,121 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/security/v1beta1"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/security"
+)
+
+// AuthorizationPoliciesAnalyzer checks that v1beta1 AuthorizationPolicies with a workload selector
+// match at least one pod, and that their conditions only use supported keys.
+type AuthorizationPoliciesAnalyzer struct{}
+
+var _ analysis.Analyzer = &AuthorizationPoliciesAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *AuthorizationPoliciesAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "auth.AuthorizationPoliciesAnalyzer",
+		Description: "Checks that AuthorizationPolicy selectors match at least one workload " +
+			"and that conditions use supported keys",
+		Inputs: collection.Names{
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+			collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+			collections.K8SCoreV1Pods.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *AuthorizationPoliciesAnalyzer) Analyze(c analysis.Context) {
+	rootNamespace := resource.Namespace(util.MeshConfig(c).GetRootNamespace())
+
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		a.analyzeSelector(r, c, rootNamespace)
+		a.analyzeConditionKeys(r, c)
+		return true
+	})
+}
+
+func (a *AuthorizationPoliciesAnalyzer) analyzeSelector(r *resource.Instance, c analysis.Context, rootNamespace resource.Namespace) {
+	ap := r.Message.(*v1beta1.AuthorizationPolicy)
+
+	// Policies without a selector apply to the whole namespace, or the whole mesh in the root namespace.
+	matchLabels := ap.GetSelector().GetMatchLabels()
+	if len(matchLabels) == 0 {
+		return
+	}
+
+	if len(selectedPods(c, r, rootNamespace)) == 0 {
+		c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+			msg.NewNoMatchingWorkloadsFound(r, labels.SelectorFromSet(matchLabels).String()))
+	}
+}
+
+func (a *AuthorizationPoliciesAnalyzer) analyzeConditionKeys(r *resource.Instance, c analysis.Context) {
+	ap := r.Message.(*v1beta1.AuthorizationPolicy)
+
+	for i, rule := range ap.GetRules() {
+		for _, condition := range rule.GetWhen() {
+			// Empty keys are rejected by validation.
+			if key := condition.GetKey(); key != "" && !security.KnownAttribute(key) {
+				c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+					msg.NewUnknownAuthorizationPolicyConditionKey(r, key, i))
+			}
+		}
+	}
+}
+
+// selectedPods returns the pods an AuthorizationPolicy applies to. Policies in the root namespace
+// apply to the pods of every namespace.
+func selectedPods(c analysis.Context, r *resource.Instance, rootNamespace resource.Namespace) []*resource.Instance {
+	ap := r.Message.(*v1beta1.AuthorizationPolicy)
+	ns := r.Metadata.FullName.Namespace
+	sel := labels.SelectorFromSet(ap.GetSelector().GetMatchLabels())
+
+	var pods []*resource.Instance
+	c.ForEach(collections.K8SCoreV1Pods.Name(), func(rp *resource.Instance) bool {
+		if ns != rootNamespace && rp.Metadata.FullName.Namespace != ns {
+			return true
+		}
+		pod := rp.Message.(*v1.Pod)
+		if sel.Matches(labels.Set(pod.ObjectMeta.Labels)) {
+			pods = append(pods, rp)
+		}
+		return true
+	})
+	return pods
+}
+
+func hasSidecar(r *resource.Instance) bool {
+	pod := r.Message.(*v1.Pod)
+	for _, container := range pod.Spec.Containers {
+		if container.Name == "istio-proxy" {
+			return true
+		}
+	}
+	return false
+}
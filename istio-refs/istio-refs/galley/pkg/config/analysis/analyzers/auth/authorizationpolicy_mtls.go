@@ -0,0 +1,160 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/security/v1beta1"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// AuthorizationPolicyMTLSAnalyzer checks for AuthorizationPolicies matching on peer identities
+// (principals and namespaces) on workloads that can't receive mutual TLS traffic, because they
+// have no sidecar or because a PeerAuthentication disables it. Such rules never match.
+type AuthorizationPolicyMTLSAnalyzer struct{}
+
+var _ analysis.Analyzer = &AuthorizationPolicyMTLSAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *AuthorizationPolicyMTLSAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "auth.AuthorizationPolicyMTLSAnalyzer",
+		Description: "Checks that AuthorizationPolicies matching on peer identities select workloads using mutual TLS",
+		Inputs: collection.Names{
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+			collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+			collections.IstioSecurityV1Beta1Peerauthentications.Name(),
+			collections.K8SCoreV1Pods.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *AuthorizationPolicyMTLSAnalyzer) Analyze(c analysis.Context) {
+	rootNamespace := resource.Namespace(util.MeshConfig(c).GetRootNamespace())
+	pas := newPeerAuthentications(c, rootNamespace)
+
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		ap := r.Message.(*v1beta1.AuthorizationPolicy)
+
+		// Only report if none of the selected workloads can use mutual TLS. Policies that don't select
+		// any workload are reported by the AuthorizationPoliciesAnalyzer.
+		pods := selectedPods(c, r, rootNamespace)
+		if len(pods) == 0 {
+			return true
+		}
+		for _, pod := range pods {
+			if hasSidecar(pod) && pas.mode(pod) != v1beta1.PeerAuthentication_MutualTLS_DISABLE {
+				return true
+			}
+		}
+
+		for i, rule := range ap.GetRules() {
+			for _, attribute := range peerAttributes(rule) {
+				c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+					msg.NewAuthorizationPolicyRequiresMutualTLS(r, attribute, i))
+			}
+		}
+		return true
+	})
+}
+
+// peerAttributes returns the fields and condition keys of a rule that depend on the peer identity.
+func peerAttributes(rule *v1beta1.Rule) []string {
+	found := map[string]bool{}
+	var out []string
+	add := func(attribute string, values ...[]string) {
+		for _, v := range values {
+			if len(v) > 0 && !found[attribute] {
+				found[attribute] = true
+				out = append(out, attribute)
+			}
+		}
+	}
+
+	for _, from := range rule.GetFrom() {
+		src := from.GetSource()
+		add("principals", src.GetPrincipals(), src.GetNotPrincipals())
+		add("namespaces", src.GetNamespaces(), src.GetNotNamespaces())
+	}
+	for _, condition := range rule.GetWhen() {
+		switch key := condition.GetKey(); key {
+		case "source.principal", "source.namespace":
+			add(key, condition.GetValues(), condition.GetNotValues())
+		}
+	}
+	return out
+}
+
+// peerAuthentications resolves the effective mutual TLS mode of pods from the PeerAuthentication policies.
+// Port level settings are not taken into account.
+type peerAuthentications struct {
+	rootNamespace resource.Namespace
+	// workload holds the policies with a workload selector, per namespace.
+	workload map[resource.Namespace][]*v1beta1.PeerAuthentication
+	// namespace holds the mode of the namespace (or mesh, in the root namespace) wide policies.
+	namespace map[resource.Namespace]v1beta1.PeerAuthentication_MutualTLS_Mode
+}
+
+func newPeerAuthentications(c analysis.Context, rootNamespace resource.Namespace) *peerAuthentications {
+	pas := &peerAuthentications{
+		rootNamespace: rootNamespace,
+		workload:      map[resource.Namespace][]*v1beta1.PeerAuthentication{},
+		namespace:     map[resource.Namespace]v1beta1.PeerAuthentication_MutualTLS_Mode{},
+	}
+	c.ForEach(collections.IstioSecurityV1Beta1Peerauthentications.Name(), func(r *resource.Instance) bool {
+		pa := r.Message.(*v1beta1.PeerAuthentication)
+		ns := r.Metadata.FullName.Namespace
+		if len(pa.GetSelector().GetMatchLabels()) > 0 {
+			pas.workload[ns] = append(pas.workload[ns], pa)
+		} else {
+			pas.namespace[ns] = pa.GetMtls().GetMode()
+		}
+		return true
+	})
+	return pas
+}
+
+// mode returns the mutual TLS mode of a pod. UNSET inherits from the namespace, then the mesh
+// policy, and defaults to PERMISSIVE.
+func (p *peerAuthentications) mode(r *resource.Instance) v1beta1.PeerAuthentication_MutualTLS_Mode {
+	pod := r.Message.(*v1.Pod)
+	ns := r.Metadata.FullName.Namespace
+	mode := v1beta1.PeerAuthentication_MutualTLS_UNSET
+	for _, pa := range p.workload[ns] {
+		if labels.SelectorFromSet(pa.GetSelector().GetMatchLabels()).Matches(labels.Set(pod.ObjectMeta.Labels)) {
+			mode = pa.GetMtls().GetMode()
+			break
+		}
+	}
+	if mode == v1beta1.PeerAuthentication_MutualTLS_UNSET {
+		mode = p.namespace[ns]
+	}
+	if mode == v1beta1.PeerAuthentication_MutualTLS_UNSET {
+		mode = p.namespace[p.rootNamespace]
+	}
+	if mode == v1beta1.PeerAuthentication_MutualTLS_UNSET {
+		mode = v1beta1.PeerAuthentication_MutualTLS_PERMISSIVE
+	}
+	return mode
+}
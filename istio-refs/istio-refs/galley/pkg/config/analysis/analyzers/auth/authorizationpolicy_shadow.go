@@ -0,0 +1,235 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"strings"
+
+	"istio.io/api/security/v1beta1"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// AuthorizationPolicyShadowAnalyzer checks for ALLOW rules that only match requests that are always
+// denied by a DENY policy applying to the same workloads, since DENY policies are evaluated first.
+// The check is conservative: a rule is only reported if it is certainly shadowed.
+type AuthorizationPolicyShadowAnalyzer struct{}
+
+var _ analysis.Analyzer = &AuthorizationPolicyShadowAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *AuthorizationPolicyShadowAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "auth.AuthorizationPolicyShadowAnalyzer",
+		Description: "Checks for AuthorizationPolicy ALLOW rules that are shadowed by DENY rules",
+		Inputs: collection.Names{
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+			collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *AuthorizationPolicyShadowAnalyzer) Analyze(c analysis.Context) {
+	rootNamespace := resource.Namespace(util.MeshConfig(c).GetRootNamespace())
+
+	var denyPolicies []*resource.Instance
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		if r.Message.(*v1beta1.AuthorizationPolicy).GetAction() == v1beta1.AuthorizationPolicy_DENY {
+			denyPolicies = append(denyPolicies, r)
+		}
+		return true
+	})
+	if len(denyPolicies) == 0 {
+		return
+	}
+
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		ap := r.Message.(*v1beta1.AuthorizationPolicy)
+		if ap.GetAction() != v1beta1.AuthorizationPolicy_ALLOW {
+			return true
+		}
+
+		for i, rule := range ap.GetRules() {
+			if deny, j, found := shadowingRule(r, rule, denyPolicies, rootNamespace); found {
+				c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+					msg.NewAuthorizationPolicyAllowRuleShadowed(r, i, j, deny.Metadata.FullName.String()))
+			}
+		}
+		return true
+	})
+}
+
+// shadowingRule returns the first DENY rule matching every request matched by the ALLOW rule, on all
+// the workloads the ALLOW policy applies to.
+func shadowingRule(allow *resource.Instance, allowRule *v1beta1.Rule, denyPolicies []*resource.Instance,
+	rootNamespace resource.Namespace) (*resource.Instance, int, bool) {
+	for _, deny := range denyPolicies {
+		if !appliesToAll(deny, allow, rootNamespace) {
+			continue
+		}
+		for j, denyRule := range deny.Message.(*v1beta1.AuthorizationPolicy).GetRules() {
+			if ruleCovers(denyRule, allowRule) {
+				return deny, j, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// appliesToAll returns true if policy a applies to every workload policy b applies to.
+func appliesToAll(a, b *resource.Instance, rootNamespace resource.Namespace) bool {
+	aNs := a.Metadata.FullName.Namespace
+	if aNs != rootNamespace && aNs != b.Metadata.FullName.Namespace {
+		return false
+	}
+
+	// Every label required by a must be required by b as well.
+	bLabels := b.Message.(*v1beta1.AuthorizationPolicy).GetSelector().GetMatchLabels()
+	for k, v := range a.Message.(*v1beta1.AuthorizationPolicy).GetSelector().GetMatchLabels() {
+		if bv, ok := bLabels[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleCovers returns true if every request matched by rule b is matched by rule a.
+func ruleCovers(a, b *v1beta1.Rule) bool {
+	if len(a.GetFrom()) > 0 {
+		if len(b.GetFrom()) == 0 {
+			return false
+		}
+		for _, bFrom := range b.GetFrom() {
+			covered := false
+			for _, aFrom := range a.GetFrom() {
+				if sourceCovers(aFrom.GetSource(), bFrom.GetSource()) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				return false
+			}
+		}
+	}
+
+	if len(a.GetTo()) > 0 {
+		if len(b.GetTo()) == 0 {
+			return false
+		}
+		for _, bTo := range b.GetTo() {
+			covered := false
+			for _, aTo := range a.GetTo() {
+				if operationCovers(aTo.GetOperation(), bTo.GetOperation()) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				return false
+			}
+		}
+	}
+
+	// All conditions of a must be implied by a condition of b on the same key.
+	for _, aCond := range a.GetWhen() {
+		if len(aCond.GetNotValues()) > 0 {
+			return false
+		}
+		implied := false
+		for _, bCond := range b.GetWhen() {
+			if bCond.GetKey() == aCond.GetKey() && len(bCond.GetNotValues()) == 0 &&
+				valuesCover(aCond.GetValues(), bCond.GetValues()) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			return false
+		}
+	}
+	return true
+}
+
+func sourceCovers(a, b *v1beta1.Source) bool {
+	if len(a.GetNotPrincipals()) > 0 || len(a.GetNotRequestPrincipals()) > 0 ||
+		len(a.GetNotNamespaces()) > 0 || len(a.GetNotIpBlocks()) > 0 {
+		return false
+	}
+	return fieldCovers(a.GetPrincipals(), b.GetPrincipals()) &&
+		fieldCovers(a.GetRequestPrincipals(), b.GetRequestPrincipals()) &&
+		fieldCovers(a.GetNamespaces(), b.GetNamespaces()) &&
+		fieldCovers(a.GetIpBlocks(), b.GetIpBlocks())
+}
+
+func operationCovers(a, b *v1beta1.Operation) bool {
+	if len(a.GetNotHosts()) > 0 || len(a.GetNotPorts()) > 0 ||
+		len(a.GetNotMethods()) > 0 || len(a.GetNotPaths()) > 0 {
+		return false
+	}
+	return fieldCovers(a.GetHosts(), b.GetHosts()) &&
+		fieldCovers(a.GetPorts(), b.GetPorts()) &&
+		fieldCovers(a.GetMethods(), b.GetMethods()) &&
+		fieldCovers(a.GetPaths(), b.GetPaths())
+}
+
+// fieldCovers returns true if the values of field a match everything the values of field b match.
+// An empty field matches everything.
+func fieldCovers(a, b []string) bool {
+	if len(a) == 0 {
+		return true
+	}
+	if len(b) == 0 {
+		return false
+	}
+	return valuesCover(a, b)
+}
+
+// valuesCover returns true if every value in b is covered by a value in a.
+func valuesCover(a, b []string) bool {
+	for _, bv := range b {
+		covered := false
+		for _, av := range a {
+			if globCovers(av, bv) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// globCovers returns true if every string matched by value is matched by pattern. Both support the
+// exact, prefix ("abc*"), suffix ("*abc") and presence ("*") matches of the AuthorizationPolicy.
+func globCovers(pattern, value string) bool {
+	switch {
+	case pattern == util.Wildcard || pattern == value:
+		return true
+	case strings.HasSuffix(pattern, "*"):
+		return !strings.HasPrefix(value, "*") && strings.HasPrefix(value, strings.TrimSuffix(pattern, "*"))
+	case strings.HasPrefix(pattern, "*"):
+		return !strings.HasSuffix(value, "*") && strings.HasSuffix(value, strings.TrimPrefix(pattern, "*"))
+	}
+	return false
+}
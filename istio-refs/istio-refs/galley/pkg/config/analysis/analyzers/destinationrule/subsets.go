@@ -0,0 +1,143 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destinationrule
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// SubsetSelectorAnalyzer checks, for the destination rules whose host is a Kubernetes service, that:
+// * each subset selects at least one pod of the service
+// * no two subsets select the same pods
+type SubsetSelectorAnalyzer struct{}
+
+var _ analysis.Analyzer = &SubsetSelectorAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *SubsetSelectorAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "destinationrule.SubsetSelectorAnalyzer",
+		Description: "Checks that the subsets of destination rules select at least one pod of the service of " +
+			"their host, and that they don't select the same pods",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+			collections.K8SCoreV1Services.Name(),
+			collections.K8SCoreV1Pods.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *SubsetSelectorAnalyzer) Analyze(c analysis.Context) {
+	c.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		a.analyzeDestinationRule(c, r)
+		return true
+	})
+}
+
+func (a *SubsetSelectorAnalyzer) analyzeDestinationRule(c analysis.Context, r *resource.Instance) {
+	dr := r.Message.(*v1alpha3.DestinationRule)
+	if len(dr.GetSubsets()) == 0 {
+		return
+	}
+
+	// Only hosts backed by a Kubernetes service with a selector have pods we can match subsets against.
+	svcName := util.GetResourceNameFromHost(r.Metadata.FullName.Namespace, dr.GetHost())
+	svc := c.Find(collections.K8SCoreV1Services.Name(), svcName)
+	if svc == nil {
+		return
+	}
+	svcSelector := svc.Message.(*v1.ServiceSpec).Selector
+	if len(svcSelector) == 0 {
+		return
+	}
+
+	pods := servicePods(c, svcName.Namespace, labels.SelectorFromSet(svcSelector))
+	// Without any pod for the service, there is nothing to tell a mislabeled subset from a service
+	// that isn't deployed, or from pods missing from the analyzed resources.
+	if len(pods) == 0 {
+		return
+	}
+
+	subsetPods := make([][]resource.FullName, len(dr.GetSubsets()))
+	for i, ss := range dr.GetSubsets() {
+		sel := labels.SelectorFromSet(ss.GetLabels())
+		for _, p := range pods {
+			if sel.Matches(labels.Set(p.Message.(*v1.Pod).ObjectMeta.Labels)) {
+				subsetPods[i] = append(subsetPods[i], p.Metadata.FullName)
+			}
+		}
+
+		if len(subsetPods[i]) == 0 {
+			m := msg.NewDestinationRuleSubsetNoMatchingPods(r, ss.GetName(), dr.GetHost())
+			c.Report(collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+				util.AtField(m, fmt.Sprintf("spec.subsets[%d].labels", i)))
+		}
+	}
+
+	for i, ss := range dr.GetSubsets() {
+		for j := i + 1; j < len(dr.GetSubsets()); j++ {
+			other := dr.GetSubsets()[j]
+			if overlaps(ss.GetLabels(), other.GetLabels()) || shareAny(subsetPods[i], subsetPods[j]) {
+				m := msg.NewOverlappingDestinationRuleSubsets(r, other.GetName(), ss.GetName(), dr.GetHost())
+				c.Report(collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+					util.AtField(m, fmt.Sprintf("spec.subsets[%d].labels", j)))
+			}
+		}
+	}
+}
+
+// servicePods returns the pods of a namespace matching the selector of a service.
+func servicePods(c analysis.Context, ns resource.Namespace, sel labels.Selector) []*resource.Instance {
+	var pods []*resource.Instance
+	c.ForEach(collections.K8SCoreV1Pods.Name(), func(r *resource.Instance) bool {
+		if r.Metadata.FullName.Namespace != ns {
+			return true
+		}
+		if sel.Matches(labels.Set(r.Message.(*v1.Pod).ObjectMeta.Labels)) {
+			pods = append(pods, r)
+		}
+		return true
+	})
+	return pods
+}
+
+// overlaps returns true if all the pods selected by one set of labels are also selected by the other.
+func overlaps(a, b map[string]string) bool {
+	return labels.SelectorFromSet(a).Matches(labels.Set(b)) || labels.SelectorFromSet(b).Matches(labels.Set(a))
+}
+
+func shareAny(a, b []resource.FullName) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destinationrule
+
+import (
+	"fmt"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// UnusedSubsetAnalyzer checks that the subsets of destination rules are referenced by at least one virtual service
+type UnusedSubsetAnalyzer struct{}
+
+var _ analysis.Analyzer = &UnusedSubsetAnalyzer{}
+
+type hostAndSubset struct {
+	host   resource.FullName
+	subset string
+}
+
+// Metadata implements Analyzer
+func (a *UnusedSubsetAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "destinationrule.UnusedSubsetAnalyzer",
+		Description: "Checks that the subsets of destination rules are referenced by at least one virtual service",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+			collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *UnusedSubsetAnalyzer) Analyze(c analysis.Context) {
+	referenced := referencedSubsets(c)
+
+	c.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		dr := r.Message.(*v1alpha3.DestinationRule)
+		host := util.GetResourceNameFromHost(r.Metadata.FullName.Namespace, dr.GetHost())
+
+		for i, ss := range dr.GetSubsets() {
+			if referenced[hostAndSubset{host: host, subset: ss.GetName()}] {
+				continue
+			}
+			m := msg.NewUnusedDestinationRuleSubset(r, ss.GetName(), dr.GetHost())
+			c.Report(collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+				util.AtField(m, fmt.Sprintf("spec.subsets[%d].name", i)))
+		}
+		return true
+	})
+}
+
+// referencedSubsets returns the host+subset combinations used by the routes and mirrors of virtual services.
+func referencedSubsets(c analysis.Context) map[hostAndSubset]bool {
+	result := make(map[hostAndSubset]bool)
+	c.ForEach(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), func(r *resource.Instance) bool {
+		vs := r.Message.(*v1alpha3.VirtualService)
+		ns := r.Metadata.FullName.Namespace
+
+		add := func(d *v1alpha3.Destination) {
+			if d.GetSubset() == "" {
+				return
+			}
+			result[hostAndSubset{host: util.GetResourceNameFromHost(ns, d.GetHost()), subset: d.GetSubset()}] = true
+		}
+
+		for _, route := range vs.GetHttp() {
+			for _, rd := range route.GetRoute() {
+				add(rd.GetDestination())
+			}
+			add(route.GetMirror())
+		}
+		for _, route := range vs.GetTcp() {
+			for _, rd := range route.GetRoute() {
+				add(rd.GetDestination())
+			}
+		}
+		for _, route := range vs.GetTls() {
+			for _, rd := range route.GetRoute() {
+				add(rd.GetDestination())
+			}
+		}
+		return true
+	})
+	return result
+}
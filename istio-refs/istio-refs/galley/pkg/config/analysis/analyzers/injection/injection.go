@@ -15,6 +15,7 @@
 package injection
 
 import (
+	"fmt"
 	"strings"
 
 	v1 "k8s.io/api/core/v1"
@@ -23,6 +24,7 @@ import (
 
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/collection"
@@ -73,7 +75,9 @@ func (a *Analyzer) Analyze(c analysis.Context) {
 			// TODO: if Istio is installed with sidecarInjectorWebhook.enableNamespacesByDefault=true
 			// (in the istio-sidecar-injector configmap), we need to reverse this logic and treat this as an injected namespace
 
-			c.Report(collections.K8SCoreV1Namespaces.Name(), msg.NewNamespaceNotInjected(r, r.Metadata.FullName.String(), r.Metadata.FullName.String()))
+			m := msg.NewNamespaceNotInjected(r, r.Metadata.FullName.String(), r.Metadata.FullName.String())
+			m.Fix = injectionLabelFix(r)
+			c.Report(collections.K8SCoreV1Namespaces.Name(), m)
 			return true
 		}
 
@@ -114,3 +118,21 @@ func (a *Analyzer) Analyze(c analysis.Context) {
 		return true
 	})
 }
+
+// injectionLabelFix returns a fix enabling injection on a namespace by setting its injection label.
+func injectionLabelFix(r *resource.Instance) *diag.Fix {
+	op := diag.PatchOperation{
+		Op:    diag.PatchAdd,
+		Path:  diag.JSONPointer("metadata", "labels", InjectionLabelName),
+		Value: InjectionLabelEnableValue,
+	}
+	// The labels map has to exist for a label to be added to it.
+	if len(r.Metadata.Labels) == 0 {
+		op.Path = diag.JSONPointer("metadata", "labels")
+		op.Value = map[string]string{InjectionLabelName: InjectionLabelEnableValue}
+	}
+	return &diag.Fix{
+		Description: fmt.Sprintf("Label namespace %s with %s=%s", r.Metadata.FullName.Name, InjectionLabelName, InjectionLabelEnableValue),
+		Patch:       []diag.PatchOperation{op},
+	}
+}
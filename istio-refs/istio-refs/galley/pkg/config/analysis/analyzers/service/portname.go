@@ -15,8 +15,12 @@
 package service
 
 import (
+	"fmt"
+	"strconv"
+
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/analysis/msg"
 	configKube "istio.io/istio/pkg/config/kube"
 	"istio.io/istio/pkg/config/resource"
@@ -64,10 +68,58 @@ func (s *PortNameAnalyzer) Analyze(c analysis.Context) {
 
 func (s *PortNameAnalyzer) analyzeService(r *resource.Instance, c analysis.Context) {
 	svc := r.Message.(*v1.ServiceSpec)
-	for _, port := range svc.Ports {
+	for i, port := range svc.Ports {
 		if instance := configKube.ConvertProtocol(port.Port, port.Name, port.Protocol); instance.IsUnsupported() {
-			c.Report(collections.K8SCoreV1Services.Name(), msg.NewPortNameIsNotUnderNamingConvention(
-				r, port.Name, int(port.Port), port.TargetPort.String()))
+			m := msg.NewPortNameIsNotUnderNamingConvention(r, port.Name, int(port.Port), port.TargetPort.String())
+			m.Fix = portNameFix(svc, i)
+			if port.Name != "" {
+				m = util.AtField(m, fmt.Sprintf("spec.ports[%d].name", i))
+			}
+			c.Report(collections.K8SCoreV1Services.Name(), m)
 		}
 	}
 }
+
+// maxPortNameLength is the maximum length of a service port name (an IANA_SVC_NAME).
+const maxPortNameLength = 15
+
+// portNameFix returns a fix renaming the i-th port of the service to follow the naming convention,
+// prefixing its name with a protocol guessed from the port. Returns nil if no valid unique name is found.
+func portNameFix(svc *v1.ServiceSpec, i int) *diag.Fix {
+	port := svc.Ports[i]
+
+	protocol := "tcp"
+	switch {
+	case port.Protocol == v1.ProtocolUDP:
+		protocol = "udp"
+	case port.Port == 443:
+		protocol = "https"
+	case port.Port == 80 || port.Port == 8080:
+		protocol = "http"
+	}
+
+	name := protocol + "-" + port.Name
+	if port.Name == "" || len(name) > maxPortNameLength {
+		name = protocol + "-" + strconv.Itoa(int(port.Port))
+	}
+	for j, other := range svc.Ports {
+		if j != i && other.Name == name {
+			return nil
+		}
+	}
+
+	op := diag.PatchReplace
+	description := fmt.Sprintf("Rename port %s to %s", port.Name, name)
+	if port.Name == "" {
+		op = diag.PatchAdd
+		description = fmt.Sprintf("Set the name of port %d to %s", port.Port, name)
+	}
+	return &diag.Fix{
+		Description: description,
+		Patch: []diag.PatchOperation{{
+			Op:    op,
+			Path:  diag.JSONPointer("spec", "ports", strconv.Itoa(i), "name"),
+			Value: name,
+		}},
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// EgressGatewayAnalyzer checks the virtual services routing traffic to hosts of ServiceEntries through a
+// gateway, i.e. bound to both the mesh and a gateway. For each such host and gateway:
+// * the gateway must accept the host
+// * a route must send the traffic from the sidecars to another host, the gateway service
+// * a route must send the traffic from the gateway to the host
+// * if the gateway server requires ISTIO_MUTUAL TLS, a destination rule must enable it for the gateway service
+type EgressGatewayAnalyzer struct{}
+
+var _ analysis.Analyzer = &EgressGatewayAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *EgressGatewayAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "serviceentry.EgressGatewayAnalyzer",
+		Description: "Checks that the configuration routing traffic to ServiceEntry hosts through egress gateways is complete",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+			collections.IstioNetworkingV1Alpha3Gateways.Name(),
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+			collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *EgressGatewayAnalyzer) Analyze(ctx analysis.Context) {
+	serviceEntries := serviceEntryHosts(ctx)
+
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), func(r *resource.Instance) bool {
+		a.analyzeVirtualService(ctx, r, serviceEntries)
+		return true
+	})
+}
+
+func (a *EgressGatewayAnalyzer) analyzeVirtualService(ctx analysis.Context, r *resource.Instance,
+	serviceEntries map[util.ScopedFqdn]*resource.Instance) {
+
+	vs := r.Message.(*v1alpha3.VirtualService)
+	ns := r.Metadata.FullName.Namespace
+
+	var gateways []string
+	boundToMesh := false
+	for _, gw := range vs.GetGateways() {
+		if gw == util.MeshGateway {
+			boundToMesh = true
+		} else {
+			gateways = append(gateways, gw)
+		}
+	}
+	if !boundToMesh || len(gateways) == 0 {
+		return
+	}
+
+	fromSidecars := routeDestinations(vs, util.MeshGateway)
+	for i, h := range vs.GetHosts() {
+		fqdn := util.ConvertHostToFQDN(ns, h)
+		if !isVisible(serviceEntries, ns, fqdn) {
+			continue
+		}
+
+		for _, gwName := range gateways {
+			gwResource := ctx.Find(collections.IstioNetworkingV1Alpha3Gateways.Name(), resource.NewShortOrFullName(ns, gwName))
+			if gwResource == nil {
+				// Reported by virtualservice.GatewayAnalyzer
+				continue
+			}
+
+			for _, problem := range a.problems(ctx, ns, fqdn, gwResource.Message.(*v1alpha3.Gateway), fromSidecars,
+				routeDestinations(vs, gwName)) {
+				m := msg.NewIncompleteEgressGatewayRoute(r, h, gwName, problem)
+				ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), util.AtField(m, fmt.Sprintf("spec.hosts[%d]", i)))
+			}
+		}
+	}
+}
+
+// problems returns what is missing for traffic to a host to go through a gateway.
+func (a *EgressGatewayAnalyzer) problems(ctx analysis.Context, ns resource.Namespace, fqdn string, gw *v1alpha3.Gateway,
+	fromSidecars, fromGateway []*v1alpha3.Destination) []string {
+
+	var problems []string
+
+	server := acceptingServer(gw, fqdn)
+	if server == nil {
+		problems = append(problems, "the gateway does not accept the host")
+	}
+
+	var toGateway []*v1alpha3.Destination
+	for _, d := range fromSidecars {
+		if util.ConvertHostToFQDN(ns, d.GetHost()) != fqdn {
+			toGateway = append(toGateway, d)
+		}
+	}
+	if len(toGateway) == 0 {
+		problems = append(problems, "no route sends the traffic from the sidecars to the gateway")
+	}
+
+	toHost := false
+	for _, d := range fromGateway {
+		if util.ConvertHostToFQDN(ns, d.GetHost()) == fqdn {
+			toHost = true
+		}
+	}
+	if !toHost {
+		problems = append(problems, "no route sends the traffic from the gateway to the host")
+	}
+
+	if server != nil && server.GetTls().GetMode() == v1alpha3.Server_TLSOptions_ISTIO_MUTUAL {
+		for _, d := range toGateway {
+			if !enablesIstioMutual(ctx, util.ConvertHostToFQDN(ns, d.GetHost())) {
+				problems = append(problems, fmt.Sprintf("the gateway requires ISTIO_MUTUAL TLS but no destination rule enables it for %s", d.GetHost()))
+			}
+		}
+	}
+
+	return problems
+}
+
+// routeDestinations returns the destinations of the routes of a virtual service which apply to a gateway.
+func routeDestinations(vs *v1alpha3.VirtualService, gateway string) []*v1alpha3.Destination {
+	var result []*v1alpha3.Destination
+
+	for _, route := range vs.GetHttp() {
+		matches := len(route.GetMatch()) == 0
+		for _, m := range route.GetMatch() {
+			matches = matches || appliesTo(m.GetGateways(), gateway)
+		}
+		if matches {
+			for _, rd := range route.GetRoute() {
+				result = append(result, rd.GetDestination())
+			}
+		}
+	}
+	for _, route := range vs.GetTls() {
+		matches := len(route.GetMatch()) == 0
+		for _, m := range route.GetMatch() {
+			matches = matches || appliesTo(m.GetGateways(), gateway)
+		}
+		if matches {
+			for _, rd := range route.GetRoute() {
+				result = append(result, rd.GetDestination())
+			}
+		}
+	}
+	for _, route := range vs.GetTcp() {
+		matches := len(route.GetMatch()) == 0
+		for _, m := range route.GetMatch() {
+			matches = matches || appliesTo(m.GetGateways(), gateway)
+		}
+		if matches {
+			for _, rd := range route.GetRoute() {
+				result = append(result, rd.GetDestination())
+			}
+		}
+	}
+
+	return result
+}
+
+// appliesTo returns true if a route match restricted to some gateways applies to a gateway. Matches which
+// don't list any gateway apply to all the gateways of the virtual service.
+func appliesTo(gateways []string, gateway string) bool {
+	if len(gateways) == 0 {
+		return true
+	}
+	for _, gw := range gateways {
+		if gw == gateway {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptingServer returns the server of a gateway accepting a host, if any.
+func acceptingServer(gw *v1alpha3.Gateway, fqdn string) *v1alpha3.Server {
+	for _, s := range gw.GetServers() {
+		for _, h := range s.GetHosts() {
+			// Hosts can be scoped to the namespace of the virtual services: "<namespace>/<host>"
+			if i := strings.Index(h, "/"); i >= 0 {
+				h = h[i+1:]
+			}
+			if util.MatchesHost(h, fqdn) {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// enablesIstioMutual returns true if a destination rule for a host enables ISTIO_MUTUAL TLS.
+func enablesIstioMutual(ctx analysis.Context, fqdn string) bool {
+	found := false
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		dr := r.Message.(*v1alpha3.DestinationRule)
+		if util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, dr.GetHost()) != fqdn {
+			return true
+		}
+
+		policies := []*v1alpha3.TrafficPolicy{dr.GetTrafficPolicy()}
+		for _, ss := range dr.GetSubsets() {
+			policies = append(policies, ss.GetTrafficPolicy())
+		}
+		for _, p := range policies {
+			if p.GetTls().GetMode() == v1alpha3.TLSSettings_ISTIO_MUTUAL {
+				found = true
+			}
+			for _, pls := range p.GetPortLevelSettings() {
+				if pls.GetTls().GetMode() == v1alpha3.TLSSettings_ISTIO_MUTUAL {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
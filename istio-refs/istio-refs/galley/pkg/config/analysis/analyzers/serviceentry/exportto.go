@@ -0,0 +1,91 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// ExportToAnalyzer checks that the hosts of destination rules and virtual services declared by ServiceEntries
+// are exported to the namespace of the destination rule or virtual service.
+type ExportToAnalyzer struct{}
+
+var _ analysis.Analyzer = &ExportToAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *ExportToAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "serviceentry.ExportToAnalyzer",
+		Description: "Checks that the ServiceEntries declaring the hosts of destination rules and virtual services " +
+			"are exported to their namespace",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+			collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+			collections.K8SCoreV1Services.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *ExportToAnalyzer) Analyze(ctx analysis.Context) {
+	serviceEntries := serviceEntryHosts(ctx)
+
+	// Kubernetes services are visible in all namespaces
+	services := make(map[string]bool)
+	ctx.ForEach(collections.K8SCoreV1Services.Name(), func(r *resource.Instance) bool {
+		services[util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, r.Metadata.FullName.Name.String())] = true
+		return true
+	})
+
+	check := func(col collection.Name, r *resource.Instance, host, path string) {
+		ns := r.Metadata.FullName.Namespace
+		fqdn := util.ConvertHostToFQDN(ns, host)
+		if strings.HasPrefix(fqdn, util.Wildcard) || services[fqdn] || isVisible(serviceEntries, ns, fqdn) {
+			return
+		}
+		for scopedFqdn, se := range serviceEntries {
+			if _, seHost := scopedFqdn.GetScopeAndFqdn(); seHost == fqdn {
+				m := msg.NewServiceEntryNotExported(r, host, se.Metadata.FullName.String(), ns.String())
+				ctx.Report(col, util.AtField(m, path))
+				return
+			}
+		}
+	}
+
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		dr := r.Message.(*v1alpha3.DestinationRule)
+		check(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), r, dr.GetHost(), "spec.host")
+		return true
+	})
+
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), func(r *resource.Instance) bool {
+		vs := r.Message.(*v1alpha3.VirtualService)
+		for i, h := range vs.GetHosts() {
+			check(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), r, h, fmt.Sprintf("spec.hosts[%d]", i))
+		}
+		return true
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"fmt"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// HostConflictAnalyzer checks for hosts declared by several ServiceEntries visible in the same namespaces,
+// with conflicting ports or resolution.
+type HostConflictAnalyzer struct{}
+
+var _ analysis.Analyzer = &HostConflictAnalyzer{}
+
+// hostDeclaration is a host of a ServiceEntry, along with the index of its field.
+type hostDeclaration struct {
+	r     *resource.Instance
+	index int
+}
+
+// Metadata implements Analyzer
+func (a *HostConflictAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "serviceentry.HostConflictAnalyzer",
+		Description: "Checks for hosts declared by several ServiceEntries visible in the same namespaces, " +
+			"with conflicting ports or resolution",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *HostConflictAnalyzer) Analyze(ctx analysis.Context) {
+	declarations := make(map[string][]hostDeclaration)
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+		for i, h := range se.GetHosts() {
+			fqdn := util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, h)
+			declarations[fqdn] = append(declarations[fqdn], hostDeclaration{r: r, index: i})
+		}
+		return true
+	})
+
+	for host, ds := range declarations {
+		for i, d := range ds {
+			for j, other := range ds {
+				if i == j || d.r == other.r || !visibleTogether(d.r, other.r) {
+					continue
+				}
+				for _, field := range conflictingFields(d.r.Message.(*v1alpha3.ServiceEntry), other.r.Message.(*v1alpha3.ServiceEntry)) {
+					m := msg.NewConflictingServiceEntryHosts(d.r, host, other.r.Metadata.FullName.String(), field)
+					ctx.Report(collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+						util.AtField(m, fmt.Sprintf("spec.hosts[%d]", d.index)))
+				}
+			}
+		}
+	}
+}
+
+// visibleTogether returns true if some namespace sees the hosts of both ServiceEntries.
+func visibleTogether(a, b *resource.Instance) bool {
+	return a.Metadata.FullName.Namespace == b.Metadata.FullName.Namespace ||
+		util.IsExportToAllNamespaces(a.Message.(*v1alpha3.ServiceEntry).GetExportTo()) ||
+		util.IsExportToAllNamespaces(b.Message.(*v1alpha3.ServiceEntry).GetExportTo())
+}
+
+// conflictingFields returns the fields of two ServiceEntries which can't both apply to a host.
+func conflictingFields(a, b *v1alpha3.ServiceEntry) []string {
+	var fields []string
+	if a.GetResolution() != b.GetResolution() {
+		fields = append(fields, "resolution")
+	}
+
+	protocols := make(map[uint32]string)
+	for _, p := range b.GetPorts() {
+		protocols[p.GetNumber()] = p.GetProtocol()
+	}
+	for _, p := range a.GetPorts() {
+		if other, ok := protocols[p.GetNumber()]; ok && other != p.GetProtocol() {
+			fields = append(fields, fmt.Sprintf("protocol for port %d", p.GetNumber()))
+		}
+	}
+	return fields
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// serviceEntryHosts maps the hosts of all ServiceEntries, scoped by the namespaces they are exported to,
+// to the ServiceEntry declaring them.
+func serviceEntryHosts(ctx analysis.Context) map[util.ScopedFqdn]*resource.Instance {
+	result := make(map[util.ScopedFqdn]*resource.Instance)
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+		scope := string(r.Metadata.FullName.Namespace)
+		if util.IsExportToAllNamespaces(se.GetExportTo()) {
+			scope = util.ExportToAllNamespaces
+		}
+		for _, h := range se.GetHosts() {
+			result[util.NewScopedFqdn(scope, r.Metadata.FullName.Namespace, h)] = r
+		}
+		return true
+	})
+	return result
+}
+
+// isVisible returns true if a ServiceEntry visible in a namespace declares a host, directly or with a wildcard.
+func isVisible(serviceEntries map[util.ScopedFqdn]*resource.Instance, ns resource.Namespace, fqdn string) bool {
+	for scopedFqdn := range serviceEntries {
+		scope, seHost := scopedFqdn.GetScopeAndFqdn()
+		if scope != util.ExportToAllNamespaces && scope != ns.String() {
+			continue
+		}
+		if util.MatchesHost(seHost, fqdn) {
+			return true
+		}
+	}
+	return false
+}
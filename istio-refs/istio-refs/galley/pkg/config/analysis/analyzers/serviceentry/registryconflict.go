@@ -0,0 +1,67 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// RegistryConflictAnalyzer checks for ServiceEntry hosts that are also provided by a Kubernetes service.
+// Pilot resolves such hosts according to its registry conflict policy, which is easy to get wrong.
+type RegistryConflictAnalyzer struct{}
+
+var _ analysis.Analyzer = &RegistryConflictAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *RegistryConflictAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "serviceentry.RegistryConflictAnalyzer",
+		Description: "Checks for ServiceEntry hosts that are also provided by a Kubernetes service",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+			collections.K8SCoreV1Services.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *RegistryConflictAnalyzer) Analyze(ctx analysis.Context) {
+	// Map each Kubernetes service FQDN to the name of the service
+	services := make(map[string]resource.FullName)
+	ctx.ForEach(collections.K8SCoreV1Services.Name(), func(r *resource.Instance) bool {
+		fqdn := util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, r.Metadata.FullName.Name.String())
+		services[fqdn] = r.Metadata.FullName
+		return true
+	})
+
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+		// ServiceEntry hosts are used as-is by Pilot, so only fully qualified hosts can conflict
+		for _, h := range se.GetHosts() {
+			if svc, ok := services[h]; ok {
+				ctx.Report(collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+					msg.NewServiceEntryHostConflictsWithService(r, h, svc.String()))
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// ResolutionAnalyzer checks that the resolution of ServiceEntries suits their hosts and ports:
+// * wildcard hosts can't be resolved with DNS
+// * without addresses, TCP ports of ServiceEntries with resolution NONE match all the traffic to the port
+type ResolutionAnalyzer struct{}
+
+var _ analysis.Analyzer = &ResolutionAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *ResolutionAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "serviceentry.ResolutionAnalyzer",
+		Description: "Checks that the resolution of ServiceEntries suits their hosts and ports",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *ResolutionAnalyzer) Analyze(ctx analysis.Context) {
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+
+		switch se.GetResolution() {
+		case v1alpha3.ServiceEntry_DNS:
+			for i, h := range se.GetHosts() {
+				if strings.HasPrefix(h, util.Wildcard) {
+					m := msg.NewServiceEntryWildcardHostDNSResolution(r, h)
+					ctx.Report(collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+						util.AtField(m, fmt.Sprintf("spec.hosts[%d]", i)))
+				}
+			}
+		case v1alpha3.ServiceEntry_NONE:
+			if len(se.GetAddresses()) > 0 {
+				break
+			}
+			// Plain TCP can only be told apart by destination address, unlike HTTP (authority) and TLS (SNI)
+			for i, p := range se.GetPorts() {
+				proto := protocol.Parse(p.GetProtocol())
+				if proto.IsTCP() && !proto.IsTLS() {
+					m := msg.NewServiceEntryTCPPortWithoutAddresses(r, int(p.GetNumber()), p.GetProtocol())
+					ctx.Report(collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+						util.AtField(m, fmt.Sprintf("spec.ports[%d]", i)))
+				}
+			}
+		}
+		return true
+	})
+}
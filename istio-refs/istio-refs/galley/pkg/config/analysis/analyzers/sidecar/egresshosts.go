@@ -0,0 +1,225 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sidecar
+
+import (
+	"strings"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// EgressHostsAnalyzer checks that the egress hosts of sidecar resources don't hide from the workloads they
+// select the hosts the resources of their namespace refer to:
+// * the hosts of virtual services bound to the mesh, whose routes are otherwise ignored
+// * the hosts of service entries
+// * the hosts of destination rules, which are the services the workloads depend on
+// Destinations of the imported virtual services aren't checked, as Pilot adds them to the scope of the sidecar.
+// Sidecars with a workload selector are skipped, as the resources of the namespace aren't necessarily used by
+// the workloads they select.
+type EgressHostsAnalyzer struct{}
+
+var _ analysis.Analyzer = &EgressHostsAnalyzer{}
+
+// egressHost is an egress host of a sidecar, in the "namespace/dnsName" format.
+type egressHost struct {
+	namespace string
+	host      string
+}
+
+// egressScope is the set of hosts reachable by the workloads selected by a sidecar.
+type egressScope struct {
+	namespace resource.Namespace
+	hosts     []egressHost
+}
+
+// Metadata implements Analyzer
+func (a *EgressHostsAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "sidecar.EgressHostsAnalyzer",
+		Description: "Checks that the egress hosts of sidecars don't hide the hosts that the virtual services, " +
+			"service entries and destination rules of their namespace refer to",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+			collections.IstioNetworkingV1Alpha3Sidecars.Name(),
+			collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+			collections.K8SCoreV1Services.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *EgressHostsAnalyzer) Analyze(c analysis.Context) {
+	c.ForEach(collections.IstioNetworkingV1Alpha3Sidecars.Name(), func(r *resource.Instance) bool {
+		s := r.Message.(*v1alpha3.Sidecar)
+
+		// Without egress listeners, all the hosts of the mesh are reachable
+		if len(s.GetEgress()) == 0 {
+			return true
+		}
+		if s.GetWorkloadSelector() != nil {
+			return true
+		}
+
+		scope := newEgressScope(r.Metadata.FullName.Namespace, s)
+		a.analyzeVirtualServices(c, r, scope)
+		a.analyzeServiceEntries(c, r, scope)
+		a.analyzeDestinationRules(c, r, scope)
+		return true
+	})
+}
+
+func newEgressScope(ns resource.Namespace, s *v1alpha3.Sidecar) *egressScope {
+	scope := &egressScope{namespace: ns}
+	for _, e := range s.GetEgress() {
+		for _, h := range e.GetHosts() {
+			parts := strings.SplitN(h, "/", 2)
+			if len(parts) != 2 {
+				// Invalid, reported by validation
+				continue
+			}
+			scope.hosts = append(scope.hosts, egressHost{namespace: parts[0], host: parts[1]})
+		}
+	}
+	return scope
+}
+
+// reaches returns true if a host declared by config in a namespace is in the scope.
+func (s *egressScope) reaches(configNamespace resource.Namespace, fqdn string) bool {
+	for _, h := range s.hosts {
+		switch h.namespace {
+		case util.Wildcard:
+		case util.ExportToNamespaceLocal:
+			if configNamespace != s.namespace {
+				continue
+			}
+		default:
+			if resource.Namespace(h.namespace) != configNamespace {
+				continue
+			}
+		}
+		if util.MatchesHost(h.host, fqdn) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *EgressHostsAnalyzer) analyzeVirtualServices(c analysis.Context, r *resource.Instance, scope *egressScope) {
+	c.ForEach(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), func(rv *resource.Instance) bool {
+		ns := rv.Metadata.FullName.Namespace
+		if ns != scope.namespace {
+			return true
+		}
+		vs := rv.Message.(*v1alpha3.VirtualService)
+		if !isBoundToMesh(vs) {
+			return true
+		}
+
+		// Pilot imports a virtual service as soon as one of its hosts is in the scope
+		for _, h := range vs.GetHosts() {
+			if scope.reaches(ns, util.ConvertHostToFQDN(ns, h)) {
+				return true
+			}
+		}
+		for _, h := range vs.GetHosts() {
+			m := msg.NewHostHiddenBySidecar(r, h, "VirtualService", rv.Metadata.FullName.Name.String())
+			c.Report(collections.IstioNetworkingV1Alpha3Sidecars.Name(), util.AtField(m, "spec.egress"))
+		}
+		return true
+	})
+}
+
+func (a *EgressHostsAnalyzer) analyzeServiceEntries(c analysis.Context, r *resource.Instance, scope *egressScope) {
+	c.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(rs *resource.Instance) bool {
+		ns := rs.Metadata.FullName.Namespace
+		if ns != scope.namespace {
+			return true
+		}
+		se := rs.Message.(*v1alpha3.ServiceEntry)
+		for _, h := range se.GetHosts() {
+			if !scope.reaches(ns, util.ConvertHostToFQDN(ns, h)) {
+				m := msg.NewHostHiddenBySidecar(r, h, "ServiceEntry", rs.Metadata.FullName.Name.String())
+				c.Report(collections.IstioNetworkingV1Alpha3Sidecars.Name(), util.AtField(m, "spec.egress"))
+			}
+		}
+		return true
+	})
+}
+
+func (a *EgressHostsAnalyzer) analyzeDestinationRules(c analysis.Context, r *resource.Instance, scope *egressScope) {
+	c.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(rd *resource.Instance) bool {
+		if rd.Metadata.FullName.Namespace != scope.namespace {
+			return true
+		}
+		dr := rd.Message.(*v1alpha3.DestinationRule)
+		fqdn := util.ConvertHostToFQDN(scope.namespace, dr.GetHost())
+
+		// Services are in the scope of a sidecar based on the namespace declaring them
+		svcNamespace, found := serviceNamespace(c, scope.namespace, dr.GetHost(), fqdn)
+		if !found || scope.reaches(svcNamespace, fqdn) {
+			return true
+		}
+		m := msg.NewHostHiddenBySidecar(r, dr.GetHost(), "DestinationRule", rd.Metadata.FullName.Name.String())
+		c.Report(collections.IstioNetworkingV1Alpha3Sidecars.Name(), util.AtField(m, "spec.egress"))
+		return true
+	})
+}
+
+// serviceNamespace returns the namespace of the Kubernetes service or of a service entry declaring a host.
+func serviceNamespace(c analysis.Context, ns resource.Namespace, host, fqdn string) (resource.Namespace, bool) {
+	svcName := util.GetResourceNameFromHost(ns, host)
+	if c.Exists(collections.K8SCoreV1Services.Name(), svcName) {
+		return svcName.Namespace, true
+	}
+
+	var result resource.Namespace
+	found := false
+	c.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+		if r.Metadata.FullName.Namespace != ns && !util.IsExportToAllNamespaces(se.GetExportTo()) {
+			return true
+		}
+		for _, h := range se.GetHosts() {
+			if util.MatchesHost(util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, h), fqdn) {
+				result = r.Metadata.FullName.Namespace
+				found = true
+				// Prefer the service entries of the namespace, as Pilot does
+				return r.Metadata.FullName.Namespace != ns
+			}
+		}
+		return true
+	})
+	return result, found
+}
+
+func isBoundToMesh(vs *v1alpha3.VirtualService) bool {
+	if len(vs.GetGateways()) == 0 {
+		return true
+	}
+	for _, gw := range vs.GetGateways() {
+		if gw == util.MeshGateway {
+			return true
+		}
+	}
+	return false
+}
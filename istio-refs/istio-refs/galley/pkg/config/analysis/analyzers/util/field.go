@@ -0,0 +1,35 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+)
+
+// AtField sets the path of the field a message is about, e.g. "spec.http[0].route[0].destination.host".
+// If the resource of the message was read from a file, the line of the field is set as well.
+func AtField(m diag.Message, path string) diag.Message {
+	m.Field = path
+	if m.Resource == nil {
+		return m
+	}
+	if o, ok := m.Resource.Origin.(*rt.Origin); ok {
+		if line, found := o.FieldLine(path); found {
+			m.Line = line
+		}
+	}
+	return m
+}
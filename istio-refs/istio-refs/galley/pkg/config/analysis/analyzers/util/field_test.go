@@ -0,0 +1,50 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/pkg/config/resource"
+)
+
+func TestAtField(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	mt := diag.NewMessageType(diag.Error, "IST-0042", "Cheese type not found: %q")
+	r := &resource.Instance{Origin: &rt.Origin{
+		Kind:     "Topping",
+		FullName: resource.NewFullName("default", "cheese"),
+		Ref:      &rt.Position{Filename: "toppings.yaml", Line: 3},
+		FieldMap: map[string]int{"spec.cheese[0].type": 8},
+	}}
+
+	m := AtField(diag.NewMessage(mt, r, "Feta"), "spec.cheese[0].type")
+	g.Expect(m.Field).To(Equal("spec.cheese[0].type"))
+	g.Expect(m.Line).To(Equal(8))
+	g.Expect(m.Location()).To(Equal("toppings.yaml:8"))
+
+	m = AtField(diag.NewMessage(mt, r, "Feta"), "spec.sauce")
+	g.Expect(m.Field).To(Equal("spec.sauce"))
+	g.Expect(m.Line).To(Equal(0))
+	g.Expect(m.Location()).To(Equal("toppings.yaml:3"))
+
+	m = AtField(diag.NewMessage(mt, nil, "Feta"), "spec.cheese")
+	g.Expect(m.Field).To(Equal("spec.cheese"))
+}
@@ -76,3 +76,11 @@ func ConvertHostToFQDN(namespace resource.Namespace, host string) string {
 	}
 	return fqdn
 }
+
+// MatchesHost returns true if a host is matched by a pattern, which may be a wildcard ("*<dns suffix>").
+func MatchesHost(pattern, host string) bool {
+	if strings.HasPrefix(pattern, Wildcard) {
+		return strings.HasSuffix(host, strings.TrimPrefix(pattern, Wildcard))
+	}
+	return pattern == host
+}
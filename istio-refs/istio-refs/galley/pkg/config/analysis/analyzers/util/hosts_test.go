@@ -82,3 +82,13 @@ func TestScopedFqdn_GetScopeAndFqdn(t *testing.T) {
 	g.Expect(ns).To(Equal("foo"))
 	g.Expect(fqdn).To(Equal("*.xyz.abc"))
 }
+
+func TestMatchesHost(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(MatchesHost("foo.com", "foo.com")).To(BeTrue())
+	g.Expect(MatchesHost("foo.com", "bar.foo.com")).To(BeFalse())
+	g.Expect(MatchesHost("*.foo.com", "bar.foo.com")).To(BeTrue())
+	g.Expect(MatchesHost("*.foo.com", "foo.com")).To(BeFalse())
+	g.Expect(MatchesHost("*", "foo.com")).To(BeTrue())
+}
@@ -69,14 +69,15 @@ func (a *DestinationHostAnalyzer) analyzeVirtualService(r *resource.Instance, ct
 
 	vs := r.Message.(*v1alpha3.VirtualService)
 
-	for _, d := range getRouteDestinations(vs) {
+	for _, rd := range getRouteDestinations(vs) {
+		d := rd.destination
 		s := getDestinationHost(r.Metadata.FullName.Namespace, d.GetHost(), serviceEntryHosts)
 		if s == nil {
 			ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
-				msg.NewReferencedResourceNotFound(r, "host", d.GetHost()))
+				util.AtField(msg.NewReferencedResourceNotFound(r, "host", d.GetHost()), rd.path+".host"))
 			continue
 		}
-		checkServiceEntryPorts(ctx, r, d, s)
+		checkServiceEntryPorts(ctx, r, rd, s)
 	}
 }
 
@@ -164,7 +165,8 @@ func initServiceEntryHostMap(ctx analysis.Context) map[util.ScopedFqdn]*v1alpha3
 	return result
 }
 
-func checkServiceEntryPorts(ctx analysis.Context, r *resource.Instance, d *v1alpha3.Destination, s *v1alpha3.ServiceEntry) {
+func checkServiceEntryPorts(ctx analysis.Context, r *resource.Instance, rd routeDestination, s *v1alpha3.ServiceEntry) {
+	d := rd.destination
 	if d.GetPort() == nil {
 		// If destination port isn't specified, it's only a problem if the service being referenced exposes multiple ports.
 		if len(s.GetPorts()) > 1 {
@@ -173,7 +175,7 @@ func checkServiceEntryPorts(ctx analysis.Context, r *resource.Instance, d *v1alp
 				portNumbers = append(portNumbers, int(p.GetNumber()))
 			}
 			ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
-				msg.NewVirtualServiceDestinationPortSelectorRequired(r, d.GetHost(), portNumbers))
+				util.AtField(msg.NewVirtualServiceDestinationPortSelectorRequired(r, d.GetHost(), portNumbers), rd.path))
 			return
 		}
 
@@ -190,6 +192,7 @@ func checkServiceEntryPorts(ctx analysis.Context, r *resource.Instance, d *v1alp
 	}
 	if !foundPort {
 		ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
-			msg.NewReferencedResourceNotFound(r, "host:port", fmt.Sprintf("%s:%d", d.GetHost(), d.GetPort().GetNumber())))
+			util.AtField(msg.NewReferencedResourceNotFound(r, "host:port", fmt.Sprintf("%s:%d", d.GetHost(), d.GetPort().GetNumber())),
+				rd.path+".port.number"))
 	}
 }
@@ -63,10 +63,11 @@ func (d *DestinationRuleAnalyzer) analyzeVirtualService(r *resource.Instance, ct
 
 	destinations := getRouteDestinations(vs)
 
-	for _, destination := range destinations {
+	for _, rd := range destinations {
+		destination := rd.destination
 		if !d.checkDestinationSubset(ns, destination, destHostsAndSubsets) {
-			ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
-				msg.NewReferencedResourceNotFound(r, "host+subset in destinationrule", fmt.Sprintf("%s+%s", destination.GetHost(), destination.GetSubset())))
+			m := msg.NewReferencedResourceNotFound(r, "host+subset in destinationrule", fmt.Sprintf("%s+%s", destination.GetHost(), destination.GetSubset()))
+			ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), util.AtField(m, rd.path+".subset"))
 		}
 	}
 }
@@ -14,30 +14,50 @@
 package virtualservice
 
 import (
+	"fmt"
+
 	"istio.io/api/networking/v1alpha3"
 )
 
-func getRouteDestinations(vs *v1alpha3.VirtualService) []*v1alpha3.Destination {
-	destinations := make([]*v1alpha3.Destination, 0)
+// routeDestination is a destination of a virtual service route, along with the path of its field.
+type routeDestination struct {
+	destination *v1alpha3.Destination
+	path        string
+}
+
+func getRouteDestinations(vs *v1alpha3.VirtualService) []routeDestination {
+	destinations := make([]routeDestination, 0)
 
-	for _, r := range vs.GetTcp() {
-		for _, rd := range r.GetRoute() {
-			destinations = append(destinations, rd.GetDestination())
+	for i, r := range vs.GetTcp() {
+		for j, rd := range r.GetRoute() {
+			destinations = append(destinations, routeDestination{
+				destination: rd.GetDestination(),
+				path:        fmt.Sprintf("spec.tcp[%d].route[%d].destination", i, j),
+			})
 		}
 	}
-	for _, r := range vs.GetTls() {
-		for _, rd := range r.GetRoute() {
-			destinations = append(destinations, rd.GetDestination())
+	for i, r := range vs.GetTls() {
+		for j, rd := range r.GetRoute() {
+			destinations = append(destinations, routeDestination{
+				destination: rd.GetDestination(),
+				path:        fmt.Sprintf("spec.tls[%d].route[%d].destination", i, j),
+			})
 		}
 	}
-	for _, r := range vs.GetHttp() {
-		for _, rd := range r.GetRoute() {
-			destinations = append(destinations, rd.GetDestination())
+	for i, r := range vs.GetHttp() {
+		for j, rd := range r.GetRoute() {
+			destinations = append(destinations, routeDestination{
+				destination: rd.GetDestination(),
+				path:        fmt.Sprintf("spec.http[%d].route[%d].destination", i, j),
+			})
 		}
 		// If there is a mirror destination, check it too
 		m := r.GetMirror()
 		if m != nil {
-			destinations = append(destinations, m)
+			destinations = append(destinations, routeDestination{
+				destination: m,
+				path:        fmt.Sprintf("spec.http[%d].mirror", i),
+			})
 		}
 	}
 
@@ -0,0 +1,87 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diag
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// JSON patch operations used by fixes.
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchMove    = "move"
+)
+
+// Fix is a machine applicable change to the resource of a message, resolving the reported issue.
+type Fix struct {
+	// Description is a short human readable summary of the change, e.g. "Rename port foo to tcp-foo".
+	Description string `json:"description"`
+
+	// Patch is the change, as a JSON patch (RFC 6902) on the resource.
+	Patch []PatchOperation `json:"patch"`
+}
+
+// PatchOperation is a single JSON patch operation.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON serializes the value of add and replace operations only, as RFC 6902 doesn't define one for the
+// other operations.
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	if o.Op == PatchAdd || o.Op == PatchReplace {
+		type operation PatchOperation
+		return json.Marshal(operation(o))
+	}
+	return json.Marshal(struct {
+		Op   string `json:"op"`
+		Path string `json:"path"`
+		From string `json:"from,omitempty"`
+	}{o.Op, o.Path, o.From})
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// JSONPointer returns the JSON pointer (RFC 6901) made of the given reference tokens, e.g.
+// JSONPointer("metadata", "annotations", "sidecar.istio.io/inject") is
+// "/metadata/annotations/sidecar.istio.io~1inject".
+func JSONPointer(tokens ...string) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString("/")
+		b.WriteString(pointerEscaper.Replace(t))
+	}
+	return b.String()
+}
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// ParseJSONPointer returns the reference tokens of a JSON pointer. It is the inverse of JSONPointer.
+func ParseJSONPointer(p string) []string {
+	if p == "" {
+		return nil
+	}
+	tokens := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for i, t := range tokens {
+		tokens[i] = pointerUnescaper.Replace(t)
+	}
+	return tokens
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diag
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestJSONPointer(t *testing.T) {
+	cases := []struct {
+		tokens  []string
+		pointer string
+	}{
+		{nil, ""},
+		{[]string{"spec", "ports", "0", "name"}, "/spec/ports/0/name"},
+		{[]string{"metadata", "annotations", "sidecar.istio.io/inject"}, "/metadata/annotations/sidecar.istio.io~1inject"},
+		{[]string{"a~b", "~1"}, "/a~0b/~01"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pointer, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(JSONPointer(c.tokens...)).To(Equal(c.pointer))
+			g.Expect(ParseJSONPointer(c.pointer)).To(Equal(c.tokens))
+		})
+	}
+}
+
+func TestPatchOperation_MarshalJSON(t *testing.T) {
+	cases := []struct {
+		op   PatchOperation
+		json string
+	}{
+		{PatchOperation{Op: PatchAdd, Path: "/a", Value: "b"}, `{"op":"add","path":"/a","value":"b"}`},
+		{PatchOperation{Op: PatchReplace, Path: "/a"}, `{"op":"replace","path":"/a","value":null}`},
+		{PatchOperation{Op: PatchRemove, Path: "/a"}, `{"op":"remove","path":"/a"}`},
+		{PatchOperation{Op: PatchMove, Path: "/a", From: "/b"}, `{"op":"move","path":"/a","from":"/b"}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.json, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			b, err := json.Marshal(c.op)
+			g.Expect(err).To(BeNil())
+			g.Expect(string(b)).To(Equal(c.json))
+		})
+	}
+}
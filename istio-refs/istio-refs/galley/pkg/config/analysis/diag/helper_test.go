@@ -15,6 +15,8 @@
 package diag
 
 import (
+	"fmt"
+
 	"istio.io/istio/pkg/config/resource"
 )
 
@@ -29,3 +31,39 @@ func (o testOrigin) FriendlyName() string {
 func (o testOrigin) Namespace() resource.Namespace {
 	return ""
 }
+
+func (o testOrigin) Reference() resource.Reference {
+	return nil
+}
+
+var _ resource.Origin = testOriginWithReference{}
+
+type testOriginWithReference struct {
+	name string
+	ref  resource.Reference
+}
+
+func (o testOriginWithReference) FriendlyName() string {
+	return o.name
+}
+
+func (o testOriginWithReference) Namespace() resource.Namespace {
+	return ""
+}
+
+func (o testOriginWithReference) Reference() resource.Reference {
+	return o.ref
+}
+
+type testPosition struct {
+	file string
+	line int
+}
+
+func (p testPosition) String() string {
+	return fmt.Sprintf("%s:%d", p.file, p.line)
+}
+
+func (p testPosition) WithLine(line int) resource.Reference {
+	return testPosition{file: p.file, line: line}
+}
@@ -58,6 +58,36 @@ type Message struct {
 
 	// DocRef is an optional reference tracker for the documentation URL
 	DocRef string
+
+	// Field is the optional path of the field the message is about, e.g. "spec.http[0].route[0].destination.host".
+	Field string
+
+	// Line is the line of Field in the source of the resource, or 0 if not known.
+	Line int
+
+	// Fix is an optional change to the resource resolving the issue.
+	Fix *Fix
+}
+
+// lineReference is implemented by references that can point at other lines of the same source.
+type lineReference interface {
+	WithLine(line int) resource.Reference
+}
+
+// Location returns the location the message refers to in the source of its resource (e.g. "vs.yaml:14"),
+// or an empty string if not known.
+func (m *Message) Location() string {
+	if m.Resource == nil || m.Resource.Origin == nil {
+		return ""
+	}
+	ref := m.Resource.Origin.Reference()
+	if ref == nil {
+		return ""
+	}
+	if lr, ok := ref.(lineReference); ok && m.Line > 0 {
+		ref = lr.WithLine(m.Line)
+	}
+	return ref.String()
 }
 
 // Unstructured returns this message as a JSON-style unstructured map
@@ -68,6 +98,15 @@ func (m *Message) Unstructured(includeOrigin bool) map[string]interface{} {
 	result["level"] = m.Type.Level().String()
 	if includeOrigin && m.Resource != nil {
 		result["origin"] = m.Resource.Origin.FriendlyName()
+		if loc := m.Location(); loc != "" {
+			result["reference"] = loc
+		}
+	}
+	if m.Field != "" {
+		result["field"] = m.Field
+	}
+	if m.Fix != nil {
+		result["fix"] = m.Fix
 	}
 	result["message"] = fmt.Sprintf(m.Type.Template(), m.Parameters...)
 
@@ -84,7 +123,11 @@ func (m *Message) Unstructured(includeOrigin bool) map[string]interface{} {
 func (m *Message) String() string {
 	origin := ""
 	if m.Resource != nil {
-		origin = "(" + m.Resource.Origin.FriendlyName() + ")"
+		origin = "(" + m.Resource.Origin.FriendlyName()
+		if loc := m.Location(); loc != "" {
+			origin += " " + loc
+		}
+		origin += ")"
 	}
 	return fmt.Sprintf(
 		"%v [%v]%s %s", m.Type.Level(), m.Type.Code(), origin, fmt.Sprintf(m.Type.Template(), m.Parameters...))
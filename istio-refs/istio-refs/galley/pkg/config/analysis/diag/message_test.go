@@ -70,3 +70,41 @@ func TestMessage_JSON(t *testing.T) {
 	g.Expect(string(j)).To(Equal(`{"code":"IST-0042","documentation_url":"https://istio.io/docs/reference/config/analysis/IST-0042"` +
 		`,"level":"Error","message":"Cheese type not found: \"Feta\"","origin":"toppings/cheese"}`))
 }
+
+func TestMessage_Location(t *testing.T) {
+	g := NewGomegaWithT(t)
+	mt := NewMessageType(Error, "IST-0042", "Cheese type not found: %q")
+	r := &resource.Instance{Origin: testOriginWithReference{
+		name: "toppings/cheese",
+		ref:  testPosition{file: "cheese.yaml", line: 3},
+	}}
+	m := NewMessage(mt, r, "Feta")
+
+	g.Expect(m.Location()).To(Equal("cheese.yaml:3"))
+	g.Expect(m.String()).To(Equal(`Error [IST-0042](toppings/cheese cheese.yaml:3) Cheese type not found: "Feta"`))
+
+	m.Field = "spec.cheese"
+	m.Line = 7
+	g.Expect(m.Location()).To(Equal("cheese.yaml:7"))
+	g.Expect(m.Unstructured(true)).To(HaveKeyWithValue("reference", "cheese.yaml:7"))
+	g.Expect(m.Unstructured(true)).To(HaveKeyWithValue("field", "spec.cheese"))
+
+	m = NewMessage(mt, &resource.Instance{Origin: testOrigin("toppings/cheese")}, "Feta")
+	g.Expect(m.Location()).To(BeEmpty())
+	g.Expect(m.Unstructured(true)).NotTo(HaveKey("reference"))
+}
+
+func TestMessage_UnstructuredFix(t *testing.T) {
+	g := NewGomegaWithT(t)
+	mt := NewMessageType(Error, "IST-0042", "Cheese type not found: %q")
+	m := NewMessage(mt, nil, "Feta")
+	g.Expect(m.Unstructured(true)).To(Not(HaveKey("fix")))
+
+	m.Fix = &Fix{
+		Description: "Use Gouda",
+		Patch:       []PatchOperation{{Op: PatchReplace, Path: "/spec/cheese", Value: "Gouda"}},
+	}
+	j, _ := json.Marshal(&m)
+	g.Expect(string(j)).To(ContainSubstring(
+		`"fix":{"description":"Use Gouda","patch":[{"op":"replace","path":"/spec/cheese","value":"Gouda"}]}`))
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"fmt"
+
+	celgo "github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/scope"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+// celResourceVariable is the name of the variable holding the analyzed resource in CEL expressions.
+const celResourceVariable = "resource"
+
+// celAnalyzer reports a message on each resource of its inputs for which a CEL expression is false.
+type celAnalyzer struct {
+	metadata    analysis.Metadata
+	messageType *diag.MessageType
+	message     string
+	program     celgo.Program
+}
+
+var _ analysis.Analyzer = &celAnalyzer{}
+
+func newCELAnalyzer(metadata analysis.Metadata, messageType *diag.MessageType, message, expression string) (*celAnalyzer, error) {
+	env, err := celgo.NewEnv(celgo.Declarations(
+		decls.NewIdent(celResourceVariable, decls.NewMapType(decls.String, decls.Dyn), nil)))
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, iss := env.Parse(expression)
+	if iss != nil && iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	checked, iss := env.Check(parsed)
+	if iss != nil && iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if checked.ResultType().GetPrimitive() != exprpb.Type_BOOL {
+		return nil, fmt.Errorf("cel expression must evaluate to a bool")
+	}
+
+	program, err := env.Program(checked)
+	if err != nil {
+		return nil, err
+	}
+
+	return &celAnalyzer{
+		metadata:    metadata,
+		messageType: messageType,
+		message:     message,
+		program:     program,
+	}, nil
+}
+
+// Metadata implements Analyzer
+func (a *celAnalyzer) Metadata() analysis.Metadata {
+	return a.metadata
+}
+
+// Analyze implements Analyzer
+func (a *celAnalyzer) Analyze(c analysis.Context) {
+	for _, col := range a.metadata.Inputs {
+		col := col
+		c.ForEach(col, func(r *resource.Instance) bool {
+			ok, err := a.evaluate(col, r)
+			if err != nil {
+				scope.Analysis.Errorf("Analyzer %q failed to evaluate %s: %v", a.metadata.Name, r.Metadata.FullName, err)
+				return true
+			}
+			if !ok {
+				c.Report(col, diag.NewMessage(a.messageType, r, a.message))
+			}
+			return true
+		})
+	}
+}
+
+func (a *celAnalyzer) evaluate(col collection.Name, r *resource.Instance) (ok bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic during evaluation: %v", r)
+		}
+	}()
+
+	u, err := toUnstructured(col, r)
+	if err != nil {
+		return false, err
+	}
+	out, _, err := a.program.Eval(map[string]interface{}{celResourceVariable: u})
+	if err != nil {
+		return false, err
+	}
+	ok, isBool := out.Value().(bool)
+	if !isBool {
+		return false, fmt.Errorf("expression evaluated to %v rather than a bool", out.Value())
+	}
+	return ok, nil
+}
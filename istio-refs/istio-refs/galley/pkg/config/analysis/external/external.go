@@ -0,0 +1,244 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package external loads analyzers declared in policy files, written in CEL or Rego, in addition to the
+// built-in analyzers.
+//
+// A policy file holds a list of analyzers:
+//
+//   analyzers:
+//   - name: org.VirtualServiceTimeouts
+//     description: Checks that all the routes of VirtualServices set a timeout
+//     inputs:
+//     - istio/networking/v1alpha3/virtualservices
+//     code: ORG0001
+//     level: Warning
+//     message: All the routes of a VirtualService must set a timeout
+//     cel: "!has(resource.spec.http) || resource.spec.http.all(r, has(r.timeout))"
+//
+// A CEL analyzer is evaluated on each resource of its input collections, available as the "resource"
+// variable. The expression must evaluate to a bool, the message is reported on the resource if it is false.
+//
+// A Rego analyzer is evaluated once, with an input mapping each input collection to the list of its
+// resources. Its "deny" rule is a set of objects, each holding the "message" to report and optionally
+// the "resource" (taken from the input) to report it on.
+//
+// Resources are made available as objects with "collection", "metadata" (name, namespace, labels and
+// annotations), "spec", and for Kubernetes objects having one, "status" fields.
+package external
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/go-multierror"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// reservedCodePrefix is the prefix of the codes of the built-in messages.
+const reservedCodePrefix = "IST"
+
+// File is the content of a policy file.
+type File struct {
+	Analyzers []Config `json:"analyzers"`
+}
+
+// Config declares an analyzer.
+type Config struct {
+	// Name of the analyzer, in the "pkg.Type" format of the built-in analyzers.
+	Name string `json:"name"`
+
+	// Description of the analyzer.
+	Description string `json:"description"`
+
+	// Inputs are the names of the collections the analyzer reads.
+	Inputs []string `json:"inputs"`
+
+	// Code of the reported messages. Codes starting with "IST" are reserved.
+	Code string `json:"code"`
+
+	// Level of the reported messages: Info, Warning or Error.
+	Level string `json:"level"`
+
+	// Message reported by CEL analyzers.
+	Message string `json:"message,omitempty"`
+
+	// CEL is the expression of a CEL analyzer.
+	CEL string `json:"cel,omitempty"`
+
+	// Rego is the policy module of a Rego analyzer.
+	Rego string `json:"rego,omitempty"`
+}
+
+// Load returns the analyzers declared in the given policy files. Directories are searched for files
+// with a ".yaml" or ".yml" extension, non recursively.
+func Load(paths []string) ([]analysis.Analyzer, error) {
+	var result []analysis.Analyzer
+	var errs error
+	for _, p := range paths {
+		files, err := policyFiles(p)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		for _, f := range files {
+			content, err := ioutil.ReadFile(f)
+			if err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			analyzers, err := Parse(content)
+			if err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("%s: %v", f, err))
+				continue
+			}
+			result = append(result, analyzers...)
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return result, nil
+}
+
+func policyFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, info := range infos {
+		ext := filepath.Ext(info.Name())
+		if !info.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(path, info.Name()))
+		}
+	}
+	return files, nil
+}
+
+// Parse returns the analyzers declared in the content of a policy file.
+func Parse(content []byte) ([]analysis.Analyzer, error) {
+	var f File
+	if err := yaml.Unmarshal(content, &f); err != nil {
+		return nil, err
+	}
+
+	var result []analysis.Analyzer
+	var errs error
+	for _, c := range f.Analyzers {
+		a, err := New(c)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("analyzer %q: %v", c.Name, err))
+			continue
+		}
+		result = append(result, a)
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return result, nil
+}
+
+// New returns the analyzer declared by a config.
+func New(c Config) (analysis.Analyzer, error) {
+	if c.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if c.Description == "" {
+		return nil, fmt.Errorf("description is required")
+	}
+
+	if len(c.Inputs) == 0 {
+		return nil, fmt.Errorf("at least one input collection is required")
+	}
+	var inputs collection.Names
+	for _, i := range c.Inputs {
+		if _, found := collections.All.Find(i); !found {
+			return nil, fmt.Errorf("unknown input collection %q", i)
+		}
+		inputs = append(inputs, collection.NewName(i))
+	}
+
+	if c.Code == "" {
+		return nil, fmt.Errorf("code is required")
+	}
+	if strings.HasPrefix(c.Code, reservedCodePrefix) {
+		return nil, fmt.Errorf("codes starting with %q are reserved", reservedCodePrefix)
+	}
+	level, err := parseLevel(c.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := analysis.Metadata{
+		Name:        c.Name,
+		Description: c.Description,
+		Inputs:      inputs,
+	}
+	messageType := diag.NewMessageType(level, c.Code, "%s")
+
+	switch {
+	case c.CEL != "" && c.Rego != "":
+		return nil, fmt.Errorf("only one of cel and rego can be set")
+	case c.CEL != "":
+		if c.Message == "" {
+			return nil, fmt.Errorf("message is required")
+		}
+		return newCELAnalyzer(metadata, messageType, c.Message, c.CEL)
+	case c.Rego != "":
+		return newRegoAnalyzer(metadata, messageType, c.Rego)
+	}
+	return nil, fmt.Errorf("one of cel or rego is required")
+}
+
+func parseLevel(s string) (diag.Level, error) {
+	s = strings.ToUpper(s)
+	if s == "WARNING" {
+		return diag.Warning, nil
+	}
+	if l, ok := diag.GetUppercaseStringToLevelMap()[s]; ok {
+		return l, nil
+	}
+	return diag.Level{}, fmt.Errorf("invalid level %q, must be one of %v", s, diag.GetAllLevelStrings())
+}
+
+// MessageTypes returns the types of the messages reported by the analyzers loaded from policy files.
+func MessageTypes(analyzers []analysis.Analyzer) []*diag.MessageType {
+	var result []*diag.MessageType
+	for _, a := range analyzers {
+		switch a := a.(type) {
+		case *celAnalyzer:
+			result = append(result, a.messageType)
+		case *regoAnalyzer:
+			result = append(result, a.messageType)
+		}
+	}
+	return result
+}
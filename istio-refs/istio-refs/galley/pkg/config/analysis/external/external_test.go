@@ -0,0 +1,116 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/local"
+	"istio.io/istio/pkg/config/schema"
+)
+
+func TestLoad(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	analyzers, err := Load([]string{"testdata/policies"})
+	g.Expect(err).To(BeNil())
+	g.Expect(analyzers).To(HaveLen(2))
+
+	g.Expect(analyzers[0].Metadata().Name).To(Equal("org.VirtualServiceTimeouts"))
+	g.Expect(analyzers[0].Metadata().Inputs).To(HaveLen(1))
+	g.Expect(analyzers[1].Metadata().Name).To(Equal("org.NoWildcardGatewayHosts"))
+
+	types := MessageTypes(analyzers)
+	g.Expect(types).To(HaveLen(2))
+	g.Expect(types[0].Code()).To(Equal("ORG0001"))
+	g.Expect(types[0].Level()).To(Equal(diag.Warning))
+	g.Expect(types[1].Level()).To(Equal(diag.Error))
+}
+
+func TestAnalyze(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	analyzers, err := Load([]string{"testdata/policies/policies.yaml"})
+	g.Expect(err).To(BeNil())
+
+	sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("external", analyzers...), "", "istio-system", nil, true, 10*time.Second)
+	g.Expect(sa.AddDefaultResources()).To(Succeed())
+	f, err := os.Open("testdata/resources.yaml")
+	g.Expect(err).To(BeNil())
+	defer f.Close()
+	g.Expect(sa.AddReaderKubeSource([]local.ReaderSource{{Name: f.Name(), Reader: f}})).To(Succeed())
+
+	result, err := sa.Analyze(make(chan struct{}))
+	g.Expect(err).To(BeNil())
+
+	var messages []string
+	for _, m := range result.Messages {
+		messages = append(messages, m.Type.Code()+" "+m.Resource.Origin.FriendlyName())
+	}
+	g.Expect(messages).To(ConsistOf(
+		"ORG0001 VirtualService ratings.default",
+		"ORG0002 Gateway public.production",
+	))
+}
+
+func TestNewErrors(t *testing.T) {
+	valid := Config{
+		Name:        "org.Test",
+		Description: "Test",
+		Inputs:      []string{"istio/networking/v1alpha3/gateways"},
+		Code:        "ORG0001",
+		Level:       "Info",
+		Message:     "Test",
+		CEL:         "true",
+	}
+
+	cases := []struct {
+		name   string
+		modify func(c *Config)
+	}{
+		{"no name", func(c *Config) { c.Name = "" }},
+		{"no description", func(c *Config) { c.Description = "" }},
+		{"no inputs", func(c *Config) { c.Inputs = nil }},
+		{"unknown input", func(c *Config) { c.Inputs = []string{"istio/networking/v1alpha3/unknowns"} }},
+		{"reserved code", func(c *Config) { c.Code = "IST0001" }},
+		{"invalid level", func(c *Config) { c.Level = "Fatal" }},
+		{"no message", func(c *Config) { c.Message = "" }},
+		{"no expression", func(c *Config) { c.CEL = "" }},
+		{"cel and rego", func(c *Config) { c.Rego = "package org.test" }},
+		{"cel syntax error", func(c *Config) { c.CEL = "resource.spec." }},
+		{"cel not a bool", func(c *Config) { c.CEL = "'true'" }},
+		{"rego syntax error", func(c *Config) { c.CEL = ""; c.Rego = "package" }},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			config := valid
+			c.modify(&config)
+			_, err := New(config)
+			g.Expect(err).NotTo(BeNil())
+		})
+	}
+
+	g := NewGomegaWithT(t)
+	_, err := New(valid)
+	g.Expect(err).To(BeNil())
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/scope"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+)
+
+const (
+	regoModuleName = "policy.rego"
+	regoDenyRule   = "deny"
+)
+
+// regoAnalyzer reports the messages of the deny rule of a Rego policy, evaluated on all the resources of
+// its inputs.
+type regoAnalyzer struct {
+	metadata    analysis.Metadata
+	messageType *diag.MessageType
+	compiler    *ast.Compiler
+	query       string
+}
+
+var _ analysis.Analyzer = &regoAnalyzer{}
+
+func newRegoAnalyzer(metadata analysis.Metadata, messageType *diag.MessageType, policy string) (*regoAnalyzer, error) {
+	module, err := ast.ParseModule(regoModuleName, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := ast.NewCompiler()
+	compiler.Compile(map[string]*ast.Module{regoModuleName: module})
+	if compiler.Failed() {
+		return nil, compiler.Errors
+	}
+
+	return &regoAnalyzer{
+		metadata:    metadata,
+		messageType: messageType,
+		compiler:    compiler,
+		query:       fmt.Sprintf("%s.%s", module.Package.Path, regoDenyRule),
+	}, nil
+}
+
+// Metadata implements Analyzer
+func (a *regoAnalyzer) Metadata() analysis.Metadata {
+	return a.metadata
+}
+
+// Analyze implements Analyzer
+func (a *regoAnalyzer) Analyze(c analysis.Context) {
+	input := make(map[string]interface{})
+	instances := make(map[string]*resource.Instance)
+	for _, col := range a.metadata.Inputs {
+		var resources []interface{}
+		c.ForEach(col, func(r *resource.Instance) bool {
+			u, err := toUnstructured(col, r)
+			if err != nil {
+				scope.Analysis.Debugf("Analyzer %q failed to convert %s: %v", a.metadata.Name, r.Metadata.FullName, err)
+				return true
+			}
+			resources = append(resources, u)
+			instances[instanceKey(col.String(), r.Metadata.FullName.Namespace.String(), r.Metadata.FullName.Name.String())] = r
+			return true
+		})
+		input[col.String()] = resources
+	}
+
+	rs, err := rego.New(
+		rego.Compiler(a.compiler),
+		rego.Query(a.query),
+		rego.Input(input),
+	).Eval(context.Background())
+	if err != nil {
+		scope.Analysis.Errorf("Analyzer %q failed to evaluate its policy: %v", a.metadata.Name, err)
+		return
+	}
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return
+	}
+
+	denials, ok := rs[0].Expressions[0].Value.([]interface{})
+	if !ok {
+		scope.Analysis.Errorf("Analyzer %q: the %s rule must be a set", a.metadata.Name, regoDenyRule)
+		return
+	}
+	for _, d := range denials {
+		a.report(c, d, instances)
+	}
+}
+
+// report reports a value of the deny rule: a message, or an object holding the message and the resource
+// to report it on.
+func (a *regoAnalyzer) report(c analysis.Context, denial interface{}, instances map[string]*resource.Instance) {
+	if message, ok := denial.(string); ok {
+		c.Report(a.metadata.Inputs[0], diag.NewMessage(a.messageType, nil, message))
+		return
+	}
+
+	d, ok := denial.(map[string]interface{})
+	if !ok {
+		scope.Analysis.Errorf("Analyzer %q: invalid %s value %v", a.metadata.Name, regoDenyRule, denial)
+		return
+	}
+	message, ok := d["message"].(string)
+	if !ok {
+		scope.Analysis.Errorf("Analyzer %q: %s value %v has no message", a.metadata.Name, regoDenyRule, denial)
+		return
+	}
+
+	col := a.metadata.Inputs[0]
+	var r *resource.Instance
+	if u, ok := d["resource"].(map[string]interface{}); ok {
+		colName, _ := u["collection"].(string)
+		metadata, _ := u["metadata"].(map[string]interface{})
+		namespace, _ := metadata["namespace"].(string)
+		name, _ := metadata["name"].(string)
+		if r = instances[instanceKey(colName, namespace, name)]; r != nil {
+			col = collection.NewName(colName)
+		}
+	}
+	c.Report(col, diag.NewMessage(a.messageType, r, message))
+}
+
+func instanceKey(col, namespace, name string) string {
+	return col + "/" + namespace + "/" + name
+}
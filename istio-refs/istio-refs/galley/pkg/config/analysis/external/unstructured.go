@@ -0,0 +1,88 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package external
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+)
+
+// toUnstructured returns a resource as a generic map, as made available to policies.
+func toUnstructured(col collection.Name, r *resource.Instance) (map[string]interface{}, error) {
+	labels := make(map[string]interface{}, len(r.Metadata.Labels))
+	for k, v := range r.Metadata.Labels {
+		labels[k] = v
+	}
+	annotations := make(map[string]interface{}, len(r.Metadata.Annotations))
+	for k, v := range r.Metadata.Annotations {
+		annotations[k] = v
+	}
+
+	u := map[string]interface{}{
+		"collection": col.String(),
+		"metadata": map[string]interface{}{
+			"name":        r.Metadata.FullName.Name.String(),
+			"namespace":   r.Metadata.FullName.Namespace.String(),
+			"labels":      labels,
+			"annotations": annotations,
+		},
+	}
+
+	spec, err := messageToMap(r.Message)
+	if err != nil {
+		return nil, err
+	}
+	// Some Kubernetes resources hold the whole object rather than just the spec.
+	if _, ok := spec["metadata"]; ok {
+		if s, ok := spec["status"]; ok {
+			u["status"] = s
+		}
+		spec, _ = spec["spec"].(map[string]interface{})
+	}
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	u["spec"] = spec
+	return u, nil
+}
+
+// messageToMap converts a message to a generic map. Istio protos use their canonical JSON encoding,
+// the Kubernetes types their usual JSON encoding.
+func messageToMap(m proto.Message) (map[string]interface{}, error) {
+	t := reflect.TypeOf(m)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !strings.HasPrefix(t.PkgPath(), "k8s.io/") {
+		return gogoprotomarshal.ToJSONMap(m)
+	}
+
+	js, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(js, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
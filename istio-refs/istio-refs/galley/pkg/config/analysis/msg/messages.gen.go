@@ -100,6 +100,62 @@ var (
 	// MeshPolicyResourceIsDeprecated defines a diag.MessageType for message "MeshPolicyResourceIsDeprecated".
 	// Description: The MeshPolicy resource is deprecated and will be removed in a future Istio release. Migrate to the PeerAuthentication resource.
 	MeshPolicyResourceIsDeprecated = diag.NewMessageType(diag.Info, "IST0121", "The MeshPolicy resource is deprecated and will be removed in a future Istio release. Migrate to the PeerAuthentication resource.")
+
+	// ServiceEntryHostConflictsWithService defines a diag.MessageType for message "ServiceEntryHostConflictsWithService".
+	// Description: A ServiceEntry host is also provided by a Kubernetes service. Which registry is used depends on Pilot's registry conflict policy.
+	ServiceEntryHostConflictsWithService = diag.NewMessageType(diag.Warning, "IST0122", "The host %s is also provided by the Kubernetes service %s. Pilot resolves the conflict according to PILOT_REGISTRY_CONFLICT_POLICY; see /debug/registryz?conflicts=true for the outcome.")
+
+	// NoMatchingWorkloadsFound defines a diag.MessageType for message "NoMatchingWorkloadsFound".
+	// Description: There aren't workloads matching the resource labels
+	NoMatchingWorkloadsFound = diag.NewMessageType(diag.Warning, "IST0123", "No matching workloads for this resource with the following labels: %s")
+
+	// AuthorizationPolicyRequiresMutualTLS defines a diag.MessageType for message "AuthorizationPolicyRequiresMutualTLS".
+	// Description: An AuthorizationPolicy matches on peer identities, but the workloads it selects do not use mutual TLS
+	AuthorizationPolicyRequiresMutualTLS = diag.NewMessageType(diag.Warning, "IST0124", "The %s in rule %d can never match, because mutual TLS is not in place for the workloads selected by this policy.")
+
+	// AuthorizationPolicyAllowRuleShadowed defines a diag.MessageType for message "AuthorizationPolicyAllowRuleShadowed".
+	// Description: An ALLOW rule of an AuthorizationPolicy only matches requests that are always denied
+	AuthorizationPolicyAllowRuleShadowed = diag.NewMessageType(diag.Warning, "IST0125", "Rule %d is shadowed by rule %d of the DENY policy %s, the requests it allows are always denied.")
+
+	// UnknownAuthorizationPolicyConditionKey defines a diag.MessageType for message "UnknownAuthorizationPolicyConditionKey".
+	// Description: An AuthorizationPolicy condition uses a key that is not supported
+	UnknownAuthorizationPolicyConditionKey = diag.NewMessageType(diag.Error, "IST0126", "The condition key %s in rule %d is not supported, the condition can never match.")
+
+	// DestinationRuleSubsetNoMatchingPods defines a diag.MessageType for message "DestinationRuleSubsetNoMatchingPods".
+	// Description: A DestinationRule subset does not select any pod of the service of its host
+	DestinationRuleSubsetNoMatchingPods = diag.NewMessageType(diag.Warning, "IST0127", "Subset %s of host %s does not select any pod, the traffic routed to it will fail.")
+
+	// UnusedDestinationRuleSubset defines a diag.MessageType for message "UnusedDestinationRuleSubset".
+	// Description: A DestinationRule subset is not referenced by any VirtualService
+	UnusedDestinationRuleSubset = diag.NewMessageType(diag.Info, "IST0128", "Subset %s of host %s is not referenced by any VirtualService.")
+
+	// OverlappingDestinationRuleSubsets defines a diag.MessageType for message "OverlappingDestinationRuleSubsets".
+	// Description: Subsets of a DestinationRule select the same pods
+	OverlappingDestinationRuleSubsets = diag.NewMessageType(diag.Warning, "IST0129", "Subsets %s and %s of host %s select the same pods, traffic routed to either of them can reach both.")
+
+	// ConflictingServiceEntryHosts defines a diag.MessageType for message "ConflictingServiceEntryHosts".
+	// Description: ServiceEntries visible in the same namespaces declare a host with different ports or resolution
+	ConflictingServiceEntryHosts = diag.NewMessageType(diag.Warning, "IST0130", "The host %s is also declared by ServiceEntry %s, with a different %s.")
+
+	// ServiceEntryWildcardHostDNSResolution defines a diag.MessageType for message "ServiceEntryWildcardHostDNSResolution".
+	// Description: A ServiceEntry with DNS resolution declares a wildcard host
+	ServiceEntryWildcardHostDNSResolution = diag.NewMessageType(diag.Warning, "IST0131", "The wildcard host %s cannot be resolved with DNS resolution, use resolution NONE to send the traffic to the host requested by the clients.")
+
+	// ServiceEntryTCPPortWithoutAddresses defines a diag.MessageType for message "ServiceEntryTCPPortWithoutAddresses".
+	// Description: A ServiceEntry with resolution NONE declares a TCP port but no addresses
+	ServiceEntryTCPPortWithoutAddresses = diag.NewMessageType(diag.Warning, "IST0132", "Port %d uses protocol %s but the ServiceEntry has no addresses, so with resolution NONE it matches all the outbound traffic to this port.")
+
+	// ServiceEntryNotExported defines a diag.MessageType for message "ServiceEntryNotExported".
+	// Description: A resource refers to a host declared by a ServiceEntry which is not exported to its namespace
+	ServiceEntryNotExported = diag.NewMessageType(diag.Warning, "IST0133", "The host %s is declared by ServiceEntry %s, which is not exported to namespace %s.")
+
+	// IncompleteEgressGatewayRoute defines a diag.MessageType for message "IncompleteEgressGatewayRoute".
+	// Description: The configuration routing traffic to an external host through an egress gateway is incomplete
+	IncompleteEgressGatewayRoute = diag.NewMessageType(diag.Warning, "IST0134", "Traffic to %s through the egress gateway %s is not routed correctly: %s.")
+
+	// HostHiddenBySidecar defines a diag.MessageType for message "HostHiddenBySidecar".
+	// Description: A resource of the namespace of a Sidecar refers to a host outside of its egress hosts
+	HostHiddenBySidecar = diag.NewMessageType(diag.Warning, "IST0135", "The host %s of %s %s is not in the egress hosts of this Sidecar, the traffic of the selected workloads to it will fail.")
 )
 
 // All returns a list of all known message types.
@@ -128,6 +184,20 @@ func All() []*diag.MessageType {
 		JwtFailureDueToInvalidServicePortPrefix,
 		PolicyResourceIsDeprecated,
 		MeshPolicyResourceIsDeprecated,
+		ServiceEntryHostConflictsWithService,
+		NoMatchingWorkloadsFound,
+		AuthorizationPolicyRequiresMutualTLS,
+		AuthorizationPolicyAllowRuleShadowed,
+		UnknownAuthorizationPolicyConditionKey,
+		DestinationRuleSubsetNoMatchingPods,
+		UnusedDestinationRuleSubset,
+		OverlappingDestinationRuleSubsets,
+		ConflictingServiceEntryHosts,
+		ServiceEntryWildcardHostDNSResolution,
+		ServiceEntryTCPPortWithoutAddresses,
+		ServiceEntryNotExported,
+		IncompleteEgressGatewayRoute,
+		HostHiddenBySidecar,
 	}
 }
 
@@ -356,3 +426,147 @@ func NewMeshPolicyResourceIsDeprecated(r *resource.Instance) diag.Message {
 		r,
 	)
 }
+
+// NewServiceEntryHostConflictsWithService returns a new diag.Message based on ServiceEntryHostConflictsWithService.
+func NewServiceEntryHostConflictsWithService(r *resource.Instance, host string, service string) diag.Message {
+	return diag.NewMessage(
+		ServiceEntryHostConflictsWithService,
+		r,
+		host,
+		service,
+	)
+}
+
+// NewNoMatchingWorkloadsFound returns a new diag.Message based on NoMatchingWorkloadsFound.
+func NewNoMatchingWorkloadsFound(r *resource.Instance, labels string) diag.Message {
+	return diag.NewMessage(
+		NoMatchingWorkloadsFound,
+		r,
+		labels,
+	)
+}
+
+// NewAuthorizationPolicyRequiresMutualTLS returns a new diag.Message based on AuthorizationPolicyRequiresMutualTLS.
+func NewAuthorizationPolicyRequiresMutualTLS(r *resource.Instance, attribute string, rule int) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyRequiresMutualTLS,
+		r,
+		attribute,
+		rule,
+	)
+}
+
+// NewAuthorizationPolicyAllowRuleShadowed returns a new diag.Message based on AuthorizationPolicyAllowRuleShadowed.
+func NewAuthorizationPolicyAllowRuleShadowed(r *resource.Instance, rule int, denyRule int, denyPolicy string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyAllowRuleShadowed,
+		r,
+		rule,
+		denyRule,
+		denyPolicy,
+	)
+}
+
+// NewUnknownAuthorizationPolicyConditionKey returns a new diag.Message based on UnknownAuthorizationPolicyConditionKey.
+func NewUnknownAuthorizationPolicyConditionKey(r *resource.Instance, key string, rule int) diag.Message {
+	return diag.NewMessage(
+		UnknownAuthorizationPolicyConditionKey,
+		r,
+		key,
+		rule,
+	)
+}
+
+// NewDestinationRuleSubsetNoMatchingPods returns a new diag.Message based on DestinationRuleSubsetNoMatchingPods.
+func NewDestinationRuleSubsetNoMatchingPods(r *resource.Instance, subset string, host string) diag.Message {
+	return diag.NewMessage(
+		DestinationRuleSubsetNoMatchingPods,
+		r,
+		subset,
+		host,
+	)
+}
+
+// NewUnusedDestinationRuleSubset returns a new diag.Message based on UnusedDestinationRuleSubset.
+func NewUnusedDestinationRuleSubset(r *resource.Instance, subset string, host string) diag.Message {
+	return diag.NewMessage(
+		UnusedDestinationRuleSubset,
+		r,
+		subset,
+		host,
+	)
+}
+
+// NewOverlappingDestinationRuleSubsets returns a new diag.Message based on OverlappingDestinationRuleSubsets.
+func NewOverlappingDestinationRuleSubsets(r *resource.Instance, subset string, otherSubset string, host string) diag.Message {
+	return diag.NewMessage(
+		OverlappingDestinationRuleSubsets,
+		r,
+		subset,
+		otherSubset,
+		host,
+	)
+}
+
+// NewConflictingServiceEntryHosts returns a new diag.Message based on ConflictingServiceEntryHosts.
+func NewConflictingServiceEntryHosts(r *resource.Instance, host string, otherServiceEntry string, field string) diag.Message {
+	return diag.NewMessage(
+		ConflictingServiceEntryHosts,
+		r,
+		host,
+		otherServiceEntry,
+		field,
+	)
+}
+
+// NewServiceEntryWildcardHostDNSResolution returns a new diag.Message based on ServiceEntryWildcardHostDNSResolution.
+func NewServiceEntryWildcardHostDNSResolution(r *resource.Instance, host string) diag.Message {
+	return diag.NewMessage(
+		ServiceEntryWildcardHostDNSResolution,
+		r,
+		host,
+	)
+}
+
+// NewServiceEntryTCPPortWithoutAddresses returns a new diag.Message based on ServiceEntryTCPPortWithoutAddresses.
+func NewServiceEntryTCPPortWithoutAddresses(r *resource.Instance, port int, protocol string) diag.Message {
+	return diag.NewMessage(
+		ServiceEntryTCPPortWithoutAddresses,
+		r,
+		port,
+		protocol,
+	)
+}
+
+// NewServiceEntryNotExported returns a new diag.Message based on ServiceEntryNotExported.
+func NewServiceEntryNotExported(r *resource.Instance, host string, serviceEntry string, namespace string) diag.Message {
+	return diag.NewMessage(
+		ServiceEntryNotExported,
+		r,
+		host,
+		serviceEntry,
+		namespace,
+	)
+}
+
+// NewIncompleteEgressGatewayRoute returns a new diag.Message based on IncompleteEgressGatewayRoute.
+func NewIncompleteEgressGatewayRoute(r *resource.Instance, host string, gateway string, problem string) diag.Message {
+	return diag.NewMessage(
+		IncompleteEgressGatewayRoute,
+		r,
+		host,
+		gateway,
+		problem,
+	)
+}
+
+// NewHostHiddenBySidecar returns a new diag.Message based on HostHiddenBySidecar.
+func NewHostHiddenBySidecar(r *resource.Instance, host string, kind string, name string) diag.Message {
+	return diag.NewMessage(
+		HostHiddenBySidecar,
+		r,
+		host,
+		kind,
+		name,
+	)
+}
@@ -90,6 +90,14 @@ type AnalysisSuppression struct {
 	ResourceName string
 }
 
+// Matches returns true if the suppression applies to the message.
+func (s AnalysisSuppression) Matches(m diag.Message) bool {
+	if m.Resource == nil || s.Code != m.Type.Code() {
+		return false
+	}
+	return glob.Glob(s.ResourceName, m.Resource.Origin.FriendlyName())
+}
+
 // NewAnalyzingDistributor returns a new instance of AnalyzingDistributor.
 func NewAnalyzingDistributor(s AnalyzingDistributorSettings) *AnalyzingDistributor {
 	// collectionReport hook function defaults to no-op
@@ -221,11 +229,7 @@ FilterMessages:
 
 		// Filter out any messages that match our suppressions.
 		for _, s := range suppressions {
-			if m.Resource == nil || s.Code != m.Type.Code() {
-				continue
-			}
-
-			if !glob.Glob(s.ResourceName, m.Resource.Origin.FriendlyName()) {
+			if !s.Matches(m) {
 				continue
 			}
 			scope.Analysis.Debugf("Suppressing code %s on resource %s due to suppressions list", m.Type.Code(), m.Resource.Origin.FriendlyName())
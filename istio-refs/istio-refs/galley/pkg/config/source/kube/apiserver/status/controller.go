@@ -199,8 +199,9 @@ mainloop:
 
 		_, err = iface.Namespace(ns).UpdateStatus(u, metav1.UpdateOptions{})
 		if err != nil {
-			// TODO: Reinsert work? It probably makes sense to reinsert (with a delay), in case of a transient failure.
-			scope.Source.Errorf("Unable to update status of Resource %v(%v): %v", st.key.col, st.key.res, err)
+			// The failure may be transient (e.g. a conflict with a concurrent update), retry with a delay.
+			scope.Source.Errorf("Unable to update status of Resource %v(%v), will retry: %v", st.key.col, st.key.res, err)
+			state.retryWork(st.key)
 		}
 	}
 	wg.Done()
@@ -17,6 +17,7 @@ package status
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -294,6 +295,42 @@ func TestBasicReconcilation_UpdateError(t *testing.T) {
 	g.Expect(actualStatusMap[subfield]).To(ConsistOf(expectedMessage(m).Unstructured(false)))
 }
 
+func TestBasicReconcilation_UpdateError_Retry(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer func(d time.Duration) { baseRetryDelay = d }(baseRetryDelay)
+	baseRetryDelay = time.Millisecond
+
+	c := NewController(subfield)
+
+	r := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"resourceVersion": "v1",
+			},
+		},
+	}
+
+	k, cl := setupClientWithReactors(r, fmt.Errorf("conflict"))
+
+	e := resource.Instance{
+		Origin: &rt.Origin{
+			Collection: basicmeta.K8SCollection1.Name(),
+			FullName:   resource.NewFullName("foo", "bar"),
+			Version:    resource.Version("v1"),
+		},
+	}
+
+	c.Start(rt.NewProvider(k, 0), basicmeta.MustGet().KubeCollections().All())
+	m := msg.NewInternalError(&e, "foo")
+	c.Report(diag.Messages{m})
+	defer c.Stop()
+
+	// The failed update is retried: get, update, get, update...
+	g.Eventually(func() int { return len(cl.Actions()) }).Should(BeNumerically(">=", 4))
+	g.Expect(cl.Actions()[3]).To(BeAssignableToTypeOf(k8stesting.UpdateActionImpl{}))
+}
+
 func TestBasicReconcilation_GetError(t *testing.T) {
 	g := NewGomegaWithT(t)
 
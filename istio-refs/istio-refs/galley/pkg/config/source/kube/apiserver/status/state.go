@@ -16,11 +16,19 @@ package status
 
 import (
 	"sync"
+	"time"
 
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/collection"
 )
 
+var (
+	// baseRetryDelay is the delay before retrying a failed status update. It doubles with each consecutive failure,
+	// up to maxRetryDelay.
+	baseRetryDelay = 100 * time.Millisecond
+	maxRetryDelay  = time.Minute
+)
+
 // use a sentinel value as the last item in a work queue. This allows doing a simple null check on the next to
 // detect whether a status is queued as work or not.
 var sentinel = &status{}
@@ -44,7 +52,7 @@ var sentinel = &status{}
 // with scheduling work only once when things are updated successively.
 //
 // The worker(s) will get a copy of the needed update from the work queue and try to reconcile. If the reconciliation
-// fails, they will need to explicitly put the entries back into the queue (TBD). If there are no outstanding work items
+// fails, they will need to explicitly put the entries back into the queue, which happens with an exponential delay. If there are no outstanding work items
 // for the reconciliation loop, then it will wait blocked in the dequeueWork call. During tear-down, the controller
 // will call quiesceWork() which will release these workers and let them exit.
 //
@@ -219,6 +227,39 @@ func (s *state) dequeueWork() (status, bool) {
 	}
 }
 
+// retryWork schedules a work item to be put back into the work queue, after a failed attempt to reconcile it.
+func (s *state) retryWork(k key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.states[k]
+	if st == nil || s.quiesce {
+		return
+	}
+	st.failures++
+	time.AfterFunc(retryDelay(st.failures), func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// The status may have been reconciled, or stopped being tracked, in the meantime.
+		if s.quiesce || s.states[k] != st || !st.needsChange() {
+			return
+		}
+		s.enqueueWork(st)
+	})
+}
+
+func retryDelay(failures int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
 func (s *state) quiesceWork() {
 	s.mu.Lock()
 	s.quiesce = true
@@ -289,3 +289,65 @@ func TestState_ClearMessages_AgainstAppliedEmptyState(t *testing.T) {
 
 	g.Expect(s.hasWork()).To(BeFalse())
 }
+
+func TestState_RetryWork(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer func(d time.Duration) { baseRetryDelay = d }(baseRetryDelay)
+	baseRetryDelay = time.Millisecond
+
+	s := newState()
+
+	res := *data.EntryN1I1V1
+	res.Origin = &rt.Origin{
+		Collection: basicmeta.K8SCollection1.Name(),
+		Kind:       "k1",
+		FullName:   res.Metadata.FullName,
+		Version:    res.Metadata.Version,
+	}
+
+	msgs := NewMessageSet()
+	msgs.Add(res.Origin.(*rt.Origin), msg.NewInternalError(&res, "t"))
+	s.applyMessages(msgs)
+
+	st, ok := s.dequeueWork()
+	g.Expect(ok).To(BeTrue())
+	g.Expect(s.hasWork()).To(BeFalse())
+
+	// The update failed, the work should be enqueued again after a delay
+	s.retryWork(st.key)
+	g.Eventually(s.hasWork).Should(BeTrue())
+
+	st, ok = s.dequeueWork()
+	g.Expect(ok).To(BeTrue())
+	g.Expect(st.failures).To(Equal(1))
+	g.Expect(st.desiredStatusVersion).To(Equal(res.Metadata.Version))
+}
+
+func TestState_RetryWork_Reconciled(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	defer func(d time.Duration) { baseRetryDelay = d }(baseRetryDelay)
+	baseRetryDelay = 10 * time.Millisecond
+
+	s := newState()
+	s.applyMessages(NewMessageSet()) // start reconciliation
+	s.setObserved(basicmeta.K8SCollection1.Name(), data.EntryN1I1V1.Metadata.FullName, data.EntryN1I1V1.Metadata.Version, "foo")
+
+	st, ok := s.dequeueWork()
+	g.Expect(ok).To(BeTrue())
+	s.retryWork(st.key)
+
+	// The status got cleared by someone else in the meantime, nothing left to retry
+	s.setObserved(basicmeta.K8SCollection1.Name(), data.EntryN1I1V1.Metadata.FullName, data.EntryN1I1V1.Metadata.Version, nil)
+	g.Consistently(s.hasWork, 50*time.Millisecond).Should(BeFalse())
+}
+
+func TestRetryDelay(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(retryDelay(1)).To(Equal(baseRetryDelay))
+	g.Expect(retryDelay(2)).To(Equal(2 * baseRetryDelay))
+	g.Expect(retryDelay(3)).To(Equal(4 * baseRetryDelay))
+	g.Expect(retryDelay(100)).To(Equal(maxRetryDelay))
+}
@@ -36,6 +36,9 @@ type status struct {
 	desiredStatus        interface{}
 	desiredStatusVersion resource.Version
 
+	// failures is the number of consecutive failed attempts to update the status to the desired status.
+	failures int
+
 	// next implements a singly-linked list for work tracking purposes.
 	next *status
 }
@@ -63,6 +66,7 @@ func returnStatusToPool(s *status) {
 	s.observedVersion = ""
 	s.desiredStatus = nil
 	s.desiredStatusVersion = ""
+	s.failures = 0
 }
 
 func (r *status) setObserved(v resource.Version, status interface{}) bool {
@@ -76,6 +80,7 @@ func (r *status) setObserved(v resource.Version, status interface{}) bool {
 func (r *status) setDesired(v resource.Version, status interface{}) bool {
 	r.desiredStatus = status
 	r.desiredStatusVersion = v
+	r.failures = 0
 
 	return r.needsChange()
 }
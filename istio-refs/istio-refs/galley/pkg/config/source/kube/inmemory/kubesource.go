@@ -32,6 +32,7 @@ import (
 	"istio.io/istio/galley/pkg/config/scope"
 	"istio.io/istio/galley/pkg/config/source/inmemory"
 	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/galley/pkg/config/util/kubeyaml"
 	"istio.io/istio/pkg/config/event"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/collection"
@@ -202,12 +203,12 @@ func (s *KubeSource) parseContent(r *collection.Schemas, name, yamlText string)
 	var errs error
 
 	reader := bufio.NewReader(strings.NewReader(yamlText))
-	decoder := yaml.NewYAMLReader(reader)
+	decoder := kubeyaml.NewLineReader(reader)
 	chunkCount := -1
 
 	for {
 		chunkCount++
-		doc, err := decoder.Read()
+		doc, line, err := decoder.Read()
 		if err == io.EOF {
 			break
 		}
@@ -220,7 +221,13 @@ func (s *KubeSource) parseContent(r *collection.Schemas, name, yamlText string)
 		}
 
 		chunk := bytes.TrimSpace(doc)
-		r, err := s.parseChunk(r, chunk)
+		var ref resource.Reference
+		var fieldMap map[string]int
+		if name != "" {
+			ref = &rt.Position{Filename: name, Document: chunkCount, Line: line}
+			fieldMap = kubeyaml.FieldLines(chunk, line)
+		}
+		r, err := s.parseChunk(r, chunk, ref, fieldMap)
 		if err != nil {
 			var uerr *unknownSchemaError
 			if errors.As(err, &uerr) {
@@ -251,7 +258,8 @@ func (e unknownSchemaError) Error() string {
 	return fmt.Sprintf("failed finding schema for group/version/kind: %s/%s/%s", e.group, e.version, e.kind)
 }
 
-func (s *KubeSource) parseChunk(r *collection.Schemas, yamlChunk []byte) (kubeResource, error) {
+func (s *KubeSource) parseChunk(r *collection.Schemas, yamlChunk []byte, ref resource.Reference,
+	fieldMap map[string]int) (kubeResource, error) {
 	// Convert to JSON
 	jsonChunk, err := yaml.ToJSON(yamlChunk)
 	if err != nil {
@@ -305,6 +313,6 @@ func (s *KubeSource) parseChunk(r *collection.Schemas, yamlChunk []byte) (kubeRe
 	return kubeResource{
 		schema:   schema,
 		sha:      sha1.Sum(yamlChunk),
-		resource: rt.ToResource(objMeta, schema, item),
+		resource: rt.ToResource(objMeta, schema, item, ref, fieldMap),
 	}, nil
 }
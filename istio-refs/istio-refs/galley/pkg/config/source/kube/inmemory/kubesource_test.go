@@ -19,6 +19,7 @@ import (
 
 	. "github.com/onsi/gomega"
 
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
 	"istio.io/istio/galley/pkg/config/testing/basicmeta"
 	"istio.io/istio/galley/pkg/config/testing/data"
 	"istio.io/istio/galley/pkg/config/testing/fixtures"
@@ -347,6 +348,26 @@ func TestKubeSource_CanHandleDocumentSeparatorInComments(t *testing.T) {
 	g.Expect(s.ContentNames()).To(Equal(map[string]struct{}{"foo": {}}))
 }
 
+func TestKubeSource_Position(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, _ := setupKubeSource()
+	s.Start()
+	defer s.Stop()
+
+	err := s.ApplyContent("foo.yaml", kubeyaml.JoinString(data.YamlN1I1V1, data.YamlN2I2V1))
+	g.Expect(err).To(BeNil())
+
+	actual := s.Get(basicmeta.K8SCollection1.Name()).AllSorted()
+	g.Expect(actual).To(HaveLen(2))
+	g.Expect(actual[0].Origin.Reference()).To(Equal(&rt.Position{Filename: "foo.yaml", Document: 0, Line: 2}))
+	g.Expect(actual[1].Origin.Reference()).To(Equal(&rt.Position{Filename: "foo.yaml", Document: 1, Line: 11}))
+
+	line, ok := actual[1].Origin.(*rt.Origin).FieldLine("spec.n2_i2")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(line).To(Equal(17))
+}
+
 func setupKubeSource() (*KubeSource, *fixtures.Accumulator) {
 	s := NewKubeSource(basicmeta.MustGet().KubeCollections())
 
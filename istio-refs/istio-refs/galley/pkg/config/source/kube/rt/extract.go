@@ -24,8 +24,10 @@ import (
 	resource2 "istio.io/istio/pkg/config/schema/resource"
 )
 
-// ToResource converts the given object and proto to a resource.Instance
-func ToResource(object metav1.Object, schema collection.Schema, item proto.Message) *resource.Instance {
+// ToResource converts the given object and proto to a resource.Instance. The optional ref and fieldMap point to the
+// location the object and its fields were read from.
+func ToResource(object metav1.Object, schema collection.Schema, item proto.Message, ref resource.Reference,
+	fieldMap map[string]int) *resource.Instance {
 	var o *Origin
 
 	name := resource.NewFullName(resource.Namespace(object.GetNamespace()), resource.LocalName(object.GetName()))
@@ -39,6 +41,8 @@ func ToResource(object metav1.Object, schema collection.Schema, item proto.Messa
 			Collection: schema.Name(),
 			Kind:       schema.Resource().Kind(),
 			Version:    version,
+			Ref:        ref,
+			FieldMap:   fieldMap,
 		}
 	}
 
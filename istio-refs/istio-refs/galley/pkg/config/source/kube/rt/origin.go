@@ -29,6 +29,11 @@ type Origin struct {
 	Kind       string
 	FullName   resource.FullName
 	Version    resource.Version
+	Ref        resource.Reference
+
+	// FieldMap holds the line of each field of the resource in the source it was read from, keyed
+	// by field path (e.g. "spec.http[0].route[0].destination.host"). Nil if not known.
+	FieldMap map[string]int
 }
 
 var _ resource.Origin = &Origin{}
@@ -53,3 +58,36 @@ func (o *Origin) Namespace() resource.Namespace {
 
 	return o.FullName.Namespace
 }
+
+// Reference implements resource.Origin
+func (o *Origin) Reference() resource.Reference {
+	return o.Ref
+}
+
+// FieldLine returns the line of the field at the given path in the source of the resource.
+func (o *Origin) FieldLine(path string) (int, bool) {
+	line, ok := o.FieldMap[path]
+	return line, ok
+}
+
+// Position is a resource.Reference to a location in a file.
+type Position struct {
+	Filename string // filename, if any
+	Document int    // index of the yaml document in the file, starting at 0
+	Line     int    // line number, starting at 1
+}
+
+var _ resource.Reference = &Position{}
+
+// String implements resource.Reference, returning "file:line", or just "file" if the line is unknown.
+func (p *Position) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d", p.Filename, p.Line)
+	}
+	return p.Filename
+}
+
+// WithLine returns the position of another line of the same document.
+func (p *Position) WithLine(line int) resource.Reference {
+	return &Position{Filename: p.Filename, Document: p.Document, Line: line}
+}
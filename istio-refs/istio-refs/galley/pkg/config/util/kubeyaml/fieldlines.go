@@ -0,0 +1,136 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeyaml
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var keyPattern = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s#'"{}\[\],&*!|>%@` + "`" + `][^#]*?)\s*:(\s|$)`)
+
+// SplitKey splits a block mapping entry, e.g. `name: value # comment`, into its key as written
+// (quotes included) and the rest of the line after the colon. The line must not be indented.
+func SplitKey(line string) (key string, rest string, ok bool) {
+	m := keyPattern.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], line[len(m[0]):], true
+}
+
+type frameKind int
+
+const (
+	mappingFrame frameKind = iota
+	sequenceFrame
+	itemFrame
+)
+
+// frame is an open block collection while scanning a document.
+type frame struct {
+	kind   frameKind
+	indent int
+	path   string
+	index  int
+}
+
+// FieldLines returns the line of every field of a yaml document, keyed by field path, e.g.
+// "spec.http[2].route[0].destination.host". Lines are offset by startLine, the line the document
+// starts at. Only block style collections are indexed, the fields of flow style collections
+// (e.g. "{a: b}") take the line of their parent.
+func FieldLines(doc []byte, startLine int) map[string]int {
+	result := make(map[string]int)
+
+	var stack []frame
+	blockScalarIndent := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(doc))
+	for line := startLine; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		content := strings.TrimLeft(text, " ")
+		indent := len(text) - len(content)
+
+		if blockScalarIndent >= 0 {
+			if content == "" || indent > blockScalarIndent {
+				continue
+			}
+			blockScalarIndent = -1
+		}
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+
+		// Sequence items, possibly with the first field of a mapping on the same line (e.g. "- name: foo").
+		for content == "-" || strings.HasPrefix(content, "- ") {
+			for len(stack) > 0 {
+				top := stack[len(stack)-1]
+				if top.indent < indent || (top.indent == indent && top.kind != itemFrame) {
+					break
+				}
+				stack = stack[:len(stack)-1]
+			}
+
+			if len(stack) > 0 && stack[len(stack)-1].kind == sequenceFrame && stack[len(stack)-1].indent == indent {
+				stack[len(stack)-1].index++
+			} else {
+				stack = append(stack, frame{kind: sequenceFrame, indent: indent, path: parentPath(stack)})
+			}
+			seq := stack[len(stack)-1]
+			itemPath := fmt.Sprintf("%s[%d]", seq.path, seq.index)
+			result[itemPath] = line
+			stack = append(stack, frame{kind: itemFrame, indent: indent, path: itemPath})
+
+			rest := strings.TrimLeft(content[1:], " ")
+			indent += len(content) - len(rest)
+			content = rest
+		}
+
+		rawKey, rest, ok := SplitKey(content)
+		if !ok {
+			continue
+		}
+		key := strings.Trim(rawKey, `"'`)
+		value := strings.TrimSpace(rest)
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		path := key
+		if parent := parentPath(stack); parent != "" {
+			path = parent + "." + key
+		}
+		result[path] = line
+
+		switch {
+		case value == "" || strings.HasPrefix(value, "#"):
+			stack = append(stack, frame{kind: mappingFrame, indent: indent, path: path})
+		case strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+			blockScalarIndent = indent
+		}
+	}
+
+	return result
+}
+
+func parentPath(stack []frame) string {
+	if len(stack) == 0 {
+		return ""
+	}
+	return stack[len(stack)-1].path
+}
@@ -15,12 +15,16 @@
 package kubeyaml
 
 import (
+	"bufio"
 	"bytes"
+	"io"
 	"strings"
+	"unicode"
 )
 
 const (
 	yamlSeparator = "---\n"
+	separator     = "---"
 )
 
 // Join the given yaml parts into a single multipart document.
@@ -67,3 +71,49 @@ func JoinString(parts ...string) string {
 
 	return st.String()
 }
+
+// LineReader reads the documents of a multipart yaml stream, keeping track of the line each document starts at.
+type LineReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+// NewLineReader returns a LineReader reading from r.
+func NewLineReader(r *bufio.Reader) *LineReader {
+	return &LineReader{reader: r}
+}
+
+// Read returns the next document and the line (starting at 1) of its first non-blank line. The document
+// separator is not included. Returns io.EOF when there are no more documents.
+func (r *LineReader) Read() ([]byte, int, error) {
+	var buffer bytes.Buffer
+	start := 0
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		if len(line) > 0 {
+			r.line++
+		}
+
+		if bytes.HasPrefix(line, []byte(separator)) &&
+			len(strings.TrimRightFunc(string(line[len(separator):]), unicode.IsSpace)) == 0 {
+			if buffer.Len() != 0 {
+				return buffer.Bytes(), start, nil
+			}
+		} else {
+			if start == 0 && len(bytes.TrimSpace(line)) > 0 {
+				start = r.line
+			}
+			buffer.Write(line)
+		}
+
+		if err == io.EOF {
+			if buffer.Len() != 0 {
+				return buffer.Bytes(), start, nil
+			}
+			return nil, 0, io.EOF
+		}
+	}
+}
@@ -15,7 +15,10 @@
 package kubeyaml
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -104,3 +107,108 @@ func TestJoinString(t *testing.T) {
 		})
 	}
 }
+
+func TestLineReader(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	input := `# leading comment
+foo: bar
+---
+
+baz: boo
+qux: quux
+---
+---
+last: doc`
+	r := NewLineReader(bufio.NewReader(strings.NewReader(input)))
+
+	expected := []struct {
+		doc  string
+		line int
+	}{
+		{"# leading comment\nfoo: bar\n", 1},
+		{"\nbaz: boo\nqux: quux\n", 5},
+		{"last: doc", 9},
+	}
+	for _, e := range expected {
+		doc, line, err := r.Read()
+		g.Expect(err).To(BeNil())
+		g.Expect(string(doc)).To(Equal(e.doc))
+		g.Expect(line).To(Equal(e.line))
+	}
+
+	_, _, err := r.Read()
+	g.Expect(err).To(Equal(io.EOF))
+}
+
+func TestFieldLines(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	doc := `kind: VirtualService
+metadata:
+  name: reviews
+spec:
+  hosts:
+    - reviews
+  http:
+  - match:
+    - uri:
+        prefix: /foo # comment
+    route:
+    - destination:
+        host: a
+      weight: 10
+    - destination:
+        host: b
+  - description: |
+      text: not a field
+      - nor an item
+    mirror:
+      host: c
+`
+	lines := FieldLines([]byte(doc), 10)
+
+	expected := map[string]int{
+		"kind":                                   10,
+		"metadata.name":                          12,
+		"spec.hosts[0]":                          15,
+		"spec.http[0]":                           17,
+		"spec.http[0].match[0].uri.prefix":       19,
+		"spec.http[0].route":                     20,
+		"spec.http[0].route[0].destination.host": 22,
+		"spec.http[0].route[0].weight":           23,
+		"spec.http[0].route[1].destination.host": 25,
+		"spec.http[1].description":               26,
+		"spec.http[1].mirror.host":               30,
+	}
+	for path, line := range expected {
+		g.Expect(lines).To(HaveKeyWithValue(path, line), path)
+	}
+	g.Expect(lines).NotTo(HaveKey("spec.http[1].description.text"))
+	g.Expect(lines).NotTo(HaveKey("spec.http[1][0]"))
+}
+
+func TestSplitKey(t *testing.T) {
+	cases := []struct {
+		line string
+		key  string
+		rest string
+		ok   bool
+	}{
+		{line: "host: reviews # comment", key: "host", rest: "reviews # comment", ok: true},
+		{line: "route:", key: "route", rest: "", ok: true},
+		{line: `"a: b": c`, key: `"a: b"`, rest: "c", ok: true},
+		{line: "- item"},
+		{line: "url: http://foo", key: "url", rest: "http://foo", ok: true},
+		{line: "http://foo"},
+	}
+	for _, c := range cases {
+		t.Run(c.line, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			key, rest, ok := SplitKey(c.line)
+			g.Expect(ok).To(Equal(c.ok))
+			g.Expect(key).To(Equal(c.key))
+			g.Expect(rest).To(Equal(c.rest))
+		})
+	}
+}
@@ -32,7 +32,9 @@ import (
 	"istio.io/pkg/log"
 	"istio.io/pkg/version"
 
+	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers"
+	"istio.io/istio/galley/pkg/config/analysis/external"
 	"istio.io/istio/galley/pkg/config/processing"
 	"istio.io/istio/galley/pkg/config/processing/snapshotter"
 	"istio.io/istio/galley/pkg/config/processor"
@@ -46,6 +48,7 @@ import (
 	"istio.io/istio/galley/pkg/server/process"
 	"istio.io/istio/galley/pkg/server/settings"
 	"istio.io/istio/pkg/config/event"
+	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/snapshots"
@@ -122,15 +125,29 @@ func (p *Processing) Start() (err error) {
 	var distributor snapshotter.Distributor = snapshotter.NewMCPDistributor(p.mcpCache)
 
 	if p.args.EnableConfigAnalysis {
-		combinedAnalyzer := analyzers.AllCombined()
+		all := analyzers.All()
+		if len(p.args.CustomAnalyzers) > 0 {
+			var custom []analysis.Analyzer
+			if custom, err = external.Load(p.args.CustomAnalyzers); err != nil {
+				return
+			}
+			all = append(all, custom...)
+		}
+		combinedAnalyzer := analysis.Combine("all", all...)
 		combinedAnalyzer.RemoveSkipped(colsInSnapshots, kubeResources.DisabledCollectionNames(), transformProviders)
 
+		var namespaces []resource.Namespace
+		for _, ns := range p.args.AnalysisNamespaces {
+			namespaces = append(namespaces, resource.Namespace(ns))
+		}
+
 		distributor = snapshotter.NewAnalyzingDistributor(snapshotter.AnalyzingDistributorSettings{
-			StatusUpdater:     updater,
-			Analyzer:          combinedAnalyzer,
-			Distributor:       distributor,
-			AnalysisSnapshots: p.args.Snapshots,
-			TriggerSnapshot:   p.args.TriggerSnapshot,
+			StatusUpdater:      updater,
+			Analyzer:           combinedAnalyzer,
+			Distributor:        distributor,
+			AnalysisSnapshots:  p.args.Snapshots,
+			TriggerSnapshot:    p.args.TriggerSnapshot,
+			AnalysisNamespaces: namespaces,
 		})
 	}
 
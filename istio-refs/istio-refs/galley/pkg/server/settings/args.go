@@ -132,6 +132,14 @@ type Args struct { // nolint:maligned
 	// Enable Config Analysis service, that will analyze and update CRD status. UseOldProcessor must be set to false.
 	EnableConfigAnalysis bool
 
+	// CustomAnalyzers are the paths to policy files, or directories of policy files, declaring additional
+	// analyzers written in CEL or Rego. Only used if EnableConfigAnalysis is set.
+	CustomAnalyzers []string
+
+	// AnalysisNamespaces limits the namespaces of the resources whose status is updated with the analysis messages.
+	// All namespaces are analyzed if empty. Only used if EnableConfigAnalysis is set.
+	AnalysisNamespaces []string
+
 	// DisableResourceReadyCheck disables the CRD readiness check. This
 	// allows Galley to start when not all supported CRD are
 	// registered with the kube-apiserver.
@@ -15,8 +15,10 @@
 package cmd
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -36,8 +38,12 @@ import (
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers"
 	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/external"
 	"istio.io/istio/galley/pkg/config/analysis/local"
 	cfgKube "istio.io/istio/galley/pkg/config/source/kube"
+	"istio.io/istio/istioctl/pkg/baseline"
+	"istio.io/istio/istioctl/pkg/fix"
+	"istio.io/istio/istioctl/pkg/util/formatting"
 	"istio.io/istio/istioctl/pkg/util/handlers"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema"
@@ -55,6 +61,8 @@ const (
 	LogOutput       = "log"
 	JSONOutput      = "json"
 	YamlOutput      = "yaml"
+	SarifOutput     = "sarif"
+	JUnitOutput     = "junit"
 )
 
 func (f AnalyzerFoundIssuesError) Error() string {
@@ -81,6 +89,10 @@ var (
 	suppress          []string
 	analysisTimeout   time.Duration
 	recursive         bool
+	applyFixes        bool
+	interactiveFixes  bool
+	customAnalyzers   []string
+	baselineFile      string
 
 	termEnvVar = env.RegisterStringVar("TERM", "", "Specifies terminal type.  Use 'dumb' to suppress color output")
 
@@ -94,7 +106,7 @@ var (
 // Analyze command
 func Analyze() *cobra.Command {
 	// Validate the output format before doing potentially expensive work to fail earlier
-	msgOutputFormats := map[string]bool{LogOutput: true, JSONOutput: true, YamlOutput: true}
+	msgOutputFormats := map[string]bool{LogOutput: true, JSONOutput: true, YamlOutput: true, SarifOutput: true, JUnitOutput: true}
 	var msgOutputFormatKeys []string
 
 	for k := range msgOutputFormats {
@@ -124,6 +136,18 @@ istioctl analyze -S "IST0103=Pod mypod.testing"
 # and suppress MisplacedAnnotation on deployment foobar in namespace default.
 istioctl analyze -S "IST0103=Pod *.testing" -S "IST0107=Deployment foobar.default"
 
+# Analyze yaml files and write a SARIF report for code scanning tools
+istioctl analyze --use-kube=false -o sarif my-app-config/ > analysis.sarif
+
+# Analyze yaml files and fix the issues that can be fixed automatically, asking before each fix
+istioctl analyze --use-kube=false --fix --interactive my-app-config/
+
+# Analyze the current live cluster with additional analyzers declared in CEL or Rego policy files
+istioctl analyze --custom-analyzers my-policies/
+
+# Analyze the current live cluster, reporting the findings accepted in a checked-in baseline file as suppressed
+istioctl analyze --baseline istio-analyze-baseline.yaml
+
 # List available analyzers
 istioctl analyze -L
 `,
@@ -136,11 +160,48 @@ istioctl analyze -L
 				}
 			}
 
+			allAnalyzers := analyzers.All()
+			var customMessageTypes []*diag.MessageType
+			if len(customAnalyzers) > 0 {
+				custom, err := external.Load(customAnalyzers)
+				if err != nil {
+					return fmt.Errorf("failed to load custom analyzers: %v", err)
+				}
+				allAnalyzers = append(allAnalyzers, custom...)
+				customMessageTypes = external.MessageTypes(custom)
+			}
+
 			if listAnalyzers {
-				fmt.Print(AnalyzersAsString(analyzers.All()))
+				fmt.Print(AnalyzersAsString(allAnalyzers))
 				return nil
 			}
 
+			if interactiveFixes && !applyFixes {
+				return CommandParseError{fmt.Errorf("--interactive can only be used with --fix")}
+			}
+			if interactiveFixes {
+				for _, f := range args {
+					if f == "-" {
+						return CommandParseError{fmt.Errorf("--interactive can't be used when reading from stdin")}
+					}
+				}
+			}
+
+			knownMessageTypes := append(msg.All(), customMessageTypes...)
+
+			var bl *baseline.File
+			if baselineFile != "" {
+				var err error
+				if bl, err = baseline.Load(baselineFile); err != nil {
+					return fmt.Errorf("failed to load baseline: %v", err)
+				}
+				for _, s := range bl.Suppressions {
+					if !isKnownMessageCode(s.Code, knownMessageTypes) {
+						fmt.Fprintf(cmd.ErrOrStderr(), "Warning: Baseline message code '%s' is an unknown message code and will not have any effect.\n", s.Code)
+					}
+				}
+			}
+
 			readers, err := gatherFiles(args)
 			if err != nil {
 				return err
@@ -156,7 +217,7 @@ istioctl analyze -L
 				selectedNamespace = ""
 			}
 
-			sa := local.NewSourceAnalyzer(schema.MustGet(), analyzers.AllCombined(),
+			sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("all", allAnalyzers...),
 				resource.Namespace(selectedNamespace), resource.Namespace(istioNamespace), nil, true, analysisTimeout)
 
 			// Check for suppressions and add them to our SourceAnalyzer
@@ -168,15 +229,7 @@ istioctl analyze -L
 				}
 				// Check to see if the supplied code is valid. If not, emit a
 				// warning but continue.
-				codeIsValid := false
-				for _, at := range msg.All() {
-					if at.Code() == parts[0] {
-						codeIsValid = true
-						break
-					}
-				}
-
-				if !codeIsValid {
+				if !isKnownMessageCode(parts[0], knownMessageTypes) {
 					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: Supplied message code '%s' is an unknown message code and will not have any effect.\n", parts[0])
 				}
 				suppressions = append(suppressions, snapshotter.AnalysisSuppression{
@@ -247,6 +300,13 @@ istioctl analyze -L
 				fmt.Fprintln(cmd.ErrOrStderr())
 			}
 
+			// Set aside the messages accepted in the baseline. Those matched by expired suppressions only are kept.
+			if bl != nil {
+				blResult := bl.Apply(result.Messages, time.Now())
+				result.Messages = blResult.Messages
+				printBaselineResult(cmd.ErrOrStderr(), blResult)
+			}
+
 			// Filter outputMessages by specified level, and append a ref arg to the doc URL
 			var outputMessages diag.Messages
 			for _, m := range result.Messages {
@@ -291,10 +351,28 @@ istioctl analyze -L
 					return err
 				}
 				fmt.Fprintln(cmd.OutOrStdout(), string(yamlOutput))
+			case SarifOutput:
+				sarifOutput, err := formatting.SARIF(outputMessages)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(sarifOutput))
+			case JUnitOutput:
+				junitOutput, err := formatting.JUnit(outputMessages, failureLevel.Level)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(junitOutput))
 			default: // This should never happen since we validate this already
 				panic(fmt.Sprintf("%q not found in output format switch statement post validate?", msgOutputFormat))
 			}
 
+			if applyFixes {
+				if err := fixMessages(cmd, outputMessages); err != nil {
+					return err
+				}
+			}
+
 			// Return code is based on the unfiltered validation message list/parse errors
 			// We're intentionally keeping failure threshold and output threshold decoupled for now
 			returnError := errorIfMessagesExceedThreshold(result.Messages)
@@ -331,9 +409,50 @@ istioctl analyze -L
 		"the duration to wait before failing")
 	analysisCmd.PersistentFlags().BoolVarP(&recursive, "recursive", "R", false,
 		"Process directory arguments recursively. Useful when you want to analyze related manifests organized within the same directory.")
+	analysisCmd.PersistentFlags().BoolVar(&applyFixes, "fix", false,
+		"Fix the reported issues that can be fixed automatically. Resources from files are fixed in place, "+
+			"preserving comments and formatting. For resources from the cluster, the kubectl commands fixing them are printed.")
+	analysisCmd.PersistentFlags().BoolVar(&interactiveFixes, "interactive", false,
+		"Ask for confirmation before applying each fix. Only used with --fix.")
+	analysisCmd.PersistentFlags().StringArrayVar(&customAnalyzers, "custom-analyzers", []string{},
+		"Policy files, or directories of policy files, declaring additional analyzers written in CEL or Rego. Can be repeated.")
+	analysisCmd.PersistentFlags().StringVar(&baselineFile, "baseline", "",
+		"Baseline file listing accepted findings, each with a code, a resource, an owner, a reason and an optional expiry date. "+
+			"Matching messages are reported as suppressed and don't affect the exit code until their suppression expires.")
 	return analysisCmd
 }
 
+func isKnownMessageCode(code string, messageTypes []*diag.MessageType) bool {
+	for _, mt := range messageTypes {
+		if mt.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+func printBaselineResult(w io.Writer, r baseline.Result) {
+	for _, sm := range r.Suppressed {
+		fmt.Fprintf(w, "Suppressed [%s] (%s): %s (owner: %s%s)\n", sm.Message.Type.Code(),
+			sm.Message.Resource.Origin.FriendlyName(), sm.Suppression.Reason, sm.Suppression.Owner, expiresSuffix(sm.Suppression))
+	}
+	for _, sm := range r.Expired {
+		fmt.Fprintf(w, "Expired suppression for [%s] (%s), owned by %s, expired on %s\n", sm.Message.Type.Code(),
+			sm.Message.Resource.Origin.FriendlyName(), sm.Suppression.Owner, sm.Suppression.Expires)
+	}
+	for _, s := range r.Unused {
+		fmt.Fprintf(w, "Warning: Baseline suppression of %s on %q, owned by %s, matches no message and can be removed.\n",
+			s.Code, s.Resource, s.Owner)
+	}
+}
+
+func expiresSuffix(s *baseline.Suppression) string {
+	if s.Expires == "" {
+		return ""
+	}
+	return ", expires: " + s.Expires
+}
+
 func gatherFiles(args []string) ([]local.ReaderSource, error) {
 	var readers []local.ReaderSource
 	for _, f := range args {
@@ -407,6 +526,39 @@ func gatherFilesInDirectory(dir string) ([]local.ReaderSource, error) {
 	return readers, err
 }
 
+// fixMessages applies the fixes attached to the messages, asking for confirmation first if requested.
+func fixMessages(cmd *cobra.Command, ms diag.Messages) error {
+	var confirm fix.Confirm
+	if interactiveFixes {
+		in := bufio.NewReader(cmd.InOrStdin())
+		confirm = func(m diag.Message) (bool, error) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s\n%s? [y/N] ", renderMessage(m), m.Fix.Description)
+			answer, err := in.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return false, err
+			}
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			return answer == "y" || answer == "yes", nil
+		}
+	}
+
+	result, err := fix.Apply(ms, confirm)
+	for _, m := range result.Applied {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Fixed [%v] (%s %s): %s\n",
+			m.Type.Code(), m.Resource.Origin.FriendlyName(), m.Location(), m.Fix.Description)
+	}
+	if len(result.Commands) > 0 {
+		fmt.Fprintln(cmd.ErrOrStderr(), "Run the following commands to fix resources in the cluster:")
+		for _, c := range result.Commands {
+			fmt.Fprintln(cmd.ErrOrStderr(), c)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("some fixes couldn't be applied: %v", err)
+	}
+	return nil
+}
+
 func colorPrefix(m diag.Message) string {
 	if !colorize {
 		return ""
@@ -431,7 +583,11 @@ func colorSuffix() string {
 func renderMessage(m diag.Message) string {
 	origin := ""
 	if m.Resource != nil {
-		origin = " (" + m.Resource.Origin.FriendlyName() + ")"
+		origin = " (" + m.Resource.Origin.FriendlyName()
+		if loc := m.Location(); loc != "" {
+			origin += " " + loc
+		}
+		origin += ")"
 	}
 	return fmt.Sprintf(
 		"%s%v%s [%v]%s %s", colorPrefix(m), m.Type.Level(), colorSuffix(), m.Type.Code(), origin, fmt.Sprintf(m.Type.Template(), m.Parameters...))
@@ -21,6 +21,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -50,6 +51,10 @@ var (
 	serviceFiles             []string
 	rootNamespace            string
 	allowNoClusterRbacConfig bool
+
+	authzRequest authz.Request
+	authzHeaders []string
+	authzClaims  []string
 )
 
 var (
@@ -63,13 +68,25 @@ with authorization and the rules used in the authorization.
 The Envoy config dump could be provided either by pod name or from a config dump file
 (the whole output of http://localhost:15000/config_dump of an Envoy instance).
 
+With --port, check instead evaluates a synthetic request received on that port against the
+RBAC filters of the inbound filter chain, and prints whether the request is allowed or denied,
+with the policy and rule that decided it. The decision is undetermined when it depends on
+conditions that can't be evaluated, e.g. HTTP attributes in a TCP filter chain.
+
 THIS COMMAND IS STILL UNDER ACTIVE DEVELOPMENT AND NOT READY FOR PRODUCTION USE.
 `,
 		Example: `  # Check Envoy authorization configuration for pod httpbin-88ddbcfdd-nt5jb:
   istioctl x authz check httpbin-88ddbcfdd-nt5jb
 
   # Check Envoy authorization configuration from a config dump file:
-  istioctl x authz check -f httpbin_config_dump.json`,
+  istioctl x authz check -f httpbin_config_dump.json
+
+  # Check whether a GET request from the default namespace to port 8000 is allowed:
+  istioctl x authz check httpbin-88ddbcfdd-nt5jb --port 8000 --source-namespace default --path /headers
+
+  # Check whether a request with a JWT of the given claims is allowed:
+  istioctl x authz check -f httpbin_config_dump.json --port 8000 --method POST \
+    --claim iss=testing@secure.istio.io --claim groups=admin -H "x-token: admin"`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 1 {
 				cmd.Println(cmd.UsageString())
@@ -99,7 +116,23 @@ THIS COMMAND IS STILL UNDER ACTIVE DEVELOPMENT AND NOT READY FOR PRODUCTION USE.
 			if err != nil {
 				return err
 			}
-			analyzer.Print(cmd.OutOrStdout(), printAll)
+			if authzRequest.DestinationPort == 0 {
+				analyzer.Print(cmd.OutOrStdout(), printAll)
+				return nil
+			}
+
+			req := authzRequest
+			if req.Headers, err = parseRequestHeaders(authzHeaders); err != nil {
+				return CommandParseError{err}
+			}
+			if req.Claims, err = parseClaims(authzClaims); err != nil {
+				return CommandParseError{err}
+			}
+			decision, err := analyzer.Evaluate(&req)
+			if err != nil {
+				return err
+			}
+			decision.Print(cmd.OutOrStdout())
 			return nil
 		},
 	}
@@ -184,6 +217,19 @@ PLEASE ALWAYS REVIEW THE CONVERTED POLICIES BEFORE APPLYING.
 	}
 )
 
+// parseClaims parses claims given as key=value. A claim given several times is a list claim.
+func parseClaims(claims []string) (map[string][]string, error) {
+	parsed := map[string][]string{}
+	for _, c := range claims {
+		kv := strings.SplitN(c, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid claim %q, expecting key=value", c)
+		}
+		parsed[kv[0]] = append(parsed[kv[0]], kv[1])
+	}
+	return parsed, nil
+}
+
 func getConfigDumpFromFile(filename string) (*configdump.Wrapper, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -376,6 +422,30 @@ func init() {
 		"Show additional information (e.g. SNI and ALPN)")
 	checkCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"The json file with Envoy config dump to be checked")
+	checkCmd.PersistentFlags().Uint32Var(&authzRequest.DestinationPort, "port", 0,
+		"Evaluate a request received on this port instead of printing the authorization configuration")
+	checkCmd.PersistentFlags().StringVar(&authzRequest.DestinationIP, "destination-ip", "",
+		"Destination IP of the evaluated request, defaults to the node IP")
+	checkCmd.PersistentFlags().StringVar(&authzRequest.SourcePrincipal, "principal", "",
+		"Peer identity of the evaluated request, e.g. cluster.local/ns/default/sa/sleep. Empty for plaintext")
+	checkCmd.PersistentFlags().StringVar(&authzRequest.SourceNamespace, "source-namespace", "",
+		"Namespace of the peer of the evaluated request, a shorthand for the principal of its default service account")
+	checkCmd.PersistentFlags().StringVar(&authzRequest.SourceIP, "source-ip", "",
+		"Source IP of the evaluated request")
+	checkCmd.PersistentFlags().StringVar(&authzRequest.ServerName, "sni", "",
+		"Server name of the connection of the evaluated request")
+	checkCmd.PersistentFlags().StringVar(&authzRequest.Method, "method", "GET",
+		"Method of the evaluated request")
+	checkCmd.PersistentFlags().StringVar(&authzRequest.Host, "host", "",
+		"Host of the evaluated request")
+	checkCmd.PersistentFlags().StringVar(&authzRequest.Path, "path", "/",
+		"Path of the evaluated request")
+	checkCmd.PersistentFlags().StringArrayVarP(&authzHeaders, "header", "H", nil,
+		`Header of the evaluated request, as "Name: value". Can be repeated`)
+	checkCmd.PersistentFlags().StringVar(&authzRequest.RequestPrincipal, "request-principal", "",
+		"Principal of the JWT of the evaluated request, defaults to the iss/sub claims")
+	checkCmd.PersistentFlags().StringArrayVar(&authzClaims, "claim", nil,
+		"Claim of the JWT of the evaluated request, as key=value. Repeat a key for a list claim")
 	convertCmd.PersistentFlags().StringSliceVarP(&v1Files, "file", "f", []string{},
 		"The yaml file with v1alpha1 RBAC policies to be converted")
 	convertCmd.PersistentFlags().StringSliceVarP(&serviceFiles, "service", "s", []string{},
@@ -92,6 +92,7 @@ func TestAuthZCheck(t *testing.T) {
 	testCases := []struct {
 		name   string
 		in     string
+		args   string
 		golden string
 	}{
 		{
@@ -99,10 +100,28 @@ func TestAuthZCheck(t *testing.T) {
 			in:     "testdata/authz/productpage_config_dump.json",
 			golden: "testdata/authz/productpage.golden",
 		},
+		{
+			name:   "allowed request",
+			in:     "testdata/authz/productpage_config_dump.json",
+			args:   " --port 9080 --source-namespace default",
+			golden: "testdata/authz/productpage-allow.golden",
+		},
+		{
+			name:   "denied request",
+			in:     "testdata/authz/productpage_config_dump.json",
+			args:   " --port 9080 --source-namespace default --method POST",
+			golden: "testdata/authz/productpage-deny.golden",
+		},
+		{
+			name:   "denied plaintext request",
+			in:     "testdata/authz/productpage_config_dump.json",
+			args:   " --port 9080 --source-namespace= --method GET",
+			golden: "testdata/authz/productpage-deny-plaintext.golden",
+		},
 	}
 
 	for _, c := range testCases {
-		command := fmt.Sprintf("experimental authz check -f %s", c.in)
+		command := fmt.Sprintf("experimental authz check -f %s%s", c.in, c.args)
 		runCommandWantOutput(command, c.golden, t)
 	}
 }
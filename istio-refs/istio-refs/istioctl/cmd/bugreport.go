@@ -0,0 +1,174 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/local"
+	cfgKube "istio.io/istio/galley/pkg/config/source/kube"
+	"istio.io/istio/istioctl/pkg/bugreport"
+	istioctl_kubernetes "istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema"
+	"istio.io/istio/pkg/kube"
+)
+
+func bugReportCmd() *cobra.Command {
+	var (
+		opts        bugreport.Options
+		outputFile  string
+		start, end  string
+		runAnalysis bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bug-report",
+		Short: "Collect the diagnostic data needed to investigate an Istio issue into an archive",
+		Long: `Collects, in a single gzipped tarball, the data usually needed to investigate an Istio issue:
+  - cluster metadata: Kubernetes and Istio versions, nodes and namespaces
+  - control plane logs and Pilot /debug/* dumps
+  - Envoy config dumps, clusters, stats and logs of the selected proxies
+  - the output of istioctl analyze
+
+Items are collected in parallel, each with a timeout. Items which can't be collected are listed in the errors.txt
+file of the archive rather than failing the command. Certificates, keys, inline secrets and tokens are redacted from
+the archive, but please review it before sharing it.
+`,
+		Example: `
+# Collect a bug report for the proxies in all namespaces
+istioctl bug-report
+
+# Collect a bug report for the proxies of the reviews app in namespace 'bookinfo', with the logs of the last hour
+istioctl bug-report --namespaces bookinfo -l app=reviews --since 1h
+
+# Collect a bug report with the logs of a time window
+istioctl bug-report --start 2020-03-01T10:00:00Z --end 2020-03-01T11:00:00Z -o incident.tar.gz
+`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			var err error
+			if opts.Start, err = parseReportTime("start", start); err != nil {
+				return CommandParseError{err}
+			}
+			if opts.End, err = parseReportTime("end", end); err != nil {
+				return CommandParseError{err}
+			}
+			opts.IstioNamespace = istioNamespace
+			if runAnalysis {
+				opts.Analyze = bugReportAnalyzer(opts.Timeout)
+			}
+			if err := opts.Validate(); err != nil {
+				return CommandParseError{err}
+			}
+
+			client, err := istioctl_kubernetes.NewClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			kubeClient, err := createInterface(kubeconfig)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+
+			now := time.Now()
+			name := "bug-report-" + now.UTC().Format("20060102-150405")
+			if outputFile == "" {
+				outputFile = name + ".tar.gz"
+			}
+
+			fmt.Fprintf(c.ErrOrStderr(), "Collecting bug report...\n")
+			bundle, err := bugreport.NewCollector(client, kubeClient, opts).Collect(now)
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Create(outputFile)
+			if err != nil {
+				return err
+			}
+			if err := bundle.WriteArchive(f, name); err != nil {
+				_ = f.Close()
+				return fmt.Errorf("failed to write %s: %v", outputFile, err)
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+
+			if errs := bundle.Errors(); len(errs) > 0 {
+				fmt.Fprintf(c.ErrOrStderr(), "%d item(s) couldn't be collected:\n  %s\n", len(errs), strings.Join(errs, "\n  "))
+			}
+			fmt.Fprintf(c.OutOrStdout(), "Wrote %d file(s) to %s\n", len(bundle.Files()), outputFile)
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "",
+		"Path of the archive to write. Defaults to bug-report-<time>.tar.gz in the current directory.")
+	cmd.PersistentFlags().StringSliceVar(&opts.Namespaces, "namespaces", nil,
+		"Namespaces of the proxies to collect data from. All namespaces if empty.")
+	cmd.PersistentFlags().StringVarP(&opts.LabelSelector, "selector", "l", "",
+		"Label selector restricting the proxies to collect data from.")
+	cmd.PersistentFlags().DurationVar(&opts.Since, "since", 0,
+		"Only collect the logs newer than a relative duration like 5s, 2m, or 3h. Defaults to all logs.")
+	cmd.PersistentFlags().StringVar(&start, "start", "",
+		"Only collect the logs written at or after this time, in the RFC3339 format. Can't be used with --since.")
+	cmd.PersistentFlags().StringVar(&end, "end", "",
+		"Only collect the logs written at or before this time, in the RFC3339 format.")
+	cmd.PersistentFlags().DurationVar(&opts.Timeout, "timeout", 30*time.Second,
+		"The duration to wait for each item collected before giving up on it.")
+	cmd.PersistentFlags().IntVar(&opts.Parallelism, "parallel", 10,
+		"The maximum number of items collected concurrently.")
+	cmd.PersistentFlags().BoolVar(&runAnalysis, "analyze", true,
+		"Include the output of istioctl analyze for the live cluster.")
+	return cmd
+}
+
+func parseReportTime(flag, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s %q, must be in the RFC3339 format (e.g. 2020-03-01T10:00:00Z)", flag, value)
+	}
+	return t, nil
+}
+
+// bugReportAnalyzer returns a function analyzing the live cluster with all the analyzers, as istioctl analyze does.
+func bugReportAnalyzer(timeout time.Duration) func() (diag.Messages, error) {
+	return func() (diag.Messages, error) {
+		restConfig, err := kube.BuildClientCmd(kubeconfig, configContext).ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("all", analyzers.All()...),
+			"", resource.Namespace(istioNamespace), nil, true, timeout)
+		sa.AddRunningKubeSource(cfgKube.NewInterfaces(restConfig))
+		result, err := sa.Analyze(make(chan struct{}))
+		if err != nil {
+			return nil, err
+		}
+		return result.Messages, nil
+	}
+}
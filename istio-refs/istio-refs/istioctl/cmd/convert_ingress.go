@@ -20,6 +20,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/ghodss/yaml"
 	"github.com/hashicorp/go-multierror"
@@ -39,35 +40,89 @@ import (
 var (
 	inFilenames        []string
 	outConvertFilename string
+	convertTarget      string
 
 	schemas = collection.SchemasFor(
 		collections.IstioNetworkingV1Alpha3Virtualservices,
-		collections.IstioNetworkingV1Alpha3Gateways)
+		collections.IstioNetworkingV1Alpha3Gateways,
+		collections.K8SServiceApisV1Alpha1Gateways,
+		collections.K8SServiceApisV1Alpha1Httproutes)
 )
 
-func convertConfigs(readers []io.Reader, writer io.Writer) error {
-	configs, ingresses, err := readConfigs(readers)
+const (
+	convertToIstio       = "istio"
+	convertToServiceAPIs = "service-apis"
+)
+
+// convertInputs holds the resources read by convert-ingress.
+type convertInputs struct {
+	configs        []model.Config
+	ingresses      []*v1beta1.Ingress
+	ingressesV1    []*convert.IngressV1
+	ingressClasses []*convert.IngressClassV1
+}
+
+// configsOfType returns the configs of a collection.
+func (in *convertInputs) configsOfType(s collection.Schema) []model.Config {
+	out := make([]model.Config, 0)
+	for _, cfg := range in.configs {
+		if cfg.GroupVersionKind() == s.Resource().GroupVersionKind() {
+			out = append(out, cfg)
+		}
+	}
+	return out
+}
+
+func convertConfigs(readers []io.Reader, writer, warnings io.Writer, target string) error {
+	in, err := readConfigs(readers)
 	if err != nil {
 		return err
 	}
 
-	if err = validateConfigs(configs); err != nil {
+	if err = validateConfigs(in.configs); err != nil {
 		return err
 	}
 
-	// ingresses without specified namespace need to generate valid output; use the default
-	for _, ingress := range ingresses {
+	// resources without specified namespace need to generate valid output; use the default
+	for _, ingress := range in.ingresses {
 		if ingress.Namespace == "" {
 			ingress.Namespace = defaultNamespace
 		}
 	}
+	for _, ingress := range in.ingressesV1 {
+		if ingress.Namespace == "" {
+			ingress.Namespace = defaultNamespace
+		}
+	}
+	for i := range in.configs {
+		if in.configs[i].Namespace == "" {
+			in.configs[i].Namespace = defaultNamespace
+		}
+	}
 
-	out := make([]model.Config, 0)
-	convertedIngresses, err := convert.IstioIngresses(ingresses, "")
-	if err == nil {
-		out = append(out, convertedIngresses...)
-	} else {
-		return multierror.Prefix(err, "Ingress rules invalid")
+	var out []model.Config
+	var warns []string
+	switch target {
+	case convertToIstio:
+		if out, warns, err = convertToIstioConfigs(in); err != nil {
+			return err
+		}
+	case convertToServiceAPIs:
+		if len(in.ingresses) > 0 || len(in.ingressesV1) > 0 {
+			return fmt.Errorf("ingresses can only be converted to %s configuration", convertToIstio)
+		}
+		out, warns, err = convert.IstioToServiceAPIs(
+			in.configsOfType(collections.IstioNetworkingV1Alpha3Gateways),
+			in.configsOfType(collections.IstioNetworkingV1Alpha3Virtualservices))
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown conversion target %q, must be %s or %s", target, convertToIstio, convertToServiceAPIs)
+	}
+
+	for _, warning := range warns {
+		fmt.Fprintf(warnings, "Warning: %s\n", warning)
 	}
 
 	writeYAMLOutput(schemas, out, writer)
@@ -79,33 +134,84 @@ func convertConfigs(readers []io.Reader, writer io.Writer) error {
 	return nil
 }
 
-func readConfigs(readers []io.Reader) ([]model.Config, []*v1beta1.Ingress, error) {
+// convertToIstioConfigs converts the ingresses and the service-apis resources to Istio configuration.
+func convertToIstioConfigs(in *convertInputs) ([]model.Config, []string, error) {
 	out := make([]model.Config, 0)
-	outIngresses := make([]*v1beta1.Ingress, 0)
+	convertedIngresses, err := convert.IstioIngresses(in.ingresses, "")
+	if err == nil {
+		out = append(out, convertedIngresses...)
+	} else {
+		return nil, nil, multierror.Prefix(err, "Ingress rules invalid")
+	}
+
+	convertedIngresses, warnings := convert.IstioIngressesV1(in.ingressesV1, in.ingressClasses, "")
+	out = append(out, convertedIngresses...)
+
+	for _, s := range []collection.Schema{
+		collections.K8SServiceApisV1Alpha1Tcproutes,
+		collections.K8SServiceApisV1Alpha1Trafficsplits,
+	} {
+		for _, cfg := range in.configsOfType(s) {
+			warnings = append(warnings, fmt.Sprintf("%s %s/%s is not supported, skipping",
+				s.Resource().Kind(), cfg.Namespace, cfg.Name))
+		}
+	}
+	converted, gatewayWarnings := convert.ServiceAPIsToIstio(
+		in.configsOfType(collections.K8SServiceApisV1Alpha1Gateways),
+		in.configsOfType(collections.K8SServiceApisV1Alpha1Httproutes), "")
+	out = append(out, converted...)
+
+	return out, append(warnings, gatewayWarnings...), nil
+}
+
+func readConfigs(readers []io.Reader) (*convertInputs, error) {
+	in := &convertInputs{
+		configs:   make([]model.Config, 0),
+		ingresses: make([]*v1beta1.Ingress, 0),
+	}
 
 	for _, reader := range readers {
 		data, err := ioutil.ReadAll(reader)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
-		configs, kinds, err := crd.ParseInputs(string(data))
+		// The service-apis resources are validated as Istio resources of the same kind, so only the Istio
+		// resources are validated, by validateConfigs.
+		configs, kinds, err := crd.ParseInputsWithoutValidation(string(data))
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 
 		recognized := 0
 		for _, nonIstio := range kinds {
-			if nonIstio.Kind == "Ingress" &&
-				nonIstio.APIVersion == "extensions/v1beta1" {
-
+			switch {
+			case nonIstio.Kind == "Ingress" && nonIstio.APIVersion == "extensions/v1beta1":
 				ingress, err := parseIngress(nonIstio)
 				if err != nil {
 					log.Errorf("Could not decode ingress %v: %v", nonIstio.Name, err)
 					continue
 				}
 
-				outIngresses = append(outIngresses, ingress)
+				in.ingresses = append(in.ingresses, ingress)
+				recognized++
+			case nonIstio.Kind == "Ingress" && nonIstio.APIVersion == "networking.k8s.io/v1":
+				ingress := &convert.IngressV1{}
+				if err := reserialize(nonIstio, ingress); err != nil {
+					log.Errorf("Could not decode ingress %v: %v", nonIstio.Name, err)
+					continue
+				}
+
+				in.ingressesV1 = append(in.ingressesV1, ingress)
+				recognized++
+			case nonIstio.Kind == "IngressClass" && strings.HasPrefix(nonIstio.APIVersion, "networking.k8s.io/"):
+				class := &convert.IngressClassV1{}
+				if err := reserialize(nonIstio, class); err != nil {
+					log.Errorf("Could not decode ingress class %v: %v", nonIstio.Name, err)
+					continue
+				}
+
+				in.ingressClasses = append(in.ingressClasses, class)
 				recognized++
 			}
 		}
@@ -123,12 +229,12 @@ func readConfigs(readers []io.Reader) ([]model.Config, []*v1beta1.Ingress, error
 				msg = multierror.Append(msg, fmt.Errorf("unsupported kind: %v", kind))
 			}
 
-			return nil, nil, msg
+			return nil, msg
 		}
 
-		out = append(out, configs...)
+		in.configs = append(in.configs, configs...)
 	}
-	return out, outIngresses, nil
+	return in, nil
 }
 
 func writeYAMLOutput(schemas collection.Schemas, configs []model.Config, writer io.Writer) {
@@ -158,29 +264,39 @@ func writeYAMLOutput(schemas collection.Schemas, configs []model.Config, writer
 func validateConfigs(configs []model.Config) error {
 	var errs error
 	for _, cfg := range configs {
-		if collections.IstioNetworkingV1Alpha3Virtualservices.Resource().Kind() == cfg.Type {
+		switch cfg.GroupVersionKind() {
+		case collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind():
 			if err := validation.ValidateVirtualService(cfg.Name, cfg.Namespace, cfg.Spec); err != nil {
 				errs = multierror.Append(err, errs)
 			}
+		case collections.IstioNetworkingV1Alpha3Gateways.Resource().GroupVersionKind():
+			if err := validation.ValidateGateway(cfg.Name, cfg.Namespace, cfg.Spec); err != nil {
+				errs = multierror.Append(err, errs)
+			}
 		}
 	}
 	return errs
 }
 
 func parseIngress(unparsed crd.IstioKind) (*v1beta1.Ingress, error) {
-	// To convert unparsed to a v1beta1.Ingress Marshal into JSON and Unmarshal back
-	b, err := json.Marshal(unparsed)
-	if err != nil {
-		return nil, multierror.Prefix(err, "can't reserialize Ingress")
+	out := &v1beta1.Ingress{}
+	if err := reserialize(unparsed, out); err != nil {
+		return nil, err
 	}
+	return out, nil
+}
 
-	out := &v1beta1.Ingress{}
-	err = json.Unmarshal(b, out)
+// reserialize converts unparsed to a Kubernetes resource, by marshaling it into JSON and unmarshaling it back.
+func reserialize(unparsed crd.IstioKind, out interface{}) error {
+	b, err := json.Marshal(unparsed)
 	if err != nil {
-		return nil, multierror.Prefix(err, "can't deserialize as Ingress")
+		return multierror.Prefix(err, fmt.Sprintf("can't reserialize %s", unparsed.Kind))
 	}
 
-	return out, nil
+	if err = json.Unmarshal(b, out); err != nil {
+		return multierror.Prefix(err, fmt.Sprintf("can't deserialize as %s", unparsed.Kind))
+	}
+	return nil
 }
 
 func convertIngress() *cobra.Command {
@@ -191,9 +307,16 @@ func convertIngress() *cobra.Command {
 			"The output should be considered a starting point for your Istio configuration and probably " +
 			"require some minor modification. " +
 			"Warnings will be generated where configs cannot be converted perfectly. " +
-			"The input must be a Kubernetes Ingress. " +
+			"The input must be Kubernetes Ingresses, of the extensions/v1beta1 or networking.k8s.io/v1 API, " +
+			"or service-apis Gateways and HTTPRoutes. " +
+			"With --to service-apis, Istio Gateways and VirtualServices are converted to service-apis " +
+			"Gateways and HTTPRoutes instead. " +
 			"The conversion of v1alpha1 Istio rules has been removed from istioctl.",
-		Example: "istioctl convert-ingress -f samples/bookinfo/platform/kube/bookinfo-ingress.yaml",
+		Example: `# Convert Ingresses to Istio configuration
+istioctl convert-ingress -f samples/bookinfo/platform/kube/bookinfo-ingress.yaml
+
+# Convert Istio gateways and virtual services to service-apis Gateways and HTTPRoutes
+istioctl convert-ingress --to service-apis -f samples/bookinfo/networking/bookinfo-gateway.yaml`,
 		RunE: func(c *cobra.Command, args []string) error {
 			if len(inFilenames) == 0 {
 				return fmt.Errorf("no input files provided")
@@ -233,7 +356,7 @@ func convertIngress() *cobra.Command {
 				writer = file
 			}
 
-			return convertConfigs(readers, writer)
+			return convertConfigs(readers, writer, c.ErrOrStderr(), convertTarget)
 		},
 	}
 
@@ -241,6 +364,8 @@ func convertIngress() *cobra.Command {
 		nil, "Input filenames")
 	convertIngressCmd.PersistentFlags().StringVarP(&outConvertFilename, "output", "o",
 		"-", "Output filename")
+	convertIngressCmd.PersistentFlags().StringVar(&convertTarget, "to", convertToIstio,
+		fmt.Sprintf("Configuration to convert to, %s or %s", convertToIstio, convertToServiceAPIs))
 
 	return convertIngressCmd
 }
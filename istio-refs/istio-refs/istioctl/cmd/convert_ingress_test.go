@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"io"
+	"io/ioutil"
 	"os"
 	"testing"
 
@@ -27,6 +28,7 @@ func TestConvertIngress(t *testing.T) {
 	tt := []struct {
 		in  []string
 		out string
+		to  string
 	}{
 		// Verify we can convert Kubernetes Istio Ingress
 		{in: []string{"myservice-ingress.yaml"},
@@ -35,6 +37,19 @@ func TestConvertIngress(t *testing.T) {
 		// Verify we can merge Ingresses
 		{in: []string{"myservice-ingress.yaml", "another-ingress.yaml"},
 			out: "merged-gateway.yaml"},
+
+		// Verify we can convert networking.k8s.io/v1 Ingresses of the Istio class
+		{in: []string{"ingress-v1.yaml"},
+			out: "ingress-v1-gateway.yaml"},
+
+		// Verify we can convert service-apis Gateways and HTTPRoutes
+		{in: []string{"service-apis.yaml"},
+			out: "service-apis-gateway.yaml"},
+
+		// Verify we can convert Istio gateways and virtual services to service-apis
+		{in: []string{"bookinfo-gateway.yaml"},
+			out: "bookinfo-service-apis.yaml",
+			to:  convertToServiceAPIs},
 	}
 
 	for _, tc := range tt {
@@ -56,7 +71,11 @@ func TestConvertIngress(t *testing.T) {
 			}
 			defer out.Close() // nolint: errcheck
 
-			if err := convertConfigs(readers, out); err != nil {
+			to := tc.to
+			if to == "" {
+				to = convertToIstio
+			}
+			if err := convertConfigs(readers, out, ioutil.Discard, to); err != nil {
 				t.Fatalf("Unexpected error converting configs: %v", err)
 			}
 
@@ -64,3 +83,31 @@ func TestConvertIngress(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertIngressErrors(t *testing.T) {
+	tt := []struct {
+		in      string
+		to      string
+		wantErr string
+	}{
+		{in: "myservice-ingress.yaml", to: convertToServiceAPIs,
+			wantErr: "ingresses can only be converted to istio configuration"},
+		{in: "myservice-ingress.yaml", to: "nginx",
+			wantErr: `unknown conversion target "nginx", must be istio or service-apis`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.to, func(t *testing.T) {
+			file, err := os.Open("testdata/ingress/" + tc.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close() // nolint: errcheck
+
+			err = convertConfigs([]io.Reader{file}, ioutil.Discard, ioutil.Discard, tc.to)
+			if err == nil || err.Error() != tc.wantErr {
+				t.Fatalf("got error %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
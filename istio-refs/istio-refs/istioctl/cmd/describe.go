@@ -175,6 +175,8 @@ func describe() *cobra.Command {
 
 	describeCmd.AddCommand(podDescribeCmd())
 	describeCmd.AddCommand(svcDescribeCmd())
+	describeCmd.AddCommand(gatewayDescribeCmd())
+	describeCmd.AddCommand(hostDescribeCmd())
 	return describeCmd
 }
 
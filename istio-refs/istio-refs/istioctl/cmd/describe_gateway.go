@@ -0,0 +1,780 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/networking/v1alpha3"
+
+	istioctl_kubernetes "istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/model"
+	pilotcontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/gateway"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+var (
+	describeHostPath = "/"
+	describeHostPort = 0
+)
+
+// gatewayDescriber holds the clients and the configuration shared by the gateway and host describe commands.
+type gatewayDescriber struct {
+	writer       io.Writer
+	kubeClient   kubernetes.Interface
+	execClient   istioctl_kubernetes.ExecClient
+	configClient model.ConfigStore
+
+	virtualServices  []model.Config
+	destinationRules []model.Config
+	// the ports the Envoy of each gateway pod listens on, by pod name and namespace
+	listeners map[string]map[uint32]bool
+}
+
+func newGatewayDescriber(writer io.Writer) (*gatewayDescriber, error) {
+	kubeClient, err := interfaceFactory(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	execClient, err := clientExecFactory(kubeconfig, configContext)
+	if err != nil {
+		return nil, err
+	}
+	configClient, err := clientFactory()
+	if err != nil {
+		return nil, err
+	}
+	vss, err := configClient.List(collections.IstioNetworkingV1Alpha3Virtualservices.Resource().GroupVersionKind(), "")
+	if err != nil {
+		return nil, err
+	}
+	drs, err := configClient.List(collections.IstioNetworkingV1Alpha3Destinationrules.Resource().GroupVersionKind(), "")
+	if err != nil {
+		return nil, err
+	}
+	sortConfigs(vss)
+	return &gatewayDescriber{
+		writer:           writer,
+		kubeClient:       kubeClient,
+		execClient:       execClient,
+		configClient:     configClient,
+		virtualServices:  vss,
+		destinationRules: drs,
+		listeners:        map[string]map[uint32]bool{},
+	}, nil
+}
+
+// sortConfigs sorts configs the way Pilot merges them: oldest first, then by name.
+func sortConfigs(configs []model.Config) {
+	sort.SliceStable(configs, func(i, j int) bool {
+		if !configs[i].CreationTimestamp.Equal(configs[j].CreationTimestamp) {
+			return configs[i].CreationTimestamp.Before(configs[j].CreationTimestamp)
+		}
+		return name(configs[i]) < name(configs[j])
+	})
+}
+
+func gatewayDescribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "gateway <gateway>",
+		Aliases: []string{"gw"},
+		Short:   "Describe a Gateway, its servers and the VirtualServices bound to it [kube-only]",
+		Long: `Analyzes a Gateway, the pods it selects, and the Services and VirtualServices of each
+of its servers, and reports whether the gateway pods are listening on the ports of the servers.
+
+THIS COMMAND IS STILL UNDER ACTIVE DEVELOPMENT AND NOT READY FOR PRODUCTION USE.
+`,
+		Example: `istioctl experimental describe gateway bookinfo-gateway`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("expecting gateway name")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gwName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
+
+			d, err := newGatewayDescriber(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			gw := d.configClient.Get(collections.IstioNetworkingV1Alpha3Gateways.Resource().GroupVersionKind(), gwName, ns)
+			if gw == nil {
+				return fmt.Errorf("gateways %q not found in namespace %s", gwName, ns)
+			}
+			return d.describeGateway(*gw)
+		},
+	}
+
+	return cmd
+}
+
+func hostDescribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "host <hostname>",
+		Short: "Describe how the ingress gateways route requests for a hostname [kube-only]",
+		Long: `Analyzes the Gateway servers accepting a hostname and reports, for a request path, the
+VirtualService route handling the request, the TLS credential served, the destination
+Services and subsets, and whether the gateway pods are listening on the ports of the servers.
+
+THIS COMMAND IS STILL UNDER ACTIVE DEVELOPMENT AND NOT READY FOR PRODUCTION USE.
+`,
+		Example: `  # Describe the routing of requests for bookinfo.example.com/productpage
+  istioctl experimental describe host bookinfo.example.com --path /productpage
+
+  # Only consider the Gateway servers of port 443
+  istioctl experimental describe host bookinfo.example.com --port 443`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("expecting hostname")
+			}
+			if !strings.HasPrefix(describeHostPath, "/") {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("--path must start with /")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			d, err := newGatewayDescriber(cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return d.describeHost(host.Name(strings.ToLower(args[0])), describeHostPath, uint32(describeHostPort))
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&describeHostPath, "path", "/",
+		"Path of the request to describe the route of")
+	cmd.PersistentFlags().IntVar(&describeHostPort, "port", 0,
+		"Only consider the Gateway servers of this port")
+
+	return cmd
+}
+
+func (d *gatewayDescriber) describeGateway(gw model.Config) error {
+	spec := gw.Spec.(*v1alpha3.Gateway)
+	fmt.Fprintf(d.writer, "Gateway: %s\n", name(gw))
+	pods, err := d.printGatewayPods(gw)
+	if err != nil {
+		return err
+	}
+
+	for _, server := range spec.Servers {
+		fmt.Fprintf(d.writer, "--------------------\n")
+		fmt.Fprintf(d.writer, "Server: %s, hosts: %s\n", renderServerPort(server), strings.Join(server.Hosts, ", "))
+		d.printServer(server, pods)
+
+		bound := false
+		for _, vs := range d.virtualServices {
+			if !virtualServiceBindsServer(vs, gw, server) {
+				continue
+			}
+			bound = true
+			vsSpec := vs.Spec.(*v1alpha3.VirtualService)
+			fmt.Fprintf(d.writer, "VirtualService: %s\n", name(vs))
+			fmt.Fprintf(d.writer, "   Hosts: %s\n", strings.Join(vsSpec.Hosts, ", "))
+			switch {
+			case gateway.IsHTTPServer(server):
+				for _, route := range vsSpec.Http {
+					fmt.Fprintf(d.writer, "   %s -> %s\n", renderMatches(route.Match), renderHTTPRouteAction(route))
+				}
+			case gateway.IsTLSServer(server):
+				for _, route := range vsSpec.Tls {
+					fmt.Fprintf(d.writer, "   %s -> %s\n", renderTLSMatches(route.Match),
+						renderRouteDestinations(route.Route))
+				}
+			default:
+				for _, route := range vsSpec.Tcp {
+					fmt.Fprintf(d.writer, "   %s -> %s\n", renderTCPMatches(route.Match),
+						renderRouteDestinations(route.Route))
+				}
+			}
+		}
+		if !bound && !gateway.IsPassThroughServer(server) {
+			fmt.Fprintf(d.writer, "   Warning: no VirtualService is bound to this server\n")
+		}
+	}
+	return nil
+}
+
+func (d *gatewayDescriber) describeHost(hostname host.Name, path string, port uint32) error {
+	gws, err := d.configClient.List(collections.IstioNetworkingV1Alpha3Gateways.Resource().GroupVersionKind(), "")
+	if err != nil {
+		return err
+	}
+	sortConfigs(gws)
+
+	fmt.Fprintf(d.writer, "Host: %s, path %s\n", hostname, path)
+	found := false
+	for _, gw := range gws {
+		for _, server := range gw.Spec.(*v1alpha3.Gateway).Servers {
+			if port != 0 && server.Port.Number != port || !serverAcceptsHost(server, hostname) {
+				continue
+			}
+			found = true
+			fmt.Fprintf(d.writer, "--------------------\n")
+			fmt.Fprintf(d.writer, "Gateway: %s, server %s\n", name(gw), renderServerPort(server))
+			pods, err := d.printGatewayPods(gw)
+			if err != nil {
+				return err
+			}
+			d.printServer(server, pods)
+			d.printHostRoute(gw, server, hostname, path)
+		}
+	}
+	if !found {
+		fmt.Fprintf(d.writer, "No Gateway server accepts host %s\n", hostname)
+	}
+	return nil
+}
+
+// printGatewayPods prints the running pods selected by the Gateway, and the Services exposing them, returning
+// the pods.
+func (d *gatewayDescriber) printGatewayPods(gw model.Config) ([]v1.Pod, error) {
+	selector := gw.Spec.(*v1alpha3.Gateway).Selector
+	if len(selector) == 0 {
+		fmt.Fprintf(d.writer, "   Warning: the Gateway has no selector\n")
+		return nil, nil
+	}
+	fmt.Fprintf(d.writer, "   Selector: %s\n", k8s_labels.SelectorFromSet(selector))
+
+	podList, err := d.kubeClient.CoreV1().Pods("").List(metav1.ListOptions{
+		LabelSelector: k8s_labels.SelectorFromSet(selector).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var pods []v1.Pod
+	for _, pod := range podList.Items {
+		if pod.Status.Phase == v1.PodRunning {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) == 0 {
+		fmt.Fprintf(d.writer, "   Warning: no running pods match the selector of the Gateway\n")
+		return nil, nil
+	}
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, kname(pod.ObjectMeta))
+	}
+	fmt.Fprintf(d.writer, "   Pods: %s\n", strings.Join(names, ", "))
+	return pods, nil
+}
+
+// printServer prints the TLS settings of the server, whether the gateway pods are listening on its port and the
+// Services exposing the port.
+func (d *gatewayDescriber) printServer(server *v1alpha3.Server, pods []v1.Pod) {
+	if server.Tls != nil {
+		d.printServerTLS(server.Tls, pods)
+	}
+
+	for _, pod := range pods {
+		listening, err := d.podListensOn(pod, server.Port.Number)
+		if err != nil {
+			fmt.Fprintf(d.writer, "   Warning: %v\n", err)
+			continue
+		}
+		if !listening {
+			fmt.Fprintf(d.writer, "   Warning: pod %s is not listening on port %d\n",
+				kname(pod.ObjectMeta), server.Port.Number)
+		}
+	}
+
+	if len(pods) == 0 {
+		return
+	}
+	svcs, err := d.kubeClient.CoreV1().Services(pods[0].Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(d.writer, "   Warning: could not list the Services of the gateway pods: %v\n", err)
+		return
+	}
+	exposed := false
+	for _, svc := range svcs.Items {
+		if len(svc.Spec.Selector) == 0 ||
+			!k8s_labels.SelectorFromSet(svc.Spec.Selector).Matches(k8s_labels.Set(pods[0].Labels)) {
+			continue
+		}
+		for _, svcPort := range svc.Spec.Ports {
+			if servicePortTarget(&pods[0], svcPort) != int(server.Port.Number) {
+				continue
+			}
+			exposed = true
+			fmt.Fprintf(d.writer, "   Exposed by Service %s port %d, address %s\n",
+				kname(svc.ObjectMeta), svcPort.Port, getIngressIP(svc, pods[0]))
+		}
+	}
+	if !exposed {
+		fmt.Fprintf(d.writer, "   Warning: no Service exposes port %d of the gateway pods\n", server.Port.Number)
+	}
+}
+
+func (d *gatewayDescriber) printServerTLS(tls *v1alpha3.Server_TLSOptions, pods []v1.Pod) {
+	if tls.HttpsRedirect {
+		fmt.Fprintf(d.writer, "   Redirects HTTP requests to HTTPS\n")
+	}
+	if tls.Mode == v1alpha3.Server_TLSOptions_PASSTHROUGH && tls.HttpsRedirect {
+		// httpsRedirect alone is expressed with the default PASSTHROUGH mode
+		return
+	}
+
+	switch {
+	case tls.CredentialName != "":
+		fmt.Fprintf(d.writer, "   TLS: %s, credential %s\n", tls.Mode, tls.CredentialName)
+		if len(pods) == 0 {
+			return
+		}
+		// The gateway reads the credentials from the namespace of its pods
+		ns := pods[0].Namespace
+		if _, err := d.kubeClient.CoreV1().Secrets(ns).Get(tls.CredentialName, metav1.GetOptions{}); err != nil {
+			fmt.Fprintf(d.writer, "   Warning: secret %s not found in namespace %s\n", tls.CredentialName, ns)
+		}
+	case tls.ServerCertificate != "":
+		fmt.Fprintf(d.writer, "   TLS: %s, certificate %s\n", tls.Mode, tls.ServerCertificate)
+	default:
+		fmt.Fprintf(d.writer, "   TLS: %s\n", tls.Mode)
+	}
+}
+
+// podListensOn returns whether the Envoy of the pod has a listener on the port.
+func (d *gatewayDescriber) podListensOn(pod v1.Pod, port uint32) (bool, error) {
+	key := kname(pod.ObjectMeta)
+	ports, ok := d.listeners[key]
+	if !ok {
+		byConfigDump, err := d.execClient.EnvoyDo(pod.Name, pod.Namespace, "GET", "config_dump", nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to execute command on gateway pod %s: %v", key, err)
+		}
+		cd := configdump.Wrapper{}
+		if err := cd.UnmarshalJSON(byConfigDump); err != nil {
+			return false, fmt.Errorf("can't parse config_dump of gateway pod %s: %v", key, err)
+		}
+		listeners, err := cd.GetListeners()
+		if err != nil {
+			return false, fmt.Errorf("can't get the listeners of gateway pod %s: %v", key, err)
+		}
+		ports = map[uint32]bool{}
+		for _, l := range listeners {
+			ports[l.GetAddress().GetSocketAddress().GetPortValue()] = true
+		}
+		d.listeners[key] = ports
+	}
+	return ports[port], nil
+}
+
+// printHostRoute prints the route of the VirtualServices bound to the server handling the requests for the
+// hostname and path, and checks its destinations.
+func (d *gatewayDescriber) printHostRoute(gw model.Config, server *v1alpha3.Server, hostname host.Name, path string) {
+	if server.Tls != nil && server.Tls.Mode == v1alpha3.Server_TLSOptions_AUTO_PASSTHROUGH {
+		fmt.Fprintf(d.writer, "Requests are routed by SNI to the service %s\n", hostname)
+		return
+	}
+
+	for _, vs := range d.virtualServices {
+		if !virtualServiceBindsServer(vs, gw, server) || !virtualServiceHasHost(vs, hostname) {
+			continue
+		}
+		fmt.Fprintf(d.writer, "VirtualService: %s\n", name(vs))
+		vsSpec := vs.Spec.(*v1alpha3.VirtualService)
+		switch {
+		case gateway.IsHTTPServer(server):
+			d.printHTTPRoute(vs, gw, vsSpec.Http, path, server.Port.Number)
+		case gateway.IsTLSServer(server):
+			for _, route := range vsSpec.Tls {
+				if tlsRouteMatchesHost(route, hostname, server.Port.Number) {
+					fmt.Fprintf(d.writer, "   Route: %s\n", renderTLSMatches(route.Match))
+					d.printDestinations(vs, route.Route)
+					return
+				}
+			}
+			fmt.Fprintf(d.writer, "   Warning: no TLS route matches SNI %s\n", hostname)
+		default:
+			for _, route := range vsSpec.Tcp {
+				if tcpRouteMatchesPort(route, server.Port.Number) {
+					fmt.Fprintf(d.writer, "   Route: %s\n", renderTCPMatches(route.Match))
+					d.printDestinations(vs, route.Route)
+					return
+				}
+			}
+			fmt.Fprintf(d.writer, "   Warning: no TCP route matches port %d\n", server.Port.Number)
+		}
+		// Pilot uses the first VirtualService of each host
+		return
+	}
+	fmt.Fprintf(d.writer, "Warning: no VirtualService bound to the Gateway has host %s\n", hostname)
+}
+
+func (d *gatewayDescriber) printHTTPRoute(vs, gw model.Config, routes []*v1alpha3.HTTPRoute, path string, port uint32) {
+	for _, route := range routes {
+		matches, conditional := httpRouteMatchesPath(route, gatewayRef(gw), path, port)
+		if !matches {
+			continue
+		}
+		if conditional {
+			fmt.Fprintf(d.writer, "   Conditional route: %s -> %s\n", renderMatches(route.Match),
+				renderHTTPRouteAction(route))
+			continue
+		}
+		fmt.Fprintf(d.writer, "   Route: %s\n", renderMatches(route.Match))
+		if route.Redirect != nil {
+			fmt.Fprintf(d.writer, "   Action: %s\n", renderHTTPRouteAction(route))
+			return
+		}
+		d.printHTTPDestinations(vs, route.Route)
+		return
+	}
+	fmt.Fprintf(d.writer, "   Warning: no route matches path %s, the gateway returns 404\n", path)
+}
+
+func (d *gatewayDescriber) printHTTPDestinations(vs model.Config, routes []*v1alpha3.HTTPRouteDestination) {
+	for _, rd := range routes {
+		d.printDestination(vs, rd.Destination, rd.Weight, len(routes))
+	}
+}
+
+func (d *gatewayDescriber) printDestinations(vs model.Config, routes []*v1alpha3.RouteDestination) {
+	for _, rd := range routes {
+		d.printDestination(vs, rd.Destination, rd.Weight, len(routes))
+	}
+}
+
+// printDestination prints a destination of a route, checking its Service, port and subset.
+func (d *gatewayDescriber) printDestination(vs model.Config, dest *v1alpha3.Destination, weight int32, routes int) {
+	if dest == nil {
+		return
+	}
+	fmt.Fprintf(d.writer, "   Destination: %s\n", renderDestination(dest, weight, routes))
+
+	fqdn := string(model.ResolveShortnameToFQDN(dest.Host, model.ConfigMeta{Namespace: vs.Namespace}))
+	if !strings.Contains(dest.Host, ".") {
+		fqdn += k8sSuffix
+	}
+	if !strings.HasSuffix(fqdn, k8sSuffix) {
+		// Not a Kubernetes service, e.g. a ServiceEntry host
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(fqdn, k8sSuffix), ".")
+	if len(parts) != 2 {
+		fmt.Fprintf(d.writer, "      Warning: %s is not a Kubernetes Service hostname\n", dest.Host)
+		return
+	}
+	svc, err := d.kubeClient.CoreV1().Services(parts[1]).Get(parts[0], metav1.GetOptions{})
+	if err != nil {
+		fmt.Fprintf(d.writer, "      Warning: Service %s.%s not found\n", parts[0], parts[1])
+		return
+	}
+	if dest.Port != nil && dest.Port.Number != 0 && !serviceHasPort(svc, dest.Port.Number) {
+		fmt.Fprintf(d.writer, "      Warning: Service %s has no port %d\n", kname(svc.ObjectMeta), dest.Port.Number)
+	}
+
+	podSelector := k8s_labels.Set(svc.Spec.Selector)
+	if dest.Subset != "" {
+		subsetLabels, ok := d.subsetLabels(fqdn, dest.Subset)
+		if !ok {
+			fmt.Fprintf(d.writer, "      Warning: subset %s is not defined by any DestinationRule for %s\n",
+				dest.Subset, fqdn)
+			return
+		}
+		podSelector = k8s_labels.Merge(podSelector, subsetLabels)
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return
+	}
+	pods, err := d.kubeClient.CoreV1().Pods(svc.Namespace).List(metav1.ListOptions{
+		LabelSelector: k8s_labels.SelectorFromSet(podSelector).String(),
+	})
+	if err != nil {
+		fmt.Fprintf(d.writer, "      Warning: could not list the pods of Service %s: %v\n", kname(svc.ObjectMeta), err)
+		return
+	}
+	running := 0
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == v1.PodRunning {
+			running++
+		}
+	}
+	if running == 0 {
+		fmt.Fprintf(d.writer, "      Warning: no running pods match %s\n", k8s_labels.SelectorFromSet(podSelector))
+		return
+	}
+	fmt.Fprintf(d.writer, "      Running pods: %d\n", running)
+}
+
+// subsetLabels returns the labels of the subset defined by the DestinationRules of the host.
+func (d *gatewayDescriber) subsetLabels(fqdn, subset string) (k8s_labels.Set, bool) {
+	for _, dr := range d.destinationRules {
+		drSpec := dr.Spec.(*v1alpha3.DestinationRule)
+		drHost := string(model.ResolveShortnameToFQDN(drSpec.Host, model.ConfigMeta{Namespace: dr.Namespace}))
+		if !strings.Contains(drSpec.Host, ".") {
+			drHost += k8sSuffix
+		}
+		if drHost != fqdn {
+			continue
+		}
+		for _, s := range drSpec.Subsets {
+			if s.Name == subset {
+				return k8s_labels.Set(s.Labels), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// servicePortTarget returns the pod port targeted by the service port, or 0.
+func servicePortTarget(pod *v1.Pod, port v1.ServicePort) int {
+	if port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal == 0 {
+		// Kubernetes defaults the target port to the port
+		return int(port.Port)
+	}
+	nport, err := pilotcontroller.FindPort(pod, &port)
+	if err != nil {
+		return 0
+	}
+	return nport
+}
+
+func serviceHasPort(svc *v1.Service, port uint32) bool {
+	for _, p := range svc.Spec.Ports {
+		if uint32(p.Port) == port {
+			return true
+		}
+	}
+	return false
+}
+
+// gatewayRef returns the namespace/name reference of the Gateway.
+func gatewayRef(gw model.Config) string {
+	return gw.Namespace + "/" + gw.Name
+}
+
+func virtualServiceHasGateway(vs model.Config, gw model.Config) bool {
+	for _, g := range vs.Spec.(*v1alpha3.VirtualService).Gateways {
+		if util.ResolveGatewayRef(g, vs.Namespace) == gatewayRef(gw) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverHostMatches returns whether a host of the server, in the [namespace/]host form, matches the host of the
+// VirtualService.
+func serverHostMatches(serverHost string, vs model.Config, vsHost host.Name) bool {
+	if parts := strings.Split(serverHost, "/"); len(parts) == 2 {
+		if parts[0] != "*" && parts[0] != vs.Namespace {
+			return false
+		}
+		serverHost = parts[1]
+	}
+	return host.Name(serverHost).Matches(vsHost)
+}
+
+// virtualServiceBindsServer returns whether the VirtualService is bound to the Gateway with a host of the server.
+func virtualServiceBindsServer(vs, gw model.Config, server *v1alpha3.Server) bool {
+	if !virtualServiceHasGateway(vs, gw) {
+		return false
+	}
+	for _, h := range vs.Spec.(*v1alpha3.VirtualService).Hosts {
+		vsHost := model.ResolveShortnameToFQDN(h, model.ConfigMeta{Namespace: vs.Namespace})
+		for _, serverHost := range server.Hosts {
+			if serverHostMatches(serverHost, vs, vsHost) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func virtualServiceHasHost(vs model.Config, hostname host.Name) bool {
+	for _, h := range vs.Spec.(*v1alpha3.VirtualService).Hosts {
+		if hostname.SubsetOf(host.Name(h)) {
+			return true
+		}
+	}
+	return false
+}
+
+func serverAcceptsHost(server *v1alpha3.Server, hostname host.Name) bool {
+	for _, h := range server.Hosts {
+		if parts := strings.Split(h, "/"); len(parts) == 2 {
+			h = parts[1]
+		}
+		if hostname.SubsetOf(host.Name(h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpRouteMatchesPath returns whether the route matches requests for the path through the gateway and port, and
+// whether the route also requires conditions on other attributes of the requests.
+func httpRouteMatchesPath(route *v1alpha3.HTTPRoute, gwRef, path string, port uint32) (bool, bool) {
+	if len(route.Match) == 0 {
+		return true, false
+	}
+	matches := false
+	for _, m := range route.Match {
+		if m.Port != 0 && m.Port != port || !matchesGateway(m.Gateways, gwRef) || !stringMatches(m.Uri, path) {
+			continue
+		}
+		if len(m.Headers) == 0 && len(m.QueryParams) == 0 && m.Method == nil && m.Authority == nil &&
+			m.Scheme == nil {
+			return true, false
+		}
+		matches = true
+	}
+	return matches, matches
+}
+
+func matchesGateway(gateways []string, gwRef string) bool {
+	if len(gateways) == 0 {
+		return true
+	}
+	ns := strings.Split(gwRef, "/")[0]
+	for _, g := range gateways {
+		if util.ResolveGatewayRef(g, ns) == gwRef {
+			return true
+		}
+	}
+	return false
+}
+
+func stringMatches(sm *v1alpha3.StringMatch, s string) bool {
+	if sm == nil {
+		return true
+	}
+	switch x := sm.MatchType.(type) {
+	case *v1alpha3.StringMatch_Exact:
+		return s == x.Exact
+	case *v1alpha3.StringMatch_Prefix:
+		return strings.HasPrefix(s, x.Prefix)
+	case *v1alpha3.StringMatch_Regex:
+		re, err := regexp.Compile("^(?:" + x.Regex + ")$")
+		return err == nil && re.MatchString(s)
+	}
+	return false
+}
+
+func tlsRouteMatchesHost(route *v1alpha3.TLSRoute, hostname host.Name, port uint32) bool {
+	for _, m := range route.Match {
+		if m.Port != 0 && m.Port != port {
+			continue
+		}
+		for _, sni := range m.SniHosts {
+			if hostname.SubsetOf(host.Name(sni)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func tcpRouteMatchesPort(route *v1alpha3.TCPRoute, port uint32) bool {
+	if len(route.Match) == 0 {
+		return true
+	}
+	for _, m := range route.Match {
+		if m.Port == 0 || m.Port == port {
+			return true
+		}
+	}
+	return false
+}
+
+func renderServerPort(server *v1alpha3.Server) string {
+	return fmt.Sprintf("%s %d (%s)", server.Port.Protocol, server.Port.Number, server.Port.Name)
+}
+
+func renderHTTPRouteAction(route *v1alpha3.HTTPRoute) string {
+	if route.Redirect != nil {
+		target := route.Redirect.Authority + route.Redirect.Uri
+		if target == "" {
+			target = "same location"
+		}
+		return "redirect to " + target
+	}
+	dests := make([]string, 0, len(route.Route))
+	for _, rd := range route.Route {
+		dests = append(dests, renderDestination(rd.Destination, rd.Weight, len(route.Route)))
+	}
+	return strings.Join(dests, ", ")
+}
+
+func renderRouteDestinations(routes []*v1alpha3.RouteDestination) string {
+	dests := make([]string, 0, len(routes))
+	for _, rd := range routes {
+		dests = append(dests, renderDestination(rd.Destination, rd.Weight, len(routes)))
+	}
+	return strings.Join(dests, ", ")
+}
+
+func renderDestination(dest *v1alpha3.Destination, weight int32, routes int) string {
+	if dest == nil {
+		return "nowhere"
+	}
+	out := dest.Host
+	if dest.Port != nil && dest.Port.Number != 0 {
+		out += fmt.Sprintf(":%d", dest.Port.Number)
+	}
+	if dest.Subset != "" {
+		out += " subset " + dest.Subset
+	}
+	if routes > 1 {
+		out += fmt.Sprintf(" %d%%", weight)
+	}
+	return out
+}
+
+func renderTLSMatches(matches []*v1alpha3.TLSMatchAttributes) string {
+	var sniHosts []string
+	for _, m := range matches {
+		sniHosts = append(sniHosts, m.SniHosts...)
+	}
+	if len(sniHosts) == 0 {
+		return "everything"
+	}
+	return "SNI " + strings.Join(sniHosts, ", ")
+}
+
+func renderTCPMatches(matches []*v1alpha3.L4MatchAttributes) string {
+	var ports []string
+	for _, m := range matches {
+		if m.Port != 0 {
+			ports = append(ports, fmt.Sprintf("port %d", m.Port))
+		}
+	}
+	if len(ports) == 0 {
+		return "everything"
+	}
+	return strings.Join(ports, ", ")
+}
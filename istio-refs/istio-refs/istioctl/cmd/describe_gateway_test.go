@@ -0,0 +1,208 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+const ingressGatewayConfigDump = "testdata/describe/istio-ingressgateway-5bf6c9887-vvvmj.json"
+
+var cannedGatewayConfig = model.Config{
+	ConfigMeta: model.ConfigMeta{
+		Name:      "bookinfo-gateway",
+		Namespace: "default",
+		Type:      collections.IstioNetworkingV1Alpha3Gateways.Resource().Kind(),
+		Group:     collections.IstioNetworkingV1Alpha3Gateways.Resource().Group(),
+		Version:   collections.IstioNetworkingV1Alpha3Gateways.Resource().Version(),
+	},
+	Spec: &networking.Gateway{
+		Selector: map[string]string{"istio": "ingressgateway"},
+		Servers: []*networking.Server{
+			{
+				Port:  &networking.Port{Number: 80, Protocol: "HTTP", Name: "http"},
+				Hosts: []string{"*"},
+			},
+			{
+				Port:  &networking.Port{Number: 443, Protocol: "HTTPS", Name: "https"},
+				Hosts: []string{"bookinfo.example.com"},
+				Tls: &networking.Server_TLSOptions{
+					Mode:           networking.Server_TLSOptions_SIMPLE,
+					CredentialName: "bookinfo-cert",
+				},
+			},
+		},
+	},
+}
+
+func TestDescribeGateway(t *testing.T) {
+	cannedConfig := map[string][]byte{
+		"istio-ingressgateway-5bf6c9887-vvvmj": util.ReadFile(ingressGatewayConfigDump, t),
+	}
+	configs := append([]model.Config{cannedGatewayConfig}, cannedIstioConfig...)
+	cases := []execAndK8sConfigTestCase{
+		{ // case 0 no gateway
+			args:           strings.Split("x describe gateway", " "),
+			expectedString: "Error: expecting gateway name",
+			wantException:  true,
+		},
+		{ // case 1 unknown gateway
+			execClientConfig: cannedConfig,
+			configs:          configs,
+			k8sConfigs:       cannedK8sEnv,
+			namespace:        "default",
+			args:             strings.Split("x describe gateway not-a-gateway", " "),
+			expectedString:   `gateways "not-a-gateway" not found in namespace default`,
+			wantException:    true,
+		},
+		{ // case 2 has data
+			execClientConfig: cannedConfig,
+			configs:          configs,
+			k8sConfigs:       cannedK8sEnv,
+			namespace:        "default",
+			args:             strings.Split("x describe gw bookinfo-gateway", " "),
+			expectedOutput: `Gateway: bookinfo-gateway
+   Selector: istio=ingressgateway
+   Pods: istio-ingressgateway-5bf6c9887-vvvmj.istio-system
+--------------------
+Server: HTTP 80 (http), hosts: *
+   Exposed by Service istio-ingressgateway.istio-system port 80, address 10.1.2.3
+VirtualService: bookinfo
+   Hosts: *
+   /productpage, /login, /logout, /api/v1/products* -> productpage:80
+--------------------
+Server: HTTPS 443 (https), hosts: bookinfo.example.com
+   TLS: SIMPLE, credential bookinfo-cert
+   Warning: secret bookinfo-cert not found in namespace istio-system
+   Warning: pod istio-ingressgateway-5bf6c9887-vvvmj.istio-system is not listening on port 443
+   Warning: no Service exposes port 443 of the gateway pods
+VirtualService: bookinfo
+   Hosts: *
+   /productpage, /login, /logout, /api/v1/products* -> productpage:80
+`,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecAndK8sConfigTestCaseTestOutput(t, c)
+		})
+	}
+}
+
+func TestDescribeHost(t *testing.T) {
+	cannedConfig := map[string][]byte{
+		"istio-ingressgateway-5bf6c9887-vvvmj": util.ReadFile(ingressGatewayConfigDump, t),
+	}
+	configs := append([]model.Config{cannedGatewayConfig}, cannedIstioConfig...)
+	cases := []execAndK8sConfigTestCase{
+		{ // case 0 bad path
+			args:           strings.Split("x describe host bookinfo.example.com --path productpage", " "),
+			expectedString: "Error: --path must start with /",
+			wantException:  true,
+		},
+		{ // case 1 routed path on the HTTP server
+			execClientConfig: cannedConfig,
+			configs:          configs,
+			k8sConfigs:       cannedK8sEnv,
+			namespace:        "default",
+			args:             strings.Split("x describe host bookinfo.example.com --path /productpage --port 80", " "),
+			expectedOutput: `Host: bookinfo.example.com, path /productpage
+--------------------
+Gateway: bookinfo-gateway, server HTTP 80 (http)
+   Selector: istio=ingressgateway
+   Pods: istio-ingressgateway-5bf6c9887-vvvmj.istio-system
+   Exposed by Service istio-ingressgateway.istio-system port 80, address 10.1.2.3
+VirtualService: bookinfo
+   Route: /productpage, /login, /logout, /api/v1/products*
+   Destination: productpage:80
+      Warning: Service productpage has no port 80
+      Running pods: 2
+`,
+		},
+		{ // case 2 unrouted path on the HTTPS server
+			execClientConfig: cannedConfig,
+			configs:          configs,
+			k8sConfigs:       cannedK8sEnv,
+			namespace:        "default",
+			args:             strings.Split("x describe host bookinfo.example.com --path /reviews --port 443", " "),
+			expectedOutput: `Host: bookinfo.example.com, path /reviews
+--------------------
+Gateway: bookinfo-gateway, server HTTPS 443 (https)
+   Selector: istio=ingressgateway
+   Pods: istio-ingressgateway-5bf6c9887-vvvmj.istio-system
+   TLS: SIMPLE, credential bookinfo-cert
+   Warning: secret bookinfo-cert not found in namespace istio-system
+   Warning: pod istio-ingressgateway-5bf6c9887-vvvmj.istio-system is not listening on port 443
+   Warning: no Service exposes port 443 of the gateway pods
+VirtualService: bookinfo
+   Warning: no route matches path /reviews, the gateway returns 404
+`,
+		},
+		{ // case 3 host not accepted by the server
+			execClientConfig: cannedConfig,
+			configs:          configs,
+			k8sConfigs:       cannedK8sEnv,
+			namespace:        "default",
+			args:             strings.Split("x describe host reviews.example.com --port 443", " "),
+			expectedOutput: `Host: reviews.example.com, path /
+No Gateway server accepts host reviews.example.com
+`,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecAndK8sConfigTestCaseTestOutput(t, c)
+		})
+	}
+}
+
+func TestHTTPRouteMatchesPath(t *testing.T) {
+	prefix := &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: "/api"}}
+	cases := []struct {
+		route           *networking.HTTPRoute
+		path            string
+		wantMatches     bool
+		wantConditional bool
+	}{
+		{&networking.HTTPRoute{}, "/", true, false},
+		{&networking.HTTPRoute{Match: []*networking.HTTPMatchRequest{{Uri: prefix}}}, "/api/v1", true, false},
+		{&networking.HTTPRoute{Match: []*networking.HTTPMatchRequest{{Uri: prefix}}}, "/login", false, false},
+		{&networking.HTTPRoute{Match: []*networking.HTTPMatchRequest{{Uri: prefix, Port: 443}}}, "/api", false, false},
+		{&networking.HTTPRoute{Match: []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{
+			MatchType: &networking.StringMatch_Regex{Regex: "/[a-z]+"}}}}}, "/login", true, false},
+		{&networking.HTTPRoute{Match: []*networking.HTTPMatchRequest{{Uri: prefix,
+			Headers: map[string]*networking.StringMatch{"end-user": prefix}}}}, "/api", true, true},
+		{&networking.HTTPRoute{Match: []*networking.HTTPMatchRequest{{Gateways: []string{"istio-system/other"}}}},
+			"/", false, false},
+		{&networking.HTTPRoute{Match: []*networking.HTTPMatchRequest{{Gateways: []string{"bookinfo-gateway"}}}},
+			"/", true, false},
+	}
+	for i, c := range cases {
+		matches, conditional := httpRouteMatchesPath(c.route, "default/bookinfo-gateway", c.path, 80)
+		if matches != c.wantMatches || conditional != c.wantConditional {
+			t.Errorf("case %d: got (%v, %v), want (%v, %v)", i, matches, conditional, c.wantMatches, c.wantConditional)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/graph"
+	"istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+func graphCmd() *cobra.Command {
+	var (
+		output     string
+		window     time.Duration
+		staticOnly bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Builds the dependency graph of the mesh from its configuration and its traffic",
+		Long: `
+Builds the dependency graph of the workloads and services of the mesh.
+
+Configured edges are declared by the Istio configuration: the egress hosts of the Sidecars,
+the destinations of the VirtualServices, and the ServiceEntries exported only to their own
+namespace. Observed edges are the requests reported by the client proxies to the Prometheus
+pod of the istio system namespace, in the istio_requests_total metric.
+
+Configured edges without requests (unobserved) and edges with requests but no configuration
+(unconfigured) are highlighted: the former may be removed from the Sidecar scopes and the
+AuthorizationPolicies, the latter are missing from them.
+
+The graph covers the whole mesh, or only the edges from and to the namespace given with -n.
+`,
+		Example: `
+# Render the graph of the mesh with Graphviz
+istioctl experimental graph | dot -Tsvg > mesh.svg
+
+# Print the graph of the bookinfo namespace as a Mermaid flowchart, from the last hour of traffic
+istioctl experimental graph -n bookinfo -o mermaid --window 1h
+
+# Print the configured edges as JSON, without Prometheus
+istioctl experimental graph -o json --static-only
+`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if !validGraphFormat(output) {
+				return CommandParseError{fmt.Errorf("invalid --output %q, must be one of %v", output, graph.Formats)}
+			}
+			if window <= 0 {
+				return CommandParseError{errors.New("--window must be positive")}
+			}
+
+			config, err := graphConfig()
+			if err != nil {
+				return err
+			}
+			g := graph.New()
+			g.AddConfig(config)
+
+			if !staticOnly {
+				if err := addGraphTraffic(g, window); err != nil {
+					return err
+				}
+			}
+			if namespace != "" {
+				g = g.Namespace(namespace)
+			}
+			return graph.Write(c.OutOrStdout(), g, output)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", graph.DOT,
+		fmt.Sprintf("Output format, one of %v", graph.Formats))
+	cmd.PersistentFlags().DurationVar(&window, "window", 10*time.Minute,
+		"Time window the requests are observed over")
+	cmd.PersistentFlags().BoolVar(&staticOnly, "static-only", false,
+		"Only show the configured edges, without reading the traffic from Prometheus")
+
+	return cmd
+}
+
+func validGraphFormat(format string) bool {
+	for _, f := range graph.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// graphConfig returns the Istio configuration, the services and the pods of all the namespaces.
+func graphConfig() (graph.Config, error) {
+	configClient, err := clientFactory()
+	if err != nil {
+		return graph.Config{}, err
+	}
+	list := func(s collection.Schema) ([]model.Config, error) {
+		configs, err := configClient.List(s.Resource().GroupVersionKind(), "")
+		if err != nil {
+			return nil, fmt.Errorf("could not list %s: %v", s.Resource().Plural(), err)
+		}
+		return configs, nil
+	}
+
+	config := graph.Config{RootNamespace: istioNamespace}
+	if config.VirtualServices, err = list(collections.IstioNetworkingV1Alpha3Virtualservices); err != nil {
+		return config, err
+	}
+	if config.Gateways, err = list(collections.IstioNetworkingV1Alpha3Gateways); err != nil {
+		return config, err
+	}
+	if config.Sidecars, err = list(collections.IstioNetworkingV1Alpha3Sidecars); err != nil {
+		return config, err
+	}
+	if config.ServiceEntries, err = list(collections.IstioNetworkingV1Alpha3Serviceentries); err != nil {
+		return config, err
+	}
+
+	client, err := interfaceFactory(kubeconfig)
+	if err != nil {
+		return config, err
+	}
+	services, err := client.CoreV1().Services("").List(metav1.ListOptions{})
+	if err != nil {
+		return config, fmt.Errorf("could not list services: %v", err)
+	}
+	config.Services = services.Items
+	pods, err := client.CoreV1().Pods("").List(metav1.ListOptions{FieldSelector: "status.phase=Running"})
+	if err != nil {
+		return config, fmt.Errorf("could not list pods: %v", err)
+	}
+	config.Pods = pods.Items
+	return config, nil
+}
+
+// addGraphTraffic adds the requests observed by the Prometheus pod of the istio system namespace to the graph.
+func addGraphTraffic(g *graph.Graph, window time.Duration) error {
+	client, err := clientExecFactory(kubeconfig, configContext)
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %v", err)
+	}
+	pl, err := client.PodsForSelector(istioNamespace, "app=prometheus")
+	if err != nil {
+		return fmt.Errorf("not able to locate Prometheus pod: %v", err)
+	}
+	if len(pl.Items) < 1 {
+		return errors.New("no Prometheus pods found, use --static-only to only show the configured edges")
+	}
+
+	fw, err := client.BuildPortForwarder(pl.Items[0].Name, istioNamespace, 0, 9090)
+	if err != nil {
+		return fmt.Errorf("could not build port forwarder for prometheus: %v", err)
+	}
+	if err := kubernetes.StartPortForwarder(fw); err != nil {
+		return err
+	}
+	defer close(fw.StopChannel)
+
+	promAPI, err := prometheusAPI(fw.LocalPort)
+	if err != nil {
+		return err
+	}
+	return g.AddTraffic(promAPI, window, time.Now())
+}
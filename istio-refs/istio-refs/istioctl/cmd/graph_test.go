@@ -0,0 +1,60 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestGraph(t *testing.T) {
+	configs := append([]model.Config{cannedGatewayConfig}, cannedIstioConfig...)
+	cases := []execAndK8sConfigTestCase{
+		{ // case 0 bad output
+			args:           strings.Split("x graph -o svg", " "),
+			expectedString: `invalid --output "svg", must be one of [dot mermaid json]`,
+			wantException:  true,
+		},
+		{ // case 1 no Prometheus
+			configs:        configs,
+			k8sConfigs:     cannedK8sEnv,
+			args:           strings.Split("x graph", " "),
+			expectedString: "no Prometheus pods found",
+			wantException:  true,
+		},
+		{ // case 2 configured edges
+			configs:    configs,
+			k8sConfigs: cannedK8sEnv,
+			namespace:  "default",
+			args:       strings.Split("x graph --static-only", " "),
+			expectedOutput: `digraph mesh {
+  rankdir=LR;
+  "istio-ingressgateway-5bf6c9887-vvvmj.istio-system" [shape=box];
+  "productpage.default.svc.cluster.local" [shape=ellipse];
+  "istio-ingressgateway-5bf6c9887-vvvmj.istio-system" -> "productpage.default.svc.cluster.local" [label=""];
+}
+`,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecAndK8sConfigTestCaseTestOutput(t, c)
+		})
+	}
+}
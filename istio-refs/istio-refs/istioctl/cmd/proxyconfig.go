@@ -398,9 +398,12 @@ func proxyConfig() *cobra.Command {
 		"Envoy config dump JSON file")
 
 	logCmd := &cobra.Command{
-		Use:   "log <pod-name[.namespace]>",
+		Use:   "log [<pod-name[.namespace]>|deployment/<name[.namespace]>]",
 		Short: "(experimental) Retrieves logging levels of the Envoy in the specified pod",
-		Long:  "(experimental) Retrieve information about logging levels of the Envoy instance in the specified pod, and update optionally",
+		Long: "(experimental) Retrieve information about logging levels of the Envoy instance in the specified pod, " +
+			"and update optionally. The levels of the Envoy instances of all the pods of a deployment, or of the " +
+			"pods matching a selector, are updated in parallel. With --ttl, the previous levels are restored by " +
+			"istioctl after the ttl, or on Ctrl-C; they are not restored if istioctl is killed.",
 		Example: `  # Retrieve information about logging levels for a given pod from Envoy.
   istioctl proxy-config log <pod-name[.namespace]>
 
@@ -412,22 +415,43 @@ func proxyConfig() *cobra.Command {
 
   # Reset levels of all the loggers to default value (warning).
   istioctl proxy-config log <pod-name[.namespace]> -r
+
+  # Update levels of the specified loggers for all the pods of a deployment, for 10 minutes.
+  istioctl proxy-config log deployment/productpage-v1 --level http:debug,connection:debug,rbac:debug --ttl 10m
+
+  # Update levels of all the loggers for the pods matching a selector.
+  istioctl proxy-config log -l app=reviews -n bookinfo --level debug
 `,
 		Aliases: []string{"o"},
 		Args: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 1 {
+			if len(args) < 1 && logSelector == "" {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("log requires pod name or --selector")
+			}
+			if len(args) > 0 && logSelector != "" {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("log requires pod name")
+				return fmt.Errorf("name cannot be provided when a selector is specified")
 			}
 			if reset && loggerLevelString != "" {
 				cmd.Println(cmd.UsageString())
 				return fmt.Errorf("--level cannot be combined with --reset")
 			}
+			if logTTL < 0 || logTTL > 0 && loggerLevelString == "" && !reset {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("--ttl requires a positive duration and --level or --reset")
+			}
+			if logConcurrency < 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("--concurrency must be positive")
+			}
 			return nil
 		},
 		RunE: func(c *cobra.Command, args []string) error {
-			podName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
-			loggerNames, err := setupEnvoyLogConfig("", podName, ns)
+			targets, err := logTargets(args)
+			if err != nil {
+				return err
+			}
+			loggerNames, err := setupEnvoyLogConfig("", targets[0].name, targets[0].namespace)
 			if err != nil {
 				return err
 			}
@@ -470,19 +494,10 @@ func proxyConfig() *cobra.Command {
 				}
 			}
 
-			var resp string
-			if len(destLoggerLevels) == 0 {
-				resp, err = setupEnvoyLogConfig("", podName, ns)
-			} else {
-				if ll, ok := destLoggerLevels[defaultLoggerName]; ok {
-					// update levels of all loggers first
-					resp, err = setupEnvoyLogConfig(defaultLoggerName+"="+levelToString[ll], podName, ns)
-					delete(destLoggerLevels, defaultLoggerName)
-				}
-				for lg, ll := range destLoggerLevels {
-					resp, err = setupEnvoyLogConfig(lg+"="+levelToString[ll], podName, ns)
-				}
+			if len(targets) > 1 || logTTL > 0 {
+				return updateFleetLoggerLevels(c.OutOrStdout(), targets, destLoggerLevels)
 			}
+			resp, err := updateLoggerLevels(targets[0], destLoggerLevels)
 			if err != nil {
 				return err
 			}
@@ -505,6 +520,12 @@ func proxyConfig() *cobra.Command {
 		fmt.Sprintf("Comma-separated minimum per-logger level of messages to output, in the form of"+
 			" [<logger>:]<level>,[<logger>:]<level>,... where logger can be one of %s and level can be one of %s",
 			s, levelListString))
+	logCmd.PersistentFlags().StringVarP(&logSelector, "selector", "l", "",
+		"Label selector of the pods whose levels are retrieved or updated, in the namespace")
+	logCmd.PersistentFlags().DurationVar(&logTTL, "ttl", 0,
+		"Restore the previous levels after this duration; istioctl keeps running until then")
+	logCmd.PersistentFlags().IntVar(&logConcurrency, "concurrency", 10,
+		"Number of pods whose levels are updated in parallel")
 
 	routeConfigCmd := &cobra.Command{
 		Use:   "route [<pod-name[.namespace]>]",
@@ -0,0 +1,294 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/util/handlers"
+)
+
+const deploymentPrefix = "deployment/"
+
+var (
+	logSelector    = ""
+	logTTL         time.Duration
+	logConcurrency = 10
+)
+
+// logTarget is a pod whose proxy logging levels are retrieved or updated.
+type logTarget struct {
+	name, namespace string
+}
+
+func (t logTarget) String() string {
+	return t.name + "." + t.namespace
+}
+
+// logTargets returns the pods of the log command: the pod argument, the pods of the deployment argument or the
+// pods matching the selector.
+func logTargets(args []string) ([]logTarget, error) {
+	ns := handlers.HandleNamespace(namespace, defaultNamespace)
+	selector := logSelector
+	if len(args) > 0 {
+		if !strings.HasPrefix(args[0], deploymentPrefix) {
+			podName, podNamespace := handlers.InferPodInfo(args[0], ns)
+			return []logTarget{{podName, podNamespace}}, nil
+		}
+
+		var name string
+		name, ns = handlers.InferPodInfo(strings.TrimPrefix(args[0], deploymentPrefix), ns)
+		client, err := interfaceFactory(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		deployment, err := client.AppsV1().Deployments(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not get deployment %s.%s: %v", name, ns, err)
+		}
+		s, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector of deployment %s.%s: %v", name, ns, err)
+		}
+		selector = s.String()
+	}
+
+	client, err := clientExecFactory(kubeconfig, configContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %v", err)
+	}
+	pods, err := sidecarPods(client, ns, selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no running pods with a sidecar match %q in namespace %s", selector, ns)
+	}
+	targets := make([]logTarget, 0, len(pods))
+	for _, pod := range pods {
+		targets = append(targets, logTarget{pod, ns})
+	}
+	return targets, nil
+}
+
+// updateLoggerLevels sets the levels of the loggers of a proxy, the default logger first, returning the logging
+// levels of the proxy.
+func updateLoggerLevels(t logTarget, levels map[string]Level) (string, error) {
+	if len(levels) == 0 {
+		return setupEnvoyLogConfig("", t.name, t.namespace)
+	}
+	var params []string
+	if ll, ok := levels[defaultLoggerName]; ok {
+		// update levels of all loggers first
+		params = append(params, defaultLoggerName+"="+levelToString[ll])
+	}
+	var loggers []string
+	for lg := range levels {
+		if lg != defaultLoggerName {
+			loggers = append(loggers, lg)
+		}
+	}
+	sort.Strings(loggers)
+	for _, lg := range loggers {
+		params = append(params, lg+"="+levelToString[levels[lg]])
+	}
+
+	var resp string
+	var err error
+	for _, param := range params {
+		if resp, err = setupEnvoyLogConfig(param, t.name, t.namespace); err != nil {
+			return "", err
+		}
+	}
+	return resp, nil
+}
+
+// parseLoggerLevels parses the logging levels returned by Envoy, by logger name.
+func parseLoggerLevels(out string) map[string]Level {
+	levels := map[string]Level{}
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ": ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if level, ok := stringToLevel[parts[1]]; ok {
+			levels[parts[0]] = level
+		}
+	}
+	return levels
+}
+
+// groupLoggerLevels returns the most common level of the loggers, as the level of the default logger, along with
+// the levels of the other loggers.
+func groupLoggerLevels(levels map[string]Level) map[string]Level {
+	if len(levels) == 0 {
+		return levels
+	}
+	counts := map[Level]int{}
+	for _, level := range levels {
+		counts[level]++
+	}
+	common := OffLevel
+	for level, count := range counts {
+		if count > counts[common] || count == counts[common] && level > common {
+			common = level
+		}
+	}
+	grouped := map[string]Level{defaultLoggerName: common}
+	for lg, level := range levels {
+		if level != common {
+			grouped[lg] = level
+		}
+	}
+	return grouped
+}
+
+// summarizeLoggerLevels returns the most common level of the loggers, followed by the other levels.
+func summarizeLoggerLevels(levels map[string]Level) string {
+	grouped := groupLoggerLevels(levels)
+	if len(grouped) == 0 {
+		return "-"
+	}
+	var others []string
+	for lg, level := range grouped {
+		if lg != defaultLoggerName {
+			others = append(others, lg+":"+levelToString[level])
+		}
+	}
+	sort.Strings(others)
+	summary := levelToString[grouped[defaultLoggerName]]
+	if len(others) > 0 {
+		summary += " (" + strings.Join(others, ",") + ")"
+	}
+	return summary
+}
+
+// logResult is the outcome of the update of the logging levels of a proxy.
+type logResult struct {
+	target logTarget
+	// the levels before and after the update
+	previous, current map[string]Level
+	err               error
+}
+
+// forEachLogTarget runs f for each target, at most concurrency at a time, returning the results in the order of
+// the targets.
+func forEachLogTarget(targets []logTarget, concurrency int, f func(logTarget) logResult) []logResult {
+	results := make([]logResult, len(targets))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t logTarget) {
+			defer wg.Done()
+			results[i] = f(t)
+			<-sem
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+func updateTargetLoggerLevels(t logTarget, levels map[string]Level) logResult {
+	result := logResult{target: t}
+	before, err := setupEnvoyLogConfig("", t.name, t.namespace)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.previous = parseLoggerLevels(before)
+	after, err := updateLoggerLevels(t, levels)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.current = parseLoggerLevels(after)
+	return result
+}
+
+// printLogResults prints the levels of each proxy, or its error, returning the number of errors.
+func printLogResults(writer io.Writer, results []logResult, action string) int {
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 5, ' ', 0)
+	fmt.Fprintln(w, "PROXY\tSTATUS\tLEVELS")
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.target, "error: "+r.err.Error(), "-")
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.target, action, summarizeLoggerLevels(r.current))
+	}
+	_ = w.Flush()
+	return failed
+}
+
+// updateFleetLoggerLevels updates the logging levels of the proxies in parallel, then restores their previous
+// levels after the ttl, if any.
+func updateFleetLoggerLevels(writer io.Writer, targets []logTarget, levels map[string]Level) error {
+	action := "updated"
+	if len(levels) == 0 {
+		action = "unchanged"
+	}
+	results := forEachLogTarget(targets, logConcurrency, func(t logTarget) logResult {
+		return updateTargetLoggerLevels(t, levels)
+	})
+	failed := printLogResults(writer, results, action)
+
+	if logTTL > 0 && len(levels) > 0 {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt)
+		defer signal.Stop(signals)
+
+		fmt.Fprintf(writer, "\nThe levels will be restored in %v, keep istioctl running or press Ctrl-C to "+
+			"restore them now\n", logTTL)
+		select {
+		case <-time.After(logTTL):
+		case <-signals:
+		}
+
+		var updated []logTarget
+		previous := map[logTarget]map[string]Level{}
+		for _, r := range results {
+			if r.err == nil {
+				updated = append(updated, r.target)
+				previous[r.target] = r.previous
+			}
+		}
+		fmt.Fprintln(writer)
+		restored := forEachLogTarget(updated, logConcurrency, func(t logTarget) logResult {
+			return updateTargetLoggerLevels(t, groupLoggerLevels(previous[t]))
+		})
+		if n := printLogResults(writer, restored, "restored"); n > 0 {
+			return fmt.Errorf("failed to restore the levels of %d of %d proxies", n, len(updated))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to update the levels of %d of %d proxies", failed, len(targets))
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/kubernetes"
+)
+
+// fakeLoggingEnvoys mocks the logging admin endpoint of the Envoys of the pods matching any selector.
+type fakeLoggingEnvoys struct {
+	mockExecConfig
+
+	mu     *sync.Mutex
+	levels map[string]map[string]string
+	// the number of updates of each pod
+	updates map[string]int
+}
+
+func newFakeLoggingEnvoys(pods ...string) fakeLoggingEnvoys {
+	f := fakeLoggingEnvoys{mu: &sync.Mutex{}, levels: map[string]map[string]string{}, updates: map[string]int{}}
+	for _, pod := range pods {
+		f.levels[pod] = map[string]string{"admin": "warning", "connection": "warning", "http": "warning",
+			"rbac": "info"}
+	}
+	return f
+}
+
+func (f fakeLoggingEnvoys) PodsForSelector(namespace, labelSelector string) (*v1.PodList, error) {
+	pods := &v1.PodList{}
+	for name := range f.levels {
+		pods.Items = append(pods.Items, v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "istio-proxy"}}},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+		})
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+	return pods, nil
+}
+
+func (f fakeLoggingEnvoys) EnvoyDo(podName, podNamespace, method, path string, body []byte) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	levels, ok := f.levels[podName]
+	if !ok {
+		return nil, fmt.Errorf("unable to retrieve Pod: pods %q not found", podName)
+	}
+	if parts := strings.SplitN(strings.TrimPrefix(path, "logging?"), "=", 2); len(parts) == 2 {
+		f.updates[podName]++
+		for lg := range levels {
+			if parts[0] == defaultLoggerName || parts[0] == lg {
+				levels[lg] = parts[1]
+			}
+		}
+	}
+	var out bytes.Buffer
+	out.WriteString("active loggers:\n")
+	for _, lg := range []string{"admin", "connection", "http", "rbac"} {
+		fmt.Fprintf(&out, "  %s: %s\n", lg, levels[lg])
+	}
+	return out.Bytes(), nil
+}
+
+func TestLoggerLevels(t *testing.T) {
+	levels := parseLoggerLevels("active loggers:\n  admin: warning\n  http: debug\n  rbac: debug\n  redis: warning\n" +
+		"  upstream: warning\n")
+	if len(levels) != 5 || levels["http"] != DebugLevel {
+		t.Fatalf("got levels %v", levels)
+	}
+	grouped := groupLoggerLevels(levels)
+	want := map[string]Level{defaultLoggerName: WarningLevel, "http": DebugLevel, "rbac": DebugLevel}
+	if fmt.Sprint(grouped) != fmt.Sprint(want) {
+		t.Errorf("got grouped levels %v, want %v", grouped, want)
+	}
+	if got := summarizeLoggerLevels(levels); got != "warning (http:debug,rbac:debug)" {
+		t.Errorf("got summary %q", got)
+	}
+	if got := summarizeLoggerLevels(map[string]Level{"http": InfoLevel, "rbac": DebugLevel}); got != "debug (http:info)" {
+		t.Errorf("got summary %q, want ties to be broken by the most verbose level", got)
+	}
+}
+
+func runLogCommand(t *testing.T, envoys fakeLoggingEnvoys, args string) (string, error) {
+	t.Helper()
+	clientExecFactory = func(_, _ string) (kubernetes.ExecClient, error) {
+		return envoys, nil
+	}
+	defer func() {
+		loggerLevelString = ""
+		reset = false
+	}()
+
+	var out bytes.Buffer
+	rootCmd := GetRootCmd(strings.Split(args, " "))
+	rootCmd.SetOutput(&out)
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+func TestProxyConfigLogSelector(t *testing.T) {
+	envoys := newFakeLoggingEnvoys("details-v1-a", "details-v1-b")
+	out, err := runLogCommand(t, envoys, "proxy-config log -l app=details --level http:debug,connection:debug")
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	for _, pod := range []string{"details-v1-a", "details-v1-b"} {
+		want := regexp.MustCompile(pod + `\.default\s+updated\s+debug \(admin:warning,rbac:info\)`)
+		if !want.MatchString(out) {
+			t.Errorf("got output\n%s\nwant a line matching %v", out, want)
+		}
+		if envoys.levels[pod]["http"] != "debug" || envoys.levels[pod]["rbac"] != "info" {
+			t.Errorf("got levels %v for %s", envoys.levels[pod], pod)
+		}
+	}
+}
+
+func TestProxyConfigLogTTL(t *testing.T) {
+	envoys := newFakeLoggingEnvoys("details-v1-a", "details-v1-b", "details-v1-c")
+	out, err := runLogCommand(t, envoys, "proxy-config log -l app=details --level trace --ttl 1ms --concurrency 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if want := regexp.MustCompile(`details-v1-c\.default\s+updated\s+trace\n`); !want.MatchString(out) {
+		t.Errorf("got output\n%s\nwant a line matching %v", out, want)
+	}
+	if want := regexp.MustCompile(`details-v1-c\.default\s+restored\s+warning \(rbac:info\)\n`); !want.MatchString(out) {
+		t.Errorf("got output\n%s\nwant a line matching %v", out, want)
+	}
+	for pod, levels := range envoys.levels {
+		if levels["http"] != "warning" || levels["rbac"] != "info" {
+			t.Errorf("got levels %v for %s, want the initial levels", levels, pod)
+		}
+		// level=trace, then level=warning and rbac=info
+		if envoys.updates[pod] != 3 {
+			t.Errorf("got %d updates of %s, want 3", envoys.updates[pod], pod)
+		}
+	}
+}
+
+func TestProxyConfigLogBadFlags(t *testing.T) {
+	cases := []struct {
+		args string
+		want string
+	}{
+		{"proxy-config log details-v1-a -l app=details", "name cannot be provided when a selector is specified"},
+		{"proxy-config log details-v1-a --ttl 10m", "--ttl requires a positive duration and --level or --reset"},
+		{"proxy-config log -l app=details --level debug --concurrency 0", "--concurrency must be positive"},
+		{"proxy-config log -l app=reviews --level debug", `no running pods with a sidecar match "app=reviews"`},
+	}
+	for _, c := range cases {
+		t.Run(c.args, func(t *testing.T) {
+			envoys := newFakeLoggingEnvoys()
+			out, err := runLogCommand(t, envoys, c.args)
+			if err == nil || !strings.Contains(out, c.want) {
+				t.Errorf("got error %v and output\n%s\nwant %q", err, out, c.want)
+			}
+		})
+	}
+}
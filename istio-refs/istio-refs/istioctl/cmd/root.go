@@ -133,6 +133,7 @@ debug and diagnose their Istio mesh.
 	rootCmd.AddCommand(dashboard())
 	rootCmd.AddCommand(statusCommand())
 	rootCmd.AddCommand(Analyze())
+	rootCmd.AddCommand(bugReportCmd())
 
 	rootCmd.AddCommand(install.NewVerifyCommand())
 	experimentalCmd.AddCommand(AuthZ())
@@ -142,6 +143,10 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(uninjectCommand())
 	experimentalCmd.AddCommand(metricsCmd)
 	experimentalCmd.AddCommand(describe())
+	experimentalCmd.AddCommand(routeTraceCmd())
+	experimentalCmd.AddCommand(topCmd())
+	experimentalCmd.AddCommand(tapCmd())
+	experimentalCmd.AddCommand(graphCmd())
 	experimentalCmd.AddCommand(addToMeshCmd())
 	experimentalCmd.AddCommand(removeFromMeshCmd())
 	experimentalCmd.AddCommand(softGraduatedCmd(Analyze()))
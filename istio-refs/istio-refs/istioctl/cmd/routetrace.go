@@ -0,0 +1,236 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	istioctl_kubernetes "istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/istioctl/pkg/routetrace"
+	"istio.io/istio/istioctl/pkg/util/clusters"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pkg/spiffe"
+)
+
+func routeTraceCmd() *cobra.Command {
+	var (
+		req     routetrace.Request
+		headers []string
+		inbound bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "route-trace <pod-name[.namespace]>",
+		Short: "Simulate how the proxies route a request sent from a pod",
+		Long: `Simulates the request path of an HTTP or TCP request sent from a pod, using the configuration the proxies
+actually received, as found in their config dumps. It reports the listener and filter chain handling the connection,
+the virtual host and route matching the request, with the routes skipped and why, the cluster chosen and its
+endpoints.
+
+With --inbound, the request is also traced through the proxy of the endpoint it is sent to, reporting the inbound
+filter chain, the RBAC filters applied to the request and the inbound cluster. The request is evaluated against the
+RBAC rules, and the authorization decision is reported with the policy deciding it. The source principal is the
+identity of the service account of the pod, when mutual TLS is used.
+
+The destination address defaults to the ClusterIP of the Kubernetes Service named by the host, if any.
+`,
+		Example: `
+# Trace a request sent from productpage to the reviews service
+istioctl experimental route-trace productpage-v1-c7765c886-7zzd4 --host reviews --port 9080 --path /reviews/0
+
+# Trace a request carrying the end-user header, through the proxy of the destination too
+istioctl experimental route-trace productpage-v1-c7765c886-7zzd4.default --host reviews --port 9080 \
+  -H "end-user: jason" --inbound
+
+# Trace the request sent to the second of the weighted clusters of a 75/25 traffic split
+istioctl experimental route-trace productpage-v1-c7765c886-7zzd4 --host reviews --port 9080 --weight-roll 0.8
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("route-trace requires a pod name")
+			}
+			if req.Host == "" || req.Port == 0 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("route-trace requires --host and --port")
+			}
+			if req.WeightRoll < 0 || req.WeightRoll >= 1 {
+				return fmt.Errorf("--weight-roll must be in [0, 1), got %v", req.WeightRoll)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if req.Headers, err = parseRequestHeaders(headers); err != nil {
+				return CommandParseError{err}
+			}
+
+			podName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
+			client, err := interfaceFactory(kubeconfig)
+			if err != nil {
+				return err
+			}
+			execClient, err := clientExecFactory(kubeconfig, configContext)
+			if err != nil {
+				return err
+			}
+
+			pod, err := client.CoreV1().Pods(ns).Get(podName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			req.SourceIP = pod.Status.PodIP
+			serviceAccount := pod.Spec.ServiceAccountName
+			if serviceAccount == "" {
+				serviceAccount = "default"
+			}
+			req.SourcePrincipal = strings.TrimPrefix(spiffe.MustGenSpiffeURI(ns, serviceAccount), spiffe.URIPrefix)
+			if req.DestinationIP == "" {
+				req.DestinationIP = serviceIP(client, req.Host, ns)
+			}
+
+			writer := cmd.OutOrStdout()
+			tracer, err := proxyTracer(execClient, podName, ns)
+			if err != nil {
+				return err
+			}
+			out, err := tracer.Outbound(&req)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(writer, "Outbound from %s.%s to %s (%s:%d):\n", podName, ns, req.Host, req.DestinationIP, req.Port)
+			out.Print(writer)
+
+			if !inbound || out.Dropped != "" || out.Cluster == nil {
+				return nil
+			}
+			return traceInbound(writer, client, execClient, &req, out)
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&req.Host, "host", "", "Host the request is sent to, e.g. reviews")
+	cmd.PersistentFlags().Uint32Var(&req.Port, "port", 0, "Port the request is sent to")
+	cmd.PersistentFlags().StringVar(&req.Path, "path", "/", "Path of the request, including the query string")
+	cmd.PersistentFlags().StringVar(&req.Method, "method", "GET", "Method of the request")
+	cmd.PersistentFlags().StringArrayVarP(&headers, "header", "H", nil,
+		`Header of the request, as "Name: value". Can be repeated`)
+	cmd.PersistentFlags().StringVar(&req.DestinationIP, "destination-ip", "",
+		"Address the request is sent to. Defaults to the ClusterIP of the Service named by the host")
+	cmd.PersistentFlags().Float64Var(&req.WeightRoll, "weight-roll", 0,
+		"Number in [0, 1) selecting the weighted cluster the request is sent to, by cumulative weight")
+	cmd.PersistentFlags().BoolVar(&inbound, "inbound", false,
+		"Also trace the request through the proxy of the endpoint it is sent to")
+
+	return cmd
+}
+
+func parseRequestHeaders(headers []string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, h := range headers {
+		i := strings.Index(h, ":")
+		// Pseudo headers such as :authority start with a colon.
+		if i == 0 {
+			i = strings.Index(h[1:], ":") + 1
+		}
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid header %q, expecting \"Name: value\"", h)
+		}
+		parsed[strings.TrimSpace(h[:i])] = strings.TrimSpace(h[i+1:])
+	}
+	return parsed, nil
+}
+
+// serviceIP returns the ClusterIP of the Kubernetes Service named by the host, or an empty string if there is none.
+func serviceIP(client kubernetes.Interface, host, defaultNs string) string {
+	parts := strings.Split(host, ".")
+	svcNs := defaultNs
+	if len(parts) > 1 {
+		svcNs = parts[1]
+	}
+	svc, err := client.CoreV1().Services(svcNs).Get(parts[0], metav1.GetOptions{})
+	if err != nil || svc.Spec.ClusterIP == v1.ClusterIPNone {
+		return ""
+	}
+	return svc.Spec.ClusterIP
+}
+
+func proxyTracer(execClient istioctl_kubernetes.ExecClient, podName, ns string) (*routetrace.Tracer, error) {
+	byConfigDump, err := execClient.EnvoyDo(podName, ns, "GET", "config_dump", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute command on %s.%s sidecar: %v", podName, ns, err)
+	}
+	cd := &configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(byConfigDump); err != nil {
+		return nil, fmt.Errorf("can't parse %s.%s sidecar config_dump: %v", podName, ns, err)
+	}
+
+	byClusters, err := execClient.EnvoyDo(podName, ns, "GET", "clusters?format=json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute command on %s.%s sidecar: %v", podName, ns, err)
+	}
+	cl := &clusters.Wrapper{}
+	if err := cl.UnmarshalJSON(byClusters); err != nil {
+		return nil, fmt.Errorf("can't parse %s.%s sidecar clusters: %v", podName, ns, err)
+	}
+	return routetrace.NewTracer(cd, cl), nil
+}
+
+func traceInbound(writer io.Writer, client kubernetes.Interface, execClient istioctl_kubernetes.ExecClient,
+	req *routetrace.Request, out *routetrace.ProxyTrace) error {
+	endpoint := out.Cluster.SelectedEndpoint()
+	if endpoint == nil {
+		fmt.Fprintf(writer, "\nCluster %s has no healthy endpoint, the proxy responds 503\n", out.Cluster.Name)
+		return nil
+	}
+
+	pods, err := client.CoreV1().Pods("").List(metav1.ListOptions{
+		FieldSelector: "status.podIP=" + endpoint.Address,
+	})
+	if err != nil {
+		return err
+	}
+	var target *v1.Pod
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == v1.PodRunning && !pods.Items[i].Spec.HostNetwork {
+			target = &pods.Items[i]
+			break
+		}
+	}
+	if target == nil {
+		fmt.Fprintf(writer, "\nEndpoint %s:%d isn't a pod of the cluster, skipping the inbound trace\n",
+			endpoint.Address, endpoint.Port)
+		return nil
+	}
+
+	tracer, err := proxyTracer(execClient, target.Name, target.Namespace)
+	if err != nil {
+		return err
+	}
+	in, err := tracer.Inbound(req, out, *endpoint)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(writer, "\nInbound at %s.%s (%s:%d):\n", target.Name, target.Namespace, endpoint.Address, endpoint.Port)
+	in.Print(writer)
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRequestHeaders(t *testing.T) {
+	cases := []struct {
+		headers []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			headers: []string{"end-user: jason", "x-request-id:42"},
+			want:    map[string]string{"end-user": "jason", "x-request-id": "42"},
+		},
+		{
+			headers: []string{":authority: reviews.default"},
+			want:    map[string]string{":authority": "reviews.default"},
+		},
+		{
+			headers: []string{"end-user"},
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		got, err := parseRequestHeaders(c.headers)
+		if (err != nil) != c.wantErr {
+			t.Fatalf("parseRequestHeaders(%v) error = %v, want error %v", c.headers, err, c.wantErr)
+		}
+		if !c.wantErr && !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("parseRequestHeaders(%v) = %v, want %v", c.headers, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/pkg/log"
+
+	"istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/istioctl/pkg/tap"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+const (
+	tapDirectionInbound  = "inbound"
+	tapDirectionOutbound = "outbound"
+	tapDirectionAll      = "all"
+
+	defaultTapConfigID = "istioctl-tap"
+	tapFilterPrefix    = "istioctl-tap-"
+
+	// unknownTapConfigID is returned by the tap admin endpoint until a tap filter with the config id is installed.
+	unknownTapConfigID = "Unknown config id"
+)
+
+var (
+	envoyFilterGVR = schema.GroupVersionResource{
+		Group:    collections.IstioNetworkingV1Alpha3Envoyfilters.Resource().Group(),
+		Version:  collections.IstioNetworkingV1Alpha3Envoyfilters.Resource().Version(),
+		Resource: collections.IstioNetworkingV1Alpha3Envoyfilters.Resource().Plural(),
+	}
+
+	tapPatchContexts = map[string]networking.EnvoyFilter_PatchContext{
+		tapDirectionInbound:  networking.EnvoyFilter_SIDECAR_INBOUND,
+		tapDirectionOutbound: networking.EnvoyFilter_SIDECAR_OUTBOUND,
+		tapDirectionAll:      networking.EnvoyFilter_ANY,
+	}
+)
+
+func tapCmd() *cobra.Command {
+	var (
+		headers       []string
+		path          string
+		status        string
+		outputFile    string
+		direction     string
+		configID      string
+		installFilter bool
+		maxBodyBytes  uint32
+		count         int
+		duration      time.Duration
+		timeout       time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "tap <pod-name[.namespace]>",
+		Short: "Captures the requests handled by the sidecar of a pod",
+		Long: `
+Captures the requests handled by the sidecar of a pod, with their responses, through the tap
+admin endpoint of Envoy, without exec'ing into the pod.
+
+A tap filter is installed on the workload of the pod with an EnvoyFilter for the duration of the
+capture. The filter only captures requests while istioctl is attached to it, and both are removed
+when the capture stops, on Ctrl-C, after --count requests or after --duration. When the sidecars
+already have a tap filter, use --install-filter=false and its config id.
+
+The captured requests are printed to the terminal. With --output, they are written to a file
+instead: a pcap file, with synthesized TCP connections and addresses, when its name ends with
+.pcap, otherwise one JSON trace per line. Bodies are truncated to --max-body-bytes.
+`,
+		Example: `
+# Capture the inbound requests of a pod
+istioctl experimental tap productpage-v1-7d6cfb7dfd-5mc96
+
+# Capture the 5xx responses of the requests to /api/ with a header
+istioctl experimental tap reviews-v1-5d6c89d8c5-xkxg8.bookinfo --path '/api/*' --status 5xx --header x-user=jason
+
+# Capture 10 outbound requests to a pcap file, for Wireshark
+istioctl experimental tap productpage-v1-7d6cfb7dfd-5mc96 --direction outbound --count 10 -o productpage.pcap
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			match, err := tapMatch(headers, path, status)
+			if err != nil {
+				return CommandParseError{err}
+			}
+			if _, ok := tapPatchContexts[direction]; !ok {
+				return CommandParseError{fmt.Errorf("invalid --direction %q, must be one of %s, %s or %s",
+					direction, tapDirectionInbound, tapDirectionOutbound, tapDirectionAll)}
+			}
+			if configID == "" {
+				return CommandParseError{errors.New("--config-id must not be empty")}
+			}
+
+			podName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
+			kubeClient, err := interfaceFactory(kubeconfig)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			pod, err := kubeClient.CoreV1().Pods(ns).Get(podName, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("could not get pod %s.%s: %v", podName, ns, err)
+			}
+			if !hasSidecar(pod) {
+				return fmt.Errorf("pod %s.%s has no %s container", podName, ns, proxyContainer)
+			}
+
+			// Interrupting the command cancels the context, so that the tap filter is removed whatever step is
+			// interrupted.
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt)
+			defer signal.Stop(signals)
+			go func() {
+				select {
+				case <-signals:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			if installFilter {
+				dynamicClient, err := crdFactory(kubeconfig)
+				if err != nil {
+					return fmt.Errorf("failed to create k8s client: %v", err)
+				}
+				name, err := installTapFilter(dynamicClient, pod, direction, configID)
+				if err != nil {
+					return err
+				}
+				defer removeTapFilter(dynamicClient, name, ns, c.ErrOrStderr())
+			}
+
+			client, err := clientExecFactory(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			fw, err := client.BuildPortForwarder(podName, ns, 0, envoyAdminPort)
+			if err != nil {
+				return fmt.Errorf("could not build port forwarder: %v", err)
+			}
+			if err = kubernetes.StartPortForwarder(fw); err != nil {
+				return err
+			}
+			defer close(fw.StopChannel)
+
+			body, err := tap.RequestBody(tap.NewRequest(configID, match, maxBodyBytes))
+			if err != nil {
+				return err
+			}
+			stream, err := startTap(ctx, fw.LocalPort, body, timeout)
+			if ctx.Err() != nil {
+				// Interrupted while waiting for the tap filter.
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			defer stream.Close()
+
+			write, closeOutput, err := tapOutput(outputFile, c.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			defer closeOutput()
+
+			fmt.Fprintf(c.ErrOrStderr(), "Capturing the requests of %s.%s, press Ctrl-C to stop\n", podName, ns)
+			return captureTraces(ctx, tap.NewReader(stream), write, count, duration)
+		},
+	}
+
+	cmd.PersistentFlags().StringArrayVar(&headers, "header", nil,
+		"Only capture the requests with this header value, as name=value; can be repeated")
+	cmd.PersistentFlags().StringVar(&path, "path", "",
+		"Only capture the requests with this path; a path ending with * is a prefix")
+	cmd.PersistentFlags().StringVar(&status, "status", "",
+		"Only capture the requests with this response status code, such as 503, or class, such as 5xx")
+	cmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "",
+		"Write the captured requests to this file instead of the terminal, as pcap if it ends with .pcap, "+
+			"otherwise as JSON")
+	cmd.PersistentFlags().StringVar(&direction, "direction", tapDirectionInbound,
+		fmt.Sprintf("Requests to capture: %s, %s, or %s (for gateways)",
+			tapDirectionInbound, tapDirectionOutbound, tapDirectionAll))
+	cmd.PersistentFlags().StringVar(&configID, "config-id", defaultTapConfigID,
+		"Config id of the tap filter, controlled through the tap admin endpoint")
+	cmd.PersistentFlags().BoolVar(&installFilter, "install-filter", true,
+		"Install a tap filter on the workload of the pod for the duration of the capture")
+	cmd.PersistentFlags().Uint32Var(&maxBodyBytes, "max-body-bytes", 1024,
+		"Size the request and response bodies are truncated to")
+	cmd.PersistentFlags().IntVar(&count, "count", 0, "Stop after capturing this number of requests, 0 for no limit")
+	cmd.PersistentFlags().DurationVar(&duration, "duration", 0, "Stop after this duration, 0 for no limit")
+	cmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Second,
+		"Time to wait for the sidecar to receive the tap filter")
+
+	return cmd
+}
+
+// tapMatch returns the criteria of the requests to capture.
+func tapMatch(headers []string, path, status string) (tap.Match, error) {
+	match := tap.Match{Path: path, Status: status}
+	for _, header := range headers {
+		parts := strings.SplitN(header, "=", 2)
+		if len(parts) != 2 {
+			return match, fmt.Errorf("invalid --header %q, must be name=value", header)
+		}
+		if match.Headers == nil {
+			match.Headers = map[string]string{}
+		}
+		match.Headers[parts[0]] = parts[1]
+	}
+	return match, match.Validate()
+}
+
+func hasSidecar(pod *v1.Pod) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == proxyContainer {
+			return true
+		}
+	}
+	return false
+}
+
+// tapEnvoyFilter returns the EnvoyFilter adding a tap filter, controlled through the admin endpoint, to the
+// sidecars of the workload of a pod.
+func tapEnvoyFilter(pod *v1.Pod, direction, configID string) *unstructured.Unstructured {
+	workloadLabels := make(map[string]interface{}, len(pod.Labels))
+	for k, v := range pod.Labels {
+		workloadLabels[k] = v
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": collections.IstioNetworkingV1Alpha3Envoyfilters.Resource().APIVersion(),
+			"kind":       collections.IstioNetworkingV1Alpha3Envoyfilters.Resource().Kind(),
+			"metadata": map[string]interface{}{
+				"namespace": pod.Namespace,
+				"name":      tapFilterPrefix + pod.Name,
+			},
+			"spec": map[string]interface{}{
+				"workloadSelector": map[string]interface{}{
+					"labels": workloadLabels,
+				},
+				"configPatches": []interface{}{
+					map[string]interface{}{
+						"applyTo": networking.EnvoyFilter_HTTP_FILTER.String(),
+						"match": map[string]interface{}{
+							"context": tapPatchContexts[direction].String(),
+							"listener": map[string]interface{}{
+								"filterChain": map[string]interface{}{
+									"filter": map[string]interface{}{
+										"name": wellknown.HTTPConnectionManager,
+										"subFilter": map[string]interface{}{
+											"name": wellknown.Router,
+										},
+									},
+								},
+							},
+						},
+						"patch": map[string]interface{}{
+							"operation": networking.EnvoyFilter_Patch_INSERT_BEFORE.String(),
+							"value": map[string]interface{}{
+								"name":         tap.FilterName,
+								"typed_config": tap.FilterConfig(configID),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// installTapFilter creates the EnvoyFilter of the tap filter of a pod, returning its name.
+func installTapFilter(client dynamic.Interface, pod *v1.Pod, direction, configID string) (string, error) {
+	if len(pod.Labels) == 0 {
+		return "", fmt.Errorf("pod %s.%s has no labels to select its workload, install a tap filter and "+
+			"use --install-filter=false", pod.Name, pod.Namespace)
+	}
+	filter := tapEnvoyFilter(pod, direction, configID)
+	_, err := client.Resource(envoyFilterGVR).Namespace(pod.Namespace).Create(filter, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("could not create EnvoyFilter %s.%s: %v", filter.GetName(), pod.Namespace, err)
+	}
+	return filter.GetName(), nil
+}
+
+func removeTapFilter(client dynamic.Interface, name, namespace string, stderr io.Writer) {
+	if err := client.Resource(envoyFilterGVR).Namespace(namespace).Delete(name, &metav1.DeleteOptions{}); err != nil {
+		fmt.Fprintf(stderr, "Warning: could not delete EnvoyFilter %s.%s, delete it manually: %v\n",
+			name, namespace, err)
+	}
+}
+
+// startTap posts the tap request to the admin endpoint, returning the stream of traces. The request is retried
+// until the sidecar has received the tap filter, or the context is canceled.
+func startTap(ctx context.Context, localPort int, body []byte, timeout time.Duration) (io.ReadCloser, error) {
+	url := fmt.Sprintf("http://localhost:%d/tap", localPort)
+	deadline := time.Now().Add(timeout)
+	for {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("could not start the tap: %v", err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp.Body, nil
+		}
+
+		msg, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if !strings.Contains(string(msg), unknownTapConfigID) || time.Now().After(deadline) {
+			return nil, fmt.Errorf("could not start the tap: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		}
+		log.Debugf("waiting for the tap filter: %s", strings.TrimSpace(string(msg)))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// tapOutput returns the function writing the captured traces, to the terminal or a file, along with a function
+// closing the file.
+func tapOutput(outputFile string, stdout io.Writer) (func(*tap.Trace) error, func(), error) {
+	if outputFile == "" {
+		return func(t *tap.Trace) error {
+			tap.WriteText(stdout, t)
+			return nil
+		}, func() {}, nil
+	}
+
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	closeFile := func() { _ = f.Close() }
+
+	write := func(t *tap.Trace) error {
+		return tap.WriteJSON(f, t)
+	}
+	if filepath.Ext(outputFile) == ".pcap" {
+		pcap, err := tap.NewPcapWriter(f)
+		if err != nil {
+			closeFile()
+			return nil, nil, err
+		}
+		write = pcap.Write
+	}
+	return func(t *tap.Trace) error {
+		fmt.Fprintln(stdout, t.Summary())
+		return write(t)
+	}, closeFile, nil
+}
+
+// captureTraces writes the traces of the reader until count traces are written, the duration elapses, the reader
+// ends or the context is canceled.
+func captureTraces(ctx context.Context, reader *tap.Reader, write func(*tap.Trace) error, count int, duration time.Duration) error {
+	type result struct {
+		trace *tap.Trace
+		err   error
+	}
+	traces := make(chan result)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			trace, err := reader.Next()
+			select {
+			case traces <- result{trace, err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var timeout <-chan time.Time
+	if duration > 0 {
+		timeout = time.After(duration)
+	}
+
+	for captured := 0; count == 0 || captured < count; captured++ {
+		select {
+		case r := <-traces:
+			if r.err == io.EOF {
+				return errors.New("the sidecar closed the tap")
+			}
+			if r.err != nil {
+				return r.err
+			}
+			if err := write(r.trace); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		case <-timeout:
+			return nil
+		}
+	}
+	return nil
+}
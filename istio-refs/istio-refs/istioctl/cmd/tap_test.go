@@ -0,0 +1,149 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestTapBadFlags(t *testing.T) {
+	cases := []testCase{
+		{
+			args:           strings.Split("experimental tap reviews-v1 --status 50x", " "),
+			expectedRegexp: regexp.MustCompile(`status "50x" must be a status code`),
+			wantException:  true,
+		},
+		{
+			args:           strings.Split("experimental tap reviews-v1 --header x-user", " "),
+			expectedRegexp: regexp.MustCompile(`invalid --header "x-user", must be name=value`),
+			wantException:  true,
+		},
+		{
+			args:           strings.Split("experimental tap reviews-v1 --path api", " "),
+			expectedRegexp: regexp.MustCompile(`path "api" must start with /`),
+			wantException:  true,
+		},
+		{
+			args:           strings.Split("experimental tap reviews-v1 --direction both", " "),
+			expectedRegexp: regexp.MustCompile(`invalid --direction "both"`),
+			wantException:  true,
+		},
+		{
+			args:          strings.Split("experimental tap", " "),
+			wantException: true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyOutput(t, c)
+		})
+	}
+}
+
+func TestTapFilter(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "reviews-v1-5d6c89d8c5-xkxg8",
+			Namespace: "bookinfo",
+			Labels:    map[string]string{"app": "reviews", "version": "v1"},
+		},
+	}
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+
+	name, err := installTapFilter(client, pod, tapDirectionOutbound, "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := client.Resource(envoyFilterGVR).Namespace("bookinfo").Get(name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("EnvoyFilter %s not created: %v", name, err)
+	}
+	if name != "istioctl-tap-reviews-v1-5d6c89d8c5-xkxg8" {
+		t.Errorf("got EnvoyFilter %s", name)
+	}
+	app, _, _ := unstructured.NestedString(filter.Object, "spec", "workloadSelector", "labels", "app")
+	if app != "reviews" {
+		t.Errorf("got workload selector app %q, want reviews", app)
+	}
+	patches, _, _ := unstructured.NestedSlice(filter.Object, "spec", "configPatches")
+	if len(patches) != 1 {
+		t.Fatalf("got %d config patches, want 1", len(patches))
+	}
+	patch := patches[0].(map[string]interface{})
+	for _, field := range []struct {
+		path []string
+		want string
+	}{
+		{[]string{"applyTo"}, "HTTP_FILTER"},
+		{[]string{"match", "context"}, "SIDECAR_OUTBOUND"},
+		{[]string{"patch", "operation"}, "INSERT_BEFORE"},
+		{[]string{"patch", "value", "name"}, "envoy.filters.http.tap"},
+		{[]string{"patch", "value", "typed_config", "common_config", "admin_config", "config_id"}, "debug"},
+	} {
+		if got, _, _ := unstructured.NestedString(patch, field.path...); got != field.want {
+			t.Errorf("got %s %q, want %q", strings.Join(field.path, "."), got, field.want)
+		}
+	}
+
+	var stderr bytes.Buffer
+	removeTapFilter(client, name, "bookinfo", &stderr)
+	if _, err := client.Resource(envoyFilterGVR).Namespace("bookinfo").Get(name, metav1.GetOptions{}); err == nil {
+		t.Errorf("EnvoyFilter %s not deleted", name)
+	}
+	removeTapFilter(client, name, "bookinfo", &stderr)
+	if !strings.Contains(stderr.String(), "Warning: could not delete EnvoyFilter") {
+		t.Errorf("got %q, want a warning for a missing EnvoyFilter", stderr.String())
+	}
+
+	pod.Labels = nil
+	if _, err := installTapFilter(client, pod, tapDirectionInbound, "debug"); err == nil {
+		t.Errorf("want an error for a pod without labels")
+	}
+}
+
+func TestStartTapCanceled(t *testing.T) {
+	// The sidecar has not received the tap filter yet.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, unknownTapConfigID+": debug", http.StatusBadRequest)
+	}))
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := startTap(ctx, port, nil, time.Minute); err != context.Canceled {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("startTap returned after %v, want it to stop once canceled", elapsed)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/istioctl/pkg/top"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/pkg/log"
+)
+
+const (
+	topSourceAuto       = "auto"
+	topSourcePrometheus = "prometheus"
+	topSourceEnvoy      = "envoy"
+
+	envoyAdminPort = 15000
+	proxyContainer = "istio-proxy"
+
+	// clearScreen moves the cursor to the top left corner and clears the terminal.
+	clearScreen = "\x1b[H\x1b[2J"
+)
+
+func topCmd() *cobra.Command {
+	var (
+		sortBy   string
+		source   string
+		selector string
+		interval time.Duration
+		window   time.Duration
+		edges    bool
+		once     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Displays a live view of the traffic of the workloads of a namespace",
+		Long: `
+Displays a live view of the traffic of the workloads of a namespace, refreshed periodically.
+
+For each workload, the requests per second, the rate of 5xx responses and the p50, p90 and p99
+latencies are shown, followed by its inbound (<-) and outbound (->) edges. Inbound traffic is
+taken from the reports of the destination proxies, outbound traffic from the reports of the
+source proxies.
+
+By default the traffic is read from the Prometheus pod of the istio system namespace. When
+there is none, or with --source envoy, the stats of the sidecars of the namespace are read
+directly through port-forwards; rates are then computed between two refreshes, so the first
+refresh is empty.
+`,
+		Example: `
+# Watch the workloads of the default namespace, busiest first
+istioctl experimental top
+
+# Watch the workloads of the bookinfo namespace, slowest first
+istioctl experimental top -n bookinfo --sort p99
+
+# Print the traffic of the sidecars labeled app=reviews once, without Prometheus
+istioctl experimental top --source envoy -l app=reviews --once
+`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if !validSortKey(sortBy) {
+				return CommandParseError{fmt.Errorf("invalid --sort %q, must be one of %v", sortBy, top.SortKeys)}
+			}
+			if source != topSourceAuto && source != topSourcePrometheus && source != topSourceEnvoy {
+				return CommandParseError{fmt.Errorf("invalid --source %q, must be one of %s, %s or %s",
+					source, topSourceAuto, topSourcePrometheus, topSourceEnvoy)}
+			}
+			if interval <= 0 {
+				return CommandParseError{errors.New("--interval must be positive")}
+			}
+
+			client, err := clientExecFactory(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			ns := handlers.HandleNamespace(namespace, defaultNamespace)
+
+			src, cleanup, err := topSource(client, source, selector, window)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt)
+			defer signal.Stop(signals)
+
+			if _, warmUp := src.(*top.EnvoySource); warmUp && once {
+				// The sidecar stats are counters: record them once so that there is a rate to print.
+				if _, err := src.Fetch(ns, time.Now()); err != nil {
+					return err
+				}
+				select {
+				case <-signals:
+					return nil
+				case <-time.After(interval):
+				}
+			}
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				now := time.Now()
+				samples, err := src.Fetch(ns, now)
+				if err != nil {
+					return err
+				}
+				if !once {
+					fmt.Fprint(c.OutOrStdout(), clearScreen)
+				}
+				snapshot := top.Aggregate(samples, ns, now)
+				if envoy, ok := src.(*top.EnvoySource); ok {
+					snapshot.Errors = envoy.Errors()
+				}
+				top.Render(c.OutOrStdout(), snapshot, top.SortKey(sortBy), edges)
+				if once {
+					return nil
+				}
+
+				select {
+				case <-signals:
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&sortBy, "sort", string(top.SortByRPS),
+		fmt.Sprintf("Column to sort the workloads by, one of %v", top.SortKeys))
+	cmd.PersistentFlags().StringVar(&source, "source", topSourceAuto,
+		"Where to read the traffic from: prometheus, envoy, or auto to use Prometheus when it is deployed")
+	cmd.PersistentFlags().StringVarP(&selector, "selector", "l", "",
+		"Label selector of the pods whose sidecar stats are read, with the envoy source")
+	cmd.PersistentFlags().DurationVar(&interval, "interval", 5*time.Second, "Time between two refreshes")
+	cmd.PersistentFlags().DurationVar(&window, "window", time.Minute,
+		"Time window the Prometheus rates are computed over")
+	cmd.PersistentFlags().BoolVar(&edges, "edges", true, "Show the inbound and outbound edges of each workload")
+	cmd.PersistentFlags().BoolVar(&once, "once", false, "Print the traffic once instead of refreshing it")
+
+	return cmd
+}
+
+func validSortKey(sortBy string) bool {
+	for _, key := range top.SortKeys {
+		if string(key) == sortBy {
+			return true
+		}
+	}
+	return false
+}
+
+// topSource returns the source of the traffic, along with a function releasing its port-forwards.
+func topSource(client kubernetes.ExecClient, source, selector string, window time.Duration) (top.Source, func(), error) {
+	if source != topSourceEnvoy {
+		pl, err := client.PodsForSelector(istioNamespace, "app=prometheus")
+		if err != nil {
+			return nil, nil, fmt.Errorf("not able to locate Prometheus pod: %v", err)
+		}
+		if len(pl.Items) > 0 {
+			fw, err := client.BuildPortForwarder(pl.Items[0].Name, istioNamespace, 0, 9090)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not build port forwarder for prometheus: %v", err)
+			}
+			if err := kubernetes.StartPortForwarder(fw); err != nil {
+				return nil, nil, err
+			}
+			cleanup := func() { close(fw.StopChannel) }
+			promAPI, err := prometheusAPI(fw.LocalPort)
+			if err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+			return &top.PrometheusSource{API: promAPI, Window: window}, cleanup, nil
+		}
+		if source == topSourcePrometheus {
+			return nil, nil, errors.New("no Prometheus pods found")
+		}
+		log.Debugf("no Prometheus pods found, reading the sidecar stats")
+	}
+
+	stats := &sidecarStats{client: client, forwarders: map[string]*kubernetes.PortForward{}}
+	src := &top.EnvoySource{
+		Pods: func(namespace string) ([]string, error) {
+			return sidecarPods(client, namespace, selector)
+		},
+		Stats: stats.fetch,
+	}
+	return src, stats.close, nil
+}
+
+// sidecarPods returns the names of the running pods of the namespace with a sidecar.
+func sidecarPods(client kubernetes.ExecClient, namespace, selector string) ([]string, error) {
+	pl, err := client.PodsForSelector(namespace, selector)
+	if err != nil {
+		return nil, fmt.Errorf("not able to list the pods of namespace %s: %v", namespace, err)
+	}
+	var pods []string
+	for _, pod := range pl.Items {
+		if pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			if container.Name == proxyContainer {
+				pods = append(pods, pod.Name)
+				break
+			}
+		}
+	}
+	return pods, nil
+}
+
+// sidecarStats reads the stats of sidecars, keeping a port-forward open to each of them between refreshes.
+type sidecarStats struct {
+	client     kubernetes.ExecClient
+	forwarders map[string]*kubernetes.PortForward
+}
+
+func (s *sidecarStats) fetch(podName, namespace string) ([]byte, error) {
+	key := podName + "." + namespace
+	fw, ok := s.forwarders[key]
+	if !ok {
+		var err error
+		if fw, err = s.client.BuildPortForwarder(podName, namespace, 0, envoyAdminPort); err != nil {
+			return nil, fmt.Errorf("could not build port forwarder: %v", err)
+		}
+		if err = kubernetes.StartPortForwarder(fw); err != nil {
+			return nil, err
+		}
+		s.forwarders[key] = fw
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%d/stats/prometheus", fw.LocalPort))
+	if err != nil {
+		// The pod may be gone: forget its port-forward so that the next refresh starts a new one.
+		close(fw.StopChannel)
+		delete(s.forwarders, key)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func (s *sidecarStats) close() {
+	for _, fw := range s.forwarders {
+		close(fw.StopChannel)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTopBadFlags(t *testing.T) {
+	cases := []testCase{
+		{
+			args:           strings.Split("experimental top --sort p50", " "),
+			expectedRegexp: regexp.MustCompile(`invalid --sort "p50"`),
+			wantException:  true,
+		},
+		{
+			args:           strings.Split("experimental top --source mixer", " "),
+			expectedRegexp: regexp.MustCompile(`invalid --source "mixer"`),
+			wantException:  true,
+		},
+		{
+			args:           strings.Split("experimental top --interval 0s", " "),
+			expectedRegexp: regexp.MustCompile(`--interval must be positive`),
+			wantException:  true,
+		},
+		{
+			args:          strings.Split("experimental top reviews-v1", " "),
+			wantException: true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyOutput(t, c)
+		})
+	}
+}
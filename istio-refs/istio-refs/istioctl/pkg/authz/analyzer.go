@@ -28,6 +28,7 @@ import (
 
 	envoy_admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoy_rbac "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
 	"github.com/golang/protobuf/ptypes"
 
 	"istio.io/istio/istioctl/pkg/util/configdump"
@@ -102,3 +103,72 @@ func (a *Analyzer) Print(writer io.Writer, printAll bool) {
 		len(parsedListeners), len(a.listenerDump.DynamicListeners), a.nodeIP)
 	PrintParsedListeners(writer, parsedListeners, printAll)
 }
+
+// Evaluate evaluates the request against the RBAC filters of the inbound filter chain receiving it. The destination
+// IP defaults to the node IP.
+func (a *Analyzer) Evaluate(req *Request) (*Decision, error) {
+	if req.DestinationIP == "" {
+		req.DestinationIP = a.nodeIP
+	}
+	name, fc := a.selectInboundFilterChain(req)
+	if fc == nil {
+		return nil, fmt.Errorf("no inbound filter chain for port %d", req.DestinationPort)
+	}
+
+	var decision *Decision
+	if len(fc.rbacHTTP) != 0 || fc.routeHTTP != "" {
+		filters := make([]*envoy_rbac.RBAC, 0, len(fc.rbacHTTP))
+		for _, f := range fc.rbacHTTP {
+			filters = append(filters, f.GetRules())
+		}
+		decision = EvaluateRBAC(filters, req, false)
+	} else {
+		filters := make([]*envoy_rbac.RBAC, 0, len(fc.rbacTCP))
+		for _, f := range fc.rbacTCP {
+			filters = append(filters, f.GetRules())
+		}
+		decision = EvaluateRBAC(filters, req, true)
+	}
+	decision.FilterChain = name
+	return decision, nil
+}
+
+// selectInboundFilterChain returns the filter chain receiving the request, either from the inbound listener bound to
+// the node IP and the destination port, or from the virtual inbound listener. Mutual TLS filter chains are preferred
+// for requests with a source principal, plaintext filter chains otherwise.
+func (a *Analyzer) selectInboundFilterChain(req *Request) (string, *filterChain) {
+	port := strconv.Itoa(int(req.DestinationPort))
+	var (
+		candidateNames []string
+		candidates     []*filterChain
+	)
+	for _, l := range a.getParsedListeners() {
+		for i, fc := range l.filterChains {
+			var matches bool
+			if l.name == "virtualInbound" {
+				matches = fc.match != nil && fc.match.destinationPort == req.DestinationPort
+			} else {
+				matches = l.ip == a.nodeIP && l.port == port
+			}
+			if matches {
+				name := l.name
+				if len(l.filterChains) > 1 {
+					name = fmt.Sprintf("%s[%d]", name, i)
+				}
+				candidateNames = append(candidateNames, name)
+				candidates = append(candidates, fc)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", nil
+	}
+
+	mTLS := req.sourcePrincipal() != ""
+	for i, fc := range candidates {
+		if (fc.tlsContext != nil) == mTLS {
+			return candidateNames[i], fc
+		}
+	}
+	return candidateNames[0], candidates[0]
+}
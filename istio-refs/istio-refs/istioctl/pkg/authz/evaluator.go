@@ -0,0 +1,533 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	envoy_rbac "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
+	envoy_matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+
+	"istio.io/istio/istioctl/pkg/util"
+	authn_model "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/spiffe"
+)
+
+// Keys of the dynamic metadata set by the Istio authentication filter and used by the RBAC policies.
+const (
+	attrSrcPrincipal      = "source.principal"
+	attrRequestPrincipal  = "request.auth.principal"
+	attrRequestAudiences  = "request.auth.audiences"
+	attrRequestPresenter  = "request.auth.presenter"
+	attrRequestClaims     = "request.auth.claims"
+	defaultServiceAccount = "default"
+	defaultTrustDomain    = "cluster.local"
+)
+
+// Request is a synthetic request evaluated against the RBAC filters of a proxy.
+type Request struct {
+	// SourcePrincipal is the identity of the peer, e.g. cluster.local/ns/default/sa/productpage. It is empty for
+	// requests received over plaintext.
+	SourcePrincipal string
+
+	// SourceNamespace defaults the source principal to the default service account of the namespace.
+	SourceNamespace string
+
+	SourceIP        string
+	DestinationIP   string
+	DestinationPort uint32
+
+	// ServerName is the SNI of the connection.
+	ServerName string
+
+	Method  string
+	Host    string
+	Path    string
+	Headers map[string]string
+
+	// RequestPrincipal is the principal of the request credential. Defaults to the "iss/sub" claims.
+	RequestPrincipal string
+
+	// Claims of the request credential. Multiple values are evaluated as a list claim.
+	Claims map[string][]string
+}
+
+func (r *Request) sourcePrincipal() string {
+	if r.SourcePrincipal == "" && r.SourceNamespace != "" {
+		return fmt.Sprintf("%s/ns/%s/sa/%s", defaultTrustDomain, r.SourceNamespace, defaultServiceAccount)
+	}
+	return r.SourcePrincipal
+}
+
+func (r *Request) requestPrincipal() string {
+	if r.RequestPrincipal != "" {
+		return r.RequestPrincipal
+	}
+	iss, sub := r.Claims["iss"], r.Claims["sub"]
+	if len(iss) == 0 || len(sub) == 0 {
+		return ""
+	}
+	return iss[0] + "/" + sub[0]
+}
+
+func (r *Request) header(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case ":method":
+		if r.Method == "" {
+			return "GET", true
+		}
+		return strings.ToUpper(r.Method), true
+	case ":path":
+		if r.Path == "" {
+			return "/", true
+		}
+		return r.Path, true
+	case ":authority", "host":
+		return r.Host, r.Host != ""
+	}
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// urlPath returns the path of the request without the query string.
+func (r *Request) urlPath() string {
+	p, _ := r.header(":path")
+	if i := strings.IndexByte(p, '?'); i >= 0 {
+		return p[:i]
+	}
+	return p
+}
+
+// metadata returns the dynamic metadata the Istio authentication filter sets for the request.
+func (r *Request) metadata() map[string]interface{} {
+	md := map[string]interface{}{}
+	if p := r.sourcePrincipal(); p != "" {
+		md[attrSrcPrincipal] = p
+	}
+	if p := r.requestPrincipal(); p != "" {
+		md[attrRequestPrincipal] = p
+	}
+	if aud := r.Claims["aud"]; len(aud) > 0 {
+		md[attrRequestAudiences] = aud[0]
+	}
+	if azp := r.Claims["azp"]; len(azp) > 0 {
+		md[attrRequestPresenter] = azp[0]
+	}
+	if len(r.Claims) > 0 {
+		claims := map[string]interface{}{}
+		for k, values := range r.Claims {
+			list := make([]interface{}, 0, len(values))
+			for _, v := range values {
+				list = append(list, v)
+			}
+			claims[k] = list
+		}
+		md[attrRequestClaims] = claims
+	}
+	return md
+}
+
+// Decision is the result of the evaluation of a request against the RBAC filters of a proxy.
+type Decision struct {
+	Allowed bool
+
+	// Undetermined is set when the decision depends on conditions the evaluator can't evaluate. Allowed is
+	// meaningless then, and Policy is the policy with such conditions.
+	Undetermined bool
+
+	// FilterChain is the inbound filter chain whose RBAC filters were evaluated, as in the check output.
+	FilterChain string
+
+	// Action of the RBAC filter which decided, ALLOW or DENY, or empty if there is no RBAC filter.
+	Action string
+
+	// Policy and Rule which decided, if any. The rule identifies the permission and principal of the policy the
+	// request matched.
+	Policy string
+	Rule   string
+
+	Reason string
+}
+
+// Result returns ALLOW, DENY or UNDETERMINED.
+func (d *Decision) Result() string {
+	switch {
+	case d.Undetermined:
+		return "UNDETERMINED"
+	case d.Allowed:
+		return "ALLOW"
+	}
+	return "DENY"
+}
+
+// Print writes the decision.
+func (d *Decision) Print(w io.Writer) {
+	if d.FilterChain != "" {
+		_, _ = fmt.Fprintf(w, "Filter chain: %s\n", d.FilterChain)
+	}
+	_, _ = fmt.Fprintf(w, "Decision:     %s\n", d.Result())
+	if d.Policy != "" {
+		_, _ = fmt.Fprintf(w, "Policy:       %s (%s)\n", d.Policy, d.Action)
+		_, _ = fmt.Fprintf(w, "Rule:         %s\n", d.Rule)
+	}
+	_, _ = fmt.Fprintf(w, "Reason:       %s\n", d.Reason)
+}
+
+// EvaluateRBAC evaluates the request as Envoy does with RBAC filters enforcing the given rules in turn: the request
+// is denied by the first DENY filter with a matching policy, or by the first ALLOW filter without one. The network
+// filter only sees the connection, not the HTTP request nor the metadata of the authentication filter.
+// Conditions the evaluator doesn't support, and HTTP conditions in network filters, can't be evaluated. The
+// decision is undetermined if it depends on them.
+func EvaluateRBAC(filters []*envoy_rbac.RBAC, req *Request, network bool) *Decision {
+	ctx := &evalContext{req: req, network: network, metadata: req.metadata()}
+
+	var allowed, undetermined *Decision
+	enforced := false
+	for _, rules := range filters {
+		if rules == nil {
+			// Without rules, the filter only runs its shadow rules, if any.
+			continue
+		}
+		enforced = true
+		result, policy, rule := ctx.matchPolicies(rules)
+		if result == unknownMatch {
+			// A later filter may still deny the request.
+			if undetermined == nil {
+				undetermined = &Decision{
+					Undetermined: true,
+					Action:       rules.GetAction().String(),
+					Policy:       policy,
+					Reason:       "the policy has conditions that can't be evaluated for the request",
+				}
+			}
+			continue
+		}
+		switch rules.GetAction() {
+		case envoy_rbac.RBAC_DENY:
+			if result == matched {
+				return &Decision{
+					Action: rules.GetAction().String(),
+					Policy: policy,
+					Rule:   rule,
+					Reason: "the request matches a DENY policy",
+				}
+			}
+		default:
+			if result == noMatch {
+				return &Decision{
+					Action: rules.GetAction().String(),
+					Reason: "the request matches no ALLOW policy",
+				}
+			}
+			if allowed == nil {
+				allowed = &Decision{
+					Allowed: true,
+					Action:  rules.GetAction().String(),
+					Policy:  policy,
+					Rule:    rule,
+					Reason:  "the request matches an ALLOW policy",
+				}
+			}
+		}
+	}
+	if undetermined != nil {
+		return undetermined
+	}
+	if allowed != nil {
+		return allowed
+	}
+	if !enforced {
+		return &Decision{Allowed: true, Reason: "no RBAC filter applies to the request"}
+	}
+	return &Decision{Allowed: true, Reason: "the request matches no DENY policy"}
+}
+
+// match is the result of the evaluation of a condition. Conditions that can't be evaluated are unknown, and
+// combined with Kleene's three-valued logic.
+type match int
+
+const (
+	noMatch match = iota
+	matched
+	unknownMatch
+)
+
+func matchOf(b bool) match {
+	if b {
+		return matched
+	}
+	return noMatch
+}
+
+func (m match) not() match {
+	switch m {
+	case matched:
+		return noMatch
+	case noMatch:
+		return matched
+	}
+	return unknownMatch
+}
+
+// and returns the conjunction of the matches, evaluated lazily: a non-match decides regardless of unknown matches.
+func and(n int, f func(i int) match) match {
+	result := matched
+	for i := 0; i < n; i++ {
+		switch f(i) {
+		case noMatch:
+			return noMatch
+		case unknownMatch:
+			result = unknownMatch
+		}
+	}
+	return result
+}
+
+// or returns the disjunction of the matches, evaluated lazily: a match decides regardless of unknown matches.
+// It returns the index of the first match, if any.
+func or(n int, f func(i int) match) (match, int) {
+	result := noMatch
+	for i := 0; i < n; i++ {
+		switch f(i) {
+		case matched:
+			return matched, i
+		case unknownMatch:
+			result = unknownMatch
+		}
+	}
+	return result, -1
+}
+
+type evalContext struct {
+	req      *Request
+	network  bool
+	metadata map[string]interface{}
+}
+
+// matchPolicies returns whether a policy of the rules matches the request. If so, it returns the name of the first
+// one in name order, with the rule it matched. Otherwise, if the match of a policy is unknown, it returns the name
+// of the first such policy.
+func (c *evalContext) matchPolicies(rules *envoy_rbac.RBAC) (match, string, string) {
+	names := make([]string, 0, len(rules.GetPolicies()))
+	for name := range rules.GetPolicies() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result, unknownPolicy := noMatch, ""
+	for _, name := range names {
+		policy := rules.GetPolicies()[name]
+		permissions, principals := policy.GetPermissions(), policy.GetPrincipals()
+		permissionMatch, permission := or(len(permissions), func(i int) match { return c.matchPermission(permissions[i]) })
+		principalMatch, principal := or(len(principals), func(i int) match { return c.matchPrincipal(principals[i]) })
+		switch {
+		case permissionMatch == matched && principalMatch == matched:
+			return matched, name, fmt.Sprintf("permissions[%d] and principals[%d]", permission, principal)
+		case permissionMatch != noMatch && principalMatch != noMatch && result == noMatch:
+			result, unknownPolicy = unknownMatch, name
+		}
+	}
+	return result, unknownPolicy, ""
+}
+
+// httpMatch returns the match of an HTTP condition, which is unknown in network filters.
+func (c *evalContext) httpMatch(f func() match) match {
+	if c.network {
+		return unknownMatch
+	}
+	return f()
+}
+
+func (c *evalContext) matchPermission(p *envoy_rbac.Permission) match {
+	switch r := p.GetRule().(type) {
+	case *envoy_rbac.Permission_Any:
+		return matchOf(r.Any)
+	case *envoy_rbac.Permission_AndRules:
+		rules := r.AndRules.GetRules()
+		return and(len(rules), func(i int) match { return c.matchPermission(rules[i]) })
+	case *envoy_rbac.Permission_OrRules:
+		rules := r.OrRules.GetRules()
+		m, _ := or(len(rules), func(i int) match { return c.matchPermission(rules[i]) })
+		return m
+	case *envoy_rbac.Permission_NotRule:
+		return c.matchPermission(r.NotRule).not()
+	case *envoy_rbac.Permission_DestinationIp:
+		return matchOf(inCidr(r.DestinationIp, c.req.DestinationIP))
+	case *envoy_rbac.Permission_DestinationPort:
+		return matchOf(r.DestinationPort == c.req.DestinationPort)
+	case *envoy_rbac.Permission_RequestedServerName:
+		return matchOf(matchString(r.RequestedServerName, c.req.ServerName))
+	case *envoy_rbac.Permission_Header:
+		return c.httpMatch(func() match { return matchOf(c.matchHeader(r.Header)) })
+	case *envoy_rbac.Permission_UrlPath:
+		return c.httpMatch(func() match { return matchOf(matchString(r.UrlPath.GetPath(), c.req.urlPath())) })
+	case *envoy_rbac.Permission_Metadata:
+		return c.httpMatch(func() match { return c.matchMetadata(r.Metadata) })
+	}
+	return unknownMatch
+}
+
+func (c *evalContext) matchPrincipal(p *envoy_rbac.Principal) match {
+	switch id := p.GetIdentifier().(type) {
+	case *envoy_rbac.Principal_Any:
+		return matchOf(id.Any)
+	case *envoy_rbac.Principal_AndIds:
+		ids := id.AndIds.GetIds()
+		return and(len(ids), func(i int) match { return c.matchPrincipal(ids[i]) })
+	case *envoy_rbac.Principal_OrIds:
+		ids := id.OrIds.GetIds()
+		m, _ := or(len(ids), func(i int) match { return c.matchPrincipal(ids[i]) })
+		return m
+	case *envoy_rbac.Principal_NotId:
+		return c.matchPrincipal(id.NotId).not()
+	case *envoy_rbac.Principal_Authenticated_:
+		principal := c.req.sourcePrincipal()
+		if principal == "" {
+			return noMatch
+		}
+		if id.Authenticated.GetPrincipalName() == nil {
+			return matched
+		}
+		// The principal name is matched against the URI SAN of the peer certificate.
+		return matchOf(matchString(id.Authenticated.GetPrincipalName(), spiffe.URIPrefix+principal))
+	case *envoy_rbac.Principal_SourceIp:
+		return matchOf(inCidr(id.SourceIp, c.req.SourceIP))
+	case *envoy_rbac.Principal_Header:
+		return c.httpMatch(func() match { return matchOf(c.matchHeader(id.Header)) })
+	case *envoy_rbac.Principal_Metadata:
+		return c.httpMatch(func() match { return c.matchMetadata(id.Metadata) })
+	}
+	return unknownMatch
+}
+
+// matchHeader follows Envoy's semantics: a missing header only satisfies an inverted presence match.
+func (c *evalContext) matchHeader(h *route.HeaderMatcher) bool {
+	value, found := c.req.header(h.GetName())
+	if !found {
+		_, present := h.GetHeaderMatchSpecifier().(*route.HeaderMatcher_PresentMatch)
+		return h.GetInvertMatch() && present
+	}
+
+	var matched bool
+	switch s := h.GetHeaderMatchSpecifier().(type) {
+	case *route.HeaderMatcher_ExactMatch:
+		matched = value == s.ExactMatch
+	case *route.HeaderMatcher_PrefixMatch:
+		matched = strings.HasPrefix(value, s.PrefixMatch)
+	case *route.HeaderMatcher_SuffixMatch:
+		matched = strings.HasSuffix(value, s.SuffixMatch)
+	case *route.HeaderMatcher_RegexMatch: // nolint: staticcheck
+		matched, _ = util.FullMatch(s.RegexMatch, value)
+	case *route.HeaderMatcher_SafeRegexMatch:
+		matched, _ = util.FullMatch(s.SafeRegexMatch.GetRegex(), value)
+	case *route.HeaderMatcher_RangeMatch:
+		v, err := strconv.ParseInt(value, 10, 64)
+		matched = err == nil && v >= s.RangeMatch.GetStart() && v < s.RangeMatch.GetEnd()
+	default:
+		matched = true
+	}
+	return matched != h.GetInvertMatch()
+}
+
+// matchMetadata only supports the metadata of the Istio authentication filter.
+func (c *evalContext) matchMetadata(m *envoy_matcher.MetadataMatcher) match {
+	if m.GetFilter() != authn_model.AuthnFilterName {
+		return unknownMatch
+	}
+	var value interface{} = c.metadata
+	for _, segment := range m.GetPath() {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return noMatch
+		}
+		if value, ok = fields[segment.GetKey()]; !ok {
+			return noMatch
+		}
+	}
+	return matchOf(matchValue(m.GetValue(), value))
+}
+
+func matchValue(m *envoy_matcher.ValueMatcher, value interface{}) bool {
+	switch p := m.GetMatchPattern().(type) {
+	case *envoy_matcher.ValueMatcher_PresentMatch:
+		return p.PresentMatch
+	case *envoy_matcher.ValueMatcher_StringMatch:
+		s, ok := value.(string)
+		return ok && matchString(p.StringMatch, s)
+	case *envoy_matcher.ValueMatcher_ListMatch:
+		list, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, v := range list {
+			if matchValue(p.ListMatch.GetOneOf(), v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchString(m *envoy_matcher.StringMatcher, value string) bool {
+	fold := func(s string) string {
+		if m.GetIgnoreCase() {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	switch p := m.GetMatchPattern().(type) {
+	case *envoy_matcher.StringMatcher_Exact:
+		return fold(value) == fold(p.Exact)
+	case *envoy_matcher.StringMatcher_Prefix:
+		return strings.HasPrefix(fold(value), fold(p.Prefix))
+	case *envoy_matcher.StringMatcher_Suffix:
+		return strings.HasSuffix(fold(value), fold(p.Suffix))
+	case *envoy_matcher.StringMatcher_Regex: // nolint: staticcheck
+		matched, _ := util.FullMatch(p.Regex, value)
+		return matched
+	case *envoy_matcher.StringMatcher_SafeRegex:
+		matched, _ := util.FullMatch(p.SafeRegex.GetRegex(), value)
+		return matched
+	}
+	return false
+}
+
+func inCidr(r *core.CidrRange, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	bits := 32
+	if ip.To4() == nil {
+		bits = 128
+	}
+	prefixLen := bits
+	if r.GetPrefixLen() != nil {
+		prefixLen = int(r.GetPrefixLen().GetValue())
+	}
+	_, network, err := net.ParseCIDR(fmt.Sprintf("%s/%d", r.GetAddressPrefix(), prefixLen))
+	return err == nil && network.Contains(ip)
+}
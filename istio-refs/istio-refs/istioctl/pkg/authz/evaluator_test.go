@@ -0,0 +1,208 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"testing"
+
+	envoy_rbac "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
+
+	"istio.io/istio/pilot/pkg/security/authz/model/matcher"
+)
+
+func anyPermission() *envoy_rbac.Permission {
+	return &envoy_rbac.Permission{Rule: &envoy_rbac.Permission_Any{Any: true}}
+}
+
+func anyPrincipal() *envoy_rbac.Principal {
+	return &envoy_rbac.Principal{Identifier: &envoy_rbac.Principal_Any{Any: true}}
+}
+
+func pathPermission(path string) *envoy_rbac.Permission {
+	return &envoy_rbac.Permission{Rule: &envoy_rbac.Permission_UrlPath{UrlPath: matcher.PathMatcher(path)}}
+}
+
+func methodPermission(method string) *envoy_rbac.Permission {
+	return &envoy_rbac.Permission{Rule: &envoy_rbac.Permission_Header{Header: matcher.HeaderMatcher(":method", method)}}
+}
+
+func namespacePrincipal(ns string) *envoy_rbac.Principal {
+	return &envoy_rbac.Principal{Identifier: &envoy_rbac.Principal_Metadata{
+		Metadata: matcher.MetadataStringMatcher("istio_authn", "source.principal", matcher.StringMatcherRegex(".*/ns/"+ns+"/.*")),
+	}}
+}
+
+func claimPrincipal(claim, value string) *envoy_rbac.Principal {
+	return &envoy_rbac.Principal{Identifier: &envoy_rbac.Principal_Metadata{
+		Metadata: matcher.MetadataListMatcher("istio_authn", []string{"request.auth.claims", claim}, value, true),
+	}}
+}
+
+func TestEvaluateRBAC(t *testing.T) {
+	deny := &envoy_rbac.RBAC{
+		Action: envoy_rbac.RBAC_DENY,
+		Policies: map[string]*envoy_rbac.Policy{
+			"ns[foo]-policy[deny-admin]-rule[0]": {
+				Permissions: []*envoy_rbac.Permission{pathPermission("/admin*")},
+				Principals:  []*envoy_rbac.Principal{anyPrincipal()},
+			},
+		},
+	}
+	allow := &envoy_rbac.RBAC{
+		Action: envoy_rbac.RBAC_ALLOW,
+		Policies: map[string]*envoy_rbac.Policy{
+			"ns[foo]-policy[viewer]-rule[0]": {
+				Permissions: []*envoy_rbac.Permission{methodPermission("GET")},
+				Principals:  []*envoy_rbac.Principal{namespacePrincipal("bar"), namespacePrincipal("default")},
+			},
+			"ns[foo]-policy[admin]-rule[0]": {
+				Permissions: []*envoy_rbac.Permission{anyPermission()},
+				Principals:  []*envoy_rbac.Principal{claimPrincipal("groups", "admin")},
+			},
+		},
+	}
+
+	unsupported := &envoy_rbac.RBAC{
+		Action: envoy_rbac.RBAC_DENY,
+		Policies: map[string]*envoy_rbac.Policy{
+			"ns[foo]-policy[custom]-rule[0]": {
+				Permissions: []*envoy_rbac.Permission{{Rule: &envoy_rbac.Permission_NotRule{NotRule: &envoy_rbac.Permission{
+					Rule: &envoy_rbac.Permission_Metadata{
+						Metadata: matcher.MetadataStringMatcher("custom_filter", "key", matcher.StringMatcher("value", false)),
+					},
+				}}}},
+				Principals: []*envoy_rbac.Principal{{Identifier: &envoy_rbac.Principal_NotId{NotId: anyPrincipal()}}, anyPrincipal()},
+			},
+		},
+	}
+
+	cases := []struct {
+		name         string
+		filters      []*envoy_rbac.RBAC
+		req          *Request
+		network      bool
+		allowed      bool
+		undetermined bool
+		policy       string
+		rule         string
+	}{
+		{
+			name:    "no RBAC filter",
+			req:     &Request{},
+			allowed: true,
+		},
+		{
+			name:    "allowed by namespace",
+			filters: []*envoy_rbac.RBAC{deny, allow},
+			req:     &Request{SourceNamespace: "default", Path: "/productpage"},
+			allowed: true,
+			policy:  "ns[foo]-policy[viewer]-rule[0]",
+			rule:    "permissions[0] and principals[1]",
+		},
+		{
+			name:    "denied by DENY policy",
+			filters: []*envoy_rbac.RBAC{deny, allow},
+			req:     &Request{SourceNamespace: "default", Path: "/admin/users?all"},
+			policy:  "ns[foo]-policy[deny-admin]-rule[0]",
+			rule:    "permissions[0] and principals[0]",
+		},
+		{
+			name:    "denied without matching ALLOW policy",
+			filters: []*envoy_rbac.RBAC{deny, allow},
+			req:     &Request{SourceNamespace: "default", Method: "POST"},
+		},
+		{
+			name:    "denied plaintext",
+			filters: []*envoy_rbac.RBAC{allow},
+			req:     &Request{},
+		},
+		{
+			name:    "allowed by claim",
+			filters: []*envoy_rbac.RBAC{deny, allow},
+			req:     &Request{Method: "DELETE", Claims: map[string][]string{"groups": {"dev", "admin"}}},
+			allowed: true,
+			policy:  "ns[foo]-policy[admin]-rule[0]",
+			rule:    "permissions[0] and principals[0]",
+		},
+		{
+			name:         "network filter can't evaluate HTTP attributes",
+			filters:      []*envoy_rbac.RBAC{allow},
+			req:          &Request{SourceNamespace: "default"},
+			network:      true,
+			undetermined: true,
+			policy:       "ns[foo]-policy[admin]-rule[0]",
+		},
+		{
+			name:         "unsupported condition",
+			filters:      []*envoy_rbac.RBAC{unsupported, allow},
+			req:          &Request{SourceNamespace: "default"},
+			undetermined: true,
+			policy:       "ns[foo]-policy[custom]-rule[0]",
+		},
+		{
+			name:    "denied after an unsupported condition",
+			filters: []*envoy_rbac.RBAC{unsupported, deny, allow},
+			req:     &Request{SourceNamespace: "default", Path: "/admin"},
+			policy:  "ns[foo]-policy[deny-admin]-rule[0]",
+			rule:    "permissions[0] and principals[0]",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := EvaluateRBAC(c.filters, c.req, c.network)
+			if got.Allowed != c.allowed || got.Undetermined != c.undetermined || got.Policy != c.policy || got.Rule != c.rule {
+				t.Fatalf("EvaluateRBAC() = %+v, want allowed %v (undetermined %v) by %q %q",
+					got, c.allowed, c.undetermined, c.policy, c.rule)
+			}
+		})
+	}
+}
+
+func TestEvaluateRBACPrincipals(t *testing.T) {
+	cidr, err := matcher.CidrRange("10.0.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := &envoy_rbac.RBAC{
+		Policies: map[string]*envoy_rbac.Policy{
+			"authenticated-from-cidr": {
+				Permissions: []*envoy_rbac.Permission{anyPermission()},
+				Principals: []*envoy_rbac.Principal{{
+					Identifier: &envoy_rbac.Principal_AndIds{AndIds: &envoy_rbac.Principal_Set{Ids: []*envoy_rbac.Principal{
+						{Identifier: &envoy_rbac.Principal_SourceIp{SourceIp: cidr}},
+						{Identifier: &envoy_rbac.Principal_Authenticated_{Authenticated: &envoy_rbac.Principal_Authenticated{
+							PrincipalName: matcher.StringMatcherWithPrefix("cluster.local/ns/default/sa/sleep", "spiffe://", true),
+						}}},
+					}}},
+				}},
+			},
+		},
+	}
+
+	cases := []struct {
+		req     *Request
+		allowed bool
+	}{
+		{&Request{SourceIP: "10.0.3.4", SourcePrincipal: "cluster.local/ns/default/sa/sleep"}, true},
+		{&Request{SourceIP: "10.1.3.4", SourcePrincipal: "cluster.local/ns/default/sa/sleep"}, false},
+		{&Request{SourceIP: "10.0.3.4", SourcePrincipal: "cluster.local/ns/default/sa/other"}, false},
+		{&Request{SourceIP: "10.0.3.4"}, false},
+	}
+	for _, c := range cases {
+		if got := EvaluateRBAC([]*envoy_rbac.RBAC{rules}, c.req, true); got.Allowed != c.allowed {
+			t.Errorf("EvaluateRBAC(%+v) = %+v, want allowed %v", c.req, got, c.allowed)
+		}
+	}
+}
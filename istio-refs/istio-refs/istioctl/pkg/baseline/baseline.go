@@ -0,0 +1,200 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package baseline implements baseline files for istioctl analyze: checked-in lists of accepted findings,
+// each with an owner, a reason and optionally an expiry date.
+//
+//   suppressions:
+//   - code: IST0102
+//     resource: Namespace legacy-*
+//     owner: platform-team
+//     reason: Legacy namespaces are migrated to sidecar injection in Q3
+//     expires: 2020-09-30
+//
+// Findings matching a suppression are reported as suppressed rather than failing the analysis, until the
+// suppression expires.
+package baseline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/go-multierror"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/processing/snapshotter"
+)
+
+// DateFormat is the format of expiry dates.
+const DateFormat = "2006-01-02"
+
+// File is the content of a baseline file.
+type File struct {
+	Suppressions []Suppression `json:"suppressions"`
+}
+
+// Suppression accepts the findings of a message code on some resources.
+type Suppression struct {
+	// Code of the suppressed messages, e.g. IST0102.
+	Code string `json:"code"`
+
+	// Resource the messages are about, in the form used by istioctl (e.g. "DestinationRule default.istio-system").
+	// The wildcard character '*' matches any sequence of characters.
+	Resource string `json:"resource"`
+
+	// Owner of the suppression, responsible for fixing the findings.
+	Owner string `json:"owner"`
+
+	// Reason for accepting the findings.
+	Reason string `json:"reason"`
+
+	// Expires is the date, in the YYYY-MM-DD format, from which the suppression no longer applies.
+	// The suppression never expires if empty.
+	Expires string `json:"expires,omitempty"`
+
+	expires time.Time
+}
+
+// SuppressedMessage is a message matched by a suppression.
+type SuppressedMessage struct {
+	Message     diag.Message
+	Suppression *Suppression
+}
+
+// Result is the outcome of applying a baseline file to the messages of an analysis.
+type Result struct {
+	// Messages not matched by any suppression, or matched by expired suppressions only.
+	Messages diag.Messages
+
+	// Suppressed messages.
+	Suppressed []SuppressedMessage
+
+	// Expired lists the messages matched by expired suppressions. They are also part of Messages.
+	Expired []SuppressedMessage
+
+	// Unused are the suppressions which didn't match any message, and can likely be removed.
+	Unused []*Suppression
+}
+
+// Load reads and validates a baseline file.
+func Load(path string) (*File, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return f, nil
+}
+
+// Parse parses and validates the content of a baseline file.
+func Parse(content []byte) (*File, error) {
+	f := &File{}
+	if err := yaml.Unmarshal(content, f); err != nil {
+		return nil, err
+	}
+
+	var errs error
+	for i := range f.Suppressions {
+		if err := f.Suppressions[i].validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("suppression %d: %v", i, err))
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return f, nil
+}
+
+func (s *Suppression) validate() error {
+	if s.Code == "" {
+		return fmt.Errorf("code is required")
+	}
+	if s.Resource == "" {
+		return fmt.Errorf("resource is required")
+	}
+	if s.Owner == "" {
+		return fmt.Errorf("owner is required")
+	}
+	if s.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	if s.Expires != "" {
+		t, err := time.Parse(DateFormat, s.Expires)
+		if err != nil {
+			return fmt.Errorf("invalid expiry date %q, must be in the YYYY-MM-DD format", s.Expires)
+		}
+		s.expires = t
+	}
+	return nil
+}
+
+// Matches returns true if the suppression applies to a message, regardless of its expiry. Messages are
+// matched the same way as by the --suppress flag of istioctl analyze.
+func (s *Suppression) Matches(m diag.Message) bool {
+	if m.Resource != nil && m.Resource.Origin == nil {
+		return false
+	}
+	return snapshotter.AnalysisSuppression{Code: s.Code, ResourceName: s.Resource}.Matches(m)
+}
+
+// IsExpired returns true if the suppression no longer applies at the given time.
+func (s *Suppression) IsExpired(now time.Time) bool {
+	return !s.expires.IsZero() && !now.Before(s.expires)
+}
+
+// Apply splits messages into suppressed and remaining ones, at the given time.
+func (f *File) Apply(ms diag.Messages, now time.Time) Result {
+	var result Result
+	used := make(map[*Suppression]bool)
+
+	for _, m := range ms {
+		var active, expired *Suppression
+		for i := range f.Suppressions {
+			s := &f.Suppressions[i]
+			if !s.Matches(m) {
+				continue
+			}
+			used[s] = true
+			if s.IsExpired(now) {
+				if expired == nil {
+					expired = s
+				}
+			} else if active == nil {
+				active = s
+			}
+		}
+
+		switch {
+		case active != nil:
+			result.Suppressed = append(result.Suppressed, SuppressedMessage{Message: m, Suppression: active})
+		case expired != nil:
+			result.Expired = append(result.Expired, SuppressedMessage{Message: m, Suppression: expired})
+			result.Messages = append(result.Messages, m)
+		default:
+			result.Messages = append(result.Messages, m)
+		}
+	}
+
+	for i := range f.Suppressions {
+		if s := &f.Suppressions[i]; !used[s] {
+			result.Unused = append(result.Unused, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baseline
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+)
+
+var _ resource.Origin = fakeOrigin("")
+
+type fakeOrigin string
+
+func (o fakeOrigin) FriendlyName() string          { return string(o) }
+func (o fakeOrigin) Namespace() resource.Namespace { return "" }
+func (o fakeOrigin) Reference() resource.Reference { return nil }
+
+func notInjected(namespace string) diag.Message {
+	r := &resource.Instance{Origin: fakeOrigin("Namespace " + namespace)}
+	return msg.NewNamespaceNotInjected(r, namespace, namespace)
+}
+
+const testBaseline = `
+suppressions:
+- code: IST0102
+  resource: Namespace legacy-*
+  owner: platform-team
+  reason: migrating to injection
+  expires: 2020-09-30
+- code: IST0102
+  resource: Namespace tools
+  owner: tools-team
+  reason: tools run without sidecars
+- code: IST0101
+  resource: VirtualService *
+  owner: nobody
+  reason: never matches
+`
+
+func TestParse_Invalid(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "missing owner",
+			content: "suppressions:\n- code: IST0102\n  resource: Namespace a\n  reason: r\n",
+			err:     "owner is required",
+		},
+		{
+			name:    "missing reason",
+			content: "suppressions:\n- code: IST0102\n  resource: Namespace a\n  owner: o\n",
+			err:     "reason is required",
+		},
+		{
+			name:    "bad date",
+			content: "suppressions:\n- code: IST0102\n  resource: Namespace a\n  owner: o\n  reason: r\n  expires: 30/09/2020\n",
+			err:     "invalid expiry date",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Parse([]byte(c.content))
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected error containing %q, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	f, err := Parse([]byte(testBaseline))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := diag.Messages{notInjected("legacy-a"), notInjected("tools"), notInjected("default")}
+
+	before := f.Apply(ms, time.Date(2020, 9, 29, 23, 0, 0, 0, time.UTC))
+	if len(before.Suppressed) != 2 || len(before.Messages) != 1 || len(before.Expired) != 0 {
+		t.Fatalf("unexpected result before expiry: %+v", before)
+	}
+	if got := before.Messages[0].Resource.Origin.FriendlyName(); got != "Namespace default" {
+		t.Errorf("unexpected remaining message for %q", got)
+	}
+	if len(before.Unused) != 1 || before.Unused[0].Code != "IST0101" {
+		t.Errorf("unexpected unused suppressions: %+v", before.Unused)
+	}
+
+	after := f.Apply(ms, time.Date(2020, 9, 30, 0, 0, 0, 0, time.UTC))
+	if len(after.Suppressed) != 1 || len(after.Messages) != 2 || len(after.Expired) != 1 {
+		t.Fatalf("unexpected result after expiry: %+v", after)
+	}
+	if got := after.Expired[0].Suppression.Owner; got != "platform-team" {
+		t.Errorf("unexpected expired suppression owner %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"path"
+	"strings"
+)
+
+// WriteArchive writes the bundle as a gzipped tarball, with all files redacted and placed in a top level
+// directory named after the report. The errors encountered during the collection are listed in errors.txt.
+func (b *Bundle) WriteArchive(w io.Writer, name string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, file := range b.Files() {
+		b.mu.Lock()
+		data := b.files[file]
+		b.mu.Unlock()
+		if err := b.writeFile(tw, path.Join(name, file), Redact(data)); err != nil {
+			return err
+		}
+	}
+	if errs := b.Errors(); len(errs) > 0 {
+		if err := b.writeFile(tw, path.Join(name, "errors.txt"), []byte(strings.Join(errs, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (b *Bundle) writeFile(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: b.Time,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteArchive(t *testing.T) {
+	b := newBundle(time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC))
+	b.add("proxies/default/productpage/config_dump.json", []byte(`{"inline_bytes": "c2VjcmV0"}`))
+	b.add("analyze.txt", []byte("No validation issues found.\n"))
+	b.addError("envoy /stats of productpage.default", errors.New("connection refused"))
+
+	var buf bytes.Buffer
+	if err := b.WriteArchive(&buf, "bug-report"); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+		files[hdr.Name] = string(data)
+	}
+
+	expectedNames := []string{
+		"bug-report/analyze.txt",
+		"bug-report/proxies/default/productpage/config_dump.json",
+		"bug-report/errors.txt",
+	}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("unexpected archive content: %v", names)
+	}
+	if got := files["bug-report/proxies/default/productpage/config_dump.json"]; got != `{"inline_bytes": "<redacted>"}` {
+		t.Errorf("config dump not redacted: %s", got)
+	}
+	if got := files["bug-report/errors.txt"]; got != "envoy /stats of productpage.default: connection refused\n" {
+		t.Errorf("unexpected errors.txt: %s", got)
+	}
+}
@@ -0,0 +1,462 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bugreport collects the diagnostic data needed to investigate Istio issues into a single archive.
+package bugreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/pkg/version"
+)
+
+// Client is the subset of the istioctl Kubernetes client used to collect a bug report.
+type Client interface {
+	EnvoyDo(podName, podNamespace, method, path string, body []byte) ([]byte, error)
+	AllPilotsDiscoveryDo(pilotNamespace, method, path string, body []byte) (map[string][]byte, error)
+	GetIstioVersions(namespace string) (*version.MeshInfo, error)
+	GetIstioPods(namespace string, params map[string]string) ([]v1.Pod, error)
+	PodLogs(podName, podNamespace, container string, opts *v1.PodLogOptions) ([]byte, error)
+}
+
+var (
+	// pilotDebugEndpoints are the Pilot debug endpoints dumped for each Pilot instance.
+	pilotDebugEndpoints = []string{"adsz", "configz", "endpointz", "mesh", "push_status", "registryz", "syncz"}
+
+	// envoyEndpoints are the Envoy admin endpoints dumped for each proxy, with the name of the resulting file.
+	envoyEndpoints = map[string]string{
+		"/config_dump": "config_dump.json",
+		"/clusters":    "clusters.txt",
+		"/stats":       "stats.txt",
+	}
+
+	// proxyContainers are the names of the containers running pilot-agent and Envoy.
+	proxyContainers = map[string]bool{
+		"istio-proxy":    true,
+		"ingressgateway": true,
+		"egressgateway":  true,
+		"ingress":        true,
+	}
+)
+
+// Options select the data collected in a bug report.
+type Options struct {
+	// IstioNamespace is the namespace of the control plane.
+	IstioNamespace string
+
+	// Namespaces of the proxies to collect data from. All namespaces if empty.
+	Namespaces []string
+
+	// LabelSelector restricts the proxies to collect data from.
+	LabelSelector string
+
+	// Since only collects the logs newer than the duration. Can't be used with Start.
+	Since time.Duration
+
+	// Start and End restrict the logs to a time window. Either can be left unset.
+	Start time.Time
+	End   time.Time
+
+	// Timeout for each individual item collected.
+	Timeout time.Duration
+
+	// Parallelism is the maximum number of items collected concurrently.
+	Parallelism int
+
+	// Analyze runs the configuration analysis. The analysis is skipped if nil.
+	Analyze func() (diag.Messages, error)
+}
+
+// Validate checks the consistency of the options.
+func (o *Options) Validate() error {
+	if o.Since < 0 {
+		return fmt.Errorf("--since must not be negative")
+	}
+	if o.Since > 0 && !o.Start.IsZero() {
+		return fmt.Errorf("--since and --start can't be used together")
+	}
+	if !o.Start.IsZero() && !o.End.IsZero() && !o.End.After(o.Start) {
+		return fmt.Errorf("--end must be after --start")
+	}
+	if o.Parallelism < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+	if o.Timeout <= 0 {
+		return fmt.Errorf("--timeout must be positive")
+	}
+	return nil
+}
+
+// Bundle holds the files collected for a bug report, along with the errors encountered.
+type Bundle struct {
+	// Time is when the collection started.
+	Time time.Time
+
+	mu     sync.Mutex
+	files  map[string][]byte
+	errors []string
+	closed bool
+}
+
+func newBundle(now time.Time) *Bundle {
+	return &Bundle{Time: now, files: map[string][]byte{}}
+}
+
+func (b *Bundle) add(name string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.files[name] = data
+	}
+}
+
+func (b *Bundle) addError(item string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.errors = append(b.errors, fmt.Sprintf("%s: %v", item, err))
+	}
+}
+
+// close prevents items which timed out from adding data to the bundle after the collection.
+func (b *Bundle) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
+
+// Files returns the names of the collected files, sorted.
+func (b *Bundle) Files() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names := make([]string, 0, len(b.files))
+	for name := range b.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Errors returns the errors encountered while collecting, sorted.
+func (b *Bundle) Errors() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	errs := append([]string(nil), b.errors...)
+	sort.Strings(errs)
+	return errs
+}
+
+// item is a piece of data to collect. It adds its files to the bundle itself, as some items produce several files.
+type item struct {
+	name    string
+	collect func(b *Bundle) error
+}
+
+// Collector collects bug reports.
+type Collector struct {
+	client Client
+	kube   kubernetes.Interface
+	opts   Options
+}
+
+// NewCollector creates a Collector using the given clients.
+func NewCollector(client Client, kube kubernetes.Interface, opts Options) *Collector {
+	return &Collector{client: client, kube: kube, opts: opts}
+}
+
+// Collect gathers the data selected by the options. Failures to collect individual items don't fail the
+// collection, they are recorded in the bundle instead.
+func (c *Collector) Collect(now time.Time) (*Bundle, error) {
+	if err := c.opts.Validate(); err != nil {
+		return nil, err
+	}
+	b := newBundle(now)
+
+	items := c.clusterItems()
+	items = append(items, c.controlPlaneItems(b)...)
+	items = append(items, c.proxyItems(b)...)
+	if c.opts.Analyze != nil {
+		items = append(items, item{name: "analysis", collect: c.analyze})
+	}
+
+	c.run(b, items)
+	b.close()
+	return b, nil
+}
+
+// run collects the items in parallel, giving up on the items exceeding the timeout.
+func (c *Collector) run(b *Bundle, items []item) {
+	sem := make(chan struct{}, c.opts.Parallelism)
+	var wg sync.WaitGroup
+	for _, it := range items {
+		it := it
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- it.collect(b)
+			}()
+			select {
+			case err := <-done:
+				if err != nil {
+					b.addError(it.name, err)
+				}
+			case <-time.After(c.opts.Timeout):
+				b.addError(it.name, fmt.Errorf("timed out after %v", c.opts.Timeout))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (c *Collector) clusterItems() []item {
+	return []item{
+		{name: "kubernetes version", collect: func(b *Bundle) error {
+			v, err := c.kube.Discovery().ServerVersion()
+			if err != nil {
+				return err
+			}
+			return addJSON(b, "cluster/kubernetes-version.json", v)
+		}},
+		{name: "istio versions", collect: func(b *Bundle) error {
+			v, err := c.client.GetIstioVersions(c.opts.IstioNamespace)
+			if v != nil {
+				if jerr := addJSON(b, "cluster/istio-versions.json", v); jerr != nil {
+					return jerr
+				}
+			}
+			return err
+		}},
+		{name: "nodes", collect: func(b *Bundle) error {
+			nodes, err := c.kube.CoreV1().Nodes().List(metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			var sb strings.Builder
+			for _, n := range nodes.Items {
+				fmt.Fprintf(&sb, "%s\tkubelet=%s\tos=%s\tregion=%s\tzone=%s\n", n.Name, n.Status.NodeInfo.KubeletVersion,
+					n.Status.NodeInfo.OSImage, n.Labels["failure-domain.beta.kubernetes.io/region"],
+					n.Labels["failure-domain.beta.kubernetes.io/zone"])
+			}
+			b.add("cluster/nodes.txt", []byte(sb.String()))
+			return nil
+		}},
+		{name: "namespaces", collect: func(b *Bundle) error {
+			namespaces, err := c.kube.CoreV1().Namespaces().List(metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			var sb strings.Builder
+			for _, ns := range namespaces.Items {
+				fmt.Fprintf(&sb, "%s\tistio-injection=%s\n", ns.Name, ns.Labels["istio-injection"])
+			}
+			b.add("cluster/namespaces.txt", []byte(sb.String()))
+			return nil
+		}},
+	}
+}
+
+func (c *Collector) controlPlaneItems(b *Bundle) []item {
+	var items []item
+	pods, err := c.client.GetIstioPods(c.opts.IstioNamespace, map[string]string{
+		"labelSelector": "istio",
+		"fieldSelector": "status.phase=Running",
+	})
+	if err != nil {
+		b.addError("control plane pods", err)
+	}
+	for i := range pods {
+		pod := &pods[i]
+		for _, container := range pod.Spec.Containers {
+			items = append(items, c.logsItem(pod, container.Name, path.Join("control-plane", pod.Name, container.Name+".log")))
+		}
+	}
+
+	for _, endpoint := range pilotDebugEndpoints {
+		endpoint := endpoint
+		items = append(items, item{name: "pilot /debug/" + endpoint, collect: func(b *Bundle) error {
+			res, err := c.client.AllPilotsDiscoveryDo(c.opts.IstioNamespace, "GET", "/debug/"+endpoint, nil)
+			for pilot, data := range res {
+				b.add(path.Join("control-plane", pilot, "debug", endpoint+".json"), data)
+			}
+			return err
+		}})
+	}
+	return items
+}
+
+func (c *Collector) proxyItems(b *Bundle) []item {
+	namespaces := c.opts.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{v1.NamespaceAll}
+	}
+
+	var items []item
+	for _, ns := range namespaces {
+		params := map[string]string{"fieldSelector": "status.phase=Running"}
+		if c.opts.LabelSelector != "" {
+			params["labelSelector"] = c.opts.LabelSelector
+		}
+		pods, err := c.client.GetIstioPods(ns, params)
+		if err != nil {
+			b.addError(fmt.Sprintf("pods in namespace %q", ns), err)
+			continue
+		}
+		for i := range pods {
+			pod := &pods[i]
+			container := proxyContainer(pod)
+			if container == "" {
+				continue
+			}
+			dir := path.Join("proxies", pod.Namespace, pod.Name)
+			items = append(items, podItem(pod, path.Join(dir, "pod.yaml")))
+			items = append(items, c.logsItem(pod, container, path.Join(dir, "proxy.log")))
+			for endpoint, file := range envoyEndpoints {
+				items = append(items, c.envoyItem(pod, endpoint, path.Join(dir, file)))
+			}
+		}
+	}
+	return items
+}
+
+func proxyContainer(pod *v1.Pod) string {
+	for _, c := range pod.Spec.Containers {
+		if proxyContainers[c.Name] {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+func podItem(pod *v1.Pod, file string) item {
+	return item{name: fmt.Sprintf("pod %s.%s", pod.Name, pod.Namespace), collect: func(b *Bundle) error {
+		data, err := yaml.Marshal(pod)
+		if err != nil {
+			return err
+		}
+		b.add(file, data)
+		return nil
+	}}
+}
+
+func (c *Collector) envoyItem(pod *v1.Pod, endpoint, file string) item {
+	return item{name: fmt.Sprintf("envoy %s of %s.%s", endpoint, pod.Name, pod.Namespace), collect: func(b *Bundle) error {
+		data, err := c.client.EnvoyDo(pod.Name, pod.Namespace, "GET", endpoint, nil)
+		if err != nil {
+			return err
+		}
+		b.add(file, data)
+		return nil
+	}}
+}
+
+func (c *Collector) logsItem(pod *v1.Pod, container, file string) item {
+	name := fmt.Sprintf("logs of %s.%s %s container", pod.Name, pod.Namespace, container)
+	return item{name: name, collect: func(b *Bundle) error {
+		data, err := c.client.PodLogs(pod.Name, pod.Namespace, container, c.logOptions())
+		if err != nil {
+			return err
+		}
+		if !c.opts.End.IsZero() {
+			data = truncateLogs(data, c.opts.End)
+		}
+		b.add(file, data)
+		return nil
+	}}
+}
+
+func (c *Collector) logOptions() *v1.PodLogOptions {
+	opts := &v1.PodLogOptions{
+		// Timestamps are needed to drop the lines after the end of the time window.
+		Timestamps: !c.opts.End.IsZero(),
+	}
+	if c.opts.Since > 0 {
+		seconds := int64(c.opts.Since.Seconds())
+		opts.SinceSeconds = &seconds
+	}
+	if !c.opts.Start.IsZero() {
+		opts.SinceTime = &metav1.Time{Time: c.opts.Start}
+	}
+	return opts
+}
+
+// truncateLogs drops the log lines timestamped after end. Lines without a timestamp are continuations of the
+// previous line, and are kept or dropped with it.
+func truncateLogs(logs []byte, end time.Time) []byte {
+	var out bytes.Buffer
+	keep := true
+	// Lines are split by hand rather than with a bufio.Scanner, which fails on lines longer than its buffer.
+	for len(logs) > 0 {
+		line := logs
+		if i := bytes.IndexByte(logs, '\n'); i >= 0 {
+			line = logs[:i+1]
+		}
+		logs = logs[len(line):]
+		if i := bytes.IndexByte(line, ' '); i > 0 {
+			if t, err := time.Parse(time.RFC3339Nano, string(line[:i])); err == nil {
+				keep = !t.After(end)
+			}
+		}
+		if keep {
+			out.Write(line)
+		}
+	}
+	return out.Bytes()
+}
+
+func (c *Collector) analyze(b *Bundle) error {
+	messages, err := c.opts.Analyze()
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	if len(messages) == 0 {
+		sb.WriteString("No validation issues found.\n")
+	}
+	for _, m := range messages.SortedDedupedCopy() {
+		sb.WriteString(m.String())
+		sb.WriteByte('\n')
+	}
+	b.add("analyze.txt", []byte(sb.String()))
+	return nil
+}
+
+func addJSON(b *Bundle, file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b.add(file, data)
+	return nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/pkg/version"
+)
+
+type fakeClient struct {
+	pods    map[string][]v1.Pod
+	blocked chan struct{}
+}
+
+func (f *fakeClient) EnvoyDo(podName, podNamespace, method, path string, body []byte) ([]byte, error) {
+	if path == "/clusters" {
+		return nil, errors.New("connection refused")
+	}
+	return []byte(path + " of " + podName), nil
+}
+
+func (f *fakeClient) AllPilotsDiscoveryDo(pilotNamespace, method, path string, body []byte) (map[string][]byte, error) {
+	return map[string][]byte{"istio-pilot-1": []byte(path)}, nil
+}
+
+func (f *fakeClient) GetIstioVersions(namespace string) (*version.MeshInfo, error) {
+	return &version.MeshInfo{{Component: "pilot"}}, nil
+}
+
+func (f *fakeClient) GetIstioPods(namespace string, params map[string]string) ([]v1.Pod, error) {
+	return f.pods[namespace], nil
+}
+
+func (f *fakeClient) PodLogs(podName, podNamespace, container string, opts *v1.PodLogOptions) ([]byte, error) {
+	if podName == "stuck" {
+		<-f.blocked
+	}
+	return []byte("logs of " + container), nil
+}
+
+func pod(name, namespace string, containers ...string) v1.Pod {
+	p := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	for _, c := range containers {
+		p.Spec.Containers = append(p.Spec.Containers, v1.Container{Name: c})
+	}
+	return p
+}
+
+func TestOptionsValidate(t *testing.T) {
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		opts Options
+		err  string
+	}{
+		{"valid", Options{Since: time.Hour, Timeout: time.Second, Parallelism: 1}, ""},
+		{"since and start", Options{Since: time.Hour, Start: start, Timeout: time.Second, Parallelism: 1}, "can't be used together"},
+		{"end before start", Options{Start: start, End: start.Add(-time.Hour), Timeout: time.Second, Parallelism: 1}, "--end must be after --start"},
+		{"no parallelism", Options{Timeout: time.Second}, "--parallel"},
+		{"no timeout", Options{Parallelism: 1}, "--timeout"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.opts.Validate()
+			if c.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected error containing %q, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	client := &fakeClient{
+		pods: map[string][]v1.Pod{
+			"istio-system": {pod("istio-pilot-1", "istio-system", "discovery", "istio-proxy")},
+			"default":      {pod("productpage", "default", "productpage", "istio-proxy"), pod("job", "default", "job")},
+			"stuck":        {pod("stuck", "stuck", "istio-proxy")},
+		},
+		blocked: make(chan struct{}),
+	}
+	defer close(client.blocked)
+
+	opts := Options{
+		IstioNamespace: "istio-system",
+		Namespaces:     []string{"default", "stuck"},
+		Timeout:        100 * time.Millisecond,
+		Parallelism:    4,
+		Analyze: func() (diag.Messages, error) {
+			return nil, nil
+		},
+	}
+	b, err := NewCollector(client, fake.NewSimpleClientset(), opts).Collect(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"analyze.txt",
+		"cluster/istio-versions.json",
+		"cluster/kubernetes-version.json",
+		"cluster/namespaces.txt",
+		"cluster/nodes.txt",
+		"control-plane/istio-pilot-1/debug/adsz.json",
+		"control-plane/istio-pilot-1/debug/configz.json",
+		"control-plane/istio-pilot-1/debug/endpointz.json",
+		"control-plane/istio-pilot-1/debug/mesh.json",
+		"control-plane/istio-pilot-1/debug/push_status.json",
+		"control-plane/istio-pilot-1/debug/registryz.json",
+		"control-plane/istio-pilot-1/debug/syncz.json",
+		"control-plane/istio-pilot-1/discovery.log",
+		"control-plane/istio-pilot-1/istio-proxy.log",
+		"proxies/default/productpage/config_dump.json",
+		"proxies/default/productpage/pod.yaml",
+		"proxies/default/productpage/proxy.log",
+		"proxies/default/productpage/stats.txt",
+		"proxies/stuck/stuck/config_dump.json",
+		"proxies/stuck/stuck/pod.yaml",
+		"proxies/stuck/stuck/stats.txt",
+	}
+	if got := b.Files(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected files:\ngot:  %v\nwant: %v", got, expected)
+	}
+
+	expectedErrors := []string{
+		"envoy /clusters of productpage.default: connection refused",
+		"envoy /clusters of stuck.stuck: connection refused",
+		"logs of stuck.stuck istio-proxy container: timed out after 100ms",
+	}
+	if got := b.Errors(); !reflect.DeepEqual(got, expectedErrors) {
+		t.Errorf("unexpected errors:\ngot:  %v\nwant: %v", got, expectedErrors)
+	}
+}
+
+func TestTruncateLogs(t *testing.T) {
+	logs := `2020-03-01T10:00:00.000000000Z first
+2020-03-01T10:30:00.000000000Z second
+continuation of second
+2020-03-01T11:00:00.000000001Z third
+continuation of third
+`
+	end := time.Date(2020, 3, 1, 11, 0, 0, 0, time.UTC)
+	expected := `2020-03-01T10:00:00.000000000Z first
+2020-03-01T10:30:00.000000000Z second
+continuation of second
+`
+	if got := string(truncateLogs([]byte(logs), end)); got != expected {
+		t.Errorf("unexpected logs:\n%s", got)
+	}
+
+	// Long lines are kept, as well as the lines after them.
+	long := "2020-03-01T10:00:00.000000000Z " + strings.Repeat("x", 2*1024*1024) + "\n"
+	logs = long + "2020-03-01T10:30:00.000000000Z second\n2020-03-01T11:00:00.000000001Z third"
+	expected = long + "2020-03-01T10:30:00.000000000Z second\n"
+	if got := string(truncateLogs([]byte(logs), end)); got != expected {
+		t.Errorf("unexpected logs with a long line: got %d bytes, want %d", len(got), len(expected))
+	}
+}
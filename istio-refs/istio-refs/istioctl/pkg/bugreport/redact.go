@@ -0,0 +1,45 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"regexp"
+)
+
+const redacted = "<redacted>"
+
+var (
+	// pemBlockRegex matches PEM encoded certificates and keys, including those embedded in JSON strings.
+	pemBlockRegex = regexp.MustCompile(`(?s)-----BEGIN ([A-Z0-9 ]+)-----.*?-----END ([A-Z0-9 ]+)-----`)
+
+	// secretFieldRegex matches the JSON fields holding inline secret data in Envoy and Pilot dumps.
+	secretFieldRegex = regexp.MustCompile(
+		`("(?:inline_bytes|inline_string|private_key|password|client_secret|token)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+	// bearerRegex matches bearer tokens, e.g. in logged headers.
+	bearerRegex = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+
+	// jwtRegex matches JSON web tokens.
+	jwtRegex = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+)
+
+// Redact scrubs secrets and certificates from collected data.
+func Redact(data []byte) []byte {
+	data = pemBlockRegex.ReplaceAll(data, []byte("-----BEGIN $1-----"+redacted+"-----END $2-----"))
+	data = secretFieldRegex.ReplaceAll(data, []byte(`$1"`+redacted+`"`))
+	data = bearerRegex.ReplaceAll(data, []byte("${1}"+redacted))
+	data = jwtRegex.ReplaceAll(data, []byte(redacted))
+	return data
+}
@@ -0,0 +1,540 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "sigs.k8s.io/service-apis/api/v1alpha1"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+const (
+	// IstioGatewayClass is the class of the service-apis Gateways converted from Istio gateways.
+	IstioGatewayClass = "istio"
+
+	httpRouteResource = "HTTPRoute"
+	serviceResource   = "Service"
+	namedAddressType  = "NamedAddress"
+	meshGateway       = "mesh"
+
+	exactMatchType  = "Exact"
+	prefixMatchType = "Prefix"
+	regexMatchType  = "RegularExpression"
+)
+
+var (
+	gatewayType          = collections.IstioNetworkingV1Alpha3Gateways.Resource()
+	serviceAPIsGateway   = collections.K8SServiceApisV1Alpha1Gateways
+	serviceAPIsHTTPRoute = collections.K8SServiceApisV1Alpha1Httproutes
+
+	defaultGatewaySelector = labels.Instance{constants.IstioLabel: "ingressgateway"}
+)
+
+// warnings collects the features which can't be converted.
+type warnings []string
+
+func (w *warnings) add(kind string, meta model.ConfigMeta, format string, args ...interface{}) {
+	*w = append(*w, fmt.Sprintf("%s %s/%s: ", kind, meta.Namespace, meta.Name)+fmt.Sprintf(format, args...))
+}
+
+// ServiceAPIsToIstio converts service-apis Gateways, and the HTTPRoutes they reference, to v1alpha3 gateways and
+// virtual services. The features which can't be converted are returned as warnings.
+func ServiceAPIsToIstio(gateways, routes []model.Config, domainSuffix string) ([]model.Config, []string) {
+	if len(domainSuffix) == 0 {
+		domainSuffix = "cluster.local"
+	}
+
+	var w warnings
+	out := make([]model.Config, 0)
+	// the Istio gateways of each route, by namespace/name
+	routeGateways := make(map[string][]string)
+	for _, obj := range gateways {
+		kgw := obj.Spec.(*k8s.GatewaySpec)
+		name := obj.Name + "-" + constants.KubernetesGatewayName
+		servers := make([]*networking.Server, 0, len(kgw.Listeners))
+		for i, l := range kgw.Listeners {
+			if l.Port == nil || l.Protocol == nil {
+				w.add("Gateway", obj.ConfigMeta, "listener %d has no port or protocol, skipping", i)
+				continue
+			}
+			server := &networking.Server{
+				Port: &networking.Port{
+					Number:   uint32(*l.Port),
+					Protocol: strings.ToUpper(*l.Protocol),
+					Name:     fmt.Sprintf("%v-%v-gateway-%s-%s", strings.ToLower(*l.Protocol), *l.Port, obj.Name, obj.Namespace),
+				},
+				Hosts: []string{"*"},
+			}
+			if l.Address != nil {
+				server.Hosts = []string{l.Address.Value}
+			}
+			servers = append(servers, server)
+		}
+
+		for _, ref := range kgw.Routes {
+			if ref.Resource != httpRouteResource {
+				w.add("Gateway", obj.ConfigMeta, "%s %s is not supported, skipping", ref.Resource, ref.Name)
+				continue
+			}
+			key := obj.Namespace + "/" + ref.Name
+			routeGateways[key] = append(routeGateways[key], name)
+		}
+
+		out = append(out, model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:      gatewayType.Kind(),
+				Group:     gatewayType.Group(),
+				Version:   gatewayType.Version(),
+				Name:      name,
+				Namespace: obj.Namespace,
+				Domain:    domainSuffix,
+			},
+			Spec: &networking.Gateway{
+				Servers:  servers,
+				Selector: defaultGatewaySelector,
+			},
+		})
+	}
+
+	found := make(map[string]bool)
+	for _, obj := range routes {
+		key := obj.Namespace + "/" + obj.Name
+		gws, ok := routeGateways[key]
+		if !ok {
+			w.add(httpRouteResource, obj.ConfigMeta, "not referenced by any Gateway, skipping")
+			continue
+		}
+		found[key] = true
+		out = append(out, httpRouteToVirtualServices(obj, gws, domainSuffix, &w)...)
+	}
+
+	missing := make([]string, 0)
+	for key := range routeGateways {
+		if !found[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	for _, key := range missing {
+		w = append(w, fmt.Sprintf("HTTPRoute %s is referenced by a Gateway but not in the input", key))
+	}
+
+	return out, w
+}
+
+// httpRouteToVirtualServices converts each host of an HTTPRoute to a virtual service, as the hosts have their own
+// rules.
+func httpRouteToVirtualServices(obj model.Config, gateways []string, domainSuffix string, w *warnings) []model.Config {
+	route := obj.Spec.(*k8s.HTTPRouteSpec)
+	out := make([]model.Config, 0, len(route.Hosts))
+	for i, h := range route.Hosts {
+		host := h.Hostname
+		if host == "" {
+			host = "*"
+		}
+
+		httpRoutes := make([]*networking.HTTPRoute, 0, len(h.Rules))
+		for j, r := range h.Rules {
+			httpRoute := &networking.HTTPRoute{}
+			if r.Match != nil {
+				match, err := httpRouteMatch(r.Match)
+				if err != nil {
+					w.add(httpRouteResource, obj.ConfigMeta, "host %q rule %d: %v, skipping", host, j, err)
+					continue
+				}
+				httpRoute.Match = []*networking.HTTPMatchRequest{match}
+			}
+			if r.Filter != nil && r.Filter.Headers != nil {
+				httpRoute.Headers = &networking.Headers{
+					Request: &networking.Headers_HeaderOperations{
+						Add:    r.Filter.Headers.Add,
+						Remove: r.Filter.Headers.Remove,
+					},
+				}
+			}
+			if r.Action == nil || r.Action.ForwardTo == nil {
+				w.add(httpRouteResource, obj.ConfigMeta, "host %q rule %d has no forwardTo action, skipping", host, j)
+				continue
+			}
+			httpRoute.Route = []*networking.HTTPRouteDestination{{
+				Destination: &networking.Destination{
+					Host: fmt.Sprintf("%s.%s.svc.%s", r.Action.ForwardTo.Name, obj.Namespace, domainSuffix),
+				},
+			}}
+			httpRoutes = append(httpRoutes, httpRoute)
+		}
+
+		name := obj.Name + "-" + constants.KubernetesGatewayName
+		if len(route.Hosts) > 1 {
+			name = fmt.Sprintf("%s-%d-%s", obj.Name, i, constants.KubernetesGatewayName)
+		}
+		out = append(out, model.Config{
+			ConfigMeta: model.ConfigMeta{
+				Type:      virtualServiceType.Kind(),
+				Group:     virtualServiceType.Group(),
+				Version:   virtualServiceType.Version(),
+				Name:      name,
+				Namespace: obj.Namespace,
+				Domain:    domainSuffix,
+			},
+			Spec: &networking.VirtualService{
+				Hosts:    []string{host},
+				Gateways: gateways,
+				Http:     httpRoutes,
+			},
+		})
+	}
+	return out
+}
+
+func httpRouteMatch(m *k8s.HTTPRouteMatch) (*networking.HTTPMatchRequest, error) {
+	match := &networking.HTTPMatchRequest{}
+	if m.Path != nil {
+		uri, err := stringMatch(m.PathType, *m.Path)
+		if err != nil {
+			return nil, fmt.Errorf("path: %v", err)
+		}
+		match.Uri = uri
+	}
+	if len(m.Header) > 0 {
+		headerType := ""
+		if m.HeaderType != nil {
+			headerType = *m.HeaderType
+		}
+		match.Headers = make(map[string]*networking.StringMatch, len(m.Header))
+		for name, value := range m.Header {
+			header, err := stringMatch(headerType, value)
+			if err != nil {
+				return nil, fmt.Errorf("header %s: %v", name, err)
+			}
+			match.Headers[name] = header
+		}
+	}
+	return match, nil
+}
+
+func stringMatch(matchType, value string) (*networking.StringMatch, error) {
+	switch matchType {
+	case "", exactMatchType:
+		return exactMatch(value), nil
+	case prefixMatchType:
+		return prefixMatch(value), nil
+	case regexMatchType:
+		return &networking.StringMatch{MatchType: &networking.StringMatch_Regex{Regex: value}}, nil
+	default:
+		return nil, fmt.Errorf("match type %q is not supported", matchType)
+	}
+}
+
+// IstioToServiceAPIs converts v1alpha3 gateways, and the virtual services bound to them, to service-apis Gateways
+// of the istio class and HTTPRoutes. The features which can't be converted are returned as warnings.
+func IstioToServiceAPIs(gateways, virtualServices []model.Config) ([]model.Config, []string, error) {
+	var w warnings
+
+	// the HTTPRoutes of each gateway, by namespace/name
+	gatewayRoutes := make(map[string][]string)
+	for _, gw := range gateways {
+		gatewayRoutes[gw.Namespace+"/"+gw.Name] = nil
+	}
+
+	out := make([]model.Config, 0)
+	var routes []model.Config
+	for _, vs := range virtualServices {
+		bound := false
+		for _, gateway := range vs.Spec.(*networking.VirtualService).Gateways {
+			if gateway == meshGateway {
+				w.add("VirtualService", vs.ConfigMeta, "routing for the mesh gateway is not converted")
+				continue
+			}
+			key := gateway
+			if !strings.Contains(key, "/") {
+				key = vs.Namespace + "/" + gateway
+			}
+			if _, ok := gatewayRoutes[key]; !ok {
+				w.add("VirtualService", vs.ConfigMeta, "gateway %s is not in the input, skipping it", gateway)
+				continue
+			}
+			if !strings.HasPrefix(key, vs.Namespace+"/") {
+				w.add("VirtualService", vs.ConfigMeta,
+					"gateway %s is in another namespace, which an HTTPRoute can't be bound to, skipping it", gateway)
+				continue
+			}
+			gatewayRoutes[key] = append(gatewayRoutes[key], vs.Name)
+			bound = true
+		}
+		if !bound {
+			w.add("VirtualService", vs.ConfigMeta, "not bound to any gateway of the input, skipping")
+			continue
+		}
+
+		route, err := virtualServiceToHTTPRoute(vs, &w)
+		if err != nil {
+			return nil, nil, err
+		}
+		routes = append(routes, route)
+	}
+
+	for _, gw := range gateways {
+		cfg, err := gatewayToServiceAPIs(gw, gatewayRoutes[gw.Namespace+"/"+gw.Name], &w)
+		if err != nil {
+			return nil, nil, err
+		}
+		out = append(out, cfg)
+	}
+
+	return append(out, routes...), w, nil
+}
+
+func gatewayToServiceAPIs(gw model.Config, routes []string, w *warnings) (model.Config, error) {
+	spec := gw.Spec.(*networking.Gateway)
+	if !labels.Instance(spec.Selector).Equals(defaultGatewaySelector) {
+		w.add("Gateway", gw.ConfigMeta, "selector %v is not converted, the Gateway is of the %s class",
+			labels.Instance(spec.Selector), IstioGatewayClass)
+	}
+
+	listeners := make([]interface{}, 0, len(spec.Servers))
+	for _, server := range spec.Servers {
+		if server.Tls != nil {
+			w.add("Gateway", gw.ConfigMeta, "TLS settings of server %s are not converted", server.Port.GetName())
+		}
+		for _, host := range server.Hosts {
+			if i := strings.Index(host, "/"); i >= 0 {
+				w.add("Gateway", gw.ConfigMeta, "namespace of host %s is not converted", host)
+				host = host[i+1:]
+			}
+			listener := map[string]interface{}{
+				"name":     server.Port.GetName(),
+				"port":     server.Port.GetNumber(),
+				"protocol": server.Port.GetProtocol(),
+			}
+			if host != "*" {
+				listener["address"] = map[string]interface{}{
+					"type":  namedAddressType,
+					"value": host,
+				}
+			}
+			listeners = append(listeners, listener)
+		}
+	}
+
+	refs := make([]interface{}, 0, len(routes))
+	sort.Strings(routes)
+	for _, route := range routes {
+		refs = append(refs, map[string]interface{}{
+			"group":    serviceAPIsHTTPRoute.Resource().Group(),
+			"resource": httpRouteResource,
+			"name":     route,
+		})
+	}
+
+	return serviceAPIsConfig(serviceAPIsGateway, gw, map[string]interface{}{
+		"class":     IstioGatewayClass,
+		"listeners": listeners,
+		"routes":    refs,
+	})
+}
+
+func virtualServiceToHTTPRoute(vs model.Config, w *warnings) (model.Config, error) {
+	spec := vs.Spec.(*networking.VirtualService)
+	if len(spec.Tcp) > 0 || len(spec.Tls) > 0 {
+		w.add("VirtualService", vs.ConfigMeta, "TCP and TLS routes are not converted")
+	}
+
+	rules := make([]interface{}, 0, len(spec.Http))
+	for i, httpRoute := range spec.Http {
+		for _, field := range unsupportedHTTPRouteFields(httpRoute) {
+			w.add("VirtualService", vs.ConfigMeta, "http route %d: %s is not converted", i, field)
+		}
+		if len(httpRoute.Route) == 0 {
+			w.add("VirtualService", vs.ConfigMeta, "http route %d has no destination, skipping", i)
+			continue
+		}
+		if len(httpRoute.Route) > 1 {
+			w.add("VirtualService", vs.ConfigMeta,
+				"http route %d: traffic splitting is not converted, forwarding to the first destination only", i)
+		}
+
+		rule := map[string]interface{}{
+			"action": map[string]interface{}{
+				"forwardTo": map[string]interface{}{
+					"resource": serviceResource,
+					"name":     serviceName(httpRoute.Route[0].Destination, vs, i, w),
+				},
+			},
+		}
+		if request := httpRoute.Headers.GetRequest(); request != nil {
+			if len(request.Set) > 0 {
+				w.add("VirtualService", vs.ConfigMeta, "http route %d: setting request headers is not converted", i)
+			}
+			headers := make(map[string]interface{})
+			if len(request.Add) > 0 {
+				headers["add"] = request.Add
+			}
+			if len(request.Remove) > 0 {
+				headers["remove"] = request.Remove
+			}
+			if len(headers) > 0 {
+				rule["filter"] = map[string]interface{}{"headers": headers}
+			}
+		}
+
+		if len(httpRoute.Match) == 0 {
+			rules = append(rules, rule)
+			continue
+		}
+		// a rule has a single match, the matches are converted to rules with the same action
+		for j, m := range httpRoute.Match {
+			match, err := serviceAPIsMatch(m)
+			if err != nil {
+				w.add("VirtualService", vs.ConfigMeta, "http route %d match %d: %v, skipping", i, j, err)
+				continue
+			}
+			matchRule := make(map[string]interface{}, len(rule)+1)
+			for k, v := range rule {
+				matchRule[k] = v
+			}
+			matchRule["match"] = match
+			rules = append(rules, matchRule)
+		}
+	}
+
+	hosts := make([]interface{}, 0, len(spec.Hosts))
+	for _, host := range spec.Hosts {
+		hosts = append(hosts, map[string]interface{}{
+			"hostname": host,
+			"rules":    rules,
+		})
+	}
+
+	return serviceAPIsConfig(serviceAPIsHTTPRoute, vs, map[string]interface{}{"hosts": hosts})
+}
+
+// unsupportedHTTPRouteFields returns the fields of an HTTP route which have no service-apis equivalent.
+func unsupportedHTTPRouteFields(r *networking.HTTPRoute) []string {
+	var fields []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"redirect", r.Redirect != nil},
+		{"rewrite", r.Rewrite != nil},
+		{"timeout", r.Timeout != nil},
+		{"retries", r.Retries != nil},
+		{"fault", r.Fault != nil},
+		{"mirror", r.Mirror != nil},
+		{"corsPolicy", r.CorsPolicy != nil},
+		{"response headers", r.Headers.GetResponse() != nil},
+	} {
+		if f.set {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// serviceName returns the name of the service of a destination, which must be in the namespace of the route.
+func serviceName(d *networking.Destination, vs model.Config, route int, w *warnings) string {
+	if d.Subset != "" {
+		w.add("VirtualService", vs.ConfigMeta, "http route %d: subset %s is not converted", route, d.Subset)
+	}
+	if d.Port != nil {
+		w.add("VirtualService", vs.ConfigMeta, "http route %d: destination port is not converted", route)
+	}
+	parts := strings.Split(d.Host, ".")
+	if len(parts) > 1 && parts[1] != vs.Namespace {
+		w.add("VirtualService", vs.ConfigMeta,
+			"http route %d: destination %s is not a service of namespace %s", route, d.Host, vs.Namespace)
+	}
+	return parts[0]
+}
+
+func serviceAPIsMatch(m *networking.HTTPMatchRequest) (map[string]interface{}, error) {
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"scheme", m.Scheme != nil},
+		{"method", m.Method != nil},
+		{"authority", m.Authority != nil},
+		{"port", m.Port != 0},
+		{"sourceLabels", len(m.SourceLabels) > 0},
+		{"queryParams", len(m.QueryParams) > 0},
+		{"ignoreUriCase", m.IgnoreUriCase},
+	} {
+		if f.set {
+			return nil, fmt.Errorf("%s match is not supported", f.name)
+		}
+	}
+
+	match := make(map[string]interface{})
+	if m.Uri != nil {
+		pathType, path := serviceAPIsStringMatch(m.Uri)
+		match["pathType"] = pathType
+		match["path"] = path
+	}
+	if len(m.Headers) > 0 {
+		headerType := ""
+		headers := make(map[string]interface{}, len(m.Headers))
+		for name, value := range m.Headers {
+			t, v := serviceAPIsStringMatch(value)
+			if headerType != "" && t != headerType {
+				return nil, fmt.Errorf("headers with different match types are not supported")
+			}
+			headerType = t
+			headers[name] = v
+		}
+		match["headerType"] = headerType
+		match["header"] = headers
+	}
+	return match, nil
+}
+
+func serviceAPIsStringMatch(m *networking.StringMatch) (string, string) {
+	switch t := m.MatchType.(type) {
+	case *networking.StringMatch_Prefix:
+		return prefixMatchType, t.Prefix
+	case *networking.StringMatch_Regex:
+		return regexMatchType, t.Regex
+	default:
+		return exactMatchType, m.GetExact()
+	}
+}
+
+// serviceAPIsConfig builds a service-apis resource named after an Istio resource.
+func serviceAPIsConfig(s collection.Schema, from model.Config, spec map[string]interface{}) (model.Config, error) {
+	cfg, err := crd.ConvertObject(s, &crd.IstioKind{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      from.Name,
+			Namespace: from.Namespace,
+		},
+		Spec: spec,
+	}, from.Domain)
+	if err != nil {
+		return model.Config{}, fmt.Errorf("could not convert %s %s/%s: %v", from.Type, from.Namespace, from.Name, err)
+	}
+	return *cfg, nil
+}
@@ -15,6 +15,7 @@
 package convert
 
 import (
+	"sort"
 	"strings"
 
 	"k8s.io/api/extensions/v1beta1"
@@ -38,6 +39,11 @@ func IstioIngresses(ingresses []*v1beta1.Ingress, domainSuffix string) ([]model.
 		ingress.ConvertIngressVirtualService(*ingrezz, domainSuffix, ingressByHost)
 	}
 
+	return ingressConfigs(ingressByHost), nil
+}
+
+// ingressConfigs returns the virtual services of the ingresses, sorted by name.
+func ingressConfigs(ingressByHost map[string]*model.Config) []model.Config {
 	out := make([]model.Config, 0, len(ingressByHost))
 	for _, vs := range ingressByHost {
 		// Ensure name is valid; ConvertIngressVirtualService will create a name that doesn't start with alphanumeric
@@ -46,6 +52,11 @@ func IstioIngresses(ingresses []*v1beta1.Ingress, domainSuffix string) ([]model.
 		}
 		out = append(out, *vs)
 	}
-
-	return out, nil
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
 }
@@ -0,0 +1,368 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// The vendored Kubernetes API predates networking.k8s.io/v1 Ingress, so the fields used by the conversion are
+// mirrored below.
+
+// IngressV1 is a networking.k8s.io/v1 Ingress.
+type IngressV1 struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec IngressSpecV1 `json:"spec,omitempty"`
+}
+
+// IngressSpecV1 is the spec of a networking.k8s.io/v1 Ingress.
+type IngressSpecV1 struct {
+	IngressClassName *string              `json:"ingressClassName,omitempty"`
+	DefaultBackend   *IngressBackendV1    `json:"defaultBackend,omitempty"`
+	TLS              []v1beta1.IngressTLS `json:"tls,omitempty"`
+	Rules            []IngressRuleV1      `json:"rules,omitempty"`
+}
+
+// IngressRuleV1 routes the requests for a host to backends.
+type IngressRuleV1 struct {
+	Host string                  `json:"host,omitempty"`
+	HTTP *HTTPIngressRuleValueV1 `json:"http,omitempty"`
+}
+
+// HTTPIngressRuleValueV1 holds the paths of an ingress rule.
+type HTTPIngressRuleValueV1 struct {
+	Paths []HTTPIngressPathV1 `json:"paths"`
+}
+
+// HTTPIngressPathV1 routes the requests matching a path to a backend.
+type HTTPIngressPathV1 struct {
+	Path     string           `json:"path,omitempty"`
+	PathType *PathType        `json:"pathType,omitempty"`
+	Backend  IngressBackendV1 `json:"backend"`
+}
+
+// PathType determines how the path of an ingress path is matched.
+type PathType string
+
+const (
+	// PathTypeExact matches the path exactly.
+	PathTypeExact = PathType("Exact")
+	// PathTypePrefix matches the path prefix, element by element.
+	PathTypePrefix = PathType("Prefix")
+	// PathTypeImplementationSpecific matches the path as the Istio ingress controller does.
+	PathTypeImplementationSpecific = PathType("ImplementationSpecific")
+)
+
+// IngressBackendV1 is the backend of an ingress path: either a service or a resource.
+type IngressBackendV1 struct {
+	Service  *IngressServiceBackendV1      `json:"service,omitempty"`
+	Resource *v1.TypedLocalObjectReference `json:"resource,omitempty"`
+}
+
+// IngressServiceBackendV1 references a port of a service.
+type IngressServiceBackendV1 struct {
+	Name string               `json:"name"`
+	Port ServiceBackendPortV1 `json:"port,omitempty"`
+}
+
+// ServiceBackendPortV1 is a service port, by name or number.
+type ServiceBackendPortV1 struct {
+	Name   string `json:"name,omitempty"`
+	Number int32  `json:"number,omitempty"`
+}
+
+// IngressClassV1 is a networking.k8s.io/v1 IngressClass.
+type IngressClassV1 struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec IngressClassSpecV1 `json:"spec,omitempty"`
+}
+
+// IngressClassSpecV1 is the spec of an IngressClass.
+type IngressClassSpecV1 struct {
+	Controller string `json:"controller,omitempty"`
+}
+
+const (
+	// IstioIngressController is the controller of the IngressClasses handled by Istio.
+	IstioIngressController = "istio.io/ingress-controller"
+
+	istioIngressClass = "istio"
+)
+
+var virtualServiceType = collections.IstioNetworkingV1Alpha3Virtualservices.Resource()
+
+// IstioIngressesV1 converts K8s networking.k8s.io/v1 Ingresses to v1alpha3 virtual services. Only the Ingresses of an
+// IngressClass of the Istio controller, of the "istio" class or without class are converted. The features which
+// can't be converted are returned as warnings.
+func IstioIngressesV1(ingresses []*IngressV1, classes []*IngressClassV1,
+	domainSuffix string) ([]model.Config, []string) {
+	if len(domainSuffix) == 0 {
+		domainSuffix = "cluster.local"
+	}
+
+	classesByName := make(map[string]*IngressClassV1, len(classes))
+	for _, class := range classes {
+		classesByName[class.Name] = class
+	}
+
+	var warnings []string
+	ingressByHost := map[string]*model.Config{}
+	for _, ingress := range ingresses {
+		if class, ok := ingressClassV1(ingress, classesByName); !ok {
+			warnings = append(warnings, fmt.Sprintf("Ingress %s/%s: class %q is not handled by Istio, skipping",
+				ingress.Namespace, ingress.Name, class))
+			continue
+		}
+		warnings = append(warnings, convertIngressV1(ingress, domainSuffix, ingressByHost)...)
+	}
+
+	return ingressConfigs(ingressByHost), warnings
+}
+
+// ingressClassV1 returns the class of an Ingress and whether it is handled by Istio. The legacy annotation takes
+// precedence over the IngressClass, as for the Kubernetes ingress controllers.
+func ingressClassV1(ingress *IngressV1, classes map[string]*IngressClassV1) (string, bool) {
+	if class, ok := ingress.Annotations[kube.IngressClassAnnotation]; ok {
+		return class, class == istioIngressClass
+	}
+	if ingress.Spec.IngressClassName == nil {
+		return "", true
+	}
+	name := *ingress.Spec.IngressClassName
+	if class, ok := classes[name]; ok {
+		return name, class.Spec.Controller == IstioIngressController
+	}
+	return name, name == istioIngressClass
+}
+
+func convertIngressV1(ingress *IngressV1, domainSuffix string, ingressByHost map[string]*model.Config) []string {
+	var warnings []string
+	warnf := func(format string, args ...interface{}) {
+		warnings = append(warnings,
+			fmt.Sprintf("Ingress %s/%s: ", ingress.Namespace, ingress.Name)+fmt.Sprintf(format, args...))
+	}
+
+	if len(ingress.Spec.TLS) > 0 {
+		warnf("TLS is not converted, configure the credentials on the %s Gateway", constants.IstioIngressGatewayName)
+	}
+
+	// The default backend handles the requests matching no path, so it is the last route of every host.
+	var defaultRoute *networking.HTTPRoute
+	if backend := ingress.Spec.DefaultBackend; backend != nil {
+		var err error
+		if defaultRoute, err = ingressBackendV1ToHTTPRoute(backend, ingress.Namespace, domainSuffix); err != nil {
+			warnf("default backend: %v, skipping", err)
+		}
+	}
+
+	wildcard := false
+	for _, rule := range ingress.Spec.Rules {
+		host := rule.Host
+		if host == "" {
+			host = "*"
+		}
+		if host == "*" {
+			wildcard = true
+		}
+
+		var routes []*networking.HTTPRoute
+		if rule.HTTP != nil {
+			for _, path := range rule.HTTP.Paths {
+				route, err := ingressBackendV1ToHTTPRoute(&path.Backend, ingress.Namespace, domainSuffix)
+				if err != nil {
+					warnf("host %q path %q: %v, skipping", host, path.Path, err)
+					continue
+				}
+				if route.Match, err = ingressPathV1Matches(path); err != nil {
+					warnf("host %q path %q: %v, skipping", host, path.Path, err)
+					continue
+				}
+				routes = append(routes, route)
+			}
+		}
+		if defaultRoute != nil {
+			routes = append(routes, defaultRoute)
+		}
+		if len(routes) == 0 {
+			warnf("host %q has no paths, skipping", host)
+			continue
+		}
+		addIngressRoutes(ingress, host, routes, domainSuffix, ingressByHost)
+	}
+
+	if defaultRoute != nil && !wildcard {
+		addIngressRoutes(ingress, "*", []*networking.HTTPRoute{defaultRoute}, domainSuffix, ingressByHost)
+	}
+
+	return warnings
+}
+
+// addIngressRoutes adds routes to the virtual service of a host, which is shared by all the ingresses.
+func addIngressRoutes(ingress *IngressV1, host string, routes []*networking.HTTPRoute, domainSuffix string,
+	ingressByHost map[string]*model.Config) {
+	if old, ok := ingressByHost[host]; ok {
+		vs := old.Spec.(*networking.VirtualService)
+		vs.Http = append(vs.Http, routes...)
+		sortIngressRoutes(vs.Http)
+		return
+	}
+
+	namePrefix := strings.Replace(strings.Replace(host, "*", "", -1), ".", "-", -1)
+	vs := &networking.VirtualService{
+		Hosts: []string{host},
+		// Note the name of the gateway is fixed - this is the Gateway that needs to be created by user (via helm
+		// or manually) with TLS secrets and explicit namespace (for security).
+		Gateways: []string{constants.IstioIngressNamespace + "/" + constants.IstioIngressGatewayName},
+		Http:     routes,
+	}
+	sortIngressRoutes(vs.Http)
+	ingressByHost[host] = &model.Config{
+		ConfigMeta: model.ConfigMeta{
+			Type:      virtualServiceType.Kind(),
+			Group:     virtualServiceType.Group(),
+			Version:   virtualServiceType.Version(),
+			Name:      namePrefix + "-" + ingress.Name + "-" + constants.IstioIngressGatewayName,
+			Namespace: ingress.Namespace,
+			Domain:    domainSuffix,
+		},
+		Spec: vs,
+	}
+}
+
+// sortIngressRoutes orders the routes as Kubernetes matches ingress paths: exact matches first, then the longest
+// prefix, then the default backend.
+func sortIngressRoutes(routes []*networking.HTTPRoute) {
+	rank := func(route *networking.HTTPRoute) (int, int) {
+		exact := false
+		prefix := -1
+		for _, match := range route.Match {
+			switch m := match.GetUri().GetMatchType().(type) {
+			case *networking.StringMatch_Exact:
+				exact = true
+			case *networking.StringMatch_Prefix:
+				if len(m.Prefix) > prefix {
+					prefix = len(m.Prefix)
+				}
+			}
+		}
+		switch {
+		case prefix >= 0:
+			return 1, prefix
+		case exact:
+			return 0, 0
+		default:
+			return 2, 0
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		ri, li := rank(routes[i])
+		rj, lj := rank(routes[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return li > lj
+	})
+}
+
+func ingressPathV1Matches(path HTTPIngressPathV1) ([]*networking.HTTPMatchRequest, error) {
+	pathType := PathTypeImplementationSpecific
+	if path.PathType != nil {
+		pathType = *path.PathType
+	}
+
+	switch pathType {
+	case PathTypeExact:
+		return []*networking.HTTPMatchRequest{{Uri: exactMatch(path.Path)}}, nil
+	case PathTypePrefix:
+		// Prefixes are matched element by element: /foo matches /foo and /foo/bar, but not /foobar. A trailing
+		// slash is ignored.
+		prefix := strings.TrimSuffix(path.Path, "/")
+		if prefix == "" {
+			return []*networking.HTTPMatchRequest{{Uri: prefixMatch("/")}}, nil
+		}
+		return []*networking.HTTPMatchRequest{{Uri: prefixMatch(prefix + "/")}, {Uri: exactMatch(prefix)}}, nil
+	case PathTypeImplementationSpecific:
+		if path.Path == "" {
+			return nil, nil
+		}
+		return []*networking.HTTPMatchRequest{{Uri: implementationSpecificMatch(path.Path)}}, nil
+	default:
+		return nil, fmt.Errorf("unknown path type %q", pathType)
+	}
+}
+
+// implementationSpecificMatch matches a path as the Istio ingress controller does: paths ending with .* or /* are
+// prefixes, other paths are exact.
+func implementationSpecificMatch(path string) *networking.StringMatch {
+	for _, suffix := range []string{".*", "/*"} {
+		if strings.HasSuffix(path, suffix) {
+			return prefixMatch(strings.TrimSuffix(path, suffix))
+		}
+	}
+	return exactMatch(path)
+}
+
+func exactMatch(s string) *networking.StringMatch {
+	return &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: s}}
+}
+
+func prefixMatch(s string) *networking.StringMatch {
+	return &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: s}}
+}
+
+func ingressBackendV1ToHTTPRoute(backend *IngressBackendV1, namespace,
+	domainSuffix string) (*networking.HTTPRoute, error) {
+	if backend.Resource != nil {
+		return nil, fmt.Errorf("resource backend %s %s is not supported", backend.Resource.Kind, backend.Resource.Name)
+	}
+	if backend.Service == nil {
+		return nil, errors.New("no backend service")
+	}
+	if backend.Service.Port.Number == 0 {
+		// Port names are not allowed in destination rules.
+		return nil, fmt.Errorf("named port %q of service %s is not supported", backend.Service.Port.Name,
+			backend.Service.Name)
+	}
+
+	return &networking.HTTPRoute{
+		Route: []*networking.HTTPRouteDestination{
+			{
+				Destination: &networking.Destination{
+					Host: fmt.Sprintf("%s.%s.svc.%s", backend.Service.Name, namespace, domainSuffix),
+					Port: &networking.PortSelector{Number: uint32(backend.Service.Port.Number)},
+				},
+				Weight: 100,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+func serviceBackend(name string, port int32) IngressBackendV1 {
+	return IngressBackendV1{Service: &IngressServiceBackendV1{Name: name, Port: ServiceBackendPortV1{Number: port}}}
+}
+
+func pathType(t PathType) *PathType {
+	return &t
+}
+
+func TestIstioIngressesV1Class(t *testing.T) {
+	istio := "istio"
+	nginx := "nginx"
+	custom := "custom"
+	classes := []*IngressClassV1{
+		{ObjectMeta: metav1.ObjectMeta{Name: custom}, Spec: IngressClassSpecV1{Controller: IstioIngressController}},
+		{ObjectMeta: metav1.ObjectMeta{Name: istio}, Spec: IngressClassSpecV1{Controller: "k8s.io/ingress-nginx"}},
+	}
+
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		className   *string
+		want        bool
+	}{
+		{name: "no class", want: true},
+		{name: "annotation", annotations: map[string]string{"kubernetes.io/ingress.class": istio}, want: true},
+		{name: "annotation precedence", annotations: map[string]string{"kubernetes.io/ingress.class": nginx},
+			className: &custom, want: false},
+		{name: "istio controller", className: &custom, want: true},
+		{name: "other controller", className: &istio, want: false},
+		{name: "undefined class", className: &nginx, want: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			backend := serviceBackend("svc", 80)
+			ingress := &IngressV1{
+				ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: "default", Annotations: c.annotations},
+				Spec: IngressSpecV1{
+					IngressClassName: c.className,
+					DefaultBackend:   &backend,
+				},
+			}
+			out, _ := IstioIngressesV1([]*IngressV1{ingress}, classes, "")
+			if got := len(out) > 0; got != c.want {
+				t.Errorf("converted %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestIstioIngressesV1Warnings(t *testing.T) {
+	ingress := &IngressV1{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: "default"},
+		Spec: IngressSpecV1{
+			TLS: []v1beta1.IngressTLS{{Hosts: []string{"foo.example.com"}, SecretName: "foo-cert"}},
+			Rules: []IngressRuleV1{{
+				Host: "foo.example.com",
+				HTTP: &HTTPIngressRuleValueV1{Paths: []HTTPIngressPathV1{
+					{Path: "/", PathType: pathType(PathTypePrefix), Backend: serviceBackend("svc", 80)},
+					{Path: "/named", PathType: pathType(PathTypeExact),
+						Backend: IngressBackendV1{Service: &IngressServiceBackendV1{
+							Name: "svc", Port: ServiceBackendPortV1{Name: "http"}}}},
+					{Path: "/bucket", PathType: pathType(PathTypePrefix),
+						Backend: IngressBackendV1{Resource: &v1.TypedLocalObjectReference{Kind: "StorageBucket", Name: "static"}}},
+				}},
+			}},
+		},
+	}
+
+	out, warnings := IstioIngressesV1([]*IngressV1{ingress}, nil, "")
+	wantWarnings := []string{
+		"Ingress default/ingress: TLS is not converted, configure the credentials on the " +
+			"istio-autogenerated-k8s-ingress Gateway",
+		`Ingress default/ingress: host "foo.example.com" path "/named": named port "http" of service svc is not ` +
+			"supported, skipping",
+		`Ingress default/ingress: host "foo.example.com" path "/bucket": resource backend StorageBucket static is ` +
+			"not supported, skipping",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("got warnings %q, want %q", warnings, wantWarnings)
+	}
+	if len(out) != 1 {
+		t.Fatalf("got %d virtual services, want 1", len(out))
+	}
+	vs := out[0].Spec.(*networking.VirtualService)
+	if len(vs.Http) != 1 || vs.Http[0].Match[0].Uri.GetPrefix() != "/" {
+		t.Errorf("got routes %v, want a single route for prefix /", vs.Http)
+	}
+}
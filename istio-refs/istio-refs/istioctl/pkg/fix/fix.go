@@ -0,0 +1,149 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fix applies the fixes attached to analysis messages.
+package fix
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+)
+
+// stdinFilename is the name of the resources read from the standard input.
+const stdinFilename = "-"
+
+// Confirm is called before applying a fix. The fix is skipped if it returns false.
+type Confirm func(m diag.Message) (bool, error)
+
+// Result is the outcome of applying fixes.
+type Result struct {
+	// Applied holds the messages whose fixes were applied to local files.
+	Applied diag.Messages
+
+	// Commands holds the kubectl commands applying the fixes of the resources that weren't read
+	// from local files.
+	Commands []string
+}
+
+// Apply applies the fixes of the messages. Resources read from local files are fixed by editing the
+// files, other resources are fixed by the returned kubectl commands. Fixes that fail are reported in
+// the returned error, the others are still applied. If confirm is not nil, it is called before each fix.
+func Apply(ms diag.Messages, confirm Confirm) (*Result, error) {
+	result := &Result{}
+	var errs error
+
+	contents := make(map[string][]byte)
+	var files []string
+	failed := make(map[string]bool)
+
+	for _, m := range ms {
+		if m.Fix == nil || m.Resource == nil {
+			continue
+		}
+		origin, ok := m.Resource.Origin.(*rt.Origin)
+		if !ok {
+			continue
+		}
+
+		if confirm != nil {
+			ok, err := confirm(m)
+			if err != nil {
+				return result, err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		pos, ok := origin.Ref.(*rt.Position)
+		if !ok {
+			cmd, err := KubectlPatch(origin, m.Fix)
+			if err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("%s: %v", origin.FriendlyName(), err))
+				continue
+			}
+			result.Commands = append(result.Commands, cmd)
+			continue
+		}
+
+		if pos.Filename == stdinFilename {
+			errs = multierror.Append(errs, fmt.Errorf("%s: resources read from stdin can't be fixed", origin.FriendlyName()))
+			continue
+		}
+		if failed[pos.Filename] {
+			continue
+		}
+		content, ok := contents[pos.Filename]
+		if !ok {
+			var err error
+			if content, err = ioutil.ReadFile(pos.Filename); err != nil {
+				errs = multierror.Append(errs, err)
+				failed[pos.Filename] = true
+				continue
+			}
+			files = append(files, pos.Filename)
+		}
+
+		out, err := ApplyPatch(content, pos.Document, m.Fix.Patch)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s (%s): %v", origin.FriendlyName(), pos, err))
+			contents[pos.Filename] = content
+			continue
+		}
+		contents[pos.Filename] = out
+		result.Applied = append(result.Applied, m)
+	}
+
+	for _, f := range files {
+		if err := writeFile(f, contents[f]); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return result, errs
+}
+
+func writeFile(name string, content []byte) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, content, info.Mode())
+}
+
+// KubectlPatch returns the kubectl command applying a fix to a resource in the cluster.
+func KubectlPatch(origin *rt.Origin, fix *diag.Fix) (string, error) {
+	patch, err := json.Marshal(fix.Patch)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"kubectl", "patch", strings.ToLower(origin.Kind), origin.FullName.Name.String()}
+	if origin.FullName.Namespace != "" {
+		args = append(args, "-n", origin.FullName.Namespace.String())
+	}
+	args = append(args, "--type=json", "-p", shellQuote(string(patch)))
+	return strings.Join(args, " "), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
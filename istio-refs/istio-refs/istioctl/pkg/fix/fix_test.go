@@ -0,0 +1,109 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fix
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/pkg/config/resource"
+)
+
+var (
+	testType = diag.NewMessageType(diag.Info, "IST0118", "Port name %s doesn't follow the naming convention.")
+
+	renameFix = &diag.Fix{
+		Description: "Rename port foo to http-foo",
+		Patch:       []diag.PatchOperation{{Op: diag.PatchReplace, Path: "/spec/ports/0/name", Value: "http-foo"}},
+	}
+)
+
+func testMessage(ref resource.Reference, fix *diag.Fix) diag.Message {
+	m := diag.NewMessage(testType, &resource.Instance{Origin: &rt.Origin{
+		Kind:     "Service",
+		FullName: resource.NewFullName("default", "details"),
+		Ref:      ref,
+	}}, "foo")
+	m.Fix = fix
+	return m
+}
+
+func TestApply(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "fix")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "service.yaml")
+	g.Expect(ioutil.WriteFile(file, []byte(service), 0644)).To(Succeed())
+
+	ms := diag.Messages{
+		testMessage(&rt.Position{Filename: file, Document: 0, Line: 2}, renameFix),
+		testMessage(&rt.Position{Filename: file, Document: 0, Line: 2}, nil),
+		testMessage(nil, renameFix),
+	}
+	result, err := Apply(ms, nil)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Applied).To(HaveLen(1))
+	g.Expect(result.Commands).To(Equal([]string{
+		`kubectl patch service details -n default --type=json -p '[{"op":"replace","path":"/spec/ports/0/name","value":"http-foo"}]'`,
+	}))
+
+	content, err := ioutil.ReadFile(file)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(content)).To(ContainSubstring("  - name: http-foo # the main port\n"))
+}
+
+func TestApplyConfirm(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var confirmed []diag.Message
+	result, err := Apply(diag.Messages{testMessage(nil, renameFix)}, func(m diag.Message) (bool, error) {
+		confirmed = append(confirmed, m)
+		return false, nil
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(confirmed).To(HaveLen(1))
+	g.Expect(result.Commands).To(BeEmpty())
+}
+
+func TestApplyErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ms := diag.Messages{
+		testMessage(&rt.Position{Filename: "-"}, renameFix),
+		testMessage(&rt.Position{Filename: "does-not-exist.yaml"}, renameFix),
+	}
+	result, err := Apply(ms, nil)
+	g.Expect(err).NotTo(BeNil())
+	g.Expect(result.Applied).To(BeEmpty())
+}
+
+func TestKubectlPatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cmd, err := KubectlPatch(&rt.Origin{Kind: "Namespace", FullName: resource.NewFullName("", "staging")}, &diag.Fix{
+		Patch: []diag.PatchOperation{{Op: diag.PatchAdd, Path: "/metadata/annotations/owner", Value: "team's"}},
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(cmd).To(Equal(
+		`kubectl patch namespace staging --type=json -p '[{"op":"add","path":"/metadata/annotations/owner","value":"team'\''s"}]'`))
+}
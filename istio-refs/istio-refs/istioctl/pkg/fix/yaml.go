@@ -0,0 +1,493 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fix
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/util/kubeyaml"
+)
+
+var (
+	prefixPattern = regexp.MustCompile(`^ *(- +)*`)
+	plainPattern  = regexp.MustCompile(`^[A-Za-z0-9_./][A-Za-z0-9_./-]*$`)
+)
+
+// rawValue is a value already formatted as yaml, e.g. a value moved from another field.
+type rawValue string
+
+// ApplyPatch applies a JSON patch to a document of a multipart yaml file, returning the new content of
+// the file. Only the lines of the changed fields are edited, so that the comments and formatting of
+// the file are preserved. The patched fields must be in block style, and only scalars can be replaced
+// or moved.
+func ApplyPatch(content []byte, document int, patch []diag.PatchOperation) ([]byte, error) {
+	lines := strings.Split(string(content), "\n")
+	start, end, err := documentBounds(lines, document)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &editor{lines: append([]string{}, lines[start:end]...)}
+	for _, op := range patch {
+		if err := e.apply(op); err != nil {
+			return nil, fmt.Errorf("%s %s: %v", op.Op, op.Path, err)
+		}
+	}
+
+	out := append(append(append([]string{}, lines[:start]...), e.lines...), lines[end:]...)
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+// documentBounds returns the range of lines of the index-th document, counting documents the way
+// kubeyaml.LineReader does.
+func documentBounds(lines []string, index int) (int, int, error) {
+	n := len(lines)
+	if n > 0 && lines[n-1] == "" {
+		n--
+	}
+
+	count := 0
+	start := 0
+	for i := 0; i <= n; i++ {
+		if i < n && !isSeparator(lines[i]) {
+			continue
+		}
+		if i > start {
+			if count == index {
+				return start, i, nil
+			}
+			count++
+		}
+		start = i + 1
+	}
+	return 0, 0, fmt.Errorf("document %d not found", index)
+}
+
+func isSeparator(line string) bool {
+	return strings.HasPrefix(line, "---") && strings.TrimSpace(line[3:]) == ""
+}
+
+// editor edits the lines of a yaml document.
+type editor struct {
+	lines []string
+}
+
+// field is a parsed "key: value" line.
+type field struct {
+	line    int
+	prefix  string // indentation and sequence item dashes before the key
+	key     string // key, as written
+	value   string // value, without comment
+	comment string // comment, with its leading whitespace
+}
+
+func (f *field) column() int {
+	return len(f.prefix)
+}
+
+func (f *field) isBlock() bool {
+	switch f.value {
+	case "", "{}", "null", "~":
+		return true
+	}
+	return false
+}
+
+func (f *field) String() string {
+	s := f.prefix + f.key + ":"
+	if f.value != "" {
+		s += " " + f.value
+	}
+	return s + f.comment
+}
+
+func (e *editor) apply(op diag.PatchOperation) error {
+	tokens := diag.ParseJSONPointer(op.Path)
+	if len(tokens) == 0 {
+		return fmt.Errorf("the document root can't be patched")
+	}
+
+	switch op.Op {
+	case diag.PatchAdd:
+		return e.add(tokens, op.Value)
+	case diag.PatchReplace:
+		if _, err := e.find(tokens); err != nil {
+			return err
+		}
+		return e.add(tokens, op.Value)
+	case diag.PatchRemove:
+		return e.remove(tokens)
+	case diag.PatchMove:
+		from := diag.ParseJSONPointer(op.From)
+		f, err := e.find(from)
+		if err != nil {
+			return err
+		}
+		if f.isBlock() || strings.HasPrefix(f.value, "|") || strings.HasPrefix(f.value, ">") {
+			return fmt.Errorf("only scalars can be moved")
+		}
+		if err := e.remove(from); err != nil {
+			return err
+		}
+		return e.add(tokens, rawValue(f.value))
+	}
+	return fmt.Errorf("unsupported operation")
+}
+
+// fields returns the line index of each field of the document, keyed by path.
+func (e *editor) fields() map[string]int {
+	return kubeyaml.FieldLines([]byte(strings.Join(e.lines, "\n")), 0)
+}
+
+// path returns the field path (as used by kubeyaml.FieldLines) of the given reference tokens.
+func path(fields map[string]int, tokens []string) string {
+	p := ""
+	for _, t := range tokens {
+		if _, err := strconv.Atoi(t); err == nil {
+			if _, found := fields[fmt.Sprintf("%s[%s]", p, t)]; found {
+				p = fmt.Sprintf("%s[%s]", p, t)
+				continue
+			}
+		}
+		if p != "" {
+			p += "."
+		}
+		p += t
+	}
+	return p
+}
+
+// find returns the field at the given reference tokens.
+func (e *editor) find(tokens []string) (*field, error) {
+	fields := e.fields()
+	p := path(fields, tokens)
+	line, found := fields[p]
+	if !found {
+		return nil, fmt.Errorf("field %s not found", p)
+	}
+	f, ok := e.parse(line)
+	if !ok || strings.HasSuffix(p, "]") {
+		return nil, fmt.Errorf("field %s is not a mapping entry", p)
+	}
+	return f, nil
+}
+
+func (e *editor) parse(line int) (*field, bool) {
+	text := strings.TrimRight(e.lines[line], " \t\r")
+	prefix := prefixPattern.FindString(text)
+	key, rest, ok := kubeyaml.SplitKey(text[len(prefix):])
+	if !ok {
+		return nil, false
+	}
+	value, comment := splitComment(rest)
+	return &field{
+		line:    line,
+		prefix:  prefix,
+		key:     key,
+		value:   strings.TrimSpace(value),
+		comment: comment,
+	}, true
+}
+
+// splitComment splits the comment at the end of a value, if any.
+func splitComment(s string) (string, string) {
+	var quote rune
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			value := strings.TrimRight(s[:i], " \t")
+			return value, s[len(value):]
+		}
+	}
+	return s, ""
+}
+
+// blockEnd returns the index of the line following the last line of the value of a field starting
+// at the given column. Trailing comments and blank lines are not part of the value.
+func (e *editor) blockEnd(line, column int, item bool) int {
+	end := line + 1
+	for i := line + 1; i < len(e.lines); i++ {
+		text := strings.TrimRight(e.lines[i], " \t\r")
+		content := strings.TrimLeft(text, " ")
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+		indent := len(text) - len(content)
+		isItem := content == "-" || strings.HasPrefix(content, "- ")
+		if indent < column || (indent == column && (item || !isItem)) {
+			break
+		}
+		end = i + 1
+	}
+	return end
+}
+
+// add sets the value of a mapping entry, creating it if needed. Its parent must exist.
+func (e *editor) add(tokens []string, value interface{}) error {
+	if existing, err := e.find(tokens); err == nil {
+		hasChildren := e.blockEnd(existing.line, existing.column(), false) > existing.line+1
+		switch {
+		case isScalar(value) && !existing.isBlock() && !hasChildren:
+			s, err := scalar(value)
+			if err != nil {
+				return err
+			}
+			existing.value = s
+			e.lines[existing.line] = existing.String()
+			return nil
+		case !isScalar(value) && existing.isBlock() && !hasChildren:
+			// Fill the empty collection in place, keeping its comments.
+			children, err := renderChildren(value, existing.column()+2)
+			if err != nil {
+				return err
+			}
+			existing.value = ""
+			e.lines[existing.line] = existing.String()
+			e.insert(existing.line+1, children)
+			return nil
+		}
+		if err := e.remove(tokens); err != nil {
+			return err
+		}
+	}
+
+	parentTokens, key := tokens[:len(tokens)-1], tokens[len(tokens)-1]
+	column, at, err := e.insertionPoint(parentTokens)
+	if err != nil {
+		return err
+	}
+	lines, err := render(key, value, column)
+	if err != nil {
+		return err
+	}
+	e.insert(at, lines)
+	return nil
+}
+
+// insertionPoint returns the column and line at which to add a new entry to the mapping at the given
+// reference tokens.
+func (e *editor) insertionPoint(tokens []string) (int, int, error) {
+	if len(tokens) == 0 {
+		// Add top level entries after the last line of the document that isn't blank or a comment.
+		end := 0
+		for i, l := range e.lines {
+			if content := strings.TrimSpace(l); content != "" && !strings.HasPrefix(content, "#") {
+				end = i + 1
+			}
+		}
+		return 0, end, nil
+	}
+
+	fields := e.fields()
+	p := path(fields, tokens)
+	line, found := fields[p]
+	if !found {
+		return 0, 0, fmt.Errorf("field %s not found", p)
+	}
+
+	if strings.HasSuffix(p, "]") {
+		// A sequence item, holding a mapping whose first entry is on the same line as the dash.
+		text := strings.TrimRight(e.lines[line], " \t\r")
+		prefix := prefixPattern.FindString(text)
+		if len(prefix) == len(text) {
+			return 0, 0, fmt.Errorf("field %s is not a mapping", p)
+		}
+		dash := len(text) - len(strings.TrimLeft(text, " "))
+		return len(prefix), e.blockEnd(line, dash, true), nil
+	}
+
+	parent, ok := e.parse(line)
+	if !ok || !parent.isBlock() {
+		return 0, 0, fmt.Errorf("field %s is not a block mapping", p)
+	}
+	end := e.blockEnd(line, parent.column(), false)
+	if end == line+1 {
+		// The mapping is empty, the new entry is its first one.
+		parent.value = ""
+		e.lines[line] = parent.String()
+		return parent.column() + 2, end, nil
+	}
+	for i := line + 1; i < end; i++ {
+		if child, ok := e.parse(i); ok {
+			return child.column(), end, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("field %s is not a block mapping", p)
+}
+
+// remove removes a mapping entry and its value.
+func (e *editor) remove(tokens []string) error {
+	f, err := e.find(tokens)
+	if err != nil {
+		return err
+	}
+	end := e.blockEnd(f.line, f.column(), false)
+
+	dashes := strings.TrimLeft(f.prefix, " ")
+	if dashes == "" {
+		// Comments right above the entry are about it, remove them as well.
+		start := f.line
+		for start > 0 {
+			text := strings.TrimRight(e.lines[start-1], " \t\r")
+			content := strings.TrimLeft(text, " ")
+			if !strings.HasPrefix(content, "#") || len(text)-len(content) != f.column() {
+				break
+			}
+			start--
+		}
+		e.delete(start, end)
+	} else {
+		// The entry starts a sequence item. Move the next entry of the item to the line of the dash, or
+		// leave an empty mapping if there is none.
+		next := -1
+		for i := end; i < len(e.lines); i++ {
+			text := strings.TrimRight(e.lines[i], " \t\r")
+			content := strings.TrimLeft(text, " ")
+			if content == "" || strings.HasPrefix(content, "#") {
+				continue
+			}
+			if len(text)-len(content) == f.column() {
+				next = i
+			}
+			break
+		}
+		if next >= 0 {
+			e.lines[f.line] = f.prefix + strings.TrimLeft(e.lines[next], " ")
+			e.delete(next, next+1)
+		} else {
+			e.lines[f.line] = f.prefix + "{}"
+		}
+		e.delete(f.line+1, end)
+	}
+
+	// Keep the parent a mapping if it became empty.
+	if len(tokens) > 1 {
+		if parent, err := e.find(tokens[:len(tokens)-1]); err == nil && parent.value == "" &&
+			e.blockEnd(parent.line, parent.column(), false) == parent.line+1 {
+			parent.value = "{}"
+			e.lines[parent.line] = parent.String()
+		}
+	}
+	return nil
+}
+
+func (e *editor) insert(at int, lines []string) {
+	e.lines = append(e.lines[:at], append(lines, e.lines[at:]...)...)
+}
+
+func (e *editor) delete(from, to int) {
+	if to > from {
+		e.lines = append(e.lines[:from], e.lines[to:]...)
+	}
+}
+
+// render returns the lines of a mapping entry.
+func render(key string, value interface{}, column int) ([]string, error) {
+	k, err := scalar(key)
+	if err != nil {
+		return nil, err
+	}
+	indent := strings.Repeat(" ", column)
+
+	if isScalar(value) {
+		v, err := scalar(value)
+		if err != nil {
+			return nil, err
+		}
+		return []string{indent + k + ": " + v}, nil
+	}
+
+	children, err := renderChildren(value, column+2)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{indent + k + ":"}, children...), nil
+}
+
+// renderChildren returns the lines of the entries of a mapping.
+func renderChildren(value interface{}, column int) ([]string, error) {
+	entries := make(map[string]interface{})
+	switch v := value.(type) {
+	case map[string]string:
+		for k, v := range v {
+			entries[k] = v
+		}
+	case map[string]interface{}:
+		entries = v
+	default:
+		return nil, fmt.Errorf("unsupported value %v", value)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, k := range keys {
+		l, err := render(k, entries[k], column)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, l...)
+	}
+	return lines, nil
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case map[string]string, map[string]interface{}:
+		return false
+	}
+	return true
+}
+
+// scalar formats a scalar value, quoting strings that would otherwise be read as another type.
+func scalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case rawValue:
+		return string(v), nil
+	case string:
+		if plainPattern.MatchString(v) && !ambiguous(v) {
+			return v, nil
+		}
+		return strconv.Quote(v), nil
+	case bool, int, int32, int64, float64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", value)
+}
+
+// ambiguous returns true if a plain string would be read as a boolean, null or number.
+func ambiguous(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return true
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -0,0 +1,318 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fix
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+)
+
+const service = `# The details service
+apiVersion: v1
+kind: Service
+metadata:
+  name: details
+spec:
+  ports:
+  - name: foo # the main port
+    port: 8080
+  - port: 9090
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: staging
+  labels:
+    team: a
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: production
+`
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: fortio
+  annotations:
+    # Belongs to the pod template
+    sidecar.istio.io/inject: "false"
+spec:
+  template:
+    metadata:
+      annotations:
+        # The annotation should be here
+      labels:
+        app: fortio
+`
+
+func TestApplyPatch(t *testing.T) {
+	cases := []struct {
+		name     string
+		content  string
+		document int
+		patch    []diag.PatchOperation
+		expected string
+	}{
+		{
+			name:     "replace keeps comments",
+			content:  service,
+			document: 0,
+			patch:    []diag.PatchOperation{{Op: diag.PatchReplace, Path: "/spec/ports/0/name", Value: "http-foo"}},
+			expected: `# The details service
+apiVersion: v1
+kind: Service
+metadata:
+  name: details
+spec:
+  ports:
+  - name: http-foo # the main port
+    port: 8080
+  - port: 9090
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: staging
+  labels:
+    team: a
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: production
+`,
+		},
+		{
+			name:     "add to sequence item",
+			content:  service,
+			document: 0,
+			patch:    []diag.PatchOperation{{Op: diag.PatchAdd, Path: "/spec/ports/1/name", Value: "tcp-9090"}},
+			expected: `# The details service
+apiVersion: v1
+kind: Service
+metadata:
+  name: details
+spec:
+  ports:
+  - name: foo # the main port
+    port: 8080
+  - port: 9090
+    name: tcp-9090
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: staging
+  labels:
+    team: a
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: production
+`,
+		},
+		{
+			name:     "add to mapping",
+			content:  service,
+			document: 1,
+			patch:    []diag.PatchOperation{{Op: diag.PatchAdd, Path: "/metadata/labels/istio-injection", Value: "enabled"}},
+			expected: `# The details service
+apiVersion: v1
+kind: Service
+metadata:
+  name: details
+spec:
+  ports:
+  - name: foo # the main port
+    port: 8080
+  - port: 9090
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: staging
+  labels:
+    team: a
+    istio-injection: enabled
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: production
+`,
+		},
+		{
+			name:     "add mapping",
+			content:  service,
+			document: 2,
+			patch: []diag.PatchOperation{{
+				Op:    diag.PatchAdd,
+				Path:  "/metadata/labels",
+				Value: map[string]string{"istio-injection": "enabled"},
+			}},
+			expected: `# The details service
+apiVersion: v1
+kind: Service
+metadata:
+  name: details
+spec:
+  ports:
+  - name: foo # the main port
+    port: 8080
+  - port: 9090
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: staging
+  labels:
+    team: a
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: production
+  labels:
+    istio-injection: enabled
+`,
+		},
+		{
+			name:    "move",
+			content: deployment,
+			patch: []diag.PatchOperation{{
+				Op:   diag.PatchMove,
+				From: "/metadata/annotations/sidecar.istio.io~1inject",
+				Path: "/spec/template/metadata/annotations/sidecar.istio.io~1inject",
+			}},
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: fortio
+  annotations: {}
+spec:
+  template:
+    metadata:
+      annotations:
+        sidecar.istio.io/inject: "false"
+        # The annotation should be here
+      labels:
+        app: fortio
+`,
+		},
+		{
+			name:    "fill empty mapping",
+			content: deployment,
+			patch: []diag.PatchOperation{
+				{Op: diag.PatchRemove, Path: "/metadata/annotations/sidecar.istio.io~1inject"},
+				{
+					Op:    diag.PatchAdd,
+					Path:  "/spec/template/metadata/annotations",
+					Value: map[string]string{"sidecar.istio.io/inject": "false"},
+				},
+			},
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: fortio
+  annotations: {}
+spec:
+  template:
+    metadata:
+      annotations:
+        sidecar.istio.io/inject: "false"
+        # The annotation should be here
+      labels:
+        app: fortio
+`,
+		},
+		{
+			name:     "remove first entry of sequence item",
+			content:  service,
+			document: 0,
+			patch:    []diag.PatchOperation{{Op: diag.PatchRemove, Path: "/spec/ports/0/name"}},
+			expected: `# The details service
+apiVersion: v1
+kind: Service
+metadata:
+  name: details
+spec:
+  ports:
+  - port: 8080
+  - port: 9090
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: staging
+  labels:
+    team: a
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: production
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			out, err := ApplyPatch([]byte(c.content), c.document, c.patch)
+			g.Expect(err).To(BeNil())
+			g.Expect(string(out)).To(Equal(c.expected))
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		document int
+		patch    []diag.PatchOperation
+	}{
+		{
+			name:     "missing document",
+			document: 3,
+			patch:    []diag.PatchOperation{{Op: diag.PatchRemove, Path: "/metadata/name"}},
+		},
+		{
+			name:  "missing parent",
+			patch: []diag.PatchOperation{{Op: diag.PatchAdd, Path: "/metadata/labels/app", Value: "details"}},
+		},
+		{
+			name:  "missing field",
+			patch: []diag.PatchOperation{{Op: diag.PatchReplace, Path: "/metadata/namespace", Value: "default"}},
+		},
+		{
+			name:  "unsupported operation",
+			patch: []diag.PatchOperation{{Op: "copy", From: "/metadata/name", Path: "/metadata/generateName"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			_, err := ApplyPatch([]byte(service), c.document, c.patch)
+			g.Expect(err).NotTo(BeNil())
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package graph builds the dependency graph of the workloads and services of the mesh, from the Istio
+// configuration and from the requests observed by Prometheus.
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// NodeKind is the kind of a node of the graph.
+type NodeKind string
+
+const (
+	// WorkloadNode is a workload, identified by its name and namespace.
+	WorkloadNode NodeKind = "workload"
+	// ServiceNode is the host of a Kubernetes Service or of a ServiceEntry inside the mesh.
+	ServiceNode NodeKind = "service"
+	// ExternalNode is the host of a MESH_EXTERNAL ServiceEntry.
+	ExternalNode NodeKind = "external"
+)
+
+// EdgeStatus tells whether an edge is configured, observed, or both.
+type EdgeStatus string
+
+const (
+	// Configured edges are configured; the traffic is not known.
+	Configured EdgeStatus = "configured"
+	// Confirmed edges are configured and observed.
+	Confirmed EdgeStatus = "confirmed"
+	// Unobserved edges are configured, but no requests were observed.
+	Unobserved EdgeStatus = "unobserved"
+	// Unconfigured edges are observed, but no configuration declares them.
+	Unconfigured EdgeStatus = "unconfigured"
+)
+
+// Node is a node of the graph.
+type Node struct {
+	// ID is the name.namespace of a workload, or a host.
+	ID        string   `json:"id"`
+	Kind      NodeKind `json:"kind"`
+	Namespace string   `json:"namespace,omitempty"`
+}
+
+// Edge is a dependency of a workload on a service.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Config lists the configurations declaring the edge, e.g. "Sidecar default/default".
+	Config []string `json:"config,omitempty"`
+	// RPS is the observed rate of requests.
+	RPS    float64    `json:"rps"`
+	Status EdgeStatus `json:"status"`
+
+	observed bool
+}
+
+type edgeKey struct {
+	from, to string
+}
+
+// Graph is a dependency graph.
+type Graph struct {
+	nodes map[string]Node
+	edges map[edgeKey]*Edge
+	// the kind of the hosts of the configuration
+	hosts map[string]NodeKind
+	// whether the observed traffic was added to the graph
+	traffic bool
+}
+
+// New returns an empty graph.
+func New() *Graph {
+	return &Graph{
+		nodes: map[string]Node{},
+		edges: map[edgeKey]*Edge{},
+		hosts: map[string]NodeKind{},
+	}
+}
+
+func (g *Graph) edge(from, to Node) *Edge {
+	g.nodes[from.ID] = from
+	g.nodes[to.ID] = to
+	key := edgeKey{from.ID, to.ID}
+	e, ok := g.edges[key]
+	if !ok {
+		e = &Edge{From: from.ID, To: to.ID}
+		g.edges[key] = e
+	}
+	return e
+}
+
+func (g *Graph) addConfigured(from, to Node, config string) {
+	e := g.edge(from, to)
+	for _, c := range e.Config {
+		if c == config {
+			return
+		}
+	}
+	e.Config = append(e.Config, config)
+	sort.Strings(e.Config)
+}
+
+func (g *Graph) addObserved(from, to Node, rps float64) {
+	e := g.edge(from, to)
+	e.observed = true
+	e.RPS += rps
+}
+
+// hostNode returns the node of a host.
+func (g *Graph) hostNode(host string) Node {
+	kind, ok := g.hosts[host]
+	if !ok {
+		kind = ServiceNode
+	}
+	n := Node{ID: host, Kind: kind}
+	if kind == ServiceNode {
+		// <name>.<namespace>.svc.<domain>
+		if parts := strings.Split(host, "."); len(parts) > 3 && parts[2] == "svc" {
+			n.Namespace = parts[1]
+		}
+	}
+	return n
+}
+
+func workloadNode(name, namespace string) Node {
+	return Node{ID: name + "." + namespace, Kind: WorkloadNode, Namespace: namespace}
+}
+
+// Nodes returns the nodes of the graph, sorted by ID.
+func (g *Graph) Nodes() []Node {
+	nodes := make([]Node, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// Edges returns the edges of the graph, along with their status, sorted by source and destination.
+func (g *Graph) Edges() []Edge {
+	edges := make([]Edge, 0, len(g.edges))
+	for _, e := range g.edges {
+		edge := *e
+		switch {
+		case !g.traffic:
+			edge.Status = Configured
+		case len(e.Config) == 0:
+			edge.Status = Unconfigured
+		case !e.observed:
+			edge.Status = Unobserved
+		default:
+			edge.Status = Confirmed
+		}
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// Namespace returns the subgraph of the edges from or to the nodes of the namespace.
+func (g *Graph) Namespace(namespace string) *Graph {
+	sub := New()
+	sub.traffic = g.traffic
+	for key, e := range g.edges {
+		from, to := g.nodes[key.from], g.nodes[key.to]
+		if from.Namespace != namespace && to.Namespace != namespace {
+			continue
+		}
+		sub.nodes[from.ID] = from
+		sub.nodes[to.ID] = to
+		sub.edges[key] = e
+	}
+	return sub
+}
@@ -0,0 +1,210 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	prommodel "github.com/prometheus/common/model"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func meshPod(name, namespace string, labels map[string]string) v1.Pod {
+	controller := true
+	labels["pod-template-hash"] = "7bbd79f8fd"
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:         name + "-7bbd79f8fd-k6j79",
+			GenerateName: name + "-7bbd79f8fd-",
+			Namespace:    namespace,
+			Labels:       labels,
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ReplicaSet", Name: name + "-7bbd79f8fd", Controller: &controller},
+			},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}, {Name: "istio-proxy"}}},
+	}
+}
+
+func service(name string) v1.Service {
+	return v1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+}
+
+func destination(host string, weight int32) *v1alpha3.HTTPRouteDestination {
+	return &v1alpha3.HTTPRouteDestination{Destination: &v1alpha3.Destination{Host: host}, Weight: weight}
+}
+
+func testConfig() Config {
+	meta := func(name, namespace string) model.ConfigMeta {
+		return model.ConfigMeta{Name: name, Namespace: namespace}
+	}
+	return Config{
+		Pods: []v1.Pod{
+			meshPod("productpage-v1", "default", map[string]string{"app": "productpage"}),
+			meshPod("reviews-v1", "default", map[string]string{"app": "reviews"}),
+			meshPod("istio-ingressgateway", "istio-system", map[string]string{"istio": "ingressgateway"}),
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "mysql", Namespace: "default"},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "mysql"}}},
+			},
+		},
+		Services: []v1.Service{service("productpage"), service("reviews"), service("ratings")},
+		Gateways: []model.Config{{
+			ConfigMeta: meta("bookinfo-gateway", "default"),
+			Spec:       &v1alpha3.Gateway{Selector: map[string]string{"istio": "ingressgateway"}},
+		}},
+		Sidecars: []model.Config{
+			{
+				ConfigMeta: meta("productpage", "default"),
+				Spec: &v1alpha3.Sidecar{
+					WorkloadSelector: &v1alpha3.WorkloadSelector{Labels: map[string]string{"app": "productpage"}},
+					Egress: []*v1alpha3.IstioEgressListener{
+						{Hosts: []string{"./reviews.default.svc.cluster.local", "istio-system/*"}},
+					},
+				},
+			},
+			{
+				ConfigMeta: meta("default", "default"),
+				Spec: &v1alpha3.Sidecar{
+					Egress: []*v1alpha3.IstioEgressListener{{Hosts: []string{"*/*"}}},
+				},
+			},
+		},
+		ServiceEntries: []model.Config{{
+			ConfigMeta: meta("httpbin", "default"),
+			Spec: &v1alpha3.ServiceEntry{
+				Hosts:    []string{"httpbin.org"},
+				Location: v1alpha3.ServiceEntry_MESH_EXTERNAL,
+				ExportTo: []string{"."},
+			},
+		}},
+		VirtualServices: []model.Config{
+			{
+				ConfigMeta: meta("bookinfo", "default"),
+				Spec: &v1alpha3.VirtualService{
+					Hosts:    []string{"*"},
+					Gateways: []string{"bookinfo-gateway"},
+					Http:     []*v1alpha3.HTTPRoute{{Route: []*v1alpha3.HTTPRouteDestination{destination("productpage", 0)}}},
+				},
+			},
+			{
+				ConfigMeta: meta("reviews", "default"),
+				Spec: &v1alpha3.VirtualService{
+					Hosts: []string{"reviews"},
+					Http: []*v1alpha3.HTTPRoute{{Route: []*v1alpha3.HTTPRouteDestination{
+						destination("reviews", 50),
+						destination("ratings", 50),
+					}}},
+				},
+			},
+		},
+		RootNamespace: "istio-system",
+	}
+}
+
+func sample(workload, namespace, destination string, rps float64) *prommodel.Sample {
+	return &prommodel.Sample{
+		Metric: prommodel.Metric{
+			sourceWorkloadLabel:          prommodel.LabelValue(workload),
+			sourceWorkloadNamespaceLabel: prommodel.LabelValue(namespace),
+			destServiceLabel:             prommodel.LabelValue(destination),
+		},
+		Value: prommodel.SampleValue(rps),
+	}
+}
+
+func testTraffic() prommodel.Vector {
+	return prommodel.Vector{
+		sample("istio-ingressgateway", "istio-system", "productpage.default.svc.cluster.local", 2.5),
+		sample("productpage-v1", "default", "reviews.default.svc.cluster.local", 1),
+		sample("reviews-v1", "default", "ratings.default.svc.cluster.local", 0.5),
+		sample("unknown", "unknown", "productpage.default.svc.cluster.local", 1),
+	}
+}
+
+func renderEdges(edges []Edge) []string {
+	var out []string
+	for _, e := range edges {
+		out = append(out, fmt.Sprintf("%s -> %s %s %.2f %v", e.From, e.To, e.Status, e.RPS, e.Config))
+	}
+	return out
+}
+
+func TestWorkloadName(t *testing.T) {
+	pod := meshPod("productpage-v1", "default", map[string]string{})
+	if got := WorkloadName(&pod); got != "productpage-v1" {
+		t.Errorf("got workload %q, want productpage-v1", got)
+	}
+	pod.OwnerReferences[0].Kind = "StatefulSet"
+	pod.OwnerReferences[0].Name = "mysql"
+	if got := WorkloadName(&pod); got != "mysql" {
+		t.Errorf("got workload %q, want mysql", got)
+	}
+	pod.GenerateName = ""
+	if got := WorkloadName(&pod); got != pod.Name {
+		t.Errorf("got workload %q, want %s", got, pod.Name)
+	}
+}
+
+func TestAddConfig(t *testing.T) {
+	g := New()
+	g.AddConfig(testConfig())
+	want := []string{
+		"istio-ingressgateway.istio-system -> productpage.default.svc.cluster.local configured 0.00 " +
+			"[VirtualService default/bookinfo]",
+		"productpage-v1.default -> httpbin.org configured 0.00 [ServiceEntry default/httpbin]",
+		"productpage-v1.default -> ratings.default.svc.cluster.local configured 0.00 [VirtualService default/reviews]",
+		"productpage-v1.default -> reviews.default.svc.cluster.local configured 0.00 " +
+			"[Sidecar default/productpage VirtualService default/reviews]",
+		"reviews-v1.default -> httpbin.org configured 0.00 [ServiceEntry default/httpbin]",
+	}
+	if got := renderEdges(g.Edges()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got edges\n%v\nwant\n%v", got, want)
+	}
+	if kind := g.nodes["httpbin.org"].Kind; kind != ExternalNode {
+		t.Errorf("got kind %v for httpbin.org, want %v", kind, ExternalNode)
+	}
+}
+
+func TestAddTraffic(t *testing.T) {
+	g := New()
+	g.AddConfig(testConfig())
+	g.addTraffic(testTraffic())
+	want := []string{
+		"istio-ingressgateway.istio-system -> productpage.default.svc.cluster.local confirmed 2.50 " +
+			"[VirtualService default/bookinfo]",
+		"productpage-v1.default -> httpbin.org unobserved 0.00 [ServiceEntry default/httpbin]",
+		"productpage-v1.default -> ratings.default.svc.cluster.local unobserved 0.00 [VirtualService default/reviews]",
+		"productpage-v1.default -> reviews.default.svc.cluster.local confirmed 1.00 " +
+			"[Sidecar default/productpage VirtualService default/reviews]",
+		"reviews-v1.default -> httpbin.org unobserved 0.00 [ServiceEntry default/httpbin]",
+		"reviews-v1.default -> ratings.default.svc.cluster.local unconfigured 0.50 []",
+	}
+	if got := renderEdges(g.Edges()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got edges\n%v\nwant\n%v", got, want)
+	}
+
+	want = want[:1]
+	if got := renderEdges(g.Namespace("istio-system").Edges()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got edges of namespace istio-system\n%v\nwant\n%v", got, want)
+	}
+}
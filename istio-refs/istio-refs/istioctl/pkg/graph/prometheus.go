@@ -0,0 +1,65 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	prommodel "github.com/prometheus/common/model"
+)
+
+const (
+	requestsMetric               = "istio_requests_total"
+	sourceWorkloadLabel          = "source_workload"
+	sourceWorkloadNamespaceLabel = "source_workload_namespace"
+	destServiceLabel             = "destination_service"
+	unknown                      = "unknown"
+)
+
+// AddTraffic adds the edges observed by Prometheus over the window to the graph, with their rate of requests.
+// The requests are the ones reported by the proxies of the clients, so the requests from outside the mesh are
+// ignored.
+func (g *Graph) AddTraffic(api promv1.API, window time.Duration, now time.Time) error {
+	query := fmt.Sprintf(`sum(rate(%s{reporter="source"}[%s])) by (%s,%s,%s)`, requestsMetric,
+		prommodel.Duration(window), sourceWorkloadLabel, sourceWorkloadNamespaceLabel, destServiceLabel)
+	val, _, err := api.Query(context.Background(), query, now)
+	if err != nil {
+		return fmt.Errorf("query() failure for '%s': %v", query, err)
+	}
+	vector, ok := val.(prommodel.Vector)
+	if !ok {
+		return fmt.Errorf("bad metric value type returned for query '%s'", query)
+	}
+	g.addTraffic(vector)
+	return nil
+}
+
+func (g *Graph) addTraffic(vector prommodel.Vector) {
+	g.traffic = true
+	for _, sample := range vector {
+		name := string(sample.Metric[sourceWorkloadLabel])
+		namespace := string(sample.Metric[sourceWorkloadNamespaceLabel])
+		dest := string(sample.Metric[destServiceLabel])
+		rps := float64(sample.Value)
+		if name == "" || name == unknown || dest == "" || math.IsNaN(rps) || rps == 0 {
+			continue
+		}
+		g.addObserved(workloadNode(name, namespace), g.hostNode(dest), rps)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Output formats of the graph.
+const (
+	DOT     = "dot"
+	Mermaid = "mermaid"
+	JSON    = "json"
+)
+
+// Formats lists the output formats of the graph.
+var Formats = []string{DOT, Mermaid, JSON}
+
+// Write writes the graph in the format.
+func Write(w io.Writer, g *Graph, format string) error {
+	switch format {
+	case DOT:
+		return WriteDOT(w, g)
+	case Mermaid:
+		return WriteMermaid(w, g)
+	case JSON:
+		return WriteJSON(w, g)
+	default:
+		return fmt.Errorf("unknown output format %q, expected one of %v", format, Formats)
+	}
+}
+
+// edgeLabel returns the rate of requests of the edge, followed by its status when it is highlighted.
+func edgeLabel(e Edge) string {
+	var parts []string
+	if e.Status != Configured && e.Status != Unobserved {
+		parts = append(parts, fmt.Sprintf("%.2f rps", e.RPS))
+	}
+	if e.Status == Unobserved || e.Status == Unconfigured {
+		parts = append(parts, string(e.Status))
+	}
+	return strings.Join(parts, ", ")
+}
+
+var dotNodeShapes = map[NodeKind]string{
+	WorkloadNode: "box",
+	ServiceNode:  "ellipse",
+	ExternalNode: "octagon",
+}
+
+var dotEdgeStyles = map[EdgeStatus]string{
+	Unobserved:   ` style=dashed color=orange`,
+	Unconfigured: ` color=red penwidth=2`,
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. The unobserved edges are orange and dashed, the
+// unconfigured edges are red.
+func WriteDOT(w io.Writer, g *Graph) error {
+	var b strings.Builder
+	b.WriteString("digraph mesh {\n  rankdir=LR;\n")
+	for _, n := range g.Nodes() {
+		fmt.Fprintf(&b, "  %q [shape=%s];\n", n.ID, dotNodeShapes[n.Kind])
+	}
+	for _, e := range g.Edges() {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q%s];\n", e.From, e.To, edgeLabel(e), dotEdgeStyles[e.Status])
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var mermaidNodeShapes = map[NodeKind][2]string{
+	WorkloadNode: {"[", "]"},
+	ServiceNode:  {"(", ")"},
+	ExternalNode: {"{{", "}}"},
+}
+
+var mermaidEdgeStyles = map[EdgeStatus]string{
+	Unobserved:   "stroke:orange,stroke-dasharray:5",
+	Unconfigured: "stroke:red,stroke-width:2px",
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart. The unobserved edges are orange and dashed, the
+// unconfigured edges are red.
+func WriteMermaid(w io.Writer, g *Graph) error {
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	ids := map[string]string{}
+	for i, n := range g.Nodes() {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+		shape := mermaidNodeShapes[n.Kind]
+		fmt.Fprintf(&b, "  %s%s%q%s\n", ids[n.ID], shape[0], n.ID, shape[1])
+	}
+	var styles []string
+	for i, e := range g.Edges() {
+		arrow := "-->"
+		if label := edgeLabel(e); label != "" {
+			arrow += "|" + label + "|"
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+		if style, ok := mermaidEdgeStyles[e.Status]; ok {
+			styles = append(styles, fmt.Sprintf("  linkStyle %d %s\n", i, style))
+		}
+	}
+	b.WriteString(strings.Join(styles, ""))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the nodes and the edges of the graph as JSON.
+func WriteJSON(w io.Writer, g *Graph) error {
+	out, err := json.MarshalIndent(struct {
+		Nodes []Node `json:"nodes"`
+		Edges []Edge `json:"edges"`
+	}{g.Nodes(), g.Edges()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testGraph() *Graph {
+	g := New()
+	g.hosts["httpbin.org"] = ExternalNode
+	productpage := workloadNode("productpage-v1", "default")
+	reviews := workloadNode("reviews-v1", "default")
+	g.addConfigured(productpage, g.hostNode("reviews.default.svc.cluster.local"), "Sidecar default/productpage")
+	g.addConfigured(productpage, g.hostNode("httpbin.org"), "ServiceEntry default/httpbin")
+	g.addTraffic(nil)
+	g.addObserved(productpage, g.hostNode("reviews.default.svc.cluster.local"), 1.5)
+	g.addObserved(reviews, g.hostNode("ratings.default.svc.cluster.local"), 0.25)
+	return g
+}
+
+func TestWrite(t *testing.T) {
+	cases := []struct {
+		format string
+		want   string
+	}{
+		{DOT, `digraph mesh {
+  rankdir=LR;
+  "httpbin.org" [shape=octagon];
+  "productpage-v1.default" [shape=box];
+  "ratings.default.svc.cluster.local" [shape=ellipse];
+  "reviews-v1.default" [shape=box];
+  "reviews.default.svc.cluster.local" [shape=ellipse];
+  "productpage-v1.default" -> "httpbin.org" [label="unobserved" style=dashed color=orange];
+  "productpage-v1.default" -> "reviews.default.svc.cluster.local" [label="1.50 rps"];
+  "reviews-v1.default" -> "ratings.default.svc.cluster.local" [label="0.25 rps, unconfigured" color=red penwidth=2];
+}
+`},
+		{Mermaid, `graph LR
+  n0{{"httpbin.org"}}
+  n1["productpage-v1.default"]
+  n2("ratings.default.svc.cluster.local")
+  n3["reviews-v1.default"]
+  n4("reviews.default.svc.cluster.local")
+  n1 -->|unobserved| n0
+  n1 -->|1.50 rps| n4
+  n3 -->|0.25 rps, unconfigured| n2
+  linkStyle 0 stroke:orange,stroke-dasharray:5
+  linkStyle 2 stroke:red,stroke-width:2px
+`},
+		{JSON, `{
+  "nodes": [
+    {
+      "id": "httpbin.org",
+      "kind": "external"
+    },
+    {
+      "id": "productpage-v1.default",
+      "kind": "workload",
+      "namespace": "default"
+    },
+    {
+      "id": "ratings.default.svc.cluster.local",
+      "kind": "service",
+      "namespace": "default"
+    },
+    {
+      "id": "reviews-v1.default",
+      "kind": "workload",
+      "namespace": "default"
+    },
+    {
+      "id": "reviews.default.svc.cluster.local",
+      "kind": "service",
+      "namespace": "default"
+    }
+  ],
+  "edges": [
+    {
+      "from": "productpage-v1.default",
+      "to": "httpbin.org",
+      "config": [
+        "ServiceEntry default/httpbin"
+      ],
+      "rps": 0,
+      "status": "unobserved"
+    },
+    {
+      "from": "productpage-v1.default",
+      "to": "reviews.default.svc.cluster.local",
+      "config": [
+        "Sidecar default/productpage"
+      ],
+      "rps": 1.5,
+      "status": "confirmed"
+    },
+    {
+      "from": "reviews-v1.default",
+      "to": "ratings.default.svc.cluster.local",
+      "rps": 0.25,
+      "status": "unconfigured"
+    }
+  ]
+}
+`},
+	}
+	for _, c := range cases {
+		t.Run(c.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Write(&out, testGraph(), c.format); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != c.want {
+				t.Errorf("got\n%s\nwant\n%s", out.String(), c.want)
+			}
+		})
+	}
+
+	if err := Write(&bytes.Buffer{}, testGraph(), "svg"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+)
+
+const (
+	defaultDomainSuffix = "cluster.local"
+	proxyContainer      = "istio-proxy"
+	meshGateway         = "mesh"
+)
+
+// Config is the configuration the configured edges of the graph are built from.
+type Config struct {
+	VirtualServices []model.Config
+	Gateways        []model.Config
+	Sidecars        []model.Config
+	ServiceEntries  []model.Config
+
+	Services []v1.Service
+	Pods     []v1.Pod
+
+	// RootNamespace is the namespace of the Sidecar applying to the workloads of namespaces without Sidecar.
+	RootNamespace string
+	// DomainSuffix is the domain of the Kubernetes services, cluster.local by default.
+	DomainSuffix string
+}
+
+// workload is a workload of the mesh, with the labels of its pods.
+type workload struct {
+	node   Node
+	labels k8s_labels.Set
+}
+
+// WorkloadName returns the name of the workload of the pod, as reported in the telemetry: the name of its
+// deployment or controller, or the pod name.
+func WorkloadName(pod *v1.Pod) string {
+	if pod.GenerateName == "" {
+		return pod.Name
+	}
+	for _, ref := range pod.OwnerReferences {
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		hash := pod.Labels["pod-template-hash"]
+		if ref.Kind == "ReplicaSet" && hash != "" && strings.HasSuffix(ref.Name, "-"+hash) {
+			return strings.TrimSuffix(ref.Name, "-"+hash)
+		}
+		return ref.Name
+	}
+	return pod.Name
+}
+
+// meshWorkloads returns the workloads of the pods with a sidecar, sorted by ID.
+func meshWorkloads(pods []v1.Pod) []workload {
+	byID := map[string]workload{}
+	for i := range pods {
+		pod := &pods[i]
+		hasProxy := false
+		for _, c := range pod.Spec.Containers {
+			if c.Name == proxyContainer {
+				hasProxy = true
+				break
+			}
+		}
+		if !hasProxy {
+			continue
+		}
+		n := workloadNode(WorkloadName(pod), pod.Namespace)
+		if _, ok := byID[n.ID]; !ok {
+			byID[n.ID] = workload{node: n, labels: k8s_labels.Set(pod.Labels)}
+		}
+	}
+	workloads := make([]workload, 0, len(byID))
+	for _, w := range byID {
+		workloads = append(workloads, w)
+	}
+	sort.Slice(workloads, func(i, j int) bool { return workloads[i].node.ID < workloads[j].node.ID })
+	return workloads
+}
+
+// sortConfigs sorts configs the way Pilot prioritizes them: oldest first, then by namespace and name.
+func sortConfigs(configs []model.Config) []model.Config {
+	sorted := append([]model.Config(nil), configs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].CreationTimestamp.Equal(sorted[j].CreationTimestamp) {
+			return sorted[i].CreationTimestamp.Before(sorted[j].CreationTimestamp)
+		}
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+func configRef(kind string, c model.Config) string {
+	return kind + " " + c.Namespace + "/" + c.Name
+}
+
+// AddConfig adds the edges declared by the configuration to the graph:
+//   - from the workloads to the hosts of the egress listeners of their Sidecar, except the whole mesh,
+//   - from the workloads of the gateways to the destinations of the VirtualServices bound to them,
+//   - from the workloads depending on the hosts of a mesh VirtualService to its destinations,
+//   - from the workloads of a namespace to the hosts of the ServiceEntries exported only to it.
+func (g *Graph) AddConfig(c Config) {
+	domain := c.DomainSuffix
+	if domain == "" {
+		domain = defaultDomainSuffix
+	}
+
+	// namespace of each host of the configuration
+	hostNamespaces := map[string]string{}
+	for _, svc := range c.Services {
+		h := svc.Name + "." + svc.Namespace + ".svc." + domain
+		g.hosts[h] = ServiceNode
+		hostNamespaces[h] = svc.Namespace
+	}
+	for _, se := range c.ServiceEntries {
+		spec := se.Spec.(*v1alpha3.ServiceEntry)
+		kind := ServiceNode
+		if spec.Location == v1alpha3.ServiceEntry_MESH_EXTERNAL {
+			kind = ExternalNode
+		}
+		for _, h := range spec.Hosts {
+			g.hosts[h] = kind
+			hostNamespaces[h] = se.Namespace
+		}
+	}
+	hosts := make([]string, 0, len(hostNamespaces))
+	for h := range hostNamespaces {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+
+	workloads := meshWorkloads(c.Pods)
+	sidecars := sortConfigs(c.Sidecars)
+	for _, w := range workloads {
+		sidecar := workloadSidecar(w, sidecars, c.RootNamespace)
+		if sidecar == nil {
+			continue
+		}
+		ref := configRef("Sidecar", *sidecar)
+		for _, egress := range sidecar.Spec.(*v1alpha3.Sidecar).Egress {
+			for _, egressHost := range egress.Hosts {
+				for _, h := range egressHosts(egressHost, sidecar.Namespace, hosts, hostNamespaces) {
+					g.addConfigured(w.node, g.hostNode(h), ref)
+				}
+			}
+		}
+	}
+
+	gateways := map[string]model.Config{}
+	for _, gw := range c.Gateways {
+		gateways[gw.Namespace+"/"+gw.Name] = gw
+	}
+	for _, vs := range sortConfigs(c.VirtualServices) {
+		g.addVirtualService(vs, gateways, workloads, domain)
+	}
+
+	for _, se := range c.ServiceEntries {
+		spec := se.Spec.(*v1alpha3.ServiceEntry)
+		if len(spec.ExportTo) != 1 || spec.ExportTo[0] != "." {
+			continue
+		}
+		for _, w := range workloads {
+			if w.node.Namespace != se.Namespace {
+				continue
+			}
+			for _, h := range spec.Hosts {
+				g.addConfigured(w.node, g.hostNode(h), configRef("ServiceEntry", se))
+			}
+		}
+	}
+}
+
+// workloadSidecar returns the Sidecar applying to the workload: the oldest Sidecar of its namespace selecting it,
+// else the Sidecar of its namespace without selector, else the Sidecar of the root namespace without selector.
+func workloadSidecar(w workload, sidecars []model.Config, rootNamespace string) *model.Config {
+	var nsDefault, rootDefault *model.Config
+	for i := range sidecars {
+		sidecar := &sidecars[i]
+		selector := sidecar.Spec.(*v1alpha3.Sidecar).WorkloadSelector
+		switch {
+		case sidecar.Namespace == w.node.Namespace && selector != nil:
+			if k8s_labels.SelectorFromSet(selector.Labels).Matches(w.labels) {
+				return sidecar
+			}
+		case sidecar.Namespace == w.node.Namespace:
+			if nsDefault == nil {
+				nsDefault = sidecar
+			}
+		case sidecar.Namespace == rootNamespace && selector == nil:
+			if rootDefault == nil {
+				rootDefault = sidecar
+			}
+		}
+	}
+	if nsDefault != nil {
+		return nsDefault
+	}
+	return rootDefault
+}
+
+// egressHosts returns the hosts matching an egress host of a Sidecar, in the namespace/dnsName form. The egress
+// hosts matching the whole mesh are ignored, since they do not declare any dependency.
+func egressHosts(egressHost, sidecarNamespace string, hosts []string, hostNamespaces map[string]string) []string {
+	parts := strings.SplitN(egressHost, "/", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	ns, dnsName := parts[0], host.Name(parts[1])
+	switch ns {
+	case "~":
+		return nil
+	case ".":
+		ns = sidecarNamespace
+	case "*":
+		if dnsName == "*" {
+			return nil
+		}
+	}
+	var matches []string
+	for _, h := range hosts {
+		if ns != "*" && hostNamespaces[h] != ns {
+			continue
+		}
+		if host.Name(h).SubsetOf(dnsName) {
+			matches = append(matches, h)
+		}
+	}
+	return matches
+}
+
+// addVirtualService adds the edges to the destinations of the VirtualService, from the workloads of the gateways
+// it is bound to, and from the workloads depending on its hosts when it is bound to the mesh.
+func (g *Graph) addVirtualService(vs model.Config, gateways map[string]model.Config, workloads []workload,
+	domain string) {
+	spec := vs.Spec.(*v1alpha3.VirtualService)
+	meta := model.ConfigMeta{Namespace: vs.Namespace, Domain: domain}
+	ref := configRef("VirtualService", vs)
+
+	var destinations []Node
+	addDestination := func(d *v1alpha3.Destination) {
+		if d != nil {
+			destinations = append(destinations, g.hostNode(string(model.ResolveShortnameToFQDN(d.Host, meta))))
+		}
+	}
+	for _, route := range spec.Http {
+		for _, rd := range route.Route {
+			addDestination(rd.Destination)
+		}
+		addDestination(route.Mirror)
+	}
+	for _, route := range spec.Tls {
+		for _, rd := range route.Route {
+			addDestination(rd.Destination)
+		}
+	}
+	for _, route := range spec.Tcp {
+		for _, rd := range route.Route {
+			addDestination(rd.Destination)
+		}
+	}
+
+	var sources []Node
+	mesh := len(spec.Gateways) == 0
+	for _, gwName := range spec.Gateways {
+		if gwName == meshGateway {
+			mesh = true
+			continue
+		}
+		gw, ok := gateways[util.ResolveGatewayRef(gwName, vs.Namespace)]
+		if !ok {
+			continue
+		}
+		selector := gw.Spec.(*v1alpha3.Gateway).Selector
+		if len(selector) == 0 {
+			continue
+		}
+		for _, w := range workloads {
+			if k8s_labels.SelectorFromSet(selector).Matches(w.labels) {
+				sources = append(sources, w.node)
+			}
+		}
+	}
+	if mesh {
+		// The workloads depending on a host of the VirtualService depend on its destinations
+		var vsHosts []host.Name
+		for _, h := range spec.Hosts {
+			vsHosts = append(vsHosts, model.ResolveShortnameToFQDN(h, meta))
+		}
+		for key, e := range g.edges {
+			if len(e.Config) == 0 || g.nodes[key.from].Kind != WorkloadNode {
+				continue
+			}
+			for _, h := range vsHosts {
+				if host.Name(key.to).SubsetOf(h) {
+					sources = append(sources, g.nodes[key.from])
+					break
+				}
+			}
+		}
+	}
+
+	for _, from := range sources {
+		for _, to := range destinations {
+			g.addConfigured(from, to, ref)
+		}
+	}
+}
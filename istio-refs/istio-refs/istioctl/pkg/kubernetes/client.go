@@ -193,6 +193,27 @@ func (client *Client) ExtractExecResult(podName, podNamespace, container string,
 	return stdout.Bytes(), nil
 }
 
+// PodLogs retrieves the logs of a container in the specified pod
+func (client *Client) PodLogs(podName, podNamespace, container string, opts *v1.PodLogOptions) ([]byte, error) {
+	logOpts := &v1.PodLogOptions{}
+	if opts != nil {
+		logOpts = opts.DeepCopy()
+	}
+	logOpts.Container = container
+	res, err := client.Get().
+		Resource("pods").
+		Namespace(podNamespace).
+		Name(podName).
+		SubResource("log").
+		VersionedParams(logOpts, scheme.ParameterCodec).
+		Do().
+		Raw()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve logs of %v/%v %v container: %v", podName, podNamespace, container, err)
+	}
+	return res, nil
+}
+
 // GetIstioPods retrieves the pod objects for Istio deployments
 func (client *Client) GetIstioPods(namespace string, params map[string]string) ([]v1.Pod, error) {
 	req := client.Get().
@@ -434,3 +455,19 @@ func RunPortForwarder(fw *PortForward, readyFunc func(fw *PortForward) error) er
 		return nil
 	}
 }
+
+// StartPortForwarder runs the port forwarder in the background, returning once it is ready. Closing its StopChannel
+// stops it.
+func StartPortForwarder(fw *PortForward) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fw.Forwarder.ForwardPorts()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failure running port forward process: %v", err)
+	case <-fw.ReadyChannel:
+		return nil
+	}
+}
@@ -0,0 +1,515 @@
+// Copyright 2020 Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/util/gogoprotomarshal"
+)
+
+const (
+	meshConfigMapName   = "istio"
+	meshConfigMapKey    = "mesh"
+	injectConfigMapName = "istio-sidecar-injector"
+	injectConfigMapKey  = "config"
+	valuesConfigMapKey  = "values"
+
+	lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+	unsetValue = "<unset>"
+	// maxValueLength is the length above which the values of a difference are truncated.
+	maxValueLength = 60
+)
+
+var (
+	// driftCollections are the collections of the Istio CRDs compared across clusters. The MeshConfig is not a CRD.
+	driftCollections = collections.Istio.Remove(collections.IstioMeshV1Alpha1MeshConfig)
+
+	// serverFields are the fields set by the API server, which differ between clusters whatever the configuration.
+	serverFields = [][]string{
+		{"status"},
+		{"metadata", "uid"},
+		{"metadata", "resourceVersion"},
+		{"metadata", "generation"},
+		{"metadata", "creationTimestamp"},
+		{"metadata", "selfLink"},
+		{"metadata", "managedFields"},
+		{"metadata", "annotations", lastAppliedConfigAnnotation},
+	}
+)
+
+// configKey identifies a resource across the clusters of the mesh. The control plane resources have no namespace, as
+// the namespace of the control plane may differ between clusters.
+type configKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+func (k configKey) String() string {
+	if k.namespace == "" {
+		return fmt.Sprintf("%v %v", k.kind, k.name)
+	}
+	return fmt.Sprintf("%v %v/%v", k.kind, k.namespace, k.name)
+}
+
+func (k configKey) less(o configKey) bool {
+	if k.kind != o.kind {
+		return k.kind < o.kind
+	}
+	if k.namespace != o.namespace {
+		return k.namespace < o.namespace
+	}
+	return k.name < o.name
+}
+
+// clusterConfigs holds the normalized resources of a cluster in their canonical JSON form.
+type clusterConfigs map[configKey]interface{}
+
+// fieldDiff is the difference of a field of a resource between the reference cluster and another cluster.
+type fieldDiff struct {
+	path      string
+	reference string
+	value     string
+}
+
+// configDrift describes how a resource differs between the reference cluster and the other clusters of the mesh.
+type configDrift struct {
+	key configKey
+	// contexts of the clusters missing the resource of the reference cluster.
+	missing []string
+	// contexts of the clusters having a resource the reference cluster doesn't have.
+	extra []string
+	// differences of the resource with the reference cluster, by context.
+	diffs map[string][]fieldDiff
+}
+
+// normalizeConfig strips the fields set by the API server from a resource and converts its spec to its canonical
+// form, so that equivalent configurations compare equal.
+func normalizeConfig(s collection.Schema, u *unstructured.Unstructured) (interface{}, error) {
+	obj := u.DeepCopy().Object
+	for _, fields := range serverFields {
+		unstructured.RemoveNestedField(obj, fields...)
+	}
+	if annotations, _, _ := unstructured.NestedMap(obj, "metadata", "annotations"); len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj, "metadata", "annotations")
+	}
+
+	if spec, ok := obj["spec"]; ok {
+		pb, err := crd.FromJSONMap(s, spec)
+		if err != nil {
+			return nil, err
+		}
+		if obj["spec"], err = gogoprotomarshal.ToJSONMap(pb); err != nil {
+			return nil, err
+		}
+	}
+	return canonicalJSON(obj)
+}
+
+// canonicalJSON returns the generic JSON form of a value, e.g. with float64 numbers.
+func canonicalJSON(v interface{}) (interface{}, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(js, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Cluster) readIstioConfigs(env Environment, configs clusterConfigs) error {
+	client, err := env.CreateDynamicClient(c.Context)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range driftCollections.All() {
+		r := s.Resource()
+		gvr := schema.GroupVersionResource{Group: r.Group(), Version: r.Version(), Resource: r.Plural()}
+		list, err := client.Resource(gvr).Namespace(metav1.NamespaceAll).List(metav1.ListOptions{})
+		if err != nil {
+			// the CRD is not installed in this cluster
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("could not list %v: %v", r.Plural(), err)
+		}
+		for i := range list.Items {
+			item := &list.Items[i]
+			key := configKey{kind: r.Kind(), namespace: item.GetNamespace(), name: item.GetName()}
+			normalized, err := normalizeConfig(s, item)
+			if err != nil {
+				env.Errorf("error: could not normalize %v in cluster %v: %v\n", key, c, err)
+				continue
+			}
+			configs[key] = normalized
+		}
+	}
+	return nil
+}
+
+// readMeshConfig reads the MeshConfig of the control plane, with its defaults applied.
+func (c *Cluster) readMeshConfig(configs clusterConfigs) error {
+	cm, err := c.client.CoreV1().ConfigMaps(c.Namespace).Get(meshConfigMapName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	meshConfig, err := mesh.ApplyMeshConfigDefaults(cm.Data[meshConfigMapKey])
+	if err != nil {
+		return fmt.Errorf("could not parse the mesh config: %v", err)
+	}
+	js, err := gogoprotomarshal.ToJSONMap(meshConfig)
+	if err != nil {
+		return err
+	}
+	if configs[configKey{kind: "MeshConfig", name: meshConfigMapName}], err = canonicalJSON(js); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readInjectionTemplate reads the sidecar injection template and values of the control plane.
+func (c *Cluster) readInjectionTemplate(configs clusterConfigs) error {
+	cm, err := c.client.CoreV1().ConfigMaps(c.Namespace).Get(injectConfigMapName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	injection := make(map[string]interface{})
+	for _, key := range []string{injectConfigMapKey, valuesConfigMapKey} {
+		data, ok := cm.Data[key]
+		if !ok {
+			continue
+		}
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(data), &value); err != nil {
+			return fmt.Errorf("could not parse %q of %v: %v", key, injectConfigMapName, err)
+		}
+		injection[key] = value
+	}
+	if configs[configKey{kind: "ConfigMap", name: injectConfigMapName}], err = canonicalJSON(injection); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readClusterConfigs(opt driftOptions, env Environment, c *Cluster) (clusterConfigs, error) {
+	configs := make(clusterConfigs)
+	if err := c.readIstioConfigs(env, configs); err != nil {
+		return nil, err
+	}
+	if opt.meshConfig {
+		if err := c.readMeshConfig(configs); err != nil {
+			return nil, err
+		}
+	}
+	if opt.injectionTemplate {
+		if err := c.readInjectionTemplate(configs); err != nil {
+			return nil, err
+		}
+	}
+	return configs, nil
+}
+
+// detectDrift compares the resources of each cluster, by context, with the ones of the reference cluster.
+func detectDrift(reference string, configsByContext map[string]clusterConfigs) []*configDrift {
+	contexts := make([]string, 0, len(configsByContext))
+	keySet := make(map[configKey]bool)
+	for context, configs := range configsByContext {
+		if context != reference {
+			contexts = append(contexts, context)
+		}
+		for key := range configs {
+			keySet[key] = true
+		}
+	}
+	sort.Strings(contexts)
+	keys := make([]configKey, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+
+	var drifts []*configDrift
+	for _, key := range keys {
+		drift := &configDrift{key: key, diffs: make(map[string][]fieldDiff)}
+		want, inReference := configsByContext[reference][key]
+		for _, context := range contexts {
+			got, ok := configsByContext[context][key]
+			switch {
+			case inReference && !ok:
+				drift.missing = append(drift.missing, context)
+			case !inReference && ok:
+				drift.extra = append(drift.extra, context)
+			case ok:
+				if diffs := diffValues("", want, got, nil); len(diffs) > 0 {
+					drift.diffs[context] = diffs
+				}
+			}
+		}
+		if len(drift.missing) > 0 || len(drift.extra) > 0 || len(drift.diffs) > 0 {
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts
+}
+
+// diffValues appends the differences between two generic JSON values, field by field. Multi-line strings, such as
+// the injection template, are compared line by line.
+func diffValues(path string, want, got interface{}, diffs []fieldDiff) []fieldDiff {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		if g, ok := got.(map[string]interface{}); ok {
+			keySet := make(map[string]bool)
+			for k := range w {
+				keySet[k] = true
+			}
+			for k := range g {
+				keySet[k] = true
+			}
+			keys := make([]string, 0, len(keySet))
+			for k := range keySet {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				diffs = diffField(fieldPath(path, k), w[k], g[k], diffs)
+			}
+			return diffs
+		}
+	case []interface{}:
+		if g, ok := got.([]interface{}); ok {
+			for i := 0; i < len(w) || i < len(g); i++ {
+				var wi, gi interface{}
+				if i < len(w) {
+					wi = w[i]
+				}
+				if i < len(g) {
+					gi = g[i]
+				}
+				diffs = diffField(fmt.Sprintf("%v[%d]", path, i), wi, gi, diffs)
+			}
+			return diffs
+		}
+	case string:
+		if g, ok := got.(string); ok && strings.Contains(w+g, "\n") {
+			return diffLines(path, w, g, diffs)
+		}
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		diffs = append(diffs, fieldDiff{path: path, reference: formatValue(want), value: formatValue(got)})
+	}
+	return diffs
+}
+
+// diffField diffs a field which may be missing from either value, in which case it is nil.
+func diffField(path string, want, got interface{}, diffs []fieldDiff) []fieldDiff {
+	switch {
+	case want == nil && got == nil:
+		return diffs
+	case want == nil:
+		return append(diffs, fieldDiff{path: path, reference: unsetValue, value: formatValue(got)})
+	case got == nil:
+		return append(diffs, fieldDiff{path: path, reference: formatValue(want), value: unsetValue})
+	default:
+		return diffValues(path, want, got, diffs)
+	}
+}
+
+// diffLines appends the first differing line of two multi-line strings.
+func diffLines(path, want, got string, diffs []fieldDiff) []fieldDiff {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		w, g := unsetValue, unsetValue
+		if i < len(wantLines) {
+			w = formatValue(wantLines[i])
+		}
+		if i < len(gotLines) {
+			g = formatValue(gotLines[i])
+		}
+		if w != g {
+			return append(diffs, fieldDiff{path: fmt.Sprintf("%v (line %d)", path, i+1), reference: w, value: g})
+		}
+	}
+	return diffs
+}
+
+// fieldPath appends a field to a path, quoting the names which are not identifiers, e.g. labels.
+func fieldPath(path, field string) string {
+	if strings.ContainsAny(field, "./ ") {
+		return fmt.Sprintf("%v[%q]", path, field)
+	}
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func formatValue(v interface{}) string {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	if len(out) > maxValueLength {
+		return string(out[:maxValueLength-3]) + "..."
+	}
+	return string(out)
+}
+
+func printDrift(env Environment, reference string, drifts []*configDrift) {
+	if len(drifts) == 0 {
+		env.Printf("no configuration drift from %v\n", reference)
+		return
+	}
+
+	env.Printf("configuration drift from %v:\n", reference)
+	indent := strings.Repeat(" ", 4)
+	for _, drift := range drifts {
+		env.Printf("\n%v\n", drift.key)
+		if len(drift.missing) > 0 {
+			env.Printf("%vmissing in: %v\n", indent, strings.Join(drift.missing, ", "))
+		}
+		if len(drift.extra) > 0 {
+			env.Printf("%vonly in: %v\n", indent, strings.Join(drift.extra, ", "))
+		}
+		if len(drift.diffs) == 0 {
+			continue
+		}
+
+		contexts := make([]string, 0, len(drift.diffs))
+		for context := range drift.diffs {
+			contexts = append(contexts, context)
+		}
+		sort.Strings(contexts)
+
+		tw := tabwriter.NewWriter(env.Stdout(), 0, 8, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "%vCONTEXT\tFIELD\t%v\tVALUE\n", indent, strings.ToUpper(reference))
+		for _, context := range contexts {
+			for _, diff := range drift.diffs[context] {
+				_, _ = fmt.Fprintf(tw, "%v%v\t%v\t%v\t%v\n", indent, context, diff.path, diff.reference, diff.value)
+			}
+		}
+		_ = tw.Flush()
+	}
+}
+
+// Drift reports the Istio configuration which differs between the reference cluster and the other clusters of the
+// mesh.
+func Drift(opt driftOptions, env Environment) error {
+	mesh, err := meshFromFileDesc(opt.filename, env)
+	if err != nil {
+		return err
+	}
+
+	reference := opt.Context
+	if reference == "" {
+		reference = env.GetConfig().CurrentContext
+	}
+	if _, ok := mesh.clustersByContext[reference]; !ok {
+		return fmt.Errorf("cluster %v not found", reference)
+	}
+
+	configsByContext := make(map[string]clusterConfigs)
+	for _, cluster := range mesh.SortedClusters() {
+		configs, err := readClusterConfigs(opt, env, cluster)
+		if err != nil {
+			// leave the cluster out rather than reporting all of its configuration as missing
+			if cluster.Context == reference {
+				return fmt.Errorf("could not read the configuration of cluster %v: %v", cluster, err)
+			}
+			env.Errorf("error: could not read the configuration of cluster %v: %v\n", cluster, err)
+			continue
+		}
+		configsByContext[cluster.Context] = configs
+	}
+
+	printDrift(env, reference, detectDrift(reference, configsByContext))
+	return nil
+}
+
+type driftOptions struct {
+	KubeOptions
+	filenameOption
+	meshConfig        bool
+	injectionTemplate bool
+}
+
+func (o *driftOptions) prepare(flags *pflag.FlagSet) error {
+	o.KubeOptions.prepare(flags)
+	return o.filenameOption.prepare()
+}
+
+func (o *driftOptions) addFlags(flags *pflag.FlagSet) {
+	o.filenameOption.addFlags(flags)
+
+	flags.BoolVar(&o.meshConfig, "mesh-config", false,
+		"also compare the MeshConfig of the control planes")
+	flags.BoolVar(&o.injectionTemplate, "injection-template", false,
+		"also compare the sidecar injection templates and values of the control planes")
+}
+
+// NewDriftCommand creates a new command for detecting Istio configuration drift between the clusters of the mesh.
+func NewDriftCommand() *cobra.Command {
+	opt := driftOptions{}
+	c := &cobra.Command{
+		Use:   "drift -f <mesh.yaml> [--mesh-config] [--injection-template]",
+		Short: `Report Istio configuration which differs between the clusters of the mesh`,
+		Long: `Report Istio configuration which differs between the clusters of the mesh.
+
+The Istio resources of every cluster of the mesh description are compared with the ones of the
+reference cluster, the current context by default. Fields set by the API server, such as status, uid
+and resourceVersion, are ignored and the specs are compared in their canonical protobuf form, so
+only semantic differences are reported, field by field.`,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opt.prepare(c.Flags()); err != nil {
+				return err
+			}
+			env, err := NewEnvironmentFromCobra(opt.Kubeconfig, opt.Context, c)
+			if err != nil {
+				return err
+			}
+			return Drift(opt, env)
+		},
+	}
+	opt.addFlags(c.PersistentFlags())
+	return c
+}
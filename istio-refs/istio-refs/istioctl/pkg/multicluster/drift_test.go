@@ -0,0 +1,231 @@
+// Copyright 2020 Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func makeIstioConfig(kind, name string, spec, meta map[string]interface{}) runtime.Object {
+	metadata := map[string]interface{}{
+		"name":      name,
+		"namespace": "default",
+	}
+	for k, v := range meta {
+		metadata[k] = v
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1alpha3",
+		"kind":       kind,
+		"metadata":   metadata,
+		"spec":       spec,
+		"status":     map[string]interface{}{"observedGeneration": metadata["generation"]},
+	}}
+}
+
+func reviewsVirtualService(weight int64, timeout string, meta map[string]interface{}) runtime.Object {
+	return makeIstioConfig("VirtualService", "reviews", map[string]interface{}{
+		"hosts": []interface{}{"reviews"},
+		"http": []interface{}{
+			map[string]interface{}{
+				"timeout": timeout,
+				"route": []interface{}{
+					map[string]interface{}{
+						"destination": map[string]interface{}{"host": "reviews", "subset": "v1"},
+						"weight":      weight,
+					},
+					map[string]interface{}{
+						"destination": map[string]interface{}{"host": "reviews", "subset": "v2"},
+						"weight":      100 - weight,
+					},
+				},
+			},
+		},
+	}, meta)
+}
+
+func makeConfigMap(name string, data map[string]string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: defaultIstioNamespace},
+		Data:       data,
+	}
+}
+
+func TestDetectDrift(t *testing.T) {
+	env := newFakeEnvironmentOrDie(t, &api.Config{})
+	env.dynamicClients = map[string]dynamic.Interface{
+		"c0": dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+			reviewsVirtualService(80, "1s", map[string]interface{}{
+				"uid":             "0b6cd3e4-6f9b-11ea-bd4c-42010a800002",
+				"resourceVersion": "1234",
+				"generation":      int64(1),
+			}),
+			makeIstioConfig("DestinationRule", "reviews", map[string]interface{}{"host": "reviews"}, nil),
+		),
+		"c1": dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+			reviewsVirtualService(50, "1.000s", map[string]interface{}{
+				"uid":             "2a3f1c6e-6f9b-11ea-8d0e-42010a8a0003",
+				"resourceVersion": "5678",
+				"generation":      int64(3),
+			}),
+			makeIstioConfig("ServiceEntry", "external", map[string]interface{}{
+				"hosts": []interface{}{"example.com"},
+			}, nil),
+		),
+	}
+
+	clusters := []*Cluster{
+		{
+			ClusterDesc: ClusterDesc{Namespace: defaultIstioNamespace},
+			Context:     "c0",
+			client: fake.NewSimpleClientset(
+				makeConfigMap(meshConfigMapName, map[string]string{
+					meshConfigMapKey: "enableTracing: true\ntrustDomain: cluster.local\n",
+				}),
+				makeConfigMap(injectConfigMapName, map[string]string{
+					injectConfigMapKey: "policy: enabled\ntemplate: |\n  initContainers:\n  - name: istio-init\n",
+					valuesConfigMapKey: `{"global":{"hub":"docker.io/istio"}}`,
+				}),
+			),
+		},
+		{
+			ClusterDesc: ClusterDesc{Namespace: defaultIstioNamespace},
+			Context:     "c1",
+			client: fake.NewSimpleClientset(
+				makeConfigMap(meshConfigMapName, map[string]string{
+					meshConfigMapKey: "connectTimeout: 10s\ntrustDomain: example.com\n",
+				}),
+				makeConfigMap(injectConfigMapName, map[string]string{
+					injectConfigMapKey: "policy: enabled\ntemplate: |\n  initContainers:\n  - name: istio-validation\n",
+					valuesConfigMapKey: `{"global": {"hub": "docker.io/istio"}}`,
+				}),
+			),
+		},
+	}
+
+	opt := driftOptions{meshConfig: true, injectionTemplate: true}
+	configsByContext := make(map[string]clusterConfigs)
+	for _, c := range clusters {
+		configs, err := readClusterConfigs(opt, env, c)
+		if err != nil {
+			t.Fatalf("could not read the configuration of %v: %v", c.Context, err)
+		}
+		configsByContext[c.Context] = configs
+	}
+
+	want := []*configDrift{
+		{
+			key: configKey{kind: "ConfigMap", name: injectConfigMapName},
+			diffs: map[string][]fieldDiff{
+				"c1": {{path: "config.template (line 2)", reference: `"- name: istio-init"`, value: `"- name: istio-validation"`}},
+			},
+		},
+		{
+			key:     configKey{kind: "DestinationRule", namespace: "default", name: "reviews"},
+			missing: []string{"c1"},
+			diffs:   map[string][]fieldDiff{},
+		},
+		{
+			key: configKey{kind: "MeshConfig", name: meshConfigMapName},
+			diffs: map[string][]fieldDiff{
+				"c1": {{path: "trustDomain", reference: `"cluster.local"`, value: `"example.com"`}},
+			},
+		},
+		{
+			key:   configKey{kind: "ServiceEntry", namespace: "default", name: "external"},
+			extra: []string{"c1"},
+			diffs: map[string][]fieldDiff{},
+		},
+		{
+			key: configKey{kind: "VirtualService", namespace: "default", name: "reviews"},
+			diffs: map[string][]fieldDiff{
+				"c1": {
+					{path: "spec.http[0].route[0].weight", reference: "80", value: "50"},
+					{path: "spec.http[0].route[1].weight", reference: "20", value: "50"},
+				},
+			},
+		},
+	}
+
+	got := detectDrift("c0", configsByContext)
+	if len(got) != len(want) {
+		t.Fatalf("got %d drifted resources, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("drift %d: got %+v want %+v", i, *got[i], *want[i])
+		}
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	cases := []struct {
+		name string
+		want interface{}
+		got  interface{}
+		diff []fieldDiff
+	}{
+		{
+			name: "equal",
+			want: map[string]interface{}{"a": []interface{}{1.0, "x"}},
+			got:  map[string]interface{}{"a": []interface{}{1.0, "x"}},
+		},
+		{
+			name: "unset fields",
+			want: map[string]interface{}{"a": 1.0},
+			got:  map[string]interface{}{"b": true},
+			diff: []fieldDiff{
+				{path: "a", reference: "1", value: unsetValue},
+				{path: "b", reference: unsetValue, value: "true"},
+			},
+		},
+		{
+			name: "longer list",
+			want: map[string]interface{}{"hosts": []interface{}{"a"}},
+			got:  map[string]interface{}{"hosts": []interface{}{"a", "b"}},
+			diff: []fieldDiff{{path: "hosts[1]", reference: unsetValue, value: `"b"`}},
+		},
+		{
+			name: "label",
+			want: map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "reviews"}},
+			got:  map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "ratings"}},
+			diff: []fieldDiff{{path: `labels["app.kubernetes.io/name"]`, reference: `"reviews"`, value: `"ratings"`}},
+		},
+		{
+			name: "type change",
+			want: map[string]interface{}{"a": map[string]interface{}{"b": 1.0}},
+			got:  map[string]interface{}{"a": "b"},
+			diff: []fieldDiff{{path: "a", reference: `{"b":1}`, value: `"b"`}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := diffValues("", c.want, c.got, nil); !reflect.DeepEqual(got, c.diff) {
+				t.Errorf("got %v want %v", got, c.diff)
+			}
+		})
+	}
+}
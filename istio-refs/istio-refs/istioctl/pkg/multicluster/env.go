@@ -22,6 +22,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd/api"
 
@@ -33,6 +34,7 @@ type ConditionFunc func() (done bool, err error)
 type Environment interface {
 	GetConfig() *api.Config
 	CreateClientSet(context string) (kubernetes.Interface, error)
+	CreateDynamicClient(context string) (dynamic.Interface, error)
 	Stdout() io.Writer
 	Stderr() io.Writer
 	ReadFile(filename string) ([]byte, error)
@@ -52,6 +54,14 @@ func (e *KubeEnvironment) CreateClientSet(context string) (kubernetes.Interface,
 	return kube.CreateClientset(e.kubeconfig, context)
 }
 
+func (e *KubeEnvironment) CreateDynamicClient(context string) (dynamic.Interface, error) {
+	config, err := kube.BuildClientConfig(e.kubeconfig, context)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(config)
+}
+
 func (e *KubeEnvironment) Printf(format string, a ...interface{}) {
 	_, _ = fmt.Fprintf(e.stdout, format, a...)
 }
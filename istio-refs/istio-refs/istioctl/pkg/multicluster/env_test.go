@@ -16,6 +16,7 @@ package multicluster
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"reflect"
 	"testing"
@@ -23,6 +24,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/clientcmd/api"
@@ -96,6 +98,7 @@ type fakeEnvironment struct {
 	KubeEnvironment
 
 	client                  *fake.Clientset
+	dynamicClients          map[string]dynamic.Interface
 	injectClientCreateError error
 	kubeconfig              string
 	wOut                    bytes.Buffer
@@ -130,6 +133,17 @@ func (f *fakeEnvironment) CreateClientSet(context string) (kubernetes.Interface,
 	return f.client, nil
 }
 
+func (f *fakeEnvironment) CreateDynamicClient(context string) (dynamic.Interface, error) {
+	if f.injectClientCreateError != nil {
+		return nil, f.injectClientCreateError
+	}
+	client, ok := f.dynamicClients[context]
+	if !ok {
+		return nil, fmt.Errorf("no dynamic client for context %v", context)
+	}
+	return client, nil
+}
+
 func (f *fakeEnvironment) Poll(interval, timeout time.Duration, condition ConditionFunc) error {
 	// TODO - add hooks to inject fake timeouts
 	condition()
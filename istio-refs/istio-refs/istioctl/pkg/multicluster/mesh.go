@@ -134,6 +134,7 @@ func NewMulticlusterCommand() *cobra.Command {
 		NewGenerateCommand(),
 		NewApplyCommand(),
 		NewDescribeCommand(),
+		NewDriftCommand(),
 	)
 
 	return c
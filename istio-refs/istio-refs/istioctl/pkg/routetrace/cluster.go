@@ -0,0 +1,125 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routetrace
+
+import (
+	"sort"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	"github.com/golang/protobuf/ptypes"
+
+	"istio.io/istio/istioctl/pkg/util/clusters"
+)
+
+// ClusterTrace describes the upstream cluster a request is sent to.
+type ClusterTrace struct {
+	Name     string
+	Type     string
+	LbPolicy string
+
+	// TLS is true if the cluster originates TLS, in which case SNI and ALPN are those sent to the upstream.
+	TLS  bool
+	SNI  string
+	ALPN []string
+
+	OutlierDetection bool
+
+	// Endpoints of the cluster, sorted by priority. They are only known if the proxy's clusters were provided.
+	Endpoints []Endpoint
+
+	// Missing is true if the cluster isn't in the config dump.
+	Missing bool
+}
+
+// Endpoint is an upstream host of a cluster.
+type Endpoint struct {
+	Address            string
+	Port               uint32
+	Priority           uint32
+	Weight             uint32
+	Health             string
+	FailedOutlierCheck bool
+}
+
+// Healthy returns true if Envoy may send requests to the endpoint.
+func (e Endpoint) Healthy() bool {
+	return (e.Health == "HEALTHY" || e.Health == "UNKNOWN") && !e.FailedOutlierCheck
+}
+
+// IsMutualTLS returns true if the cluster uses Istio mutual TLS, identified by the "istio" ALPN.
+func (c *ClusterTrace) IsMutualTLS() bool {
+	return c.TLS && contains(c.ALPN, "istio")
+}
+
+// SelectedEndpoint returns the first healthy endpoint of the highest priority, the priority Envoy sends requests to
+// as long as it has enough healthy endpoints.
+func (c *ClusterTrace) SelectedEndpoint() *Endpoint {
+	for i := range c.Endpoints {
+		if c.Endpoints[i].Healthy() {
+			return &c.Endpoints[i]
+		}
+	}
+	return nil
+}
+
+func traceCluster(cluster *xdsapi.Cluster, name string, cl *clusters.Wrapper) (*ClusterTrace, error) {
+	if cluster == nil {
+		return &ClusterTrace{Name: name, Missing: true}, nil
+	}
+	ct := &ClusterTrace{
+		Name:             name,
+		Type:             cluster.GetType().String(),
+		LbPolicy:         cluster.GetLbPolicy().String(),
+		OutlierDetection: cluster.GetOutlierDetection() != nil,
+	}
+
+	// nolint: staticcheck
+	tlsContext := cluster.GetTlsContext()
+	if ts := cluster.GetTransportSocket(); ts != nil && ts.GetTypedConfig() != nil {
+		tlsContext = &auth.UpstreamTlsContext{}
+		if err := ptypes.UnmarshalAny(ts.GetTypedConfig(), tlsContext); err != nil {
+			return nil, err
+		}
+	}
+	if tlsContext != nil {
+		ct.TLS = true
+		ct.SNI = tlsContext.GetSni()
+		ct.ALPN = tlsContext.GetCommonTlsContext().GetAlpnProtocols()
+	}
+
+	if cl != nil && cl.Clusters != nil {
+		for _, status := range cl.ClusterStatuses {
+			if status.GetName() != name {
+				continue
+			}
+			for _, host := range status.GetHostStatuses() {
+				addr := host.GetAddress().GetSocketAddress()
+				ct.Endpoints = append(ct.Endpoints, Endpoint{
+					Address:            addr.GetAddress(),
+					Port:               addr.GetPortValue(),
+					Priority:           host.GetPriority(),
+					Weight:             host.GetWeight(),
+					Health:             host.GetHealthStatus().GetEdsHealthStatus().String(),
+					FailedOutlierCheck: host.GetHealthStatus().GetFailedOutlierCheck(),
+				})
+			}
+		}
+		sort.SliceStable(ct.Endpoints, func(i, j int) bool {
+			return ct.Endpoints[i].Priority < ct.Endpoints[j].Priority
+		})
+	}
+	return ct, nil
+}
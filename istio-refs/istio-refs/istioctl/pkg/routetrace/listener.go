@@ -0,0 +1,275 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routetrace
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+)
+
+const (
+	virtualInboundListener = "virtualInbound"
+
+	// rawBufferTransport is the transport protocol of plaintext connections.
+	rawBufferTransport = "raw_buffer"
+	tlsTransport       = "tls"
+)
+
+// connection holds the properties of a downstream connection used to select a listener and a filter chain.
+type connection struct {
+	destinationIP        string
+	destinationPort      uint32
+	sourceIP             string
+	sourcePrincipal      string
+	serverName           string
+	transportProtocol    string
+	applicationProtocols []string
+}
+
+func listenerAddress(l *xdsapi.Listener) (string, uint32) {
+	sa := l.GetAddress().GetSocketAddress()
+	return sa.GetAddress(), sa.GetPortValue()
+}
+
+func isWildcardAddress(address string) bool {
+	return address == "0.0.0.0" || address == "::"
+}
+
+// selectOutboundListener returns the listener handling an outbound connection. Outbound connections are captured by
+// the virtual outbound listener, which hands them off to the listener bound to their original destination if any.
+func selectOutboundListener(listeners []*xdsapi.Listener, conn connection) (*xdsapi.Listener, string) {
+	var wildcard, virtual *xdsapi.Listener
+	for _, l := range listeners {
+		address, port := listenerAddress(l)
+		switch {
+		case port == conn.destinationPort && conn.destinationIP != "" && address == conn.destinationIP:
+			return l, fmt.Sprintf("bound to the destination %s:%d", address, port)
+		case port == conn.destinationPort && isWildcardAddress(address):
+			if wildcard == nil {
+				wildcard = l
+			}
+		case l.GetUseOriginalDst().GetValue():
+			if virtual == nil {
+				virtual = l
+			}
+		}
+	}
+	if wildcard != nil {
+		return wildcard, fmt.Sprintf("bound to all addresses on port %d", conn.destinationPort)
+	}
+	if virtual != nil {
+		return virtual, fmt.Sprintf("no listener for port %d, the connection stays on the virtual outbound listener",
+			conn.destinationPort)
+	}
+	return nil, ""
+}
+
+// selectInboundListener returns the listener handling an inbound connection. Inbound connections are captured by
+// the virtual inbound listener, or handed off to the listener bound to the destination by older proxies.
+func selectInboundListener(listeners []*xdsapi.Listener, conn connection) (*xdsapi.Listener, string) {
+	var bound *xdsapi.Listener
+	for _, l := range listeners {
+		if l.Name == virtualInboundListener {
+			return l, "captures the inbound connections"
+		}
+		address, port := listenerAddress(l)
+		if bound == nil && port == conn.destinationPort && address == conn.destinationIP {
+			bound = l
+		}
+	}
+	if bound != nil {
+		return bound, fmt.Sprintf("bound to the destination %s:%d", conn.destinationIP, conn.destinationPort)
+	}
+	return nil, ""
+}
+
+// filterChainCriterion is a step of the filter chain match. Its score is negative if a filter chain doesn't match,
+// zero if the filter chain doesn't use the criterion, and larger for more specific matches.
+type filterChainCriterion struct {
+	score    func(m *listener.FilterChainMatch, conn connection) int
+	describe func(m *listener.FilterChainMatch, conn connection) string
+}
+
+// filterChainCriteria are in the order Envoy applies them.
+var filterChainCriteria = []filterChainCriterion{
+	{
+		score: func(m *listener.FilterChainMatch, conn connection) int {
+			if m.GetDestinationPort() == nil {
+				return 0
+			}
+			if m.GetDestinationPort().GetValue() == conn.destinationPort {
+				return 1
+			}
+			return -1
+		},
+		describe: func(m *listener.FilterChainMatch, conn connection) string {
+			return fmt.Sprintf("destination port %d", conn.destinationPort)
+		},
+	},
+	{
+		score: func(m *listener.FilterChainMatch, conn connection) int {
+			return cidrScore(m.GetPrefixRanges(), conn.destinationIP)
+		},
+		describe: func(m *listener.FilterChainMatch, conn connection) string {
+			return fmt.Sprintf("destination IP %s in %s", conn.destinationIP, cidrs(m.GetPrefixRanges()))
+		},
+	},
+	{
+		score: func(m *listener.FilterChainMatch, conn connection) int {
+			if len(m.GetServerNames()) == 0 {
+				return 0
+			}
+			best := -1
+			for _, name := range m.GetServerNames() {
+				if strings.EqualFold(name, conn.serverName) {
+					// Exact server names are more specific than any wildcard.
+					return len(name) + 1024
+				}
+				if strings.HasPrefix(name, "*") && len(conn.serverName) > len(name)-1 &&
+					strings.HasSuffix(strings.ToLower(conn.serverName), strings.ToLower(name[1:])) && len(name) > best {
+					best = len(name)
+				}
+			}
+			return best
+		},
+		describe: func(m *listener.FilterChainMatch, conn connection) string {
+			return fmt.Sprintf("server name %q in %v", conn.serverName, m.GetServerNames())
+		},
+	},
+	{
+		score: func(m *listener.FilterChainMatch, conn connection) int {
+			if m.GetTransportProtocol() == "" {
+				return 0
+			}
+			if m.GetTransportProtocol() == conn.transportProtocol {
+				return 1
+			}
+			return -1
+		},
+		describe: func(m *listener.FilterChainMatch, conn connection) string {
+			return fmt.Sprintf("transport protocol %s", conn.transportProtocol)
+		},
+	},
+	{
+		score: func(m *listener.FilterChainMatch, conn connection) int {
+			if len(m.GetApplicationProtocols()) == 0 {
+				return 0
+			}
+			for _, p := range conn.applicationProtocols {
+				if contains(m.GetApplicationProtocols(), p) {
+					return 1
+				}
+			}
+			return -1
+		},
+		describe: func(m *listener.FilterChainMatch, conn connection) string {
+			return fmt.Sprintf("application protocols %v in %v", conn.applicationProtocols, m.GetApplicationProtocols())
+		},
+	},
+	{
+		score: func(m *listener.FilterChainMatch, conn connection) int {
+			return cidrScore(m.GetSourcePrefixRanges(), conn.sourceIP)
+		},
+		describe: func(m *listener.FilterChainMatch, conn connection) string {
+			return fmt.Sprintf("source IP %s in %s", conn.sourceIP, cidrs(m.GetSourcePrefixRanges()))
+		},
+	},
+}
+
+// selectFilterChain returns the index of the filter chain handling the connection, or -1 if none does, along with
+// the criteria the filter chain matched on. As Envoy does, the criteria are applied in turn, each keeping the most
+// specific matching filter chains, or the filter chains not using the criterion if none matches.
+func selectFilterChain(chains []*listener.FilterChain, conn connection) (int, []string) {
+	candidates := make([]int, 0, len(chains))
+	for i := range chains {
+		candidates = append(candidates, i)
+	}
+	for _, criterion := range filterChainCriteria {
+		best := -1
+		var kept []int
+		for _, i := range candidates {
+			score := criterion.score(chains[i].GetFilterChainMatch(), conn)
+			if score > best {
+				best = score
+				kept = kept[:0]
+			}
+			if score == best && score >= 0 {
+				kept = append(kept, i)
+			}
+		}
+		if len(kept) == 0 {
+			return -1, nil
+		}
+		candidates = kept
+	}
+
+	chosen := candidates[0]
+	m := chains[chosen].GetFilterChainMatch()
+	var reasons []string
+	for _, criterion := range filterChainCriteria {
+		if criterion.score(m, conn) > 0 {
+			reasons = append(reasons, criterion.describe(m, conn))
+		}
+	}
+	if len(reasons) == 0 {
+		reasons = append(reasons, "no match criteria")
+	}
+	return chosen, reasons
+}
+
+// cidrScore returns the prefix length plus one of the most specific range containing the address.
+func cidrScore(ranges []*core.CidrRange, address string) int {
+	if len(ranges) == 0 {
+		return 0
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return -1
+	}
+	best := -1
+	for _, r := range ranges {
+		length := int(r.GetPrefixLen().GetValue())
+		_, network, err := net.ParseCIDR(fmt.Sprintf("%s/%d", r.GetAddressPrefix(), length))
+		if err != nil {
+			continue
+		}
+		if network.Contains(ip) && length+1 > best {
+			best = length + 1
+		}
+	}
+	return best
+}
+
+func cidrs(ranges []*core.CidrRange) string {
+	out := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		out = append(out, fmt.Sprintf("%s/%d", r.GetAddressPrefix(), r.GetPrefixLen().GetValue()))
+	}
+	return "[" + strings.Join(out, " ") + "]"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
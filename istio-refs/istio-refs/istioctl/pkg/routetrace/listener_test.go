@@ -0,0 +1,105 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routetrace
+
+import (
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+func TestSelectFilterChain(t *testing.T) {
+	chains := []*listener.FilterChain{
+		{
+			FilterChainMatch: &listener.FilterChainMatch{
+				PrefixRanges: []*core.CidrRange{{AddressPrefix: "10.0.1.3", PrefixLen: &wrappers.UInt32Value{Value: 32}}},
+			},
+		},
+		{
+			FilterChainMatch: &listener.FilterChainMatch{
+				ApplicationProtocols: []string{"http/1.0", "http/1.1", "h2c"},
+			},
+		},
+		{
+			FilterChainMatch: &listener.FilterChainMatch{
+				TransportProtocol:    tlsTransport,
+				ApplicationProtocols: []string{"istio-http/1.0", "istio-http/1.1", "istio-h2"},
+			},
+		},
+		{
+			FilterChainMatch: &listener.FilterChainMatch{
+				TransportProtocol:    tlsTransport,
+				ApplicationProtocols: []string{"istio"},
+			},
+		},
+		{
+			FilterChainMatch: &listener.FilterChainMatch{},
+		},
+	}
+
+	cases := []struct {
+		name string
+		conn connection
+		want int
+	}{
+		{
+			name: "own address",
+			conn: connection{destinationIP: "10.0.1.3", transportProtocol: rawBufferTransport, applicationProtocols: []string{"http/1.1"}},
+			want: 0,
+		},
+		{
+			name: "plaintext HTTP",
+			conn: connection{destinationIP: "10.96.0.10", transportProtocol: rawBufferTransport, applicationProtocols: []string{"http/1.1"}},
+			want: 1,
+		},
+		{
+			name: "mutual TLS HTTP",
+			conn: connection{destinationIP: "10.96.0.10", transportProtocol: tlsTransport, applicationProtocols: []string{"istio-http/1.1", "istio"}},
+			want: 2,
+		},
+		{
+			name: "mutual TLS TCP",
+			conn: connection{destinationIP: "10.96.0.10", transportProtocol: tlsTransport, applicationProtocols: []string{"istio"}},
+			want: 3,
+		},
+		{
+			name: "plaintext TCP",
+			conn: connection{destinationIP: "10.96.0.10", transportProtocol: rawBufferTransport},
+			want: 4,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, reasons := selectFilterChain(chains, c.conn)
+			if got != c.want {
+				t.Fatalf("selectFilterChain() = %d %v, want %d", got, reasons, c.want)
+			}
+			if len(reasons) == 0 {
+				t.Fatalf("selectFilterChain() returned no reasons")
+			}
+		})
+	}
+}
+
+func TestSelectFilterChainNoMatch(t *testing.T) {
+	chains := []*listener.FilterChain{
+		{FilterChainMatch: &listener.FilterChainMatch{TransportProtocol: tlsTransport}},
+	}
+	if got, _ := selectFilterChain(chains, connection{transportProtocol: rawBufferTransport}); got != -1 {
+		t.Fatalf("selectFilterChain() = %d, want -1", got)
+	}
+}
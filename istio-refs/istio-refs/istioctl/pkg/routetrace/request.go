@@ -0,0 +1,128 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routetrace
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Request is the synthetic request traced through the proxies.
+type Request struct {
+	// Host the request is sent to, e.g. reviews or reviews.default.svc.cluster.local.
+	Host string
+
+	// Port the request is sent to.
+	Port uint32
+
+	// DestinationIP is the address the request is sent to, usually the VIP of the Service. It is optional, but
+	// needed to select the listeners and filter chains bound to specific addresses.
+	DestinationIP string
+
+	// SourceIP is the address the request is sent from. It is optional.
+	SourceIP string
+
+	// SourcePrincipal is the identity of the workload sending the request, e.g.
+	// cluster.local/ns/default/sa/productpage. The inbound proxy only sees it over Istio mutual TLS.
+	SourcePrincipal string
+
+	// Method of the request. Defaults to GET.
+	Method string
+
+	// Path of the request, including the query string. Defaults to /.
+	Path string
+
+	// Headers of the request. The Host header defaults to the host, followed by the port unless it is 80, as curl does.
+	Headers map[string]string
+
+	// WeightRoll, in [0, 1), selects the weighted cluster the request is sent to. The clusters are taken in order,
+	// and the first one whose cumulative weight exceeds WeightRoll times the total weight is chosen.
+	WeightRoll float64
+}
+
+func (r *Request) method() string {
+	if r.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(r.Method)
+}
+
+func (r *Request) fullPath() string {
+	if r.Path == "" {
+		return "/"
+	}
+	return r.Path
+}
+
+// path returns the path of the request without the query string.
+func (r *Request) path() string {
+	p := r.fullPath()
+	if i := strings.IndexByte(p, '?'); i >= 0 {
+		return p[:i]
+	}
+	return p
+}
+
+func (r *Request) query() url.Values {
+	p := r.fullPath()
+	i := strings.IndexByte(p, '?')
+	if i < 0 {
+		return url.Values{}
+	}
+	values, err := url.ParseQuery(p[i+1:])
+	if err != nil {
+		return url.Values{}
+	}
+	return values
+}
+
+// authority returns the Host header of the request.
+func (r *Request) authority() string {
+	if host, ok := r.userHeader("host"); ok {
+		return host
+	}
+	if host, ok := r.userHeader(":authority"); ok {
+		return host
+	}
+	if r.Port == 80 || r.Port == 0 {
+		return r.Host
+	}
+	return fmt.Sprintf("%s:%d", r.Host, r.Port)
+}
+
+// header returns the value of a request header, including the pseudo headers used by Envoy route matching.
+func (r *Request) header(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case ":method":
+		return r.method(), true
+	case ":path":
+		return r.fullPath(), true
+	case ":authority", "host":
+		return r.authority(), true
+	case ":scheme":
+		return "http", true
+	}
+	return r.userHeader(name)
+}
+
+func (r *Request) userHeader(name string) (string, bool) {
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
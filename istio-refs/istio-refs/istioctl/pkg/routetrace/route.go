@@ -0,0 +1,244 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routetrace
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+
+	"istio.io/istio/istioctl/pkg/util"
+)
+
+// selectVirtualHost returns the virtual host handling the authority, along with the domain it matched. As Envoy does,
+// exact domains are preferred to suffix wildcards, then to prefix wildcards, the longest wildcard winning, and
+// finally to the catch-all domain.
+func selectVirtualHost(vhosts []*route.VirtualHost, authority string) (*route.VirtualHost, string) {
+	authority = strings.ToLower(authority)
+
+	var (
+		suffix, prefix, catchAll   *route.VirtualHost
+		suffixDomain, prefixDomain string
+		suffixLen, prefixLen       = -1, -1
+	)
+	for _, vh := range vhosts {
+		for _, domain := range vh.GetDomains() {
+			d := strings.ToLower(domain)
+			switch {
+			case d == authority:
+				return vh, domain
+			case d == "*":
+				if catchAll == nil {
+					catchAll = vh
+				}
+			case strings.HasPrefix(d, "*"):
+				if len(authority) > len(d)-1 && strings.HasSuffix(authority, d[1:]) && len(d) > suffixLen {
+					suffix, suffixDomain, suffixLen = vh, domain, len(d)
+				}
+			case strings.HasSuffix(d, "*"):
+				if len(authority) > len(d)-1 && strings.HasPrefix(authority, d[:len(d)-1]) && len(d) > prefixLen {
+					prefix, prefixDomain, prefixLen = vh, domain, len(d)
+				}
+			}
+		}
+	}
+	switch {
+	case suffix != nil:
+		return suffix, suffixDomain
+	case prefix != nil:
+		return prefix, prefixDomain
+	case catchAll != nil:
+		return catchAll, "*"
+	}
+	return nil, ""
+}
+
+// matchRoute returns whether the route matches the request. It returns the conditions the request satisfied if the
+// route matches, or the first condition the request failed otherwise.
+func matchRoute(r *route.Route, req *Request) (bool, []string) {
+	m := r.GetMatch()
+	caseSensitive := m.GetCaseSensitive() == nil || m.GetCaseSensitive().GetValue()
+
+	ok, reason := matchPath(m, req.path(), caseSensitive)
+	if !ok {
+		return false, []string{reason}
+	}
+	reasons := []string{reason}
+
+	for _, h := range m.GetHeaders() {
+		ok, reason := matchHeader(h, req)
+		if !ok {
+			return false, []string{reason + " not satisfied"}
+		}
+		reasons = append(reasons, reason)
+	}
+
+	query := req.query()
+	for _, q := range m.GetQueryParameters() {
+		ok, reason := matchQueryParameter(q, query)
+		if !ok {
+			return false, []string{reason + " not satisfied"}
+		}
+		reasons = append(reasons, reason)
+	}
+	return true, reasons
+}
+
+func matchPath(m *route.RouteMatch, path string, caseSensitive bool) (bool, string) {
+	fold := func(s string) string {
+		if caseSensitive {
+			return s
+		}
+		return strings.ToLower(s)
+	}
+
+	switch ps := m.GetPathSpecifier().(type) {
+	case *route.RouteMatch_Prefix:
+		if strings.HasPrefix(fold(path), fold(ps.Prefix)) {
+			return true, fmt.Sprintf("path prefix %q", ps.Prefix)
+		}
+		return false, fmt.Sprintf("path %q doesn't start with %q", path, ps.Prefix)
+	case *route.RouteMatch_Path:
+		if fold(path) == fold(ps.Path) {
+			return true, fmt.Sprintf("path %q", ps.Path)
+		}
+		return false, fmt.Sprintf("path %q isn't %q", path, ps.Path)
+	case *route.RouteMatch_Regex:
+		return matchPathRegex(ps.Regex, path)
+	case *route.RouteMatch_SafeRegex:
+		return matchPathRegex(ps.SafeRegex.GetRegex(), path)
+	default:
+		return false, "unsupported path match"
+	}
+}
+
+func matchPathRegex(expr, path string) (bool, string) {
+	ok, err := util.FullMatch(expr, path)
+	if err != nil {
+		return false, fmt.Sprintf("invalid path regex %q: %v", expr, err)
+	}
+	if ok {
+		return true, fmt.Sprintf("path regex %q", expr)
+	}
+	return false, fmt.Sprintf("path %q doesn't match regex %q", path, expr)
+}
+
+// matchHeader follows Envoy's semantics: a missing header only satisfies an inverted presence match.
+func matchHeader(h *route.HeaderMatcher, req *Request) (bool, string) {
+	value, found := req.header(h.GetName())
+
+	var (
+		matched bool
+		desc    string
+		present bool
+		err     error
+	)
+	switch s := h.GetHeaderMatchSpecifier().(type) {
+	case *route.HeaderMatcher_ExactMatch:
+		matched, desc = value == s.ExactMatch, fmt.Sprintf("exact %q", s.ExactMatch)
+	case *route.HeaderMatcher_PrefixMatch:
+		matched, desc = strings.HasPrefix(value, s.PrefixMatch), fmt.Sprintf("prefix %q", s.PrefixMatch)
+	case *route.HeaderMatcher_SuffixMatch:
+		matched, desc = strings.HasSuffix(value, s.SuffixMatch), fmt.Sprintf("suffix %q", s.SuffixMatch)
+	case *route.HeaderMatcher_RegexMatch:
+		desc = fmt.Sprintf("regex %q", s.RegexMatch)
+		matched, err = util.FullMatch(s.RegexMatch, value)
+	case *route.HeaderMatcher_SafeRegexMatch:
+		desc = fmt.Sprintf("regex %q", s.SafeRegexMatch.GetRegex())
+		matched, err = util.FullMatch(s.SafeRegexMatch.GetRegex(), value)
+	case *route.HeaderMatcher_RangeMatch:
+		desc = fmt.Sprintf("in range [%d, %d)", s.RangeMatch.GetStart(), s.RangeMatch.GetEnd())
+		v, perr := strconv.ParseInt(value, 10, 64)
+		matched = perr == nil && v >= s.RangeMatch.GetStart() && v < s.RangeMatch.GetEnd()
+	default:
+		// Without specifier, or with a present match, the header only needs to be present.
+		matched, desc, present = true, "present", true
+	}
+	if err != nil {
+		return false, fmt.Sprintf("header %q with invalid %s: %v", h.GetName(), desc, err)
+	}
+
+	if h.GetInvertMatch() {
+		desc = "not " + desc
+	}
+	desc = fmt.Sprintf("header %q %s", h.GetName(), desc)
+	if !found {
+		return h.GetInvertMatch() && present, desc
+	}
+	return matched != h.GetInvertMatch(), desc
+}
+
+func matchQueryParameter(q *route.QueryParameterMatcher, query url.Values) (bool, string) {
+	values, found := query[q.GetName()]
+	value := ""
+	if found && len(values) > 0 {
+		value = values[0]
+	}
+
+	var (
+		matched bool
+		desc    string
+		err     error
+	)
+	switch s := q.GetQueryParameterMatchSpecifier().(type) {
+	case *route.QueryParameterMatcher_StringMatch:
+		matched, desc, err = matchString(s.StringMatch, value)
+	case *route.QueryParameterMatcher_PresentMatch:
+		matched, desc = true, "present"
+	default:
+		// Deprecated fields: an empty value only requires the parameter to be present.
+		// nolint: staticcheck
+		expected, isRegex := q.GetValue(), q.GetRegex().GetValue()
+		switch {
+		case expected == "":
+			matched, desc = true, "present"
+		case isRegex:
+			desc = fmt.Sprintf("regex %q", expected)
+			matched, err = util.FullMatch(expected, value)
+		default:
+			matched, desc = value == expected, fmt.Sprintf("exact %q", expected)
+		}
+	}
+	if err != nil {
+		return false, fmt.Sprintf("query parameter %q with invalid %s: %v", q.GetName(), desc, err)
+	}
+	return found && matched, fmt.Sprintf("query parameter %q %s", q.GetName(), desc)
+}
+
+func matchString(sm *matcher.StringMatcher, value string) (bool, string, error) {
+	fold := func(s string) string {
+		if sm.GetIgnoreCase() {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	switch p := sm.GetMatchPattern().(type) {
+	case *matcher.StringMatcher_Exact:
+		return fold(value) == fold(p.Exact), fmt.Sprintf("exact %q", p.Exact), nil
+	case *matcher.StringMatcher_Prefix:
+		return strings.HasPrefix(fold(value), fold(p.Prefix)), fmt.Sprintf("prefix %q", p.Prefix), nil
+	case *matcher.StringMatcher_Suffix:
+		return strings.HasSuffix(fold(value), fold(p.Suffix)), fmt.Sprintf("suffix %q", p.Suffix), nil
+	case *matcher.StringMatcher_SafeRegex:
+		ok, err := util.FullMatch(p.SafeRegex.GetRegex(), value)
+		return ok, fmt.Sprintf("regex %q", p.SafeRegex.GetRegex()), err
+	default:
+		return false, "unsupported match", nil
+	}
+}
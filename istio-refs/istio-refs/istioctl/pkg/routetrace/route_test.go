@@ -0,0 +1,158 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routetrace
+
+import (
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+func TestSelectVirtualHost(t *testing.T) {
+	vhosts := []*route.VirtualHost{
+		{Name: "reviews", Domains: []string{"reviews.default.svc.cluster.local", "reviews", "reviews:9080"}},
+		{Name: "suffix", Domains: []string{"*.example.com"}},
+		{Name: "longer-suffix", Domains: []string{"*.api.example.com"}},
+		{Name: "prefix", Domains: []string{"ratings.*"}},
+		{Name: "allow_any", Domains: []string{"*"}},
+	}
+
+	cases := []struct {
+		authority string
+		want      string
+	}{
+		{"reviews:9080", "reviews"},
+		{"REVIEWS", "reviews"},
+		{"www.example.com", "suffix"},
+		{"v1.api.example.com", "longer-suffix"},
+		{"ratings.default", "prefix"},
+		{"details", "allow_any"},
+	}
+	for _, c := range cases {
+		t.Run(c.authority, func(t *testing.T) {
+			vh, domain := selectVirtualHost(vhosts, c.authority)
+			if vh == nil || vh.Name != c.want {
+				t.Fatalf("selectVirtualHost(%q) = %v (domain %q), want %s", c.authority, vh, domain, c.want)
+			}
+		})
+	}
+
+	if vh, _ := selectVirtualHost(vhosts[:1], "details"); vh != nil {
+		t.Fatalf("selectVirtualHost() = %v, want none", vh)
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	cases := []struct {
+		name  string
+		match *route.RouteMatch
+		req   *Request
+		want  bool
+	}{
+		{
+			name:  "prefix",
+			match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/api"}},
+			req:   &Request{Path: "/api/v1?limit=1"},
+			want:  true,
+		},
+		{
+			name:  "prefix mismatch",
+			match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/api"}},
+			req:   &Request{Path: "/"},
+			want:  false,
+		},
+		{
+			name:  "default path",
+			match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Path{Path: "/"}},
+			req:   &Request{},
+			want:  true,
+		},
+		{
+			name: "case insensitive path",
+			match: &route.RouteMatch{
+				PathSpecifier: &route.RouteMatch_Path{Path: "/Login"},
+				CaseSensitive: &wrappers.BoolValue{Value: false},
+			},
+			req:  &Request{Path: "/login"},
+			want: true,
+		},
+		{
+			name: "safe regex",
+			match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_SafeRegex{SafeRegex: &matcher.RegexMatcher{
+				Regex: "/reviews/[0-9]+",
+			}}},
+			req:  &Request{Path: "/reviews/12"},
+			want: true,
+		},
+		{
+			name: "exact header",
+			match: &route.RouteMatch{
+				PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+				Headers: []*route.HeaderMatcher{{
+					Name:                 "end-user",
+					HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "jason"},
+				}},
+			},
+			req:  &Request{Headers: map[string]string{"End-User": "jason"}},
+			want: true,
+		},
+		{
+			name: "missing header",
+			match: &route.RouteMatch{
+				PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+				Headers: []*route.HeaderMatcher{{
+					Name:                 "end-user",
+					HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "jason"},
+				}},
+			},
+			req:  &Request{},
+			want: false,
+		},
+		{
+			name: "method",
+			match: &route.RouteMatch{
+				PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+				Headers: []*route.HeaderMatcher{{
+					Name:                 ":method",
+					HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "POST"},
+				}},
+			},
+			req:  &Request{},
+			want: false,
+		},
+		{
+			name: "query parameter",
+			match: &route.RouteMatch{
+				PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+				QueryParameters: []*route.QueryParameterMatcher{{
+					Name:                         "debug",
+					QueryParameterMatchSpecifier: &route.QueryParameterMatcher_PresentMatch{PresentMatch: true},
+				}},
+			},
+			req:  &Request{Path: "/?debug"},
+			want: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, reasons := matchRoute(&route.Route{Match: c.match}, c.req)
+			if got != c.want {
+				t.Fatalf("matchRoute() = %v %v, want %v", got, reasons, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,507 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package routetrace simulates how proxies handle a request, by walking the configuration they actually use, as found
+// in their config dumps: listener, filter chain, virtual host, route, cluster and endpoints.
+package routetrace
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	rbac_http "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/rbac/v2"
+	http_conn "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	rbac_network "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/rbac/v2"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
+	rbac "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
+	"github.com/golang/protobuf/ptypes"
+
+	"istio.io/istio/istioctl/pkg/authz"
+	"istio.io/istio/istioctl/pkg/util/clusters"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+)
+
+// RouteTrace identifies a route of a virtual host.
+type RouteTrace struct {
+	Index int
+	Name  string
+
+	// Reasons are the conditions the request satisfied for a matched route, or the condition it failed otherwise.
+	Reasons []string
+}
+
+func (r RouteTrace) String() string {
+	if r.Name == "" {
+		return fmt.Sprintf("#%d", r.Index)
+	}
+	return fmt.Sprintf("#%d %q", r.Index, r.Name)
+}
+
+// WeightedCluster is one of the clusters a route splits traffic between.
+type WeightedCluster struct {
+	Name   string
+	Weight uint32
+	Chosen bool
+}
+
+// RBACFilter is an RBAC filter applied to the request.
+type RBACFilter struct {
+	Name     string
+	Action   string
+	Shadow   bool
+	Policies []string
+}
+
+// ProxyTrace is the path of a request through a proxy.
+type ProxyTrace struct {
+	Listener       string
+	ListenerReason string
+
+	// FilterChain is the index of the filter chain handling the connection, with the criteria it matched.
+	FilterChain        int
+	FilterChainReasons []string
+
+	// Filter is the name of the network filter handling the request: an HTTP connection manager or a TCP proxy.
+	Filter string
+
+	// RBAC filters of the filter chain.
+	RBAC []RBACFilter
+
+	// Authz is the decision of the RBAC filters on the request, if there are any.
+	Authz *authz.Decision
+
+	RouteConfig   string
+	VirtualHost   string
+	Domain        string
+	Route         *RouteTrace
+	SkippedRoutes []RouteTrace
+
+	// Action is what the route does with the request when it doesn't forward it to a cluster, e.g. a redirect.
+	Action string
+
+	WeightedClusters []WeightedCluster
+	Cluster          *ClusterTrace
+
+	// Dropped explains why the proxy doesn't forward the request, if it doesn't.
+	Dropped string
+
+	// Enforced rules of the network and HTTP RBAC filters.
+	networkRules []*rbac.RBAC
+	httpRules    []*rbac.RBAC
+}
+
+// IsHTTP returns true if the request was handled as HTTP.
+func (pt *ProxyTrace) IsHTTP() bool {
+	return pt.RouteConfig != ""
+}
+
+// Tracer traces requests through the configuration of a proxy.
+type Tracer struct {
+	configDump *configdump.Wrapper
+	clusters   *clusters.Wrapper
+}
+
+// NewTracer creates a Tracer for the proxy with the given config dump. The clusters, as returned by the Envoy admin
+// /clusters endpoint, are optional and provide the endpoints of the upstream clusters.
+func NewTracer(cd *configdump.Wrapper, cl *clusters.Wrapper) *Tracer {
+	return &Tracer{configDump: cd, clusters: cl}
+}
+
+// Outbound traces a request sent by the application next to the proxy.
+func (t *Tracer) Outbound(req *Request) (*ProxyTrace, error) {
+	listeners, err := t.configDump.GetListeners()
+	if err != nil {
+		return nil, err
+	}
+	conn := connection{
+		destinationIP:        req.DestinationIP,
+		destinationPort:      req.Port,
+		sourceIP:             req.SourceIP,
+		transportProtocol:    rawBufferTransport,
+		applicationProtocols: []string{"http/1.1"},
+	}
+	l, reason := selectOutboundListener(listeners, conn)
+	return t.trace(l, reason, conn, req)
+}
+
+// Inbound traces a request received by the proxy from another proxy, which sent it to the endpoint of a cluster.
+func (t *Tracer) Inbound(req *Request, from *ProxyTrace, endpoint Endpoint) (*ProxyTrace, error) {
+	listeners, err := t.configDump.GetListeners()
+	if err != nil {
+		return nil, err
+	}
+	conn := connection{
+		destinationIP:        endpoint.Address,
+		destinationPort:      endpoint.Port,
+		sourceIP:             req.SourceIP,
+		transportProtocol:    rawBufferTransport,
+		applicationProtocols: []string{"http/1.1"},
+	}
+	if upstream := from.Cluster; upstream != nil && upstream.TLS {
+		conn.transportProtocol = tlsTransport
+		conn.serverName = upstream.SNI
+		conn.applicationProtocols = upstream.ALPN
+		if upstream.IsMutualTLS() {
+			conn.sourcePrincipal = req.SourcePrincipal
+			if from.IsHTTP() {
+				// The source proxy advertises the protocol of the HTTP requests it sends over mutual TLS.
+				conn.applicationProtocols = []string{"istio-http/1.1", "istio"}
+			}
+		}
+	}
+	l, reason := selectInboundListener(listeners, conn)
+	return t.trace(l, reason, conn, req)
+}
+
+func (t *Tracer) trace(l *xdsapi.Listener, reason string, conn connection, req *Request) (*ProxyTrace, error) {
+	pt := &ProxyTrace{FilterChain: -1}
+	if l == nil {
+		pt.Dropped = "no listener accepts the connection"
+		return pt, nil
+	}
+	pt.Listener, pt.ListenerReason = l.GetName(), reason
+
+	i, reasons := selectFilterChain(l.GetFilterChains(), conn)
+	if i < 0 {
+		pt.Dropped = "no filter chain matches the connection, which is closed"
+		return pt, nil
+	}
+	pt.FilterChain, pt.FilterChainReasons = i, reasons
+
+	for _, f := range l.GetFilterChains()[i].GetFilters() {
+		config := f.GetTypedConfig()
+		if config == nil {
+			continue
+		}
+		switch {
+		case ptypes.Is(config, &rbac_network.RBAC{}):
+			filter := &rbac_network.RBAC{}
+			if err := ptypes.UnmarshalAny(config, filter); err != nil {
+				return nil, err
+			}
+			pt.addRBAC(f.GetName(), filter.GetRules(), filter.GetShadowRules(), true)
+		case ptypes.Is(config, &http_conn.HttpConnectionManager{}):
+			hcm := &http_conn.HttpConnectionManager{}
+			if err := ptypes.UnmarshalAny(config, hcm); err != nil {
+				return nil, err
+			}
+			pt.Filter = f.GetName()
+			err := t.traceHTTP(pt, hcm, req)
+			pt.authorize(req, conn, true)
+			return pt, err
+		case ptypes.Is(config, &tcp.TcpProxy{}):
+			proxy := &tcp.TcpProxy{}
+			if err := ptypes.UnmarshalAny(config, proxy); err != nil {
+				return nil, err
+			}
+			pt.Filter = f.GetName()
+			err := t.traceTCP(pt, proxy, req)
+			pt.authorize(req, conn, false)
+			return pt, err
+		}
+	}
+	pt.Dropped = "the filter chain has neither an HTTP connection manager nor a TCP proxy"
+	return pt, nil
+}
+
+func (pt *ProxyTrace) addRBAC(name string, rules, shadowRules *rbac.RBAC, network bool) {
+	if rules != nil {
+		if network {
+			pt.networkRules = append(pt.networkRules, rules)
+		} else {
+			pt.httpRules = append(pt.httpRules, rules)
+		}
+	}
+	for _, r := range []struct {
+		rules  *rbac.RBAC
+		shadow bool
+	}{{rules, false}, {shadowRules, true}} {
+		if r.rules == nil {
+			continue
+		}
+		policies := make([]string, 0, len(r.rules.GetPolicies()))
+		for policy := range r.rules.GetPolicies() {
+			policies = append(policies, policy)
+		}
+		sort.Strings(policies)
+		pt.RBAC = append(pt.RBAC, RBACFilter{
+			Name:     name,
+			Action:   r.rules.GetAction().String(),
+			Shadow:   r.shadow,
+			Policies: policies,
+		})
+	}
+}
+
+// authorize evaluates the request against the enforced rules of the RBAC filters: the network filters, which only
+// see the connection, then the HTTP filters.
+func (pt *ProxyTrace) authorize(req *Request, conn connection, http bool) {
+	if len(pt.networkRules) == 0 && len(pt.httpRules) == 0 {
+		return
+	}
+	ar := &authz.Request{
+		SourcePrincipal: conn.sourcePrincipal,
+		SourceIP:        conn.sourceIP,
+		DestinationIP:   conn.destinationIP,
+		DestinationPort: conn.destinationPort,
+		ServerName:      conn.serverName,
+		Method:          req.method(),
+		Host:            req.authority(),
+		Path:            req.fullPath(),
+		Headers:         req.Headers,
+	}
+	pt.Authz = authz.EvaluateRBAC(pt.networkRules, ar, true)
+	if pt.Authz.Allowed && !pt.Authz.Undetermined && len(pt.httpRules) > 0 {
+		pt.Authz = authz.EvaluateRBAC(pt.httpRules, ar, false)
+	}
+	if pt.Authz.Allowed || pt.Authz.Undetermined {
+		return
+	}
+	if http {
+		pt.Dropped = "the RBAC filters deny the request, the proxy responds 403"
+	} else {
+		pt.Dropped = "the RBAC filters deny the connection, which is closed"
+	}
+}
+
+func (t *Tracer) traceHTTP(pt *ProxyTrace, hcm *http_conn.HttpConnectionManager, req *Request) error {
+	for _, f := range hcm.GetHttpFilters() {
+		if config := f.GetTypedConfig(); config != nil && ptypes.Is(config, &rbac_http.RBAC{}) {
+			filter := &rbac_http.RBAC{}
+			if err := ptypes.UnmarshalAny(config, filter); err != nil {
+				return err
+			}
+			pt.addRBAC(f.GetName(), filter.GetRules(), filter.GetShadowRules(), false)
+		}
+	}
+
+	rc := hcm.GetRouteConfig()
+	if rds := hcm.GetRds(); rds != nil {
+		var err error
+		if rc, err = t.configDump.GetRouteConfiguration(rds.GetRouteConfigName()); err != nil {
+			return err
+		}
+		if rc == nil {
+			pt.RouteConfig = rds.GetRouteConfigName()
+			pt.Dropped = fmt.Sprintf("route configuration %q isn't in the config dump", rds.GetRouteConfigName())
+			return nil
+		}
+	}
+	if rc == nil {
+		pt.Dropped = "the HTTP connection manager has no route configuration"
+		return nil
+	}
+	pt.RouteConfig = rc.GetName()
+
+	authority := req.authority()
+	vh, domain := selectVirtualHost(rc.GetVirtualHosts(), authority)
+	if vh == nil {
+		pt.Dropped = fmt.Sprintf("no virtual host matches the host %q, the proxy responds 404", authority)
+		return nil
+	}
+	pt.VirtualHost, pt.Domain = vh.GetName(), domain
+
+	for i, r := range vh.GetRoutes() {
+		ok, reasons := matchRoute(r, req)
+		rt := RouteTrace{Index: i, Name: r.GetName(), Reasons: reasons}
+		if !ok {
+			pt.SkippedRoutes = append(pt.SkippedRoutes, rt)
+			continue
+		}
+		pt.Route = &rt
+		return t.traceRouteAction(pt, r, req)
+	}
+	pt.Dropped = "no route matches the request, the proxy responds 404"
+	return nil
+}
+
+func (t *Tracer) traceRouteAction(pt *ProxyTrace, r *route.Route, req *Request) error {
+	switch a := r.GetAction().(type) {
+	case *route.Route_Route:
+		action := a.Route
+		switch {
+		case action.GetCluster() != "":
+			return t.traceCluster(pt, action.GetCluster())
+		case action.GetClusterHeader() != "":
+			name, ok := req.header(action.GetClusterHeader())
+			if !ok {
+				pt.Dropped = fmt.Sprintf("header %q naming the cluster is missing, the proxy responds 404", action.GetClusterHeader())
+				return nil
+			}
+			return t.traceCluster(pt, name)
+		case action.GetWeightedClusters() != nil:
+			var names []string
+			var weights []uint32
+			for _, wc := range action.GetWeightedClusters().GetClusters() {
+				names = append(names, wc.GetName())
+				weights = append(weights, wc.GetWeight().GetValue())
+			}
+			return t.traceWeightedClusters(pt, names, weights, req.WeightRoll)
+		}
+		pt.Dropped = "the route has no cluster"
+	case *route.Route_Redirect:
+		pt.Action = fmt.Sprintf("redirect to host %q, path %q", a.Redirect.GetHostRedirect(), a.Redirect.GetPathRedirect())
+	case *route.Route_DirectResponse:
+		pt.Action = fmt.Sprintf("direct response with status %d", a.DirectResponse.GetStatus())
+	default:
+		pt.Dropped = "unsupported route action"
+	}
+	return nil
+}
+
+func (t *Tracer) traceTCP(pt *ProxyTrace, proxy *tcp.TcpProxy, req *Request) error {
+	if proxy.GetCluster() != "" {
+		return t.traceCluster(pt, proxy.GetCluster())
+	}
+	if wcs := proxy.GetWeightedClusters(); wcs != nil {
+		var names []string
+		var weights []uint32
+		for _, wc := range wcs.GetClusters() {
+			names = append(names, wc.GetName())
+			weights = append(weights, wc.GetWeight())
+		}
+		return t.traceWeightedClusters(pt, names, weights, req.WeightRoll)
+	}
+	pt.Dropped = "the TCP proxy has no cluster"
+	return nil
+}
+
+func (t *Tracer) traceWeightedClusters(pt *ProxyTrace, names []string, weights []uint32, roll float64) error {
+	if len(names) == 0 {
+		pt.Dropped = "the route has no cluster"
+		return nil
+	}
+
+	chosen := chooseWeighted(weights, roll)
+	for i, name := range names {
+		pt.WeightedClusters = append(pt.WeightedClusters, WeightedCluster{Name: name, Weight: weights[i], Chosen: i == chosen})
+	}
+	return t.traceCluster(pt, names[chosen])
+}
+
+// chooseWeighted returns the index of the first weight whose cumulative weight exceeds roll times the total weight.
+func chooseWeighted(weights []uint32, roll float64) int {
+	var total uint64
+	for _, w := range weights {
+		total += uint64(w)
+	}
+	if roll < 0 {
+		roll = 0
+	}
+	target := roll * float64(total)
+	var cumulative uint64
+	last := 0
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		last = i
+		cumulative += uint64(w)
+		if target < float64(cumulative) {
+			return i
+		}
+	}
+	return last
+}
+
+func (t *Tracer) traceCluster(pt *ProxyTrace, name string) error {
+	cluster, err := t.configDump.GetCluster(name)
+	if err != nil {
+		return err
+	}
+	if pt.Cluster, err = traceCluster(cluster, name, t.clusters); err != nil {
+		return err
+	}
+	if pt.Cluster.Missing {
+		pt.Dropped = fmt.Sprintf("cluster %q isn't in the config dump, the proxy responds 503", name)
+	}
+	return nil
+}
+
+// Print writes a human readable description of the trace.
+func (pt *ProxyTrace) Print(w io.Writer) {
+	if pt.Listener != "" {
+		fmt.Fprintf(w, "  Listener:       %s (%s)\n", pt.Listener, pt.ListenerReason)
+	}
+	if pt.FilterChain >= 0 {
+		fmt.Fprintf(w, "  Filter chain:   #%d (%s)\n", pt.FilterChain, strings.Join(pt.FilterChainReasons, ", "))
+	}
+	if pt.Filter != "" {
+		fmt.Fprintf(w, "  Filter:         %s\n", pt.Filter)
+	}
+	for _, r := range pt.RBAC {
+		shadow := ""
+		if r.Shadow {
+			shadow = " (shadow)"
+		}
+		fmt.Fprintf(w, "  RBAC:           %s %s%s, policies: %s\n", r.Name, r.Action, shadow, strings.Join(r.Policies, ", "))
+	}
+	if d := pt.Authz; d != nil {
+		decidedBy := ""
+		if d.Policy != "" {
+			decidedBy = fmt.Sprintf(" by %s %s", d.Action, d.Policy)
+			if d.Rule != "" {
+				decidedBy += fmt.Sprintf(" (%s)", d.Rule)
+			}
+		}
+		fmt.Fprintf(w, "  Authorization:  %s%s: %s\n", d.Result(), decidedBy, d.Reason)
+	}
+	if pt.RouteConfig != "" {
+		fmt.Fprintf(w, "  Routes:         %s\n", pt.RouteConfig)
+	}
+	if pt.VirtualHost != "" {
+		fmt.Fprintf(w, "  Virtual host:   %s (domain %q)\n", pt.VirtualHost, pt.Domain)
+	}
+	for _, r := range pt.SkippedRoutes {
+		fmt.Fprintf(w, "  Skipped route:  %s: %s\n", r, strings.Join(r.Reasons, ", "))
+	}
+	if pt.Route != nil {
+		fmt.Fprintf(w, "  Route:          %s (%s)\n", pt.Route, strings.Join(pt.Route.Reasons, ", "))
+	}
+	if pt.Action != "" {
+		fmt.Fprintf(w, "  Action:         %s\n", pt.Action)
+	}
+	if len(pt.WeightedClusters) > 0 {
+		fmt.Fprintf(w, "  Weighted clusters:\n")
+		for _, wc := range pt.WeightedClusters {
+			chosen := ""
+			if wc.Chosen {
+				chosen = " (chosen)"
+			}
+			fmt.Fprintf(w, "    %s weight %d%s\n", wc.Name, wc.Weight, chosen)
+		}
+	}
+	if c := pt.Cluster; c != nil && !c.Missing {
+		tls := "plaintext"
+		if c.IsMutualTLS() {
+			tls = "Istio mutual TLS"
+		} else if c.TLS {
+			tls = "TLS"
+		}
+		fmt.Fprintf(w, "  Cluster:        %s (%s, %s, %s)\n", c.Name, c.Type, c.LbPolicy, tls)
+		if len(c.Endpoints) > 0 {
+			fmt.Fprintf(w, "  Endpoints:\n")
+			for _, e := range c.Endpoints {
+				fmt.Fprintf(w, "    %s:%d priority %d weight %d %s\n", e.Address, e.Port, e.Priority, e.Weight, e.Health)
+			}
+		}
+	}
+	if pt.Dropped != "" {
+		fmt.Fprintf(w, "  Dropped:        %s\n", pt.Dropped)
+	}
+}
@@ -0,0 +1,414 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routetrace
+
+import (
+	"testing"
+
+	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbac_http "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/rbac/v2"
+	http_conn "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	tcp "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
+	rbac "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"istio.io/istio/istioctl/pkg/util/clusters"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/security/authz/model/matcher"
+)
+
+func marshalAny(t *testing.T, m proto.Message) *any.Any {
+	t.Helper()
+	a, err := ptypes.MarshalAny(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func socketAddress(address string, port uint32) *core.Address {
+	return &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+		Address:       address,
+		PortSpecifier: &core.SocketAddress_PortValue{PortValue: port},
+	}}}
+}
+
+func hostStatus(address string, port uint32, health corev3.HealthStatus) *adminapi.HostStatus {
+	return &adminapi.HostStatus{
+		Address: &corev3.Address{Address: &corev3.Address_SocketAddress{SocketAddress: &corev3.SocketAddress{
+			Address:       address,
+			PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: port},
+		}}},
+		HealthStatus: &adminapi.HostHealthStatus{EdsHealthStatus: health},
+		Weight:       1,
+	}
+}
+
+func httpFilter(t *testing.T, rds string, httpFilters ...*http_conn.HttpFilter) *listener.Filter {
+	return &listener.Filter{
+		Name: "envoy.http_connection_manager",
+		ConfigType: &listener.Filter_TypedConfig{TypedConfig: marshalAny(t, &http_conn.HttpConnectionManager{
+			RouteSpecifier: &http_conn.HttpConnectionManager_Rds{Rds: &http_conn.Rds{RouteConfigName: rds}},
+			HttpFilters:    httpFilters,
+		})},
+	}
+}
+
+func tcpFilter(t *testing.T, cluster string) *listener.Filter {
+	return &listener.Filter{
+		Name: "envoy.tcp_proxy",
+		ConfigType: &listener.Filter_TypedConfig{TypedConfig: marshalAny(t, &tcp.TcpProxy{
+			ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: cluster},
+		})},
+	}
+}
+
+func newConfigDump(t *testing.T, listeners []*xdsapi.Listener, routes []*xdsapi.RouteConfiguration,
+	cls []*xdsapi.Cluster) *configdump.Wrapper {
+	t.Helper()
+	listenerDump := &adminapi.ListenersConfigDump{}
+	for _, l := range listeners {
+		listenerDump.DynamicListeners = append(listenerDump.DynamicListeners, &adminapi.ListenersConfigDump_DynamicListener{
+			Name:        l.Name,
+			ActiveState: &adminapi.ListenersConfigDump_DynamicListenerState{Listener: marshalAny(t, l)},
+		})
+	}
+	routeDump := &adminapi.RoutesConfigDump{}
+	for _, r := range routes {
+		routeDump.DynamicRouteConfigs = append(routeDump.DynamicRouteConfigs, &adminapi.RoutesConfigDump_DynamicRouteConfig{
+			RouteConfig: marshalAny(t, r),
+		})
+	}
+	clusterDump := &adminapi.ClustersConfigDump{}
+	for _, c := range cls {
+		clusterDump.DynamicActiveClusters = append(clusterDump.DynamicActiveClusters, &adminapi.ClustersConfigDump_DynamicCluster{
+			Cluster: marshalAny(t, c),
+		})
+	}
+	return &configdump.Wrapper{ConfigDump: &adminapi.ConfigDump{Configs: []*any.Any{
+		marshalAny(t, listenerDump),
+		marshalAny(t, routeDump),
+		marshalAny(t, clusterDump),
+	}}}
+}
+
+func clientProxy(t *testing.T) *Tracer {
+	listeners := []*xdsapi.Listener{
+		{
+			Name:           "virtualOutbound",
+			Address:        socketAddress("0.0.0.0", 15001),
+			UseOriginalDst: &wrappers.BoolValue{Value: true},
+			FilterChains:   []*listener.FilterChain{{Filters: []*listener.Filter{tcpFilter(t, "PassthroughCluster")}}},
+		},
+		{
+			Name:    "0.0.0.0_9080",
+			Address: socketAddress("0.0.0.0", 9080),
+			FilterChains: []*listener.FilterChain{
+				{
+					FilterChainMatch: &listener.FilterChainMatch{
+						PrefixRanges: []*core.CidrRange{{AddressPrefix: "10.0.1.2", PrefixLen: &wrappers.UInt32Value{Value: 32}}},
+					},
+					Filters: []*listener.Filter{tcpFilter(t, "BlackHoleCluster")},
+				},
+				{
+					FilterChainMatch: &listener.FilterChainMatch{ApplicationProtocols: []string{"http/1.0", "http/1.1", "h2c"}},
+					Filters:          []*listener.Filter{httpFilter(t, "9080")},
+				},
+				{
+					Filters: []*listener.Filter{tcpFilter(t, "PassthroughCluster")},
+				},
+			},
+		},
+		{
+			Name:         "10.96.0.20_3306",
+			Address:      socketAddress("10.96.0.20", 3306),
+			FilterChains: []*listener.FilterChain{{Filters: []*listener.Filter{tcpFilter(t, "outbound|3306||mysql.default.svc.cluster.local")}}},
+		},
+	}
+
+	routes := []*xdsapi.RouteConfiguration{{
+		Name: "9080",
+		VirtualHosts: []*route.VirtualHost{
+			{
+				Name:    "reviews.default.svc.cluster.local:9080",
+				Domains: []string{"reviews.default.svc.cluster.local", "reviews", "reviews:9080", "10.96.0.10:9080"},
+				Routes: []*route.Route{
+					{
+						Name: "jason",
+						Match: &route.RouteMatch{
+							PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+							Headers: []*route.HeaderMatcher{{
+								Name:                 "end-user",
+								HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "jason"},
+							}},
+						},
+						Action: &route.Route_Route{Route: &route.RouteAction{
+							ClusterSpecifier: &route.RouteAction_Cluster{Cluster: "outbound|9080|v2|reviews.default.svc.cluster.local"},
+						}},
+					},
+					{
+						Name:  "default",
+						Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"}},
+						Action: &route.Route_Route{Route: &route.RouteAction{
+							ClusterSpecifier: &route.RouteAction_WeightedClusters{WeightedClusters: &route.WeightedCluster{
+								Clusters: []*route.WeightedCluster_ClusterWeight{
+									{Name: "outbound|9080|v1|reviews.default.svc.cluster.local", Weight: &wrappers.UInt32Value{Value: 75}},
+									{Name: "outbound|9080|v3|reviews.default.svc.cluster.local", Weight: &wrappers.UInt32Value{Value: 25}},
+								},
+							}},
+						}},
+					},
+				},
+			},
+		},
+	}}
+
+	var cls []*xdsapi.Cluster
+	for _, name := range []string{
+		"outbound|9080|v1|reviews.default.svc.cluster.local",
+		"outbound|9080|v2|reviews.default.svc.cluster.local",
+		"outbound|9080|v3|reviews.default.svc.cluster.local",
+	} {
+		cls = append(cls, &xdsapi.Cluster{
+			Name:                 name,
+			ClusterDiscoveryType: &xdsapi.Cluster_Type{Type: xdsapi.Cluster_EDS},
+			TransportSocket: &core.TransportSocket{
+				Name: "envoy.transport_sockets.tls",
+				ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: marshalAny(t, &auth.UpstreamTlsContext{
+					Sni:              name,
+					CommonTlsContext: &auth.CommonTlsContext{AlpnProtocols: []string{"istio-peer-exchange", "istio"}},
+				})},
+			},
+		})
+	}
+
+	endpoints := &clusters.Wrapper{Clusters: &adminapi.Clusters{ClusterStatuses: []*adminapi.ClusterStatus{{
+		Name: "outbound|9080|v2|reviews.default.svc.cluster.local",
+		HostStatuses: []*adminapi.HostStatus{
+			hostStatus("10.0.1.4", 9080, corev3.HealthStatus_UNHEALTHY),
+			hostStatus("10.0.1.3", 9080, corev3.HealthStatus_HEALTHY),
+		},
+	}}}}
+
+	return NewTracer(newConfigDump(t, listeners, routes, cls), endpoints)
+}
+
+func serverProxy(t *testing.T) *Tracer {
+	rbacFilter := &http_conn.HttpFilter{
+		Name: "envoy.filters.http.rbac",
+		ConfigType: &http_conn.HttpFilter_TypedConfig{TypedConfig: marshalAny(t, &rbac_http.RBAC{
+			Rules: &rbac.RBAC{
+				Action: rbac.RBAC_ALLOW,
+				Policies: map[string]*rbac.Policy{"ns[default]-policy[reviews-viewer]-rule[0]": {
+					Permissions: []*rbac.Permission{{Rule: &rbac.Permission_Header{Header: matcher.HeaderMatcher(":method", "GET")}}},
+					Principals: []*rbac.Principal{{Identifier: &rbac.Principal_Authenticated_{Authenticated: &rbac.Principal_Authenticated{
+						PrincipalName: matcher.StringMatcherWithPrefix("cluster.local/ns/default/sa/productpage", "spiffe://", true),
+					}}}},
+				}},
+			},
+		})},
+	}
+	listeners := []*xdsapi.Listener{{
+		Name:    "virtualInbound",
+		Address: socketAddress("0.0.0.0", 15006),
+		FilterChains: []*listener.FilterChain{
+			{
+				FilterChainMatch: &listener.FilterChainMatch{
+					DestinationPort:      &wrappers.UInt32Value{Value: 9080},
+					TransportProtocol:    tlsTransport,
+					ApplicationProtocols: []string{"istio-http/1.0", "istio-http/1.1", "istio-h2"},
+				},
+				Filters: []*listener.Filter{httpFilter(t, "inbound|9080|http|reviews.default.svc.cluster.local", rbacFilter)},
+			},
+			{
+				FilterChainMatch: &listener.FilterChainMatch{
+					DestinationPort:      &wrappers.UInt32Value{Value: 9080},
+					ApplicationProtocols: []string{"http/1.0", "http/1.1", "h2c"},
+				},
+				Filters: []*listener.Filter{httpFilter(t, "inbound|9080|http|reviews.default.svc.cluster.local", rbacFilter)},
+			},
+		},
+	}}
+	routes := []*xdsapi.RouteConfiguration{{
+		Name: "inbound|9080|http|reviews.default.svc.cluster.local",
+		VirtualHosts: []*route.VirtualHost{{
+			Name:    "inbound|http|9080",
+			Domains: []string{"*"},
+			Routes: []*route.Route{{
+				Name:  "default",
+				Match: &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"}},
+				Action: &route.Route_Route{Route: &route.RouteAction{
+					ClusterSpecifier: &route.RouteAction_Cluster{Cluster: "inbound|9080|http|reviews.default.svc.cluster.local"},
+				}},
+			}},
+		}},
+	}}
+	cls := []*xdsapi.Cluster{{
+		Name:                 "inbound|9080|http|reviews.default.svc.cluster.local",
+		ClusterDiscoveryType: &xdsapi.Cluster_Type{Type: xdsapi.Cluster_STATIC},
+	}}
+	return NewTracer(newConfigDump(t, listeners, routes, cls), nil)
+}
+
+func TestOutbound(t *testing.T) {
+	tracer := clientProxy(t)
+
+	cases := []struct {
+		name         string
+		req          *Request
+		listener     string
+		filter       string
+		route        string
+		cluster      string
+		skippedRoute int
+		dropped      bool
+	}{
+		{
+			name:         "header match",
+			req:          &Request{Host: "reviews", Port: 9080, DestinationIP: "10.96.0.10", Headers: map[string]string{"end-user": "jason"}},
+			listener:     "0.0.0.0_9080",
+			filter:       "envoy.http_connection_manager",
+			route:        "jason",
+			cluster:      "outbound|9080|v2|reviews.default.svc.cluster.local",
+			skippedRoute: 0,
+		},
+		{
+			name:         "weighted clusters",
+			req:          &Request{Host: "reviews", Port: 9080, DestinationIP: "10.96.0.10", WeightRoll: 0.8},
+			listener:     "0.0.0.0_9080",
+			filter:       "envoy.http_connection_manager",
+			route:        "default",
+			cluster:      "outbound|9080|v3|reviews.default.svc.cluster.local",
+			skippedRoute: 1,
+		},
+		{
+			name:     "unknown host",
+			req:      &Request{Host: "details", Port: 9080, DestinationIP: "10.96.0.30"},
+			listener: "0.0.0.0_9080",
+			filter:   "envoy.http_connection_manager",
+			dropped:  true,
+		},
+		{
+			name:     "TCP",
+			req:      &Request{Host: "mysql", Port: 3306, DestinationIP: "10.96.0.20"},
+			listener: "10.96.0.20_3306",
+			filter:   "envoy.tcp_proxy",
+			cluster:  "outbound|3306||mysql.default.svc.cluster.local",
+			dropped:  true,
+		},
+		{
+			name:     "passthrough",
+			req:      &Request{Host: "example.com", Port: 443, DestinationIP: "93.184.216.34"},
+			listener: "virtualOutbound",
+			filter:   "envoy.tcp_proxy",
+			cluster:  "PassthroughCluster",
+			dropped:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pt, err := tracer.Outbound(c.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pt.Listener != c.listener || pt.Filter != c.filter {
+				t.Fatalf("got listener %q filter %q, want %q %q", pt.Listener, pt.Filter, c.listener, c.filter)
+			}
+			if c.route != "" && (pt.Route == nil || pt.Route.Name != c.route) {
+				t.Fatalf("got route %v, want %q", pt.Route, c.route)
+			}
+			if len(pt.SkippedRoutes) != c.skippedRoute {
+				t.Fatalf("got skipped routes %v, want %d", pt.SkippedRoutes, c.skippedRoute)
+			}
+			if c.cluster != "" && (pt.Cluster == nil || pt.Cluster.Name != c.cluster) {
+				t.Fatalf("got cluster %v, want %q", pt.Cluster, c.cluster)
+			}
+			if (pt.Dropped != "") != c.dropped {
+				t.Fatalf("got dropped %q, want dropped %v", pt.Dropped, c.dropped)
+			}
+		})
+	}
+}
+
+func TestInbound(t *testing.T) {
+	req := &Request{Host: "reviews", Port: 9080, DestinationIP: "10.96.0.10", Headers: map[string]string{"end-user": "jason"},
+		SourcePrincipal: "cluster.local/ns/default/sa/productpage"}
+	out, err := clientProxy(t).Outbound(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Cluster.IsMutualTLS() {
+		t.Fatalf("cluster %s doesn't use mutual TLS", out.Cluster.Name)
+	}
+	endpoint := out.Cluster.SelectedEndpoint()
+	if endpoint == nil || endpoint.Address != "10.0.1.3" {
+		t.Fatalf("got endpoint %v, want 10.0.1.3", endpoint)
+	}
+
+	in, err := serverProxy(t).Inbound(req, out, *endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Listener != "virtualInbound" || in.FilterChain != 0 {
+		t.Fatalf("got listener %q filter chain %d, want virtualInbound 0", in.Listener, in.FilterChain)
+	}
+	if len(in.RBAC) != 1 || in.RBAC[0].Policies[0] != "ns[default]-policy[reviews-viewer]-rule[0]" {
+		t.Fatalf("got RBAC %v", in.RBAC)
+	}
+	if in.Cluster == nil || in.Cluster.Name != "inbound|9080|http|reviews.default.svc.cluster.local" || in.Dropped != "" {
+		t.Fatalf("got cluster %v, dropped %q", in.Cluster, in.Dropped)
+	}
+	if in.Authz == nil || !in.Authz.Allowed || in.Authz.Policy != "ns[default]-policy[reviews-viewer]-rule[0]" {
+		t.Fatalf("got authorization %+v, want ALLOW by ns[default]-policy[reviews-viewer]-rule[0]", in.Authz)
+	}
+
+	// The request of another workload is denied.
+	req.SourcePrincipal = "cluster.local/ns/default/sa/ratings"
+	if in, err = serverProxy(t).Inbound(req, out, *endpoint); err != nil {
+		t.Fatal(err)
+	}
+	if in.Authz == nil || in.Authz.Allowed || in.Authz.Undetermined || in.Dropped == "" {
+		t.Fatalf("got authorization %+v, dropped %q, want DENY", in.Authz, in.Dropped)
+	}
+}
+
+func TestChooseWeighted(t *testing.T) {
+	cases := []struct {
+		weights []uint32
+		roll    float64
+		want    int
+	}{
+		{[]uint32{75, 25}, 0, 0},
+		{[]uint32{75, 25}, 0.74, 0},
+		{[]uint32{75, 25}, 0.75, 1},
+		{[]uint32{75, 25}, 0.99, 1},
+		{[]uint32{0, 100}, 0, 1},
+		{[]uint32{50, 0}, 0.99, 0},
+	}
+	for _, c := range cases {
+		if got := chooseWeighted(c.weights, c.roll); got != c.want {
+			t.Errorf("chooseWeighted(%v, %v) = %d, want %d", c.weights, c.roll, got, c.want)
+		}
+	}
+}
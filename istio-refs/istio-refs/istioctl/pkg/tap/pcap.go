@@ -0,0 +1,230 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	datatap "github.com/envoyproxy/go-control-plane/envoy/data/tap/v2alpha"
+)
+
+const (
+	pcapMagic    = 0xa1b2c3d4
+	pcapSnapLen  = 65535
+	linkTypeRaw  = 101
+	tcpProtocol  = 6
+	maxSegment   = 1460
+	serverPort   = 80
+	firstPort    = 32768
+	portRange    = 28232
+	tcpFlagFin   = 0x01
+	tcpFlagSyn   = 0x02
+	tcpFlagPush  = 0x08
+	tcpFlagAck   = 0x10
+	tcpWindow    = 65535
+	ipHeaderLen  = 20
+	tcpHeaderLen = 20
+)
+
+var (
+	clientIP = net.IPv4(10, 0, 0, 1).To4()
+	serverIP = net.IPv4(10, 0, 0, 2).To4()
+)
+
+// PcapWriter writes traces to a pcap file, each as an HTTP/1.1 exchange over its own synthesized TCP connection,
+// so that they can be inspected with Wireshark or tcpdump. The addresses and ports are not the original ones.
+type PcapWriter struct {
+	w           io.Writer
+	connections int
+}
+
+// NewPcapWriter writes the pcap file header, and returns a writer of the traces.
+func NewPcapWriter(w io.Writer) (*PcapWriter, error) {
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], pcapSnapLen)
+	binary.LittleEndian.PutUint32(header[20:], linkTypeRaw)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &PcapWriter{w: w}, nil
+}
+
+// Write writes the packets of a trace.
+func (p *PcapWriter) Write(t *Trace) error {
+	conn := &tcpConnection{
+		w:          p.w,
+		time:       t.Time,
+		clientPort: uint16(firstPort + p.connections%portRange),
+	}
+	p.connections++
+
+	conn.send(true, tcpFlagSyn, nil)
+	conn.send(false, tcpFlagSyn|tcpFlagAck, nil)
+	conn.send(true, tcpFlagAck, nil)
+	conn.sendData(true, httpRequest(t.Trace.GetRequest()))
+	if t.Trace.GetResponse() != nil {
+		conn.sendData(false, httpResponse(t.Trace.GetResponse()))
+	}
+	conn.send(true, tcpFlagFin|tcpFlagAck, nil)
+	conn.send(false, tcpFlagFin|tcpFlagAck, nil)
+	conn.send(true, tcpFlagAck, nil)
+	return conn.err
+}
+
+// httpRequest returns a request as HTTP/1.1.
+func httpRequest(message *datatap.HttpBufferedTrace_Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", Header(message, ":method"), Header(message, ":path"))
+	if authority := Header(message, ":authority"); authority != "" {
+		fmt.Fprintf(&b, "host: %s\r\n", authority)
+	}
+	writeHTTPMessage(&b, message)
+	return b.Bytes()
+}
+
+// httpResponse returns a response as HTTP/1.1.
+func httpResponse(message *datatap.HttpBufferedTrace_Message) []byte {
+	var b bytes.Buffer
+	status := Header(message, ":status")
+	code, _ := strconv.Atoi(status)
+	fmt.Fprintf(&b, "HTTP/1.1 %s %s\r\n", status, http.StatusText(code))
+	writeHTTPMessage(&b, message)
+	return b.Bytes()
+}
+
+// writeHTTPMessage writes the headers and body of a message. The body may have been truncated, so its length
+// replaces the original framing headers.
+func writeHTTPMessage(b *bytes.Buffer, message *datatap.HttpBufferedTrace_Message) {
+	for _, h := range message.GetHeaders() {
+		key := strings.ToLower(h.Key)
+		if strings.HasPrefix(key, ":") || key == "content-length" || key == "transfer-encoding" {
+			continue
+		}
+		fmt.Fprintf(b, "%s: %s\r\n", h.Key, h.Value)
+	}
+	body, _ := Body(message)
+	fmt.Fprintf(b, "content-length: %d\r\n\r\n", len(body))
+	b.Write(body)
+}
+
+// tcpConnection writes the packets of a TCP connection between the client and the server.
+type tcpConnection struct {
+	w          io.Writer
+	time       time.Time
+	clientPort uint16
+	// the next sequence numbers of the client and the server
+	clientSeq, serverSeq uint32
+	err                  error
+}
+
+func (c *tcpConnection) sendData(fromClient bool, data []byte) {
+	for len(data) > 0 {
+		n := len(data)
+		if n > maxSegment {
+			n = maxSegment
+		}
+		c.send(fromClient, tcpFlagPush|tcpFlagAck, data[:n])
+		data = data[n:]
+	}
+}
+
+func (c *tcpConnection) send(fromClient bool, flags byte, payload []byte) {
+	if c.err != nil {
+		return
+	}
+
+	srcIP, dstIP, srcPort, dstPort := clientIP, serverIP, c.clientPort, uint16(serverPort)
+	seq, ack := &c.clientSeq, c.serverSeq
+	if !fromClient {
+		srcIP, dstIP, srcPort, dstPort = serverIP, clientIP, serverPort, c.clientPort
+		seq, ack = &c.serverSeq, c.clientSeq
+	}
+	if flags&tcpFlagAck == 0 {
+		ack = 0
+	}
+
+	packet := make([]byte, ipHeaderLen+tcpHeaderLen+len(payload))
+	ip := packet[:ipHeaderLen]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(len(packet)))
+	binary.BigEndian.PutUint16(ip[6:], 0x4000)
+	ip[8] = 64
+	ip[9] = tcpProtocol
+	copy(ip[12:], srcIP)
+	copy(ip[16:], dstIP)
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip, 0))
+
+	tcp := packet[ipHeaderLen:]
+	binary.BigEndian.PutUint16(tcp[0:], srcPort)
+	binary.BigEndian.PutUint16(tcp[2:], dstPort)
+	binary.BigEndian.PutUint32(tcp[4:], *seq)
+	binary.BigEndian.PutUint32(tcp[8:], ack)
+	tcp[12] = (tcpHeaderLen / 4) << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:], tcpWindow)
+	copy(tcp[tcpHeaderLen:], payload)
+	binary.BigEndian.PutUint16(tcp[16:], checksum(tcp, pseudoHeaderSum(srcIP, dstIP, len(tcp))))
+
+	// SYN and FIN consume a sequence number
+	*seq += uint32(len(payload))
+	if flags&(tcpFlagSyn|tcpFlagFin) != 0 {
+		*seq++
+	}
+
+	record := make([]byte, 16)
+	binary.LittleEndian.PutUint32(record[0:], uint32(c.time.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(c.time.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(packet)))
+	if _, c.err = c.w.Write(record); c.err == nil {
+		_, c.err = c.w.Write(packet)
+	}
+	// keep the packets ordered in time
+	c.time = c.time.Add(time.Microsecond)
+}
+
+func pseudoHeaderSum(src, dst net.IP, length int) uint32 {
+	var sum uint32
+	for i := 0; i < 4; i += 2 {
+		sum += uint32(src[i])<<8 | uint32(src[i+1])
+		sum += uint32(dst[i])<<8 | uint32(dst[i+1])
+	}
+	return sum + tcpProtocol + uint32(length)
+}
+
+// checksum returns the internet checksum of the data, starting from sum.
+func checksum(data []byte, sum uint32) uint16 {
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
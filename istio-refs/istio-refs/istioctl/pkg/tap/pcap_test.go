@@ -0,0 +1,124 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+type packet struct {
+	flags   byte
+	seq     uint32
+	ack     uint32
+	payload string
+}
+
+// readPcap returns the TCP packets of a pcap file written by PcapWriter, checking their checksums.
+func readPcap(t *testing.T, data []byte) []packet {
+	t.Helper()
+	if len(data) < 24 || binary.LittleEndian.Uint32(data) != pcapMagic {
+		t.Fatalf("invalid pcap header")
+	}
+	if linkType := binary.LittleEndian.Uint32(data[20:]); linkType != linkTypeRaw {
+		t.Fatalf("got link type %d, want %d", linkType, linkTypeRaw)
+	}
+	data = data[24:]
+
+	var packets []packet
+	for len(data) > 0 {
+		length := int(binary.LittleEndian.Uint32(data[8:]))
+		p := data[16 : 16+length]
+		data = data[16+length:]
+
+		ip, tcp := p[:ipHeaderLen], p[ipHeaderLen:]
+		if checksum(ip, 0) != 0 {
+			t.Errorf("invalid IP checksum")
+		}
+		if checksum(tcp, pseudoHeaderSum(ip[12:16], ip[16:20], len(tcp))) != 0 {
+			t.Errorf("invalid TCP checksum")
+		}
+		packets = append(packets, packet{
+			flags:   tcp[13],
+			seq:     binary.BigEndian.Uint32(tcp[4:]),
+			ack:     binary.BigEndian.Uint32(tcp[8:]),
+			payload: string(tcp[tcpHeaderLen:]),
+		})
+	}
+	return packets
+}
+
+func TestPcapWriter(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewPcapWriter(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(readTraces(t)[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	request := "POST /reviews/0 HTTP/1.1\r\nhost: reviews:9080\r\ncontent-length: 12\r\n\r\n{\"rating\":5}"
+	response := "HTTP/1.1 503 Service Unavailable\r\ncontent-length: 0\r\n\r\n"
+	clientData := uint32(1 + len(request))
+	serverData := uint32(1 + len(response))
+	want := []packet{
+		{flags: tcpFlagSyn},
+		{flags: tcpFlagSyn | tcpFlagAck, ack: 1},
+		{flags: tcpFlagAck, seq: 1, ack: 1},
+		{flags: tcpFlagPush | tcpFlagAck, seq: 1, ack: 1, payload: request},
+		{flags: tcpFlagPush | tcpFlagAck, seq: 1, ack: clientData, payload: response},
+		{flags: tcpFlagFin | tcpFlagAck, seq: clientData, ack: serverData},
+		{flags: tcpFlagFin | tcpFlagAck, seq: serverData, ack: clientData + 1},
+		{flags: tcpFlagAck, seq: clientData + 1, ack: serverData + 1},
+	}
+	got := readPcap(t, out.Bytes())
+	if len(got) != len(want) {
+		t.Fatalf("got %d packets, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("packet %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPcapWriterSegments(t *testing.T) {
+	trace := readTraces(t)[0]
+	trace.Trace.Response.Body.BodyType = nil
+	trace.Trace.Request.Headers[3].Value = strings.Repeat("x", 2*maxSegment)
+
+	var out bytes.Buffer
+	w, err := NewPcapWriter(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(trace); err != nil {
+		t.Fatal(err)
+	}
+	var request string
+	for _, p := range readPcap(t, out.Bytes())[3:6] {
+		if len(p.payload) > maxSegment {
+			t.Errorf("got a segment of %d bytes, want at most %d", len(p.payload), maxSegment)
+		}
+		request += p.payload
+	}
+	if !strings.HasPrefix(request, "GET /productpage HTTP/1.1\r\nhost: productpage:9080\r\n") ||
+		!strings.HasSuffix(request, strings.Repeat("x", 100)+"\r\ncontent-length: 0\r\n\r\n") {
+		t.Errorf("got request %q", request)
+	}
+}
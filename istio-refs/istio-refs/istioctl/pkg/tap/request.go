@@ -0,0 +1,167 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tap captures the requests handled by Envoy through the tap admin endpoint.
+package tap
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v2alpha"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	tapv2 "github.com/envoyproxy/go-control-plane/envoy/service/tap/v2alpha"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+const (
+	// FilterName is the name of the Envoy HTTP tap filter.
+	FilterName = "envoy.filters.http.tap"
+	// FilterType is the type of the config of the Envoy HTTP tap filter.
+	FilterType = "type.googleapis.com/envoy.config.filter.http.tap.v2alpha.Tap"
+)
+
+var statusRegexp = regexp.MustCompile(`^[1-5]([0-9][0-9]|xx)$`)
+
+// Match are the criteria of the requests to capture. Empty criteria match all the requests.
+type Match struct {
+	// Headers are the values of the request headers, by name.
+	Headers map[string]string
+	// Path is the request path; a path ending with * is a prefix.
+	Path string
+	// Status is the response status code, such as 503, or its class, such as 5xx.
+	Status string
+}
+
+// Validate checks the criteria are well formed.
+func (m Match) Validate() error {
+	if m.Path != "" && !strings.HasPrefix(m.Path, "/") {
+		return fmt.Errorf("path %q must start with /", m.Path)
+	}
+	if m.Status != "" && !statusRegexp.MatchString(m.Status) {
+		return fmt.Errorf("status %q must be a status code, such as 503, or a class, such as 5xx", m.Status)
+	}
+	for name := range m.Headers {
+		if name == "" || strings.HasPrefix(name, ":") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	return nil
+}
+
+// predicate returns the Envoy match predicate of the criteria.
+func (m Match) predicate() *tapv2.MatchPredicate {
+	var requestHeaders []*route.HeaderMatcher
+	names := make([]string, 0, len(m.Headers))
+	for name := range m.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		requestHeaders = append(requestHeaders, &route.HeaderMatcher{
+			Name:                 strings.ToLower(name),
+			HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: m.Headers[name]},
+		})
+	}
+	if m.Path != "" {
+		requestHeaders = append(requestHeaders, headerMatcher(":path", m.Path, "*"))
+	}
+
+	var rules []*tapv2.MatchPredicate
+	if len(requestHeaders) > 0 {
+		rules = append(rules, &tapv2.MatchPredicate{
+			Rule: &tapv2.MatchPredicate_HttpRequestHeadersMatch{
+				HttpRequestHeadersMatch: &tapv2.HttpHeadersMatch{Headers: requestHeaders},
+			},
+		})
+	}
+	if m.Status != "" {
+		rules = append(rules, &tapv2.MatchPredicate{
+			Rule: &tapv2.MatchPredicate_HttpResponseHeadersMatch{
+				HttpResponseHeadersMatch: &tapv2.HttpHeadersMatch{
+					Headers: []*route.HeaderMatcher{headerMatcher(":status", m.Status, "xx")},
+				},
+			},
+		})
+	}
+
+	switch len(rules) {
+	case 0:
+		return &tapv2.MatchPredicate{Rule: &tapv2.MatchPredicate_AnyMatch{AnyMatch: true}}
+	case 1:
+		return rules[0]
+	default:
+		return &tapv2.MatchPredicate{
+			Rule: &tapv2.MatchPredicate_AndMatch{AndMatch: &tapv2.MatchPredicate_MatchSet{Rules: rules}},
+		}
+	}
+}
+
+// headerMatcher matches the value of a header exactly, or its prefix when the value ends with wildcard.
+func headerMatcher(name, value, wildcard string) *route.HeaderMatcher {
+	if strings.HasSuffix(value, wildcard) {
+		return &route.HeaderMatcher{
+			Name:                 name,
+			HeaderMatchSpecifier: &route.HeaderMatcher_PrefixMatch{PrefixMatch: strings.TrimSuffix(value, wildcard)},
+		}
+	}
+	return &route.HeaderMatcher{
+		Name:                 name,
+		HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: value},
+	}
+}
+
+// NewRequest returns the request of the tap admin endpoint streaming the requests matching the criteria to the
+// tap filters with the config id. The bodies are truncated to maxBodyBytes.
+func NewRequest(configID string, match Match, maxBodyBytes uint32) *admin.TapRequest {
+	return &admin.TapRequest{
+		ConfigId: configID,
+		TapConfig: &tapv2.TapConfig{
+			MatchConfig: match.predicate(),
+			OutputConfig: &tapv2.OutputConfig{
+				Sinks: []*tapv2.OutputSink{{
+					Format:         tapv2.OutputSink_JSON_BODY_AS_STRING,
+					OutputSinkType: &tapv2.OutputSink_StreamingAdmin{StreamingAdmin: &tapv2.StreamingAdminSink{}},
+				}},
+				MaxBufferedRxBytes: &wrappers.UInt32Value{Value: maxBodyBytes},
+				MaxBufferedTxBytes: &wrappers.UInt32Value{Value: maxBodyBytes},
+			},
+		},
+	}
+}
+
+// RequestBody returns the body posted to the tap admin endpoint for a request.
+func RequestBody(request *admin.TapRequest) ([]byte, error) {
+	m := jsonpb.Marshaler{OrigName: true}
+	js, err := m.MarshalToString(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal tap request: %v", err)
+	}
+	return []byte(js), nil
+}
+
+// FilterConfig returns the config of the tap filter, controlled by the tap admin endpoint with the config id.
+func FilterConfig(configID string) map[string]interface{} {
+	return map[string]interface{}{
+		"@type": FilterType,
+		"common_config": map[string]interface{}{
+			"admin_config": map[string]interface{}{
+				"config_id": configID,
+			},
+		},
+	}
+}
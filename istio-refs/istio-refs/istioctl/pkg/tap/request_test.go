@@ -0,0 +1,116 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tap
+
+import (
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	tapv2 "github.com/envoyproxy/go-control-plane/envoy/service/tap/v2alpha"
+	"github.com/golang/protobuf/proto"
+)
+
+func TestMatchValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		match   Match
+		wantErr bool
+	}{
+		{name: "empty", match: Match{}},
+		{name: "all", match: Match{Headers: map[string]string{"x-user": "jason"}, Path: "/api/*", Status: "5xx"}},
+		{name: "status code", match: Match{Status: "503"}},
+		{name: "relative path", match: Match{Path: "api"}, wantErr: true},
+		{name: "bad status", match: Match{Status: "50x"}, wantErr: true},
+		{name: "status out of range", match: Match{Status: "600"}, wantErr: true},
+		{name: "pseudo header", match: Match{Headers: map[string]string{":path": "/"}}, wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.match.Validate(); (err != nil) != c.wantErr {
+				t.Errorf("got error %v, want error %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func requestHeaders(headers ...*route.HeaderMatcher) *tapv2.MatchPredicate {
+	return &tapv2.MatchPredicate{Rule: &tapv2.MatchPredicate_HttpRequestHeadersMatch{
+		HttpRequestHeadersMatch: &tapv2.HttpHeadersMatch{Headers: headers},
+	}}
+}
+
+func TestMatchPredicate(t *testing.T) {
+	cases := []struct {
+		name  string
+		match Match
+		want  *tapv2.MatchPredicate
+	}{
+		{
+			name:  "any",
+			match: Match{},
+			want:  &tapv2.MatchPredicate{Rule: &tapv2.MatchPredicate_AnyMatch{AnyMatch: true}},
+		},
+		{
+			name:  "exact path",
+			match: Match{Path: "/productpage"},
+			want: requestHeaders(&route.HeaderMatcher{
+				Name:                 ":path",
+				HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "/productpage"},
+			}),
+		},
+		{
+			name:  "headers, path prefix and status class",
+			match: Match{Headers: map[string]string{"X-User": "jason", "a": "b"}, Path: "/api/*", Status: "5xx"},
+			want: &tapv2.MatchPredicate{Rule: &tapv2.MatchPredicate_AndMatch{AndMatch: &tapv2.MatchPredicate_MatchSet{
+				Rules: []*tapv2.MatchPredicate{
+					requestHeaders(
+						&route.HeaderMatcher{Name: "x-user",
+							HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "jason"}},
+						&route.HeaderMatcher{Name: "a",
+							HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "b"}},
+						&route.HeaderMatcher{Name: ":path",
+							HeaderMatchSpecifier: &route.HeaderMatcher_PrefixMatch{PrefixMatch: "/api/"}},
+					),
+					{Rule: &tapv2.MatchPredicate_HttpResponseHeadersMatch{
+						HttpResponseHeadersMatch: &tapv2.HttpHeadersMatch{Headers: []*route.HeaderMatcher{{
+							Name:                 ":status",
+							HeaderMatchSpecifier: &route.HeaderMatcher_PrefixMatch{PrefixMatch: "5"},
+						}}},
+					}},
+				},
+			}}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.match.predicate(); !proto.Equal(got, c.want) {
+				t.Errorf("got predicate %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRequestBody(t *testing.T) {
+	body, err := RequestBody(NewRequest("istioctl-tap", Match{}, 1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"config_id":"istioctl-tap","tap_config":{"match_config":{"any_match":true},` +
+		`"output_config":{"sinks":[{"format":"JSON_BODY_AS_STRING","streaming_admin":{}}],` +
+		`"max_buffered_rx_bytes":1024,"max_buffered_tx_bytes":1024}}}`
+	if string(body) != want {
+		t.Errorf("got body\n%s\nwant\n%s", body, want)
+	}
+}
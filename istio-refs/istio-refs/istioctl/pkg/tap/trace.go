@@ -0,0 +1,142 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	datatap "github.com/envoyproxy/go-control-plane/envoy/data/tap/v2alpha"
+	"github.com/golang/protobuf/jsonpb"
+)
+
+// Trace is a request captured by Envoy, with its response.
+type Trace struct {
+	// Time is when the trace was received.
+	Time  time.Time
+	Trace *datatap.HttpBufferedTrace
+}
+
+// Reader decodes the traces streamed by the tap admin endpoint, a sequence of JSON trace wrappers.
+type Reader struct {
+	decoder *json.Decoder
+	now     func() time.Time
+}
+
+// NewReader returns a reader of the traces streamed by r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{decoder: json.NewDecoder(r), now: time.Now}
+}
+
+// Next returns the next trace, or io.EOF at the end of the stream.
+func (r *Reader) Next() (*Trace, error) {
+	for {
+		var raw json.RawMessage
+		if err := r.decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		wrapper := &datatap.TraceWrapper{}
+		u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+		if err := u.Unmarshal(strings.NewReader(string(raw)), wrapper); err != nil {
+			return nil, fmt.Errorf("could not decode trace: %v", err)
+		}
+		// The admin sink only streams buffered traces, skip anything else.
+		if trace := wrapper.GetHttpBufferedTrace(); trace != nil {
+			return &Trace{Time: r.now(), Trace: trace}, nil
+		}
+	}
+}
+
+// Header returns the value of a header of a request or response, or the empty string.
+func Header(message *datatap.HttpBufferedTrace_Message, name string) string {
+	for _, h := range message.GetHeaders() {
+		if strings.EqualFold(h.Key, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// Body returns the body of a request or response, and whether it was truncated.
+func Body(message *datatap.HttpBufferedTrace_Message) ([]byte, bool) {
+	body := message.GetBody()
+	if body == nil {
+		return nil, false
+	}
+	if s, ok := body.BodyType.(*datatap.Body_AsString); ok {
+		return []byte(s.AsString), body.Truncated
+	}
+	return body.GetAsBytes(), body.Truncated
+}
+
+// Summary returns a one line summary of a trace: its time, method, authority, path and status.
+func (t *Trace) Summary() string {
+	req, resp := t.Trace.GetRequest(), t.Trace.GetResponse()
+	status := Header(resp, ":status")
+	if status == "" {
+		status = "-"
+	}
+	return fmt.Sprintf("%s %s %s%s %s", t.Time.Format(time.RFC3339), Header(req, ":method"),
+		Header(req, ":authority"), Header(req, ":path"), status)
+}
+
+// WriteText writes the summary of a trace followed by its request (>) and response (<), headers and bodies.
+func WriteText(w io.Writer, t *Trace) {
+	fmt.Fprintf(w, "%s\n", t.Summary())
+	writeMessage(w, "> ", t.Trace.GetRequest())
+	writeMessage(w, "< ", t.Trace.GetResponse())
+	fmt.Fprintln(w)
+}
+
+func writeMessage(w io.Writer, prefix string, message *datatap.HttpBufferedTrace_Message) {
+	if message == nil {
+		return
+	}
+	writeHeaders(w, prefix, message.Headers)
+	if body, truncated := Body(message); len(body) > 0 {
+		fmt.Fprintln(w, strings.TrimSpace(prefix))
+		for _, line := range strings.Split(strings.TrimSuffix(string(body), "\n"), "\n") {
+			fmt.Fprintf(w, "%s%s\n", prefix, line)
+		}
+		if truncated {
+			fmt.Fprintf(w, "%s[body truncated]\n", prefix)
+		}
+	}
+	if len(message.Trailers) > 0 {
+		fmt.Fprintln(w, strings.TrimSpace(prefix))
+		writeHeaders(w, prefix, message.Trailers)
+	}
+}
+
+func writeHeaders(w io.Writer, prefix string, headers []*core.HeaderValue) {
+	for _, h := range headers {
+		fmt.Fprintf(w, "%s%s: %s\n", prefix, h.Key, h.Value)
+	}
+}
+
+// WriteJSON writes a trace as a line of JSON, the trace wrapper of Envoy.
+func WriteJSON(w io.Writer, t *Trace) error {
+	m := jsonpb.Marshaler{OrigName: true}
+	wrapper := &datatap.TraceWrapper{Trace: &datatap.TraceWrapper_HttpBufferedTrace{HttpBufferedTrace: t.Trace}}
+	if err := m.Marshal(w, wrapper); err != nil {
+		return fmt.Errorf("could not marshal trace: %v", err)
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tap
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// traces is the output of the tap admin endpoint for two requests, with a trace of another kind between them.
+const traces = `{
+ "http_buffered_trace": {
+  "request": {
+   "headers": [
+    {"key": ":authority", "value": "productpage:9080"},
+    {"key": ":path", "value": "/productpage"},
+    {"key": ":method", "value": "GET"},
+    {"key": "user-agent", "value": "curl/7.64.0"}
+   ],
+   "trailers": []
+  },
+  "response": {
+   "headers": [
+    {"key": ":status", "value": "200"},
+    {"key": "content-type", "value": "text/html"}
+   ],
+   "body": {"truncated": true, "as_string": "<html>\n<head>"},
+   "trailers": []
+  }
+ }
+}
+{"http_streamed_trace_segment": {"trace_id": "1"}}
+{
+ "http_buffered_trace": {
+  "request": {
+   "headers": [
+    {"key": ":authority", "value": "reviews:9080"},
+    {"key": ":path", "value": "/reviews/0"},
+    {"key": ":method", "value": "POST"}
+   ],
+   "body": {"as_bytes": "eyJyYXRpbmciOjV9"}
+  },
+  "response": {
+   "headers": [{"key": ":status", "value": "503"}]
+  }
+ }
+}
+`
+
+func readTraces(t *testing.T) []*Trace {
+	t.Helper()
+	r := NewReader(strings.NewReader(traces))
+	r.now = func() time.Time { return time.Date(2020, 3, 5, 10, 0, 0, 0, time.UTC) }
+	var out []*Trace
+	for {
+		trace, err := r.Next()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, trace)
+	}
+}
+
+func TestReader(t *testing.T) {
+	got := readTraces(t)
+	if len(got) != 2 {
+		t.Fatalf("got %d traces, want 2", len(got))
+	}
+	wantSummaries := []string{
+		"2020-03-05T10:00:00Z GET productpage:9080/productpage 200",
+		"2020-03-05T10:00:00Z POST reviews:9080/reviews/0 503",
+	}
+	for i, trace := range got {
+		if summary := trace.Summary(); summary != wantSummaries[i] {
+			t.Errorf("got summary %q, want %q", summary, wantSummaries[i])
+		}
+	}
+	if body, truncated := Body(got[1].Trace.Request); string(body) != `{"rating":5}` || truncated {
+		t.Errorf("got body %q truncated %v, want the whole request body", body, truncated)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var out bytes.Buffer
+	WriteText(&out, readTraces(t)[0])
+	want := `2020-03-05T10:00:00Z GET productpage:9080/productpage 200
+> :authority: productpage:9080
+> :path: /productpage
+> :method: GET
+> user-agent: curl/7.64.0
+< :status: 200
+< content-type: text/html
+<
+< <html>
+< <head>
+< [body truncated]
+
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteJSON(&out, readTraces(t)[1]); err != nil {
+		t.Fatal(err)
+	}
+	trace, err := NewReader(&out).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary := trace.Summary(); !strings.HasSuffix(summary, "POST reviews:9080/reviews/0 503") {
+		t.Errorf("got summary %q after a round trip", summary)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// StatsFetcher returns the stats of the proxy of a pod, in the Prometheus text format.
+type StatsFetcher func(podName, namespace string) ([]byte, error)
+
+// EnvoySource fetches the traffic from the stats of the sidecars of the namespace. As the stats are counters, rates
+// are computed between two fetches: the first fetch of a pod only records its counters. A pod whose stats can't be
+// read is skipped, the error is kept until the next fetch.
+type EnvoySource struct {
+	// Pods lists the pods of the namespace with a sidecar.
+	Pods  func(namespace string) ([]string, error)
+	Stats StatsFetcher
+
+	mu       sync.Mutex
+	previous map[string]scrape
+	errors   []error
+}
+
+var _ Source = &EnvoySource{}
+
+// scrape holds the cumulative counters of a proxy, in Sample form, at a point in time.
+type scrape struct {
+	time     time.Time
+	counters sampleSet
+}
+
+// Fetch returns the traffic of the workloads of the namespace since the previous fetch.
+func (e *EnvoySource) Fetch(namespace string, now time.Time) ([]Sample, error) {
+	pods, err := e.Pods(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.previous == nil {
+		e.previous = map[string]scrape{}
+	}
+
+	samples := sampleSet{}
+	current := map[string]scrape{}
+	e.errors = nil
+	for _, pod := range pods {
+		counters, err := e.scrape(pod, namespace)
+		if err != nil {
+			e.errors = append(e.errors, err)
+			// Keep the previous counters, the rates are computed from them once the pod is scraped again.
+			if prev, ok := e.previous[pod]; ok {
+				current[pod] = prev
+			}
+			continue
+		}
+		current[pod] = scrape{time: now, counters: counters}
+		if prev, ok := e.previous[pod]; ok {
+			addRates(samples, prev, current[pod])
+		}
+	}
+	// Forget the pods which are gone.
+	e.previous = current
+	return samples.list(), nil
+}
+
+// Errors returns the errors of the pods skipped by the last fetch.
+func (e *EnvoySource) Errors() []error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.errors
+}
+
+func (e *EnvoySource) scrape(pod, namespace string) (sampleSet, error) {
+	stats, err := e.Stats(pod, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the stats of %s.%s: %v", pod, namespace, err)
+	}
+	counters, err := parseStats(stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the stats of %s.%s: %v", pod, namespace, err)
+	}
+	return counters, nil
+}
+
+// addRates adds the rates of the counters between two scrapes of a proxy. A counter lower than in the previous scrape
+// was reset by a restart of the proxy, its current value is then the increase.
+func addRates(samples sampleSet, prev, cur scrape) {
+	elapsed := cur.time.Sub(prev.time).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	rate := func(cur, prev float64) float64 {
+		if cur < prev {
+			return cur / elapsed
+		}
+		return (cur - prev) / elapsed
+	}
+
+	for key, c := range cur.counters {
+		p, ok := prev.counters[key]
+		if !ok {
+			p = &Sample{}
+		}
+		t := Traffic{
+			RPS:      rate(c.RPS, p.RPS),
+			ErrorRPS: rate(c.ErrorRPS, p.ErrorRPS),
+			Buckets:  map[float64]float64{},
+		}
+		for le, count := range c.Buckets {
+			t.Buckets[le] = rate(count, p.Buckets[le])
+		}
+		samples.get(key).add(t)
+	}
+}
+
+// parseStats returns the cumulative request counts and latency histograms found in the stats of a proxy.
+func parseStats(stats []byte) (sampleSet, error) {
+	families, err := (&expfmt.TextParser{}).TextToMetricFamilies(bytes.NewReader(stats))
+	if err != nil {
+		return nil, err
+	}
+
+	counters := sampleSet{}
+	if f, ok := families[requestsMetric]; ok {
+		for _, m := range f.GetMetric() {
+			labels := labelPairs(m.GetLabel())
+			s := counters.get(keyFromLabels(labels))
+			s.RPS += m.GetCounter().GetValue()
+			if isServerError(labels[responseCodeLabel]) {
+				s.ErrorRPS += m.GetCounter().GetValue()
+			}
+		}
+	}
+	for _, d := range durationMetrics {
+		f, ok := families[d.name]
+		if !ok {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			h := m.GetHistogram()
+			buckets := map[float64]float64{math.Inf(1): float64(h.GetSampleCount())}
+			for _, b := range h.GetBucket() {
+				buckets[b.GetUpperBound()/d.perSecond] = float64(b.GetCumulativeCount())
+			}
+			counters.get(keyFromLabels(labelPairs(m.GetLabel()))).add(Traffic{Buckets: buckets})
+		}
+		break
+	}
+	return counters, nil
+}
+
+func labelPairs(pairs []*dto.LabelPair) map[string]string {
+	labels := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		labels[p.GetName()] = p.GetValue()
+	}
+	return labels
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const statsFormat = `# TYPE istio_requests_total counter
+istio_requests_total{reporter="destination",source_workload="productpage-v1",source_workload_namespace="default",destination_workload="reviews-v1",destination_workload_namespace="default",response_code="200"} %d
+istio_requests_total{reporter="destination",source_workload="productpage-v1",source_workload_namespace="default",destination_workload="reviews-v1",destination_workload_namespace="default",response_code="503"} %d
+# TYPE istio_request_duration_milliseconds histogram
+istio_request_duration_milliseconds_bucket{reporter="destination",source_workload="productpage-v1",source_workload_namespace="default",destination_workload="reviews-v1",destination_workload_namespace="default",le="10"} %d
+istio_request_duration_milliseconds_bucket{reporter="destination",source_workload="productpage-v1",source_workload_namespace="default",destination_workload="reviews-v1",destination_workload_namespace="default",le="100"} %d
+istio_request_duration_milliseconds_bucket{reporter="destination",source_workload="productpage-v1",source_workload_namespace="default",destination_workload="reviews-v1",destination_workload_namespace="default",le="+Inf"} %d
+istio_request_duration_milliseconds_sum{reporter="destination",source_workload="productpage-v1",source_workload_namespace="default",destination_workload="reviews-v1",destination_workload_namespace="default"} 0
+istio_request_duration_milliseconds_count{reporter="destination",source_workload="productpage-v1",source_workload_namespace="default",destination_workload="reviews-v1",destination_workload_namespace="default"} %d
+` // nolint: lll
+
+func stats(ok, failed, fast, medium int) []byte {
+	total := ok + failed
+	return []byte(fmt.Sprintf(statsFormat, ok, failed, fast, medium, total, total))
+}
+
+func TestEnvoySource(t *testing.T) {
+	scrapes := map[string][][]byte{
+		"reviews-v1-a": {stats(100, 0, 50, 100), stats(180, 20, 100, 190)},
+		"reviews-v1-b": {stats(10, 0, 10, 10), stats(30, 0, 30, 30)},
+	}
+	calls := map[string]int{}
+	source := &EnvoySource{
+		Pods: func(string) ([]string, error) {
+			return []string{"reviews-v1-a", "reviews-v1-b"}, nil
+		},
+		Stats: func(pod, _ string) ([]byte, error) {
+			s := scrapes[pod][calls[pod]]
+			calls[pod]++
+			return s, nil
+		},
+	}
+
+	now := time.Now()
+	samples, err := source.Fetch("default", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 0 {
+		t.Fatalf("got samples %v on the first fetch, want none", samples)
+	}
+
+	samples, err = source.Fetch("default", now.Add(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 {
+		t.Fatalf("got %d samples, want 1", len(samples))
+	}
+	s := samples[0]
+	if s.Reporter != reporterDestination || s.Source != productpage || s.Destination != reviews {
+		t.Fatalf("got sample %+v, want productpage to reviews reported by the destination", s)
+	}
+	// (100 + 20) requests in 10s, 20 of which failed.
+	if s.RPS != 12 || s.ErrorRPS != 2 {
+		t.Fatalf("got %v RPS, %v errors, want 12 and 2", s.RPS, s.ErrorRPS)
+	}
+	want := map[float64]float64{0.01: 7, 0.1: 11, math.Inf(1): 12}
+	for le, rate := range want {
+		if got := s.Buckets[le]; math.Abs(got-rate) > 1e-9 {
+			t.Errorf("got bucket %v rate %v, want %v", le, got, rate)
+		}
+	}
+}
+
+func TestEnvoySourceSkipsFailedPods(t *testing.T) {
+	scrapes := map[string][][]byte{
+		"reviews-v1-a": {stats(100, 0, 50, 100), nil, stats(300, 0, 150, 300)},
+		"reviews-v1-b": {[]byte("not stats {"), stats(10, 0, 10, 10), stats(30, 0, 30, 30)},
+	}
+	calls := map[string]int{}
+	source := &EnvoySource{
+		Pods: func(string) ([]string, error) {
+			return []string{"reviews-v1-a", "reviews-v1-b"}, nil
+		},
+		Stats: func(pod, _ string) ([]byte, error) {
+			s := scrapes[pod][calls[pod]]
+			calls[pod]++
+			if s == nil {
+				return nil, errors.New("connection refused")
+			}
+			return s, nil
+		},
+	}
+
+	now := time.Now()
+	for i, wantErrors := range []int{1, 1, 0} {
+		samples, err := source.Fetch("default", now.Add(time.Duration(i)*10*time.Second))
+		if err != nil {
+			t.Fatalf("fetch %d: %v", i, err)
+		}
+		if got := len(source.Errors()); got != wantErrors {
+			t.Fatalf("fetch %d: got errors %v, want %d", i, source.Errors(), wantErrors)
+		}
+		if i < 2 {
+			continue
+		}
+		// reviews-v1-a is rated since its last successful scrape, 20s earlier, and reviews-v1-b over 10s.
+		if len(samples) != 1 || samples[0].RPS != 10+2 {
+			t.Fatalf("fetch %d: got samples %+v, want 12 RPS", i, samples)
+		}
+	}
+}
+
+func TestAddRatesCounterReset(t *testing.T) {
+	prev, err := parseStats(stats(100, 0, 100, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := parseStats(stats(10, 0, 10, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	samples := sampleSet{}
+	addRates(samples, scrape{time: now, counters: prev}, scrape{time: now.Add(time.Second), counters: cur})
+	for _, s := range samples {
+		if s.RPS != 10 {
+			t.Fatalf("got %v RPS after a restart of the proxy, want 10", s.RPS)
+		}
+	}
+}
+
+func TestPrometheusSamples(t *testing.T) {
+	metric := func(code, le string) model.Metric {
+		m := model.Metric{
+			reporterLabel:                reporterSource,
+			sourceWorkloadLabel:          "productpage-v1",
+			sourceWorkloadNamespaceLabel: "default",
+			destWorkloadLabel:            "reviews-v1",
+			destWorkloadNamespaceLabel:   "default",
+		}
+		if code != "" {
+			m[responseCodeLabel] = model.LabelValue(code)
+		}
+		if le != "" {
+			m[bucketUpperBoundLabel] = model.LabelValue(le)
+		}
+		return m
+	}
+
+	samples := sampleSet{}
+	addRequests(samples, model.Vector{
+		{Metric: metric("200", ""), Value: 3},
+		{Metric: metric("500", ""), Value: 1},
+		{Metric: metric("404", ""), Value: 1},
+	})
+	addBuckets(samples, model.Vector{
+		{Metric: metric("", "25"), Value: 4},
+		{Metric: metric("", "+Inf"), Value: 5},
+	}, 1000)
+
+	list := samples.list()
+	if len(list) != 1 {
+		t.Fatalf("got %d samples, want 1", len(list))
+	}
+	s := list[0]
+	if s.Reporter != reporterSource || s.Source != productpage || s.Destination != reviews {
+		t.Fatalf("got sample %+v, want productpage to reviews reported by the source", s)
+	}
+	if s.RPS != 5 || s.ErrorRPS != 1 {
+		t.Fatalf("got %v RPS, %v errors, want 5 and 1", s.RPS, s.ErrorRPS)
+	}
+	if s.Buckets[0.025] != 4 || s.Buckets[math.Inf(1)] != 5 {
+		t.Fatalf("got buckets %v", s.Buckets)
+	}
+}
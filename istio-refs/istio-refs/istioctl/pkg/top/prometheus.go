@@ -0,0 +1,122 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// PrometheusSource fetches the traffic from Prometheus, as rates over a window.
+type PrometheusSource struct {
+	API    promv1.API
+	Window time.Duration
+}
+
+var _ Source = &PrometheusSource{}
+
+// selectors of the series reported by the proxies of the workloads of a namespace, by reporter.
+func selectors(namespace string) []string {
+	return []string{
+		fmt.Sprintf(`reporter=%q,%s=%q`, reporterDestination, destWorkloadNamespaceLabel, namespace),
+		fmt.Sprintf(`reporter=%q,%s=%q`, reporterSource, sourceWorkloadNamespaceLabel, namespace),
+	}
+}
+
+const groupByWorkloads = reporterLabel + "," + sourceWorkloadLabel + "," + sourceWorkloadNamespaceLabel + "," +
+	destWorkloadLabel + "," + destWorkloadNamespaceLabel
+
+// Fetch returns the traffic of the workloads of the namespace.
+func (p *PrometheusSource) Fetch(namespace string, now time.Time) ([]Sample, error) {
+	window := model.Duration(p.Window).String()
+	samples := sampleSet{}
+	for _, selector := range selectors(namespace) {
+		query := fmt.Sprintf(`sum(rate(%s{%s}[%s])) by (%s,%s)`,
+			requestsMetric, selector, window, groupByWorkloads, responseCodeLabel)
+		vector, err := p.query(query, now)
+		if err != nil {
+			return nil, err
+		}
+		addRequests(samples, vector)
+
+		for _, m := range durationMetrics {
+			query := fmt.Sprintf(`sum(rate(%s_bucket{%s}[%s])) by (%s,%s)`,
+				m.name, selector, window, groupByWorkloads, bucketUpperBoundLabel)
+			vector, err := p.query(query, now)
+			if err != nil {
+				return nil, err
+			}
+			if len(vector) > 0 {
+				addBuckets(samples, vector, m.perSecond)
+				break
+			}
+		}
+	}
+	return samples.list(), nil
+}
+
+func (p *PrometheusSource) query(query string, now time.Time) (model.Vector, error) {
+	val, _, err := p.API.Query(context.Background(), query, now)
+	if err != nil {
+		return nil, fmt.Errorf("query() failure for '%s': %v", query, err)
+	}
+	vector, ok := val.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("bad metric value type returned for query '%s'", query)
+	}
+	return vector, nil
+}
+
+func labelsOf(m model.Metric) map[string]string {
+	labels := make(map[string]string, len(m))
+	for k, v := range m {
+		labels[string(k)] = string(v)
+	}
+	return labels
+}
+
+func addRequests(samples sampleSet, vector model.Vector) {
+	for _, v := range vector {
+		labels := labelsOf(v.Metric)
+		rate := float64(v.Value)
+		if math.IsNaN(rate) {
+			continue
+		}
+		s := samples.get(keyFromLabels(labels))
+		s.RPS += rate
+		if isServerError(labels[responseCodeLabel]) {
+			s.ErrorRPS += rate
+		}
+	}
+}
+
+func addBuckets(samples sampleSet, vector model.Vector, perSecond float64) {
+	for _, v := range vector {
+		labels := labelsOf(v.Metric)
+		le, err := strconv.ParseFloat(labels[bucketUpperBoundLabel], 64)
+		rate := float64(v.Value)
+		if err != nil || math.IsNaN(rate) {
+			continue
+		}
+		s := samples.get(keyFromLabels(labels))
+		s.add(Traffic{Buckets: map[float64]float64{le / perSecond: rate}})
+	}
+}
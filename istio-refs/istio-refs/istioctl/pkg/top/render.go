@@ -0,0 +1,77 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Render writes the workloads of the snapshot as a table sorted by the key, with their inbound and outbound edges
+// if requested.
+func Render(w io.Writer, s *Snapshot, by SortKey, edges bool) {
+	workloads := append([]*Workload(nil), s.Workloads...)
+	Sort(workloads, by)
+
+	_, _ = fmt.Fprintf(w, "Namespace %s at %s, sorted by %s\n\n", s.Namespace, s.Time.Format("15:04:05"), by)
+	defer renderErrors(w, s.Errors)
+	if len(workloads) == 0 {
+		_, _ = fmt.Fprintln(w, "No traffic")
+		return
+	}
+
+	tw := new(tabwriter.Writer).Init(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "WORKLOAD\tRPS\t5XX\tP50\tP90\tP99")
+	for _, wl := range workloads {
+		printRow(tw, wl.String(), wl.Traffic)
+		if !edges {
+			continue
+		}
+		for _, e := range wl.Inbound {
+			printRow(tw, "  <- "+e.Peer.String(), e.Traffic)
+		}
+		for _, e := range wl.Outbound {
+			printRow(tw, "  -> "+e.Peer.String(), e.Traffic)
+		}
+	}
+	_ = tw.Flush()
+}
+
+func renderErrors(w io.Writer, errs []error) {
+	if len(errs) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(w)
+	for _, err := range errs {
+		_, _ = fmt.Fprintf(w, "Skipped: %v\n", err)
+	}
+}
+
+func printRow(w io.Writer, name string, t Traffic) {
+	_, _ = fmt.Fprintf(w, "%s\t%.2f\t%.2f%%\t%s\t%s\t%s\n", name, t.RPS, 100*t.ErrorRate(),
+		formatLatency(t.Quantile(0.5)), formatLatency(t.Quantile(0.9)), formatLatency(t.Quantile(0.99)))
+}
+
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,309 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package top computes live per-workload traffic statistics of a namespace, either from Prometheus or from the
+// stats of the sidecars, and renders them as a table.
+package top
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	requestsMetric = "istio_requests_total"
+
+	reporterSource      = "source"
+	reporterDestination = "destination"
+	unknownWorkload     = "unknown"
+
+	reporterLabel                = "reporter"
+	sourceWorkloadLabel          = "source_workload"
+	sourceWorkloadNamespaceLabel = "source_workload_namespace"
+	destWorkloadLabel            = "destination_workload"
+	destWorkloadNamespaceLabel   = "destination_workload_namespace"
+	responseCodeLabel            = "response_code"
+	bucketUpperBoundLabel        = "le"
+)
+
+// durationMetrics are the request duration histograms, the one of Telemetry v2 first, with their units per second.
+var durationMetrics = []struct {
+	name      string
+	perSecond float64
+}{
+	{name: "istio_request_duration_milliseconds", perSecond: 1000},
+	{name: "istio_request_duration_seconds", perSecond: 1},
+}
+
+// Source fetches the traffic of the workloads of a namespace.
+type Source interface {
+	Fetch(namespace string, now time.Time) ([]Sample, error)
+}
+
+// WorkloadID identifies a workload.
+type WorkloadID struct {
+	Name      string
+	Namespace string
+}
+
+func (w WorkloadID) String() string {
+	if w.Name == "" || w.Name == unknownWorkload {
+		return unknownWorkload
+	}
+	return w.Name + "." + w.Namespace
+}
+
+// Traffic holds the rate of requests and their latency distribution.
+type Traffic struct {
+	// RPS is the rate of requests per second, ErrorRPS the rate of requests with a 5xx response.
+	RPS      float64
+	ErrorRPS float64
+
+	// Buckets is the rate of requests by latency upper bound in seconds, cumulative as in Prometheus histograms.
+	Buckets map[float64]float64
+}
+
+func (t *Traffic) add(o Traffic) {
+	t.RPS += o.RPS
+	t.ErrorRPS += o.ErrorRPS
+	for le, rate := range o.Buckets {
+		if t.Buckets == nil {
+			t.Buckets = map[float64]float64{}
+		}
+		t.Buckets[le] += rate
+	}
+}
+
+// ErrorRate returns the ratio of requests with a 5xx response.
+func (t Traffic) ErrorRate() float64 {
+	if t.RPS == 0 {
+		return 0
+	}
+	return t.ErrorRPS / t.RPS
+}
+
+// Quantile estimates the latency quantile from the buckets, as the histogram_quantile function of Prometheus does.
+func (t Traffic) Quantile(q float64) time.Duration {
+	les := make([]float64, 0, len(t.Buckets))
+	for le := range t.Buckets {
+		les = append(les, le)
+	}
+	sort.Float64s(les)
+	if len(les) < 2 || !math.IsInf(les[len(les)-1], 1) {
+		return 0
+	}
+	total := t.Buckets[les[len(les)-1]]
+	if total <= 0 {
+		return 0
+	}
+
+	rank := q * total
+	i := sort.Search(len(les), func(i int) bool { return t.Buckets[les[i]] >= rank })
+	if i == len(les)-1 {
+		return seconds(les[len(les)-2])
+	}
+	if i == 0 && les[0] <= 0 {
+		return seconds(les[0])
+	}
+
+	start, prevCount := 0.0, 0.0
+	if i > 0 {
+		start, prevCount = les[i-1], t.Buckets[les[i-1]]
+	}
+	end, count := les[i], t.Buckets[les[i]]
+	if count == prevCount {
+		return seconds(end)
+	}
+	return seconds(start + (end-start)*(rank-prevCount)/(count-prevCount))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Sample is the traffic between two workloads, as reported by one of their proxies.
+type Sample struct {
+	Reporter    string
+	Source      WorkloadID
+	Destination WorkloadID
+	Traffic
+}
+
+type sampleKey struct {
+	reporter    string
+	source      WorkloadID
+	destination WorkloadID
+}
+
+func (s *Sample) key() sampleKey {
+	return sampleKey{reporter: s.Reporter, source: s.Source, destination: s.Destination}
+}
+
+// sampleSet merges the samples with the same reporter, source and destination.
+type sampleSet map[sampleKey]*Sample
+
+func (s sampleSet) get(key sampleKey) *Sample {
+	sample, ok := s[key]
+	if !ok {
+		sample = &Sample{Reporter: key.reporter, Source: key.source, Destination: key.destination}
+		s[key] = sample
+	}
+	return sample
+}
+
+func (s sampleSet) list() []Sample {
+	samples := make([]Sample, 0, len(s))
+	for _, sample := range s {
+		samples = append(samples, *sample)
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		a, b := samples[i].key(), samples[j].key()
+		if a.reporter != b.reporter {
+			return a.reporter < b.reporter
+		}
+		if a.source != b.source {
+			return a.source.String() < b.source.String()
+		}
+		return a.destination.String() < b.destination.String()
+	})
+	return samples
+}
+
+// Edge is the traffic between a workload and one of its peers.
+type Edge struct {
+	Peer WorkloadID
+	Traffic
+}
+
+// Workload is the traffic of a workload: received, as reported by its proxies, and exchanged with its peers.
+type Workload struct {
+	WorkloadID
+	Traffic
+
+	Inbound  []Edge
+	Outbound []Edge
+}
+
+// Snapshot is the traffic of the workloads of a namespace at a point in time.
+type Snapshot struct {
+	Namespace string
+	Time      time.Time
+	Workloads []*Workload
+	// Errors are the errors of the proxies which could not be read, their traffic is missing.
+	Errors []error
+}
+
+// Aggregate builds the workloads of the namespace from the samples. Inbound traffic is taken from the reports of the
+// destination proxies and outbound traffic from the reports of the source proxies, so that each is measured by the
+// workload's own proxies.
+func Aggregate(samples []Sample, namespace string, now time.Time) *Snapshot {
+	workloads := map[WorkloadID]*Workload{}
+	get := func(id WorkloadID) *Workload {
+		w, ok := workloads[id]
+		if !ok {
+			w = &Workload{WorkloadID: id}
+			workloads[id] = w
+		}
+		return w
+	}
+
+	for _, s := range samples {
+		switch {
+		case s.Reporter == reporterDestination && s.Destination.Namespace == namespace:
+			w := get(s.Destination)
+			w.Traffic.add(s.Traffic)
+			w.Inbound = addEdge(w.Inbound, s.Source, s.Traffic)
+		case s.Reporter == reporterSource && s.Source.Namespace == namespace:
+			w := get(s.Source)
+			w.Outbound = addEdge(w.Outbound, s.Destination, s.Traffic)
+		}
+	}
+
+	snapshot := &Snapshot{Namespace: namespace, Time: now}
+	for _, w := range workloads {
+		sortEdges(w.Inbound)
+		sortEdges(w.Outbound)
+		snapshot.Workloads = append(snapshot.Workloads, w)
+	}
+	Sort(snapshot.Workloads, SortByRPS)
+	return snapshot
+}
+
+func addEdge(edges []Edge, peer WorkloadID, t Traffic) []Edge {
+	for i := range edges {
+		if edges[i].Peer == peer {
+			edges[i].Traffic.add(t)
+			return edges
+		}
+	}
+	edge := Edge{Peer: peer}
+	edge.Traffic.add(t)
+	return append(edges, edge)
+}
+
+func sortEdges(edges []Edge) {
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].RPS != edges[j].RPS {
+			return edges[i].RPS > edges[j].RPS
+		}
+		return edges[i].Peer.String() < edges[j].Peer.String()
+	})
+}
+
+// SortKey is the column the workloads are sorted by, in decreasing order.
+type SortKey string
+
+const (
+	SortByRPS       SortKey = "rps"
+	SortByP99       SortKey = "p99"
+	SortByErrorRate SortKey = "5xx"
+)
+
+// SortKeys are the supported sort keys.
+var SortKeys = []SortKey{SortByRPS, SortByP99, SortByErrorRate}
+
+// Sort sorts the workloads by the key in decreasing order, then by name.
+func Sort(workloads []*Workload, by SortKey) {
+	value := func(w *Workload) float64 {
+		switch by {
+		case SortByP99:
+			return float64(w.Quantile(0.99))
+		case SortByErrorRate:
+			return w.ErrorRate()
+		default:
+			return w.RPS
+		}
+	}
+	sort.SliceStable(workloads, func(i, j int) bool {
+		vi, vj := value(workloads[i]), value(workloads[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return workloads[i].String() < workloads[j].String()
+	})
+}
+
+func keyFromLabels(labels map[string]string) sampleKey {
+	return sampleKey{
+		reporter:    labels[reporterLabel],
+		source:      WorkloadID{Name: labels[sourceWorkloadLabel], Namespace: labels[sourceWorkloadNamespaceLabel]},
+		destination: WorkloadID{Name: labels[destWorkloadLabel], Namespace: labels[destWorkloadNamespaceLabel]},
+	}
+}
+
+func isServerError(responseCode string) bool {
+	return strings.HasPrefix(responseCode, "5")
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package top
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var (
+	productpage = WorkloadID{Name: "productpage-v1", Namespace: "default"}
+	reviews     = WorkloadID{Name: "reviews-v1", Namespace: "default"}
+	ratings     = WorkloadID{Name: "ratings-v1", Namespace: "default"}
+	ingress     = WorkloadID{Name: "istio-ingressgateway", Namespace: "istio-system"}
+)
+
+func TestQuantile(t *testing.T) {
+	traffic := Traffic{Buckets: map[float64]float64{
+		0.01:        50,
+		0.1:         90,
+		1:           100,
+		math.Inf(1): 100,
+	}}
+	cases := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0.25, 5 * time.Millisecond},
+		{0.5, 10 * time.Millisecond},
+		{0.7, 55 * time.Millisecond},
+		{0.99, 910 * time.Millisecond},
+	}
+	for _, c := range cases {
+		if got := traffic.Quantile(c.q); got.Round(time.Millisecond) != c.want {
+			t.Errorf("Quantile(%v) = %v, want %v", c.q, got, c.want)
+		}
+	}
+
+	if got := (Traffic{}).Quantile(0.99); got != 0 {
+		t.Errorf("Quantile() without buckets = %v, want 0", got)
+	}
+	overflow := Traffic{Buckets: map[float64]float64{0.5: 0, math.Inf(1): 10}}
+	if got := overflow.Quantile(0.99); got != 500*time.Millisecond {
+		t.Errorf("Quantile() in the +Inf bucket = %v, want the largest finite bound", got)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	samples := []Sample{
+		{Reporter: reporterDestination, Source: ingress, Destination: productpage, Traffic: Traffic{RPS: 10}},
+		{Reporter: reporterSource, Source: productpage, Destination: reviews, Traffic: Traffic{RPS: 9, ErrorRPS: 1}},
+		{Reporter: reporterDestination, Source: productpage, Destination: reviews, Traffic: Traffic{RPS: 8, ErrorRPS: 2}},
+		{Reporter: reporterSource, Source: reviews, Destination: ratings, Traffic: Traffic{RPS: 4}},
+		{Reporter: reporterDestination, Source: reviews, Destination: ratings, Traffic: Traffic{RPS: 4}},
+		// Reported by the ingress gateway, outside of the namespace.
+		{Reporter: reporterSource, Source: ingress, Destination: productpage, Traffic: Traffic{RPS: 10}},
+	}
+	s := Aggregate(samples, "default", time.Now())
+
+	if len(s.Workloads) != 3 {
+		t.Fatalf("got %d workloads, want 3", len(s.Workloads))
+	}
+	p := s.Workloads[0]
+	if p.WorkloadID != productpage || p.RPS != 10 || len(p.Inbound) != 1 || p.Inbound[0].Peer != ingress {
+		t.Fatalf("got %+v, want productpage with 10 RPS from the ingress gateway", p)
+	}
+	if len(p.Outbound) != 1 || p.Outbound[0].Peer != reviews || p.Outbound[0].RPS != 9 {
+		t.Fatalf("got outbound %+v, want 9 RPS to reviews as reported by productpage", p.Outbound)
+	}
+	r := s.Workloads[1]
+	if r.WorkloadID != reviews || r.RPS != 8 || r.ErrorRate() != 0.25 {
+		t.Fatalf("got %+v, want reviews with 8 RPS, 25%% errors", r)
+	}
+}
+
+func TestSort(t *testing.T) {
+	slow := Traffic{RPS: 1, Buckets: map[float64]float64{1: 1, math.Inf(1): 1}}
+	fast := Traffic{RPS: 5, ErrorRPS: 5, Buckets: map[float64]float64{0.01: 5, math.Inf(1): 5}}
+	workloads := []*Workload{
+		{WorkloadID: reviews, Traffic: slow},
+		{WorkloadID: ratings, Traffic: fast},
+		{WorkloadID: productpage},
+	}
+
+	cases := []struct {
+		by   SortKey
+		want []WorkloadID
+	}{
+		{SortByRPS, []WorkloadID{ratings, reviews, productpage}},
+		{SortByP99, []WorkloadID{reviews, ratings, productpage}},
+		{SortByErrorRate, []WorkloadID{ratings, productpage, reviews}},
+	}
+	for _, c := range cases {
+		Sort(workloads, c.by)
+		for i, w := range workloads {
+			if w.WorkloadID != c.want[i] {
+				t.Errorf("Sort(%s)[%d] = %s, want %s", c.by, i, w.WorkloadID, c.want[i])
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	samples := []Sample{
+		{Reporter: reporterDestination, Source: productpage, Destination: reviews, Traffic: Traffic{
+			RPS:     8,
+			Buckets: map[float64]float64{0.01: 4, 0.1: 8, math.Inf(1): 8},
+		}},
+		{Reporter: reporterSource, Source: reviews, Destination: ratings, Traffic: Traffic{RPS: 4}},
+	}
+	now := time.Date(2020, 3, 1, 10, 30, 0, 0, time.UTC)
+	out := &bytes.Buffer{}
+	Render(out, Aggregate(samples, "default", now), SortByRPS, true)
+
+	want := `Namespace default at 10:30:00, sorted by rps
+
+WORKLOAD                     RPS   5XX    P50   P90   P99
+reviews-v1.default           8.00  0.00%  10ms  82ms  98ms
+  <- productpage-v1.default  8.00  0.00%  10ms  82ms  98ms
+  -> ratings-v1.default      4.00  0.00%  -     -     -
+`
+	if got := out.String(); got != want {
+		t.Fatalf("Render() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	now := time.Date(2020, 3, 1, 10, 30, 0, 0, time.UTC)
+	s := Aggregate(nil, "default", now)
+	s.Errors = []error{errors.New("failed to get the stats of reviews-v1-a.default: connection refused")}
+	out := &bytes.Buffer{}
+	Render(out, s, SortByRPS, true)
+
+	want := `Namespace default at 10:30:00, sorted by rps
+
+No traffic
+
+Skipped: failed to get the stats of reviews-v1-a.default: connection refused
+`
+	if got := out.String(); got != want {
+		t.Fatalf("Render() =\n%s\nwant\n%s", got, want)
+	}
+}
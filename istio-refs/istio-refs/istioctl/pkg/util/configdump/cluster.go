@@ -20,6 +20,7 @@ import (
 	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
 )
 
 // GetDynamicClusterDump retrieves a cluster dump with just dynamic active clusters in it
@@ -64,3 +65,37 @@ func (w *Wrapper) GetClusterConfigDump() (*adminapi.ClustersConfigDump, error) {
 	}
 	return clusterDump, nil
 }
+
+// GetCluster retrieves the dynamic active or static cluster with the given name from the config dump.
+// It returns nil if there is no such cluster.
+func (w *Wrapper) GetCluster(name string) (*xdsapi.Cluster, error) {
+	clusterDump, err := w.GetClusterConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	for _, dac := range clusterDump.DynamicActiveClusters {
+		if cluster, err := unmarshalCluster(dac.Cluster, name); cluster != nil || err != nil {
+			return cluster, err
+		}
+	}
+	for _, sc := range clusterDump.StaticClusters {
+		if cluster, err := unmarshalCluster(sc.Cluster, name); cluster != nil || err != nil {
+			return cluster, err
+		}
+	}
+	return nil, nil
+}
+
+func unmarshalCluster(a *any.Any, name string) (*xdsapi.Cluster, error) {
+	if a == nil {
+		return nil, nil
+	}
+	cluster := &xdsapi.Cluster{}
+	if err := ptypes.UnmarshalAny(a, cluster); err != nil {
+		return nil, err
+	}
+	if cluster.Name != name {
+		return nil, nil
+	}
+	return cluster, nil
+}
@@ -147,3 +147,41 @@ func TestWrapper_GetDynamicClusterDump(t *testing.T) {
 		})
 	}
 }
+
+func TestWrapper_GetCluster(t *testing.T) {
+	tests := []struct {
+		name      string
+		cluster   string
+		wantFound bool
+	}{
+		{
+			name:      "retrieves dynamic active cluster",
+			cluster:   "outbound|15004||istio-policy.istio-system.svc.cluster.local",
+			wantFound: true,
+		},
+		{
+			name:      "retrieves static cluster",
+			cluster:   "xds-grpc",
+			wantFound: true,
+		},
+		{
+			name:    "returns nil if the cluster doesn't exist",
+			cluster: "outbound|9080||reviews.default.svc.cluster.local",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := setupWrapper(t)
+			got, err := w.GetCluster(tt.cluster)
+			if err != nil {
+				t.Fatalf("Wrapper.GetCluster() error = %v", err)
+			}
+			if tt.wantFound != (got != nil) {
+				t.Fatalf("wanted found %v, got %v", tt.wantFound, got)
+			}
+			if got != nil && got.Name != tt.cluster {
+				t.Errorf("wanted cluster %v, got %v", tt.cluster, got.Name)
+			}
+		})
+	}
+}
@@ -71,3 +71,33 @@ func (w *Wrapper) GetListenerConfigDump() (*adminapi.ListenersConfigDump, error)
 	}
 	return listenerDump, nil
 }
+
+// GetListeners retrieves the active dynamic listeners and the static listeners from the config dump
+func (w *Wrapper) GetListeners() ([]*xdsapi.Listener, error) {
+	listenerDump, err := w.GetListenerConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	listeners := make([]*xdsapi.Listener, 0, len(listenerDump.DynamicListeners)+len(listenerDump.StaticListeners))
+	for _, l := range listenerDump.DynamicListeners {
+		if l.ActiveState == nil || l.ActiveState.Listener == nil {
+			continue
+		}
+		listener := &xdsapi.Listener{}
+		if err := ptypes.UnmarshalAny(l.ActiveState.Listener, listener); err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	for _, l := range listenerDump.StaticListeners {
+		if l.Listener == nil {
+			continue
+		}
+		listener := &xdsapi.Listener{}
+		if err := ptypes.UnmarshalAny(l.Listener, listener); err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
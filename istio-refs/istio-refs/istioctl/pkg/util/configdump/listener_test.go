@@ -15,6 +15,7 @@
 package configdump
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/golang/protobuf/ptypes/any"
@@ -137,3 +138,23 @@ func TestWrapper_GetDynamicListenerDump(t *testing.T) {
 		})
 	}
 }
+
+func TestWrapper_GetListeners(t *testing.T) {
+	w := setupWrapper(t)
+	got, err := w.GetListeners()
+	if err != nil {
+		t.Fatalf("Wrapper.GetListeners() error = %v", err)
+	}
+	var names []string
+	for _, l := range got {
+		names = append(names, l.Name)
+	}
+	if want := []string{"172.21.134.116_443", "0.0.0.0_8080"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("wanted listeners %v, got %v", want, names)
+	}
+
+	w.Configs = []*any.Any{}
+	if _, err := w.GetListeners(); err == nil {
+		t.Errorf("wanted an error if no listener dump exists")
+	}
+}
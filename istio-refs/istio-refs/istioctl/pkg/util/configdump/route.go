@@ -21,6 +21,7 @@ import (
 	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
 )
 
 // GetLastUpdatedDynamicRouteTime retrieves the LastUpdated timestamp of the
@@ -90,3 +91,37 @@ func (w *Wrapper) GetRouteConfigDump() (*adminapi.RoutesConfigDump, error) {
 	}
 	return routeDump, nil
 }
+
+// GetRouteConfiguration retrieves the dynamic or static route configuration with the given name from the config dump.
+// It returns nil if there is no such route configuration.
+func (w *Wrapper) GetRouteConfiguration(name string) (*xdsapi.RouteConfiguration, error) {
+	routeDump, err := w.GetRouteConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	for _, drc := range routeDump.DynamicRouteConfigs {
+		if route, err := unmarshalRouteConfiguration(drc.RouteConfig, name); route != nil || err != nil {
+			return route, err
+		}
+	}
+	for _, src := range routeDump.StaticRouteConfigs {
+		if route, err := unmarshalRouteConfiguration(src.RouteConfig, name); route != nil || err != nil {
+			return route, err
+		}
+	}
+	return nil, nil
+}
+
+func unmarshalRouteConfiguration(a *any.Any, name string) (*xdsapi.RouteConfiguration, error) {
+	if a == nil {
+		return nil, nil
+	}
+	route := &xdsapi.RouteConfiguration{}
+	if err := ptypes.UnmarshalAny(a, route); err != nil {
+		return nil, err
+	}
+	if route.Name != name {
+		return nil, nil
+	}
+	return route, nil
+}
@@ -132,3 +132,41 @@ func TestWrapper_GetDynamicRouteDump(t *testing.T) {
 		})
 	}
 }
+
+func TestWrapper_GetRouteConfiguration(t *testing.T) {
+	tests := []struct {
+		name      string
+		route     string
+		wantFound bool
+	}{
+		{
+			name:      "retrieves dynamic route configuration",
+			route:     "15004",
+			wantFound: true,
+		},
+		{
+			name:      "retrieves static route configuration",
+			route:     "inbound|9080||productpage.default.svc.cluster.local",
+			wantFound: true,
+		},
+		{
+			name:  "returns nil if the route configuration doesn't exist",
+			route: "9080",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := setupWrapper(t)
+			got, err := w.GetRouteConfiguration(tt.route)
+			if err != nil {
+				t.Fatalf("Wrapper.GetRouteConfiguration() error = %v", err)
+			}
+			if tt.wantFound != (got != nil) {
+				t.Fatalf("wanted found %v, got %v", tt.wantFound, got)
+			}
+			if got != nil && got.Name != tt.route {
+				t.Errorf("wanted route configuration %v, got %v", tt.route, got.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formatting
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/pkg/config/resource"
+)
+
+func testMessages() diag.Messages {
+	fileResource := &resource.Instance{Origin: &rt.Origin{
+		Kind:     "VirtualService",
+		FullName: resource.NewFullName("default", "reviews"),
+		Ref:      &rt.Position{Filename: "vs.yaml", Line: 12},
+	}}
+	clusterResource := &resource.Instance{Origin: &rt.Origin{
+		Kind:     "Pod",
+		FullName: resource.NewFullName("default", "ratings"),
+	}}
+
+	fieldMessage := diag.NewMessage(diag.NewMessageType(diag.Error, "IST0101", "Referenced %s not found: %q"), fileResource, "gateway", "gw")
+	fieldMessage.Field = "spec.gateways[0]"
+	fieldMessage.Line = 15
+
+	return diag.Messages{
+		diag.NewMessage(diag.NewMessageType(diag.Error, "IST0101", "Referenced %s not found: %q"), fileResource, "host", "reviews"),
+		diag.NewMessage(diag.NewMessageType(diag.Warning, "IST0103", "The pod %s is missing the Istio proxy."), clusterResource, "ratings"),
+		fieldMessage,
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	out, err := SARIF(testMessages())
+	g.Expect(err).To(BeNil())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal(out, &log)).To(Succeed())
+	g.Expect(log.Version).To(Equal("2.1.0"))
+	g.Expect(log.Runs).To(HaveLen(1))
+
+	run := log.Runs[0]
+	g.Expect(run.Tool.Driver.Rules).To(HaveLen(2))
+	g.Expect(run.Results).To(HaveLen(3))
+
+	g.Expect(run.Results[0].RuleID).To(Equal("IST0101"))
+	g.Expect(run.Results[0].Level).To(Equal("error"))
+	g.Expect(run.Results[0].Message.Text).To(Equal(`Referenced host not found: "reviews"`))
+	g.Expect(run.Results[0].Locations).To(Equal([]sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: "vs.yaml"},
+		Region:           &sarifRegion{StartLine: 12},
+	}}}))
+	g.Expect(run.Results[0].LogicalLocations[0].FullyQualifiedName).To(Equal("VirtualService reviews.default"))
+
+	g.Expect(run.Results[1].Level).To(Equal("warning"))
+	g.Expect(run.Results[1].Locations).To(BeEmpty())
+
+	g.Expect(run.Results[2].Locations[0].PhysicalLocation.Region.StartLine).To(Equal(15))
+}
+
+func TestSARIFNoMessages(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	out, err := SARIF(nil)
+	g.Expect(err).To(BeNil())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal(out, &log)).To(Succeed())
+	g.Expect(log.Runs).To(HaveLen(1))
+	g.Expect(log.Runs[0].Results).To(BeEmpty())
+}
+
+func TestJUnit(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	out, err := JUnit(testMessages(), diag.Warning)
+	g.Expect(err).To(BeNil())
+
+	var suites junitTestSuites
+	g.Expect(xml.Unmarshal(out, &suites)).To(Succeed())
+	g.Expect(suites.Suites).To(HaveLen(1))
+
+	suite := suites.Suites[0]
+	g.Expect(suite.Tests).To(Equal(3))
+	g.Expect(suite.Failures).To(Equal(3))
+	g.Expect(suite.Cases[0].Name).To(Equal("IST0101 VirtualService reviews.default"))
+	g.Expect(suite.Cases[0].File).To(Equal("vs.yaml"))
+	g.Expect(suite.Cases[0].Line).To(Equal(12))
+	g.Expect(suite.Cases[0].Failure.Type).To(Equal("Error"))
+	g.Expect(suite.Cases[1].File).To(BeEmpty())
+	g.Expect(suite.Cases[2].Line).To(Equal(15))
+}
+
+func TestJUnitFailureThreshold(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	out, err := JUnit(testMessages(), diag.Error)
+	g.Expect(err).To(BeNil())
+
+	var suites junitTestSuites
+	g.Expect(xml.Unmarshal(out, &suites)).To(Succeed())
+
+	suite := suites.Suites[0]
+	g.Expect(suite.Tests).To(Equal(3))
+	g.Expect(suite.Failures).To(Equal(2))
+	g.Expect(suite.Cases[0].Failure).NotTo(BeNil())
+	g.Expect(suite.Cases[1].Name).To(Equal("IST0103 Pod ratings.default"))
+	g.Expect(suite.Cases[1].Failure).To(BeNil())
+	g.Expect(suite.Cases[2].Failure).NotTo(BeNil())
+}
+
+func TestJUnitNoMessages(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	out, err := JUnit(nil, diag.Warning)
+	g.Expect(err).To(BeNil())
+
+	var suites junitTestSuites
+	g.Expect(xml.Unmarshal(out, &suites)).To(Succeed())
+	g.Expect(suites.Suites[0].Tests).To(Equal(1))
+	g.Expect(suites.Suites[0].Failures).To(Equal(0))
+	g.Expect(suites.Suites[0].Cases[0].Failure).To(BeNil())
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formatting
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit renders the messages as a JUnit XML report, with one test case per message. Only the messages at
+// or above failureThreshold are reported as failures. When there are no messages, the report holds a single
+// passing test case so that the run is still recorded.
+func JUnit(ms diag.Messages, failureThreshold diag.Level) ([]byte, error) {
+	suite := junitTestSuite{Name: toolName}
+
+	for _, m := range ms {
+		name := m.Type.Code()
+		if m.Resource != nil {
+			name = fmt.Sprintf("%s %s", m.Type.Code(), m.Resource.Origin.FriendlyName())
+		}
+		text := fmt.Sprintf(m.Type.Template(), m.Parameters...)
+		tc := junitTestCase{
+			Name:      name,
+			Classname: toolName,
+		}
+		if pos := position(m); pos != nil {
+			tc.File = pos.Filename
+			tc.Line = pos.Line
+		}
+		if m.Type.Level().IsWorseThanOrEqualTo(failureThreshold) {
+			tc.Failure = &junitFailure{
+				Message: text,
+				Type:    m.Type.Level().String(),
+				Text:    m.String(),
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if len(suite.Cases) == 0 {
+		suite.Cases = append(suite.Cases, junitTestCase{Name: "analysis", Classname: toolName})
+	}
+	suite.Tests = len(suite.Cases)
+
+	out, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formatting
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "istioctl analyze"
+)
+
+// The subset of the SARIF 2.1.0 object model used to report analysis messages.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID      string `json:"id"`
+	HelpURI string `json:"helpUri"`
+}
+
+type sarifResult struct {
+	RuleID           string                 `json:"ruleId"`
+	Level            string                 `json:"level"`
+	Message          sarifMessage           `json:"message"`
+	Locations        []sarifLocation        `json:"locations,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+var sarifLevels = map[diag.Level]string{
+	diag.Info:    "note",
+	diag.Warning: "warning",
+	diag.Error:   "error",
+}
+
+// SARIF renders the messages as a SARIF 2.1.0 log, for code scanning tools. Messages about resources read
+// from files are located at the file and line of the resource.
+func SARIF(ms diag.Messages) ([]byte, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			InformationURI: diag.DocPrefix,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	seenRules := map[string]bool{}
+	for _, m := range ms {
+		code := m.Type.Code()
+		if !seenRules[code] {
+			seenRules[code] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:      code,
+				HelpURI: fmt.Sprintf("%s/%s", diag.DocPrefix, code),
+			})
+		}
+
+		result := sarifResult{
+			RuleID:  code,
+			Level:   sarifLevels[m.Type.Level()],
+			Message: sarifMessage{Text: fmt.Sprintf(m.Type.Template(), m.Parameters...)},
+		}
+		if m.Resource != nil {
+			result.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: m.Resource.Origin.FriendlyName()}}
+		}
+		if pos := position(m); pos != nil {
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: pos.Filename},
+			}}
+			if pos.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: pos.Line}
+			}
+			result.Locations = []sarifLocation{loc}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	return json.MarshalIndent(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+}
+
+// position returns the file position a message is about, or nil if its resource wasn't read from a file.
+// The position is that of the message field if known, or of the resource otherwise.
+func position(m diag.Message) *rt.Position {
+	if m.Resource == nil || m.Resource.Origin == nil {
+		return nil
+	}
+	pos, ok := m.Resource.Origin.Reference().(*rt.Position)
+	if !ok || pos.Filename == "" {
+		return nil
+	}
+	if m.Line > 0 {
+		return pos.WithLine(m.Line).(*rt.Position)
+	}
+	return pos
+}
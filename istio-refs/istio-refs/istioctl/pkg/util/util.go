@@ -0,0 +1,46 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package util holds the helpers shared by the istioctl commands reading the Istio and Envoy configuration.
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ResolveGatewayRef returns the namespace/name reference of a gateway of a VirtualService, the way Pilot
+// resolves it.
+func ResolveGatewayRef(gwName, namespace string) string {
+	if parts := strings.Split(gwName, "/"); len(parts) == 2 {
+		if parts[0] == "." {
+			return namespace + "/" + parts[1]
+		}
+		return gwName
+	}
+	if parts := strings.Split(gwName, "."); len(parts) > 1 {
+		// the FQDN of the gateway
+		return parts[1] + "/" + parts[0]
+	}
+	return namespace + "/" + gwName
+}
+
+// FullMatch returns whether the regular expression matches the whole value, as Envoy regex matchers require.
+func FullMatch(expr, value string) (bool, error) {
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(value), nil
+}
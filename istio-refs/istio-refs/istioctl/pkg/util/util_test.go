@@ -0,0 +1,56 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+)
+
+func TestResolveGatewayRef(t *testing.T) {
+	cases := []struct {
+		gateway string
+		want    string
+	}{
+		{gateway: "bookinfo-gateway", want: "default/bookinfo-gateway"},
+		{gateway: "./bookinfo-gateway", want: "default/bookinfo-gateway"},
+		{gateway: "istio-system/ingressgateway", want: "istio-system/ingressgateway"},
+		{gateway: "ingressgateway.istio-system.svc.cluster.local", want: "istio-system/ingressgateway"},
+	}
+	for _, c := range cases {
+		if got := ResolveGatewayRef(c.gateway, "default"); got != c.want {
+			t.Errorf("ResolveGatewayRef(%q) = %q, want %q", c.gateway, got, c.want)
+		}
+	}
+}
+
+func TestFullMatch(t *testing.T) {
+	cases := []struct {
+		expr  string
+		value string
+		want  bool
+		err   bool
+	}{
+		{expr: "/api/.*", value: "/api/v1", want: true},
+		{expr: "/api", value: "/api/v1", want: false},
+		{expr: "a|b", value: "ab", want: false},
+		{expr: "(", value: "(", err: true},
+	}
+	for _, c := range cases {
+		got, err := FullMatch(c.expr, c.value)
+		if (err != nil) != c.err || got != c.want {
+			t.Errorf("FullMatch(%q, %q) = %v, %v, want %v", c.expr, c.value, got, err, c.want)
+		}
+	}
+}
@@ -121,6 +121,9 @@ func init() {
 		fmt.Sprintf("File name for Istio mesh configuration. If not specified, a default mesh will be used."))
 	discoveryCmd.PersistentFlags().StringVar(&serverArgs.NetworksConfigFile, "networksConfig", "/etc/istio/config/meshNetworks",
 		fmt.Sprintf("File name for Istio mesh networks configuration. If not specified, a default mesh networks will be used."))
+	discoveryCmd.PersistentFlags().StringVar(&serverArgs.DiscoverySelectorsFile, "discoverySelectorsConfig", "/etc/istio/config/discoverySelectors",
+		"File name for the discovery selectors of the Kubernetes registries. The file is watched for changes. "+
+			"If not present, the PILOT_DISCOVERY_NAMESPACE_SELECTORS and PILOT_DISCOVERY_SERVICE_SELECTORS variables are used.")
 	discoveryCmd.PersistentFlags().StringVarP(&serverArgs.Namespace, "namespace", "n", bootstrap.PodNamespaceVar.Get(),
 		"Select a namespace where the controller resides. If not set, uses ${POD_NAMESPACE} environment variable")
 	discoveryCmd.PersistentFlags().StringSliceVar(&serverArgs.Plugins, "plugins", bootstrap.DefaultPlugins,
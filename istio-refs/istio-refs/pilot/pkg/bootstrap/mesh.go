@@ -29,6 +29,7 @@ import (
 	"istio.io/pkg/log"
 	"istio.io/pkg/version"
 
+	"istio.io/istio/pilot/pkg/features"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config/mesh"
 )
@@ -98,6 +99,46 @@ func (s *Server) initMeshNetworks(args *PilotArgs, fileWatcher filewatcher.FileW
 	}
 }
 
+// initDiscoverySelectors loads the discovery selectors of the Kubernetes registries from the file
+// provided in the args and adds a watcher for changes in this file. The selectors of the
+// PILOT_DISCOVERY_NAMESPACE_SELECTORS and PILOT_DISCOVERY_SERVICE_SELECTORS variables are used
+// if the file is not present.
+func (s *Server) initDiscoverySelectors(args *PilotArgs, fileWatcher filewatcher.FileWatcher) (err error) {
+	selectors := &args.Config.ControllerOptions.DiscoverySelectors
+	if args.DiscoverySelectorsFile != "" {
+		if *selectors, err = kubecontroller.ReadDiscoverySelectors(args.DiscoverySelectorsFile); err == nil {
+			mesh.AddFileWatcher(fileWatcher, args.DiscoverySelectorsFile, func() {
+				s.reloadDiscoverySelectors(args.DiscoverySelectorsFile)
+			})
+			return nil
+		}
+		log.Infof("discovery selectors not loaded from %s: %v", args.DiscoverySelectorsFile, err)
+	}
+
+	if selectors.Namespaces, err = kubecontroller.ParseDiscoverySelectors(features.DiscoveryNamespaceSelectors); err != nil {
+		return err
+	}
+	selectors.Services, err = kubecontroller.ParseDiscoverySelectors(features.DiscoveryServiceSelectors)
+	return err
+}
+
+// reloadDiscoverySelectors applies the discovery selectors of the file to the Kubernetes registries.
+// Services no longer selected are removed, together with their endpoints.
+func (s *Server) reloadDiscoverySelectors(filename string) {
+	selectors, err := kubecontroller.ReadDiscoverySelectors(filename)
+	if err != nil {
+		log.Warnf("failed to read discovery selectors from %q: %v", filename, err)
+		return
+	}
+	log.Infof("discovery selectors updated from %s", filename)
+	if s.kubeRegistry != nil {
+		s.kubeRegistry.SetDiscoverySelectors(selectors)
+	}
+	if s.multicluster != nil {
+		s.multicluster.SetDiscoverySelectors(selectors)
+	}
+}
+
 // getMeshConfig fetches the ProxyMesh configuration from Kubernetes ConfigMap.
 // Deprecated - does not watch !
 func getMeshConfig(kube kubernetes.Interface, namespace, name string) (*meshconfig.MeshConfig, error) {
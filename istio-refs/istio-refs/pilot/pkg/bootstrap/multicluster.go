@@ -31,7 +31,8 @@ func (s *Server) initClusterRegistries(args *PilotArgs) (err error) {
 			args.Config.ControllerOptions.ResyncPeriod,
 			s.ServiceController(),
 			s.EnvoyXdsServer,
-			s.environment)
+			s.environment,
+			args.Config.ControllerOptions.DiscoverySelectors)
 
 		if err != nil {
 			log.Info("Unable to create new Multicluster object")
@@ -83,6 +83,10 @@ type PilotArgs struct {
 	KeepaliveOptions   *istiokeepalive.Options
 	// ForceStop is set as true when used for testing to make the server stop quickly
 	ForceStop bool
+
+	// DiscoverySelectorsFile is a YAML file with the namespace and service selectors of the
+	// Kubernetes registries. It is watched, and replaces the PILOT_DISCOVERY_*_SELECTORS variables if present.
+	DiscoverySelectorsFile string
 }
 
 // DiscoveryServiceOptions contains options for create a new discovery
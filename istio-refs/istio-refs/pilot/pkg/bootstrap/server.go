@@ -162,9 +162,16 @@ type Server struct {
 
 // NewServer creates a new Server instance based on the provided arguments.
 func NewServer(args *PilotArgs) (*Server, error) {
+	conflictPolicy, err := aggregate.ParseConflictPolicy(features.RegistryConflictPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("registry conflict policy: %v", err)
+	}
 	e := &model.Environment{
-		ServiceDiscovery: aggregate.NewController(),
-		PushContext:      model.NewPushContext(),
+		ServiceDiscovery: aggregate.NewController(aggregate.Options{
+			ConflictPolicy:   conflictPolicy,
+			RegistryPriority: aggregate.ParseRegistryPriority(features.RegistryPriority),
+		}),
+		PushContext: model.NewPushContext(),
 	}
 
 	s := &Server{
@@ -189,6 +196,9 @@ func NewServer(args *PilotArgs) (*Server, error) {
 		return nil, fmt.Errorf("mesh: %v", err)
 	}
 	s.initMeshNetworks(args, fileWatcher)
+	if err := s.initDiscoverySelectors(args, fileWatcher); err != nil {
+		return nil, fmt.Errorf("discovery selectors: %v", err)
+	}
 	// Certificate controller is created before MCP
 	// controller in case MCP server pod waits to mount a certificate
 	// to be provisioned by the certificate controller.
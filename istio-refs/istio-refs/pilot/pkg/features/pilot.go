@@ -259,6 +259,56 @@ var (
 			"Currently this is mutual exclusive - either Endpoints or EndpointSlices will be used",
 	).Get()
 
+	// RegistryConflictPolicy decides how a hostname provided by more than one service registry (i.e. a Kubernetes
+	// service and a ServiceEntry) is resolved by the aggregate controller.
+	RegistryConflictPolicy = env.RegisterStringVar(
+		"PILOT_REGISTRY_CONFLICT_POLICY",
+		"merge-endpoints",
+		"Decides how a hostname provided by more than one service registry is resolved. One of "+
+			"\"merge-endpoints\" (keep the services from all registries and merge their endpoints), "+
+			"\"first-wins\" (only use the first registry providing the hostname) or \"priority\" "+
+			"(only use the registry ranked highest in PILOT_REGISTRY_PRIORITY). The policy applies to the "+
+			"services and to the EDS endpoints, and conflicts are recomputed on every full push. Conflicts are "+
+			"listed in /debug/registryz?conflicts=true.",
+	).Get()
+
+	// RegistryPriority ranks the service registry providers for the "priority" RegistryConflictPolicy.
+	RegistryPriority = env.RegisterStringVar(
+		"PILOT_REGISTRY_PRIORITY",
+		"Kubernetes,MCP,External,Consul",
+		"Comma separated list of registry providers, highest priority first. Only used when "+
+			"PILOT_REGISTRY_CONFLICT_POLICY is \"priority\". Providers that are not listed rank last.",
+	).Get()
+
+	SendUnhealthyEndpoints = env.RegisterBoolVar(
+		"PILOT_SEND_UNHEALTHY_ENDPOINTS",
+		false,
+		"If enabled, Pilot will include not ready Kubernetes endpoints in EDS, with health status DRAINING "+
+			"for terminating pods and UNHEALTHY otherwise, instead of omitting them. Note that Envoy "+
+			"panic routing applies if too many endpoints of a cluster are not healthy.",
+	).Get()
+
+	DiscoveryNamespaceSelectors = env.RegisterStringVar(
+		"PILOT_DISCOVERY_NAMESPACE_SELECTORS",
+		"",
+		"Semicolon separated list of Kubernetes label selectors. If set, the Kubernetes registry only exposes "+
+			"services in namespaces matching at least one of the selectors, e.g. \"istio-discovery=enabled\".",
+	).Get()
+
+	DiscoveryServiceSelectors = env.RegisterStringVar(
+		"PILOT_DISCOVERY_SERVICE_SELECTORS",
+		"",
+		"Semicolon separated list of Kubernetes label selectors. If set, the Kubernetes registry only exposes "+
+			"services matching at least one of the selectors.",
+	).Get()
+
+	EnableServiceEntryHealthChecks = env.RegisterBoolVar(
+		"PILOT_ENABLE_SERVICE_ENTRY_HEALTH_CHECKS",
+		false,
+		"If enabled, Pilot will actively probe the endpoints of ServiceEntries carrying the "+
+			"networking.istio.io/healthCheck annotation, and mark failing endpoints unhealthy in EDS.",
+	).Get()
+
 	EnableCRDValidation = env.RegisterBoolVar(
 		"PILOT_ENABLE_CRD_VALIDATION",
 		false,
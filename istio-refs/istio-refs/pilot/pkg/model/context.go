@@ -781,6 +781,39 @@ const (
 	InterceptionRedirect TrafficInterceptionMode = "REDIRECT"
 )
 
+// SupportsAddressFamily returns true if the proxy has at least one address of the given IP family.
+// Proxies without any non-loopback IP address are assumed to support every family.
+func (node *Proxy) SupportsAddressFamily(f AddressFamily) bool {
+	ipv4, ipv6 := node.addressFamilies()
+	if !ipv4 && !ipv6 {
+		return true
+	}
+	return (f == AddressFamilyIPv4 && ipv4) || (f == AddressFamilyIPv6 && ipv6)
+}
+
+// IsDualStack returns true if the proxy has both IPv4 and IPv6 addresses.
+func (node *Proxy) IsDualStack() bool {
+	ipv4, ipv6 := node.addressFamilies()
+	return ipv4 && ipv6
+}
+
+// addressFamilies returns the IP families of the proxy addresses. Loopback addresses are ignored,
+// since IPv4 hosts commonly have ::1 as well.
+func (node *Proxy) addressFamilies() (ipv4 bool, ipv6 bool) {
+	for _, addr := range node.IPAddresses {
+		ip := net.ParseIP(addr)
+		if ip == nil || ip.IsLoopback() {
+			continue
+		}
+		if ip.To4() != nil {
+			ipv4 = true
+		} else {
+			ipv6 = true
+		}
+	}
+	return ipv4, ipv6
+}
+
 // GetInterceptionMode extracts the interception mode associated with the proxy
 // from the proxy metadata
 func (node *Proxy) GetInterceptionMode() TrafficInterceptionMode {
@@ -499,3 +499,32 @@ func TestSetServiceInstances(t *testing.T) {
 	assert.Equal(t, proxy.ServiceInstances[1].Service.Hostname, host.Name("test3.com"))
 	assert.Equal(t, proxy.ServiceInstances[2].Service.Hostname, host.Name("test1.com"))
 }
+
+func TestProxyAddressFamilies(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		ipv4      bool
+		ipv6      bool
+		dualStack bool
+	}{
+		{"ipv4 only", []string{"10.1.1.1", "127.0.0.1", "::1"}, true, false, false},
+		{"ipv6 only", []string{"fd00:10::1", "::1"}, false, true, false},
+		{"dual stack", []string{"10.1.1.1", "fd00:10::1"}, true, true, true},
+		{"loopback only", []string{"127.0.0.1", "::1"}, true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &model.Proxy{IPAddresses: tt.addresses}
+			if got := proxy.SupportsAddressFamily(model.AddressFamilyIPv4); got != tt.ipv4 {
+				t.Errorf("SupportsAddressFamily(IPv4) = %v, want %v", got, tt.ipv4)
+			}
+			if got := proxy.SupportsAddressFamily(model.AddressFamilyIPv6); got != tt.ipv6 {
+				t.Errorf("SupportsAddressFamily(IPv6) = %v, want %v", got, tt.ipv6)
+			}
+			if got := proxy.IsDualStack(); got != tt.dualStack {
+				t.Errorf("IsDualStack() = %v, want %v", got, tt.dualStack)
+			}
+		})
+	}
+}
@@ -25,6 +25,7 @@ package model
 import (
 	"bytes"
 	"fmt"
+	"net"
 	"sort"
 	"strconv"
 	"strings"
@@ -317,6 +318,61 @@ type IstioEndpoint struct {
 
 	// TLSMode endpoint is injected with istio sidecar and ready to configure Istio mTLS
 	TLSMode string
+
+	// AddressFamily is the IP family of Address. Registries that do not set it leave it
+	// as AddressFamilyUnknown; use AddressFamilyOf to compute it from the address.
+	AddressFamily AddressFamily
+
+	// HealthStatus of the endpoint, published in EDS. Registries that do not track health leave it Healthy.
+	HealthStatus HealthStatus
+}
+
+// HealthStatus is the health of an endpoint as known by its registry.
+type HealthStatus int
+
+const (
+	// Healthy endpoints receive traffic. This is the default.
+	Healthy HealthStatus = iota
+	// UnHealthy endpoints are excluded from load balancing by Envoy.
+	UnHealthy
+	// Draining endpoints are shutting down. Envoy sends them no new requests, but lets
+	// in-flight requests complete.
+	Draining
+)
+
+// AddressFamily is the IP family of an endpoint or proxy address.
+type AddressFamily int
+
+const (
+	// AddressFamilyUnknown is used for addresses that are not IPs, such as unix domain sockets or hostnames.
+	AddressFamilyUnknown AddressFamily = iota
+	// AddressFamilyIPv4 is used for IPv4 addresses.
+	AddressFamilyIPv4
+	// AddressFamilyIPv6 is used for IPv6 addresses.
+	AddressFamilyIPv6
+)
+
+// AddressFamilyOf returns the IP family of the given address.
+func AddressFamilyOf(addr string) AddressFamily {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return AddressFamilyUnknown
+	}
+	if ip.To4() != nil {
+		return AddressFamilyIPv4
+	}
+	return AddressFamilyIPv6
+}
+
+func (f AddressFamily) String() string {
+	switch f {
+	case AddressFamilyIPv4:
+		return "IPv4"
+	case AddressFamilyIPv6:
+		return "IPv6"
+	default:
+		return "Unknown"
+	}
 }
 
 // ServiceAttributes represents a group of custom attributes of the service.
@@ -2249,6 +2249,11 @@ func buildListener(opts buildListenerOpts) *xdsapi.Listener {
 		DeprecatedV1:    deprecatedV1,
 	}
 
+	// A dual-stack proxy listening on :: must also accept IPv4 connections.
+	if opts.bind == WildcardIPv6Address && opts.proxy.IsDualStack() {
+		listener.Address.GetSocketAddress().Ipv4Compat = true
+	}
+
 	if util.IsIstioVersionGE13(opts.proxy) && opts.proxy.Type != model.Router {
 		listener.ListenerFiltersTimeout = gogo.DurationToProtoDuration(opts.push.Mesh.ProtocolDetectionTimeout)
 
@@ -2443,7 +2448,11 @@ func ipv4AndIpv6Support(node *model.Proxy) (bool, bool) {
 // getSidecarInboundBindIP returns the IP that the proxy can bind to along with the sidecar specified port.
 // It looks for an unicast address, if none found, then the default wildcard address is used.
 // This will make the inbound listener bind to instance_ip:port instead of 0.0.0.0:port where applicable.
+// Dual-stack proxies bind to :: instead, so that the listener accepts both IPv4 and IPv6 traffic.
 func getSidecarInboundBindIP(node *model.Proxy) string {
+	if node.IsDualStack() {
+		return WildcardIPv6Address
+	}
 	defaultInboundIP, _ := getActualWildcardAndLocalHost(node)
 	for _, ipAddr := range node.IPAddresses {
 		ip := net.ParseIP(ipAddr)
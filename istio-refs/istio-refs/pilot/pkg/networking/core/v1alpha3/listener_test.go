@@ -661,6 +661,47 @@ func TestOutboundListenerConfigWithSidecarHTTPProxy(t *testing.T) {
 	}
 }
 
+func TestGetSidecarInboundBindIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxy    *model.Proxy
+		expected string
+	}{
+		{
+			name:     "ipv4 only",
+			proxy:    &model.Proxy{IPAddresses: []string{"127.0.0.1", "10.1.1.1"}},
+			expected: "10.1.1.1",
+		},
+		{
+			name:     "ipv6 only",
+			proxy:    &model.Proxy{IPAddresses: []string{"::1", "fd00:10::1"}},
+			expected: "fd00:10::1",
+		},
+		{
+			name:     "dual stack",
+			proxy:    &model.Proxy{IPAddresses: []string{"10.1.1.1", "fd00:10::1"}},
+			expected: WildcardIPv6Address,
+		},
+		{
+			name:     "ipv4 with ipv6 loopback",
+			proxy:    &model.Proxy{IPAddresses: []string{"10.1.1.1", "::1"}},
+			expected: "10.1.1.1",
+		},
+		{
+			name:     "no unicast address",
+			proxy:    &model.Proxy{IPAddresses: []string{"127.0.0.1"}},
+			expected: WildcardAddress,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getSidecarInboundBindIP(tt.proxy); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestGetActualWildcardAndLocalHost(t *testing.T) {
 	tests := []struct {
 		name     string
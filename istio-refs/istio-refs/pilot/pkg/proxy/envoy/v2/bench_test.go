@@ -59,7 +59,7 @@ func SetupDiscoveryServer(t testing.TB, cfgs ...model.Config) *DiscoveryServer {
 	store := memory.Make(collections.Pilot)
 	configController := memory.NewController(store)
 	istioConfigStore := model.MakeIstioStore(configController)
-	serviceControllers := aggregate.NewController()
+	serviceControllers := aggregate.NewController(aggregate.Options{})
 	serviceEntryStore := external.NewServiceDiscovery(configController, istioConfigStore, s)
 	go configController.Run(make(chan struct{}))
 	serviceEntryRegistry := serviceregistry.Simple{
@@ -133,6 +133,8 @@ func (s *DiscoveryServer) InitDebug(mux *http.ServeMux, sctl *aggregate.Controll
 	s.addDebugHandler(mux, "/debug/config_distribution", "Version status of all Envoys connected to this Pilot instance", s.distributedVersions)
 
 	s.addDebugHandler(mux, "/debug/registryz", "Debug support for registry", s.registryz)
+	// Served by the registryz handler, only listed in the help.
+	s.debugHandlers["/debug/registryz?conflicts=true"] = "Hostnames provided by more than one registry"
 	s.addDebugHandler(mux, "/debug/endpointz", "Debug support for endpoints", s.endpointz)
 	s.addDebugHandler(mux, "/debug/endpointShardz", "Info about the endpoint shards", s.endpointShardz)
 	s.addDebugHandler(mux, "/debug/configz", "Debug support for config", s.configz)
@@ -207,6 +209,20 @@ func (s *DiscoveryServer) registryz(w http.ResponseWriter, req *http.Request) {
 	_ = req.ParseForm()
 	w.Header().Add("Content-Type", "application/json")
 
+	if req.Form.Get("conflicts") != "" {
+		var conflicts []aggregate.Conflict
+		if ctl, ok := s.Env.ServiceDiscovery.(*aggregate.Controller); ok {
+			conflicts = ctl.Conflicts()
+		}
+		b, err := json.MarshalIndent(conflicts, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(b)
+		return
+	}
+
 	all, err := s.Env.ServiceDiscovery.Services()
 	if err != nil {
 		return
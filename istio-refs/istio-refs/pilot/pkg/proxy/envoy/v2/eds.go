@@ -22,6 +22,7 @@ import (
 	"time"
 
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	"github.com/golang/protobuf/ptypes/wrappers"
 
@@ -121,6 +122,13 @@ func buildEnvoyLbEndpoint(e *model.IstioEndpoint, push *model.PushContext) *endp
 	// Do not remove
 	ep.Metadata = util.BuildLbEndpointMetadata(e.UID, e.Network, e.TLSMode, push)
 
+	switch e.HealthStatus {
+	case model.UnHealthy:
+		ep.HealthStatus = core.HealthStatus_UNHEALTHY
+	case model.Draining:
+		ep.HealthStatus = core.HealthStatus_DRAINING
+	}
+
 	return ep
 }
 
@@ -177,7 +185,7 @@ func (s *DiscoveryServer) updateClusterInc(push *model.PushContext, clusterName
 		return s.updateCluster(push, clusterName, edsCluster)
 	}
 
-	locEps := buildLocalityLbEndpointsFromShards(se, svcPort, subsetLabels, clusterName, push)
+	locEps := buildLocalityLbEndpointsFromShards(se, svcPort, subsetLabels, clusterName, push, s.shardFilter(hostname), nil)
 	// There is a chance multiple goroutines will update the cluster at the same time.
 	// This could be prevented by a lock - but because the update may be slow, it may be
 	// better to accept the extra computations.
@@ -245,7 +253,8 @@ func (s *DiscoveryServer) updateServiceShards(push *model.PushContext) error {
 			}
 
 			// TODO(nmittler): Should we get the cluster from the endpoints instead? May require organizing endpoints by cluster first.
-			s.edsUpdate(registry.Cluster(), string(svc.Hostname), svc.Attributes.Namespace, endpoints, true)
+			s.edsUpdate(serviceregistry.ShardKey(registry.Provider(), registry.Cluster()), string(svc.Hostname),
+				svc.Attributes.Namespace, endpoints, true)
 		}
 	}
 
@@ -596,7 +605,8 @@ func (s *DiscoveryServer) loadAssignmentsForClusterIsolated(proxy *model.Proxy,
 		return s.loadAssignmentsForClusterLegacy(push, clusterName)
 	}
 
-	locEps := buildLocalityLbEndpointsFromShards(se, svcPort, subsetLabels, clusterName, push)
+	locEps := buildLocalityLbEndpointsFromShards(se, svcPort, subsetLabels, clusterName, push, s.shardFilter(hostname),
+		EndpointsByAddressFamilyFilter(proxy))
 
 	return &xdsapi.ClusterLoadAssignment{
 		ClusterName: clusterName,
@@ -830,19 +840,36 @@ func endpointDiscoveryResponse(loadAssignments []*xdsapi.ClusterLoadAssignment,
 	return out
 }
 
+// shardFilter returns the function deciding whether the endpoint shards of the hostname are used, according to
+// the registry conflict policy of the aggregate controller, or nil when all the shards are used.
+func (s *DiscoveryServer) shardFilter(hostname host.Name) func(shard string) bool {
+	agg, ok := s.Env.ServiceDiscovery.(*aggregate.Controller)
+	if !ok {
+		return nil
+	}
+	return func(shard string) bool {
+		return agg.ShardAllowed(hostname, shard)
+	}
+}
+
 // build LocalityLbEndpoints for a cluster from existing EndpointShards.
 func buildLocalityLbEndpointsFromShards(
 	shards *EndpointShards,
 	svcPort *model.Port,
 	epLabels labels.Collection,
 	clusterName string,
-	push *model.PushContext) []*endpoint.LocalityLbEndpoints {
+	push *model.PushContext,
+	shardAllowed func(shard string) bool,
+	endpointAllowed func(ep *model.IstioEndpoint) bool) []*endpoint.LocalityLbEndpoints {
 	localityEpMap := make(map[string]*endpoint.LocalityLbEndpoints)
 
 	shards.mutex.Lock()
 	// The shards are updated independently, now need to filter and merge
 	// for this cluster
-	for _, endpoints := range shards.Shards {
+	for shard, endpoints := range shards.Shards {
+		if shardAllowed != nil && !shardAllowed(shard) {
+			continue
+		}
 		for _, ep := range endpoints {
 			if svcPort.Name != ep.ServicePortName {
 				continue
@@ -851,6 +878,9 @@ func buildLocalityLbEndpointsFromShards(
 			if !epLabels.HasSubsetOf(ep.Labels) {
 				continue
 			}
+			if endpointAllowed != nil && !endpointAllowed(ep) {
+				continue
+			}
 
 			locLbEps, found := localityEpMap[ep.Locality.Label]
 			if !found {
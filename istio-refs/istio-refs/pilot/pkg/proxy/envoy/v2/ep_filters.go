@@ -107,6 +107,19 @@ func EndpointsByNetworkFilter(push *model.PushContext, proxyNetwork string, endp
 	return filtered
 }
 
+// EndpointsByAddressFamilyFilter returns the function keeping the endpoints whose IP family the proxy can reach, or
+// nil if the proxy reaches both families. Dual-stack services provide both IPv4 and IPv6 endpoints; a single-stack
+// proxy must only receive the ones of its own family. Endpoints of an unknown family (i.e. hostnames or unix domain
+// sockets, or endpoints of registries that do not set IstioEndpoint.AddressFamily) are always kept.
+func EndpointsByAddressFamilyFilter(proxy *model.Proxy) func(ep *model.IstioEndpoint) bool {
+	if proxy.IsDualStack() {
+		return nil
+	}
+	return func(ep *model.IstioEndpoint) bool {
+		return ep.AddressFamily == model.AddressFamilyUnknown || proxy.SupportsAddressFamily(ep.AddressFamily)
+	}
+}
+
 // TODO: remove this, filtering should be done before generating the config, and
 // network metadata should not be included in output. A node only receives endpoints
 // in the same network as itself - so passing an network meta, with exactly
@@ -207,6 +207,57 @@ func TestEndpointsByNetworkFilter(t *testing.T) {
 	}
 }
 
+func TestEndpointsByAddressFamilyFilter(t *testing.T) {
+	endpoints := []*model.IstioEndpoint{
+		{Address: "10.0.0.1", AddressFamily: model.AddressFamilyIPv4},
+		{Address: "fd00:10::1", AddressFamily: model.AddressFamilyIPv6},
+		{Address: "10.0.0.2", AddressFamily: model.AddressFamilyIPv4},
+		{Address: "fd00:10::2", AddressFamily: model.AddressFamilyIPv6},
+		{Address: "foo.example.com"},
+	}
+
+	tests := []struct {
+		name  string
+		proxy *model.Proxy
+		want  []string
+	}{
+		{
+			name:  "ipv4 only",
+			proxy: &model.Proxy{IPAddresses: []string{"10.0.1.1"}},
+			want:  []string{"10.0.0.1", "10.0.0.2", "foo.example.com"},
+		},
+		{
+			name:  "ipv6 only",
+			proxy: &model.Proxy{IPAddresses: []string{"fd00:10::3"}},
+			want:  []string{"fd00:10::1", "fd00:10::2", "foo.example.com"},
+		},
+		{
+			name:  "dual stack",
+			proxy: &model.Proxy{IPAddresses: []string{"10.0.1.1", "fd00:10::3"}},
+			want:  []string{"10.0.0.1", "fd00:10::1", "10.0.0.2", "fd00:10::2", "foo.example.com"},
+		},
+		{
+			name:  "no addresses",
+			proxy: &model.Proxy{},
+			want:  []string{"10.0.0.1", "fd00:10::1", "10.0.0.2", "fd00:10::2", "foo.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := EndpointsByAddressFamilyFilter(tt.proxy)
+			var got []string
+			for _, ep := range endpoints {
+				if allowed == nil || allowed(ep) {
+					got = append(got, ep.Address)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got endpoints %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestEndpointsByNetworkFilter_RegistryServiceName(t *testing.T) {
 	//  - 1 gateway for network1
 	//  - 1 gateway for network2
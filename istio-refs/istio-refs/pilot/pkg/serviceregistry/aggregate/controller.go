@@ -15,6 +15,9 @@
 package aggregate
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/hashicorp/go-multierror"
@@ -36,16 +39,95 @@ var (
 var _ model.ServiceDiscovery = &Controller{}
 var _ model.Controller = &Controller{}
 
+// ConflictPolicy decides how a hostname provided by more than one registry provider is resolved.
+type ConflictPolicy string
+
+const (
+	// MergeEndpoints keeps the service from every provider and merges their endpoints. This is the
+	// historical behavior, where the outcome depends on the order registries were added in.
+	MergeEndpoints ConflictPolicy = "merge-endpoints"
+	// FirstWins only keeps the service and endpoints of the first registry that provides the hostname.
+	FirstWins ConflictPolicy = "first-wins"
+	// RegistryPriority only keeps the service and endpoints of the provider ranked highest
+	// in Options.RegistryPriority. Providers that are not listed rank last, in registration order.
+	RegistryPriority ConflictPolicy = "priority"
+)
+
+// ParseConflictPolicy converts the given string to a ConflictPolicy. An empty string maps to MergeEndpoints.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case "":
+		return MergeEndpoints, nil
+	case MergeEndpoints, FirstWins, RegistryPriority:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown registry conflict policy %q, must be one of %s, %s or %s",
+			s, MergeEndpoints, FirstWins, RegistryPriority)
+	}
+}
+
+// ParseRegistryPriority converts a comma separated list of providers (i.e. "Kubernetes,External,Consul")
+// into a priority list for the RegistryPriority policy.
+func ParseRegistryPriority(s string) []serviceregistry.ProviderID {
+	var out []serviceregistry.ProviderID
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, serviceregistry.ProviderID(p))
+		}
+	}
+	return out
+}
+
+// Options stores the configurable attributes of an aggregate Controller.
+type Options struct {
+	// ConflictPolicy decides which registry wins when the same hostname is provided by more than one provider.
+	// Defaults to MergeEndpoints.
+	ConflictPolicy ConflictPolicy
+
+	// RegistryPriority orders providers, highest priority first. Only used by the RegistryPriority policy.
+	RegistryPriority []serviceregistry.ProviderID
+}
+
+// Conflict describes a hostname that is provided by more than one registry provider.
+// Multiple Kubernetes clusters providing the same hostname are not a conflict, they are merged.
+type Conflict struct {
+	Hostname  host.Name                    `json:"hostname"`
+	Providers []serviceregistry.ProviderID `json:"providers"`
+	Winner    serviceregistry.ProviderID   `json:"winner"`
+	Policy    ConflictPolicy               `json:"policy"`
+}
+
 // Controller aggregates data across different registries and monitors for changes
 type Controller struct {
 	registries []serviceregistry.Instance
 	storeLock  sync.RWMutex
+
+	conflictPolicy   ConflictPolicy
+	registryPriority map[serviceregistry.ProviderID]int
+
+	// conflicts is recomputed on every call to Services, i.e. on every full push, which service changes
+	// trigger. Until then, InstancesByPort, GetService and ShardAllowed use the conflicts of the last push.
+	conflicts     map[host.Name]*Conflict
+	conflictsLock sync.RWMutex
 }
 
 // NewController creates a new Aggregate controller
-func NewController() *Controller {
+func NewController(opts Options) *Controller {
+	policy := opts.ConflictPolicy
+	if policy == "" {
+		policy = MergeEndpoints
+	}
+	priority := make(map[serviceregistry.ProviderID]int, len(opts.RegistryPriority))
+	for i, p := range opts.RegistryPriority {
+		if _, f := priority[p]; !f {
+			priority[p] = i
+		}
+	}
 	return &Controller{
-		registries: make([]serviceregistry.Instance, 0),
+		registries:       make([]serviceregistry.Instance, 0),
+		conflictPolicy:   policy,
+		registryPriority: priority,
+		conflicts:        make(map[host.Name]*Conflict),
 	}
 }
 
@@ -106,12 +188,23 @@ func (c *Controller) Services() ([]*model.Service, error) {
 	services := make([]*model.Service, 0)
 	var errs error
 	// Locking Registries list while walking it to prevent inconsistent results
-	for _, r := range c.GetRegistries() {
+	registries := c.GetRegistries()
+	registryServices := make([][]*model.Service, len(registries))
+	for i, r := range registries {
 		svcs, err := r.Services()
 		if err != nil {
 			errs = multierror.Append(errs, err)
 			continue
 		}
+		registryServices[i] = svcs
+	}
+	conflicts := c.resolveConflicts(registries, registryServices)
+
+	for i, r := range registries {
+		svcs := registryServices[i]
+		if c.conflictPolicy != MergeEndpoints {
+			svcs = filterConflicting(svcs, r.Provider(), conflicts)
+		}
 		// Race condition: multiple threads may call Services, and multiple services
 		// may modify one of the service's cluster ID
 		clusterAddressesMutex.Lock()
@@ -157,10 +250,140 @@ func (c *Controller) Services() ([]*model.Service, error) {
 	return services, errs
 }
 
+// resolveConflicts finds the hostnames provided by more than one provider, picks the winning provider for
+// each of them according to the conflict policy and records the result for Conflicts and InstancesByPort.
+func (c *Controller) resolveConflicts(registries []serviceregistry.Instance,
+	registryServices [][]*model.Service) map[host.Name]*Conflict {
+	all := make(map[host.Name]*Conflict)
+	for i, r := range registries {
+		for _, s := range registryServices[i] {
+			cf, f := all[s.Hostname]
+			if !f {
+				cf = &Conflict{Hostname: s.Hostname, Policy: c.conflictPolicy}
+				all[s.Hostname] = cf
+			}
+			if !containsProvider(cf.Providers, r.Provider()) {
+				cf.Providers = append(cf.Providers, r.Provider())
+			}
+		}
+	}
+
+	conflicts := make(map[host.Name]*Conflict)
+	for hostname, cf := range all {
+		if len(cf.Providers) < 2 {
+			continue
+		}
+		cf.Winner = c.winner(cf.Providers)
+		conflicts[hostname] = cf
+	}
+
+	c.conflictsLock.Lock()
+	c.conflicts = conflicts
+	c.conflictsLock.Unlock()
+	return conflicts
+}
+
+// winner returns the provider whose service and endpoints are used for a conflicting hostname.
+// providers is in registration order.
+func (c *Controller) winner(providers []serviceregistry.ProviderID) serviceregistry.ProviderID {
+	if c.conflictPolicy != RegistryPriority {
+		return providers[0]
+	}
+	winner := providers[0]
+	for _, p := range providers[1:] {
+		if c.rank(p) < c.rank(winner) {
+			winner = p
+		}
+	}
+	return winner
+}
+
+func (c *Controller) rank(p serviceregistry.ProviderID) int {
+	if r, f := c.registryPriority[p]; f {
+		return r
+	}
+	return len(c.registryPriority)
+}
+
+// conflictWinner returns the winning provider for the hostname, if the hostname is conflicting and
+// the conflict policy only keeps a single provider.
+func (c *Controller) conflictWinner(hostname host.Name) (serviceregistry.ProviderID, bool) {
+	if c.conflictPolicy == MergeEndpoints {
+		return "", false
+	}
+	c.conflictsLock.RLock()
+	defer c.conflictsLock.RUnlock()
+	cf, f := c.conflicts[hostname]
+	if !f {
+		return "", false
+	}
+	return cf.Winner, true
+}
+
+// ShardAllowed returns whether the endpoints of the hostname pushed to EDS under the shard, the
+// serviceregistry.ShardKey of the registry that pushed them, are kept by the conflict policy. The endpoint shards of all registries are
+// merged by EDS, so the endpoints of the registries losing a conflict are dropped there.
+func (c *Controller) ShardAllowed(hostname host.Name, shard string) bool {
+	winner, conflicting := c.conflictWinner(hostname)
+	if !conflicting {
+		return true
+	}
+	for _, r := range c.GetRegistries() {
+		if r.Provider() == winner && serviceregistry.ShardKey(r.Provider(), r.Cluster()) == shard {
+			return true
+		}
+	}
+	return false
+}
+
+// Conflicts returns the hostnames that were provided by more than one provider during the last call
+// to Services, sorted by hostname.
+func (c *Controller) Conflicts() []Conflict {
+	c.conflictsLock.RLock()
+	defer c.conflictsLock.RUnlock()
+
+	out := make([]Conflict, 0, len(c.conflicts))
+	for _, cf := range c.conflicts {
+		out = append(out, *cf)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Hostname < out[j].Hostname
+	})
+	return out
+}
+
+func filterConflicting(svcs []*model.Service, provider serviceregistry.ProviderID,
+	conflicts map[host.Name]*Conflict) []*model.Service {
+	if len(conflicts) == 0 {
+		return svcs
+	}
+	out := make([]*model.Service, 0, len(svcs))
+	for _, s := range svcs {
+		if cf, f := conflicts[s.Hostname]; f && cf.Winner != provider {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func containsProvider(providers []serviceregistry.ProviderID, p serviceregistry.ProviderID) bool {
+	for _, existing := range providers {
+		if existing == p {
+			return true
+		}
+	}
+	return false
+}
+
 // GetService retrieves a service by hostname if exists
 func (c *Controller) GetService(hostname host.Name) (*model.Service, error) {
 	var errs error
+	winner, conflicting := c.conflictWinner(hostname)
 	for _, r := range c.GetRegistries() {
+		if conflicting && r.Provider() != winner {
+			continue
+		}
 		service, err := r.GetService(hostname)
 		if err != nil {
 			errs = multierror.Append(errs, err)
@@ -203,7 +426,11 @@ func (c *Controller) InstancesByPort(svc *model.Service, port int,
 	labels labels.Collection) ([]*model.ServiceInstance, error) {
 	var instances, tmpInstances []*model.ServiceInstance
 	var errs error
+	winner, conflicting := c.conflictWinner(svc.Hostname)
 	for _, r := range c.GetRegistries() {
+		if conflicting && r.Provider() != winner {
+			continue
+		}
 		var err error
 		tmpInstances, err = r.InstancesByPort(svc, port, labels)
 		if err != nil {
@@ -56,7 +56,7 @@ func buildMockController() *Controller {
 		Controller:       &mock.Controller{},
 	}
 
-	ctls := NewController()
+	ctls := NewController(Options{})
 	ctls.AddRegistry(registry1)
 	ctls.AddRegistry(registry2)
 
@@ -89,7 +89,7 @@ func buildMockControllerForMultiCluster() *Controller {
 		Controller:       &mock.Controller{},
 	}
 
-	ctls := NewController()
+	ctls := NewController(Options{})
 	ctls.AddRegistry(registry1)
 	ctls.AddRegistry(registry2)
 
@@ -479,7 +479,7 @@ func TestAddRegistry(t *testing.T) {
 			ClusterID:  "cluster2",
 		},
 	}
-	ctrl := NewController()
+	ctrl := NewController(Options{})
 	for _, r := range registries {
 		ctrl.AddRegistry(r)
 	}
@@ -499,7 +499,7 @@ func TestDeleteRegistry(t *testing.T) {
 			ClusterID:  "cluster2",
 		},
 	}
-	ctrl := NewController()
+	ctrl := NewController(Options{})
 	for _, r := range registries {
 		ctrl.AddRegistry(r)
 	}
@@ -520,7 +520,7 @@ func TestGetRegistries(t *testing.T) {
 			ClusterID:  "cluster2",
 		},
 	}
-	ctrl := NewController()
+	ctrl := NewController(Options{})
 	for _, r := range registries {
 		ctrl.AddRegistry(r)
 	}
@@ -535,3 +535,143 @@ func TestGetRegistries(t *testing.T) {
 		}
 	}
 }
+
+func buildConflictingController(opts Options) *Controller {
+	kube := mock.NewDiscovery(
+		map[host.Name]*model.Service{
+			mock.HelloService.Hostname: mock.MakeService(mock.HelloService.Hostname, "10.1.1.0"),
+		}, 2)
+	external := mock.NewDiscovery(
+		map[host.Name]*model.Service{
+			mock.HelloService.Hostname: mock.MakeService(mock.HelloService.Hostname, "10.1.2.0"),
+			mock.WorldService.Hostname: mock.WorldService,
+		}, 3)
+
+	ctls := NewController(opts)
+	ctls.AddRegistry(serviceregistry.Simple{
+		ProviderID:       serviceregistry.External,
+		ServiceDiscovery: external,
+		Controller:       &mock.Controller{},
+	})
+	ctls.AddRegistry(serviceregistry.Simple{
+		ProviderID:       serviceregistry.Kubernetes,
+		ClusterID:        "cluster1",
+		ServiceDiscovery: kube,
+		Controller:       &mock.Controller{},
+	})
+	return ctls
+}
+
+func TestConflictPolicy(t *testing.T) {
+	cases := []struct {
+		name      string
+		opts      Options
+		addresses []string
+		instances int
+		winner    serviceregistry.ProviderID
+		shards    map[string]bool
+	}{
+		{
+			name:      "merge endpoints",
+			opts:      Options{},
+			addresses: []string{"10.1.2.0", "10.1.1.0"},
+			instances: 5,
+			winner:    serviceregistry.External,
+			shards:    map[string]bool{"External/": true, "cluster1": true},
+		},
+		{
+			name:      "first wins",
+			opts:      Options{ConflictPolicy: FirstWins},
+			addresses: []string{"10.1.2.0"},
+			instances: 3,
+			winner:    serviceregistry.External,
+			shards:    map[string]bool{"External/": true, "Consul/": false, "cluster1": false},
+		},
+		{
+			name: "priority",
+			opts: Options{
+				ConflictPolicy:   RegistryPriority,
+				RegistryPriority: []serviceregistry.ProviderID{serviceregistry.Kubernetes, serviceregistry.External},
+			},
+			addresses: []string{"10.1.1.0"},
+			instances: 2,
+			winner:    serviceregistry.Kubernetes,
+			shards:    map[string]bool{"External/": false, "cluster1": true},
+		},
+		{
+			name:      "priority with unlisted providers",
+			opts:      Options{ConflictPolicy: RegistryPriority, RegistryPriority: []serviceregistry.ProviderID{"Consul"}},
+			addresses: []string{"10.1.2.0"},
+			instances: 3,
+			winner:    serviceregistry.External,
+			shards:    map[string]bool{"External/": true, "Consul/": false, "cluster1": false},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctl := buildConflictingController(tt.opts)
+			services, err := ctl.Services()
+			if err != nil {
+				t.Fatalf("Services() encountered unexpected error: %v", err)
+			}
+
+			var addresses []string
+			for _, svc := range services {
+				if svc.Hostname == mock.HelloService.Hostname {
+					addresses = append(addresses, svc.Address)
+				}
+			}
+			if !reflect.DeepEqual(addresses, tt.addresses) {
+				t.Fatalf("got addresses %v, expected %v", addresses, tt.addresses)
+			}
+
+			instances, err := ctl.InstancesByPort(mock.HelloService, 80, labels.Collection{})
+			if err != nil {
+				t.Fatalf("InstancesByPort() encountered unexpected error: %v", err)
+			}
+			if len(instances) != tt.instances {
+				t.Fatalf("got %d instances, expected %d", len(instances), tt.instances)
+			}
+
+			expected := []Conflict{{
+				Hostname:  mock.HelloService.Hostname,
+				Providers: []serviceregistry.ProviderID{serviceregistry.External, serviceregistry.Kubernetes},
+				Winner:    tt.winner,
+				Policy:    ctl.conflictPolicy,
+			}}
+			if got := ctl.Conflicts(); !reflect.DeepEqual(got, expected) {
+				t.Fatalf("got conflicts %+v, expected %+v", got, expected)
+			}
+
+			for shard, allowed := range tt.shards {
+				if got := ctl.ShardAllowed(mock.HelloService.Hostname, shard); got != allowed {
+					t.Fatalf("ShardAllowed(%q) = %v, expected %v", shard, got, allowed)
+				}
+			}
+			if !ctl.ShardAllowed(mock.WorldService.Hostname, "cluster1") {
+				t.Fatal("ShardAllowed should allow all the shards of hostnames without conflicts")
+			}
+		})
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	for in, expected := range map[string]ConflictPolicy{
+		"":                "merge-endpoints",
+		"merge-endpoints": MergeEndpoints,
+		"first-wins":      FirstWins,
+		"priority":        RegistryPriority,
+	} {
+		got, err := ParseConflictPolicy(in)
+		if err != nil {
+			t.Fatalf("ParseConflictPolicy(%q) encountered unexpected error: %v", in, err)
+		}
+		if got != expected {
+			t.Fatalf("ParseConflictPolicy(%q) = %q, expected %q", in, got, expected)
+		}
+	}
+	if _, err := ParseConflictPolicy("last-wins"); err == nil {
+		t.Fatal("ParseConflictPolicy should fail for unknown policies")
+	}
+}
@@ -149,6 +149,7 @@ func convertEndpoint(service *model.Service, servicePort *networking.Port,
 			EndpointPort:    instancePort,
 			ServicePortName: servicePort.Name,
 			Network:         endpoint.Network,
+			AddressFamily:   model.AddressFamilyOf(addr),
 			Locality: model.Locality{
 				Label: endpoint.Locality,
 			},
@@ -370,6 +370,7 @@ func makeInstance(cfg *model.Config, address string, port int,
 			ServicePortName: svcPort.Name,
 			Labels:          svcLabels,
 			TLSMode:         tlsMode,
+			AddressFamily:   model.AddressFamilyOf(address),
 		},
 		ServicePort: &model.Port{
 			Name:     svcPort.Name,
@@ -0,0 +1,188 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/pkg/log"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+var scope = log.RegisterScope("healthcheck", "ServiceEntry endpoint health checking", 0)
+
+// Target is an endpoint address and port to probe.
+type Target struct {
+	Address string
+	Port    uint32
+}
+
+// Prober periodically probes targets and tracks their health. Targets start out healthy,
+// so endpoints are not removed from the load balancing pool before they have been probed.
+type Prober struct {
+	mu sync.RWMutex
+	// owners maps an owner (i.e. a ServiceEntry) to the targets it wants probed.
+	owners map[string]map[Target]*Spec
+	probes map[Target]*probe
+
+	check    CheckFunc
+	onChange func(Target)
+}
+
+type probe struct {
+	spec      *Spec
+	cancel    context.CancelFunc
+	healthy   bool
+	successes int
+	failures  int
+}
+
+// NewProber creates a Prober calling onChange every time a target changes health status.
+func NewProber(onChange func(Target)) *Prober {
+	return newProber(Check, onChange)
+}
+
+func newProber(check CheckFunc, onChange func(Target)) *Prober {
+	return &Prober{
+		owners:   map[string]map[Target]*Spec{},
+		probes:   map[Target]*probe{},
+		check:    check,
+		onChange: onChange,
+	}
+}
+
+// Update replaces the targets probed on behalf of owner. Passing no targets removes the owner.
+// A target requested by several owners is probed once, using the spec of the first owner in
+// lexical order.
+func (p *Prober) Update(owner string, targets map[Target]*Spec) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(targets) == 0 {
+		delete(p.owners, owner)
+	} else {
+		p.owners[owner] = targets
+	}
+
+	owners := make([]string, 0, len(p.owners))
+	for o := range p.owners {
+		owners = append(owners, o)
+	}
+	sort.Strings(owners)
+	desired := map[Target]*Spec{}
+	for _, o := range owners {
+		for t, spec := range p.owners[o] {
+			if _, f := desired[t]; !f {
+				desired[t] = spec
+			}
+		}
+	}
+
+	for t, pr := range p.probes {
+		if spec, f := desired[t]; !f || !reflect.DeepEqual(spec, pr.spec) {
+			pr.cancel()
+			delete(p.probes, t)
+		}
+	}
+	for t, spec := range desired {
+		if _, f := p.probes[t]; f {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.probes[t] = &probe{spec: spec, cancel: cancel, healthy: true}
+		go p.run(ctx, t, spec)
+	}
+}
+
+// Status returns the health status of target. Unknown targets are reported healthy.
+func (p *Prober) Status(target Target) model.HealthStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if pr, f := p.probes[target]; f && !pr.healthy {
+		return model.UnHealthy
+	}
+	return model.Healthy
+}
+
+// Stop stops probing all targets.
+func (p *Prober) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for t, pr := range p.probes {
+		pr.cancel()
+		delete(p.probes, t)
+	}
+	p.owners = map[string]map[Target]*Spec{}
+}
+
+func (p *Prober) run(ctx context.Context, target Target, spec *Spec) {
+	ticker := time.NewTicker(spec.Interval)
+	defer ticker.Stop()
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, spec.Timeout)
+		err := p.check(checkCtx, target, spec)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			scope.Debugf("health check of %s:%d failed: %v", target.Address, target.Port, err)
+		}
+		if p.record(target, err == nil) {
+			p.onChange(target)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// record stores the result of a probe, returning true if the target changed health status.
+func (p *Prober) record(target Target, success bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pr, f := p.probes[target]
+	if !f {
+		return false
+	}
+
+	if success {
+		pr.successes++
+		pr.failures = 0
+		if !pr.healthy && pr.successes >= pr.spec.HealthyThreshold {
+			pr.healthy = true
+			scope.Infof("endpoint %s:%d is healthy", target.Address, target.Port)
+			return true
+		}
+		return false
+	}
+
+	pr.failures++
+	pr.successes = 0
+	if pr.healthy && pr.failures >= pr.spec.UnhealthyThreshold {
+		pr.healthy = false
+		scope.Infof("endpoint %s:%d is unhealthy", target.Address, target.Port)
+		return true
+	}
+	return false
+}
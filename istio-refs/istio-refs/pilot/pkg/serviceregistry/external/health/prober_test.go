@@ -0,0 +1,196 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestParseSpec(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		want  *Spec
+		err   bool
+	}{
+		{
+			name:  "defaults",
+			value: `{}`,
+			want: &Spec{Type: TCP, Interval: defaultInterval, Timeout: defaultTimeout,
+				HealthyThreshold: defaultHealthyThreshold, UnhealthyThreshold: defaultUnhealthyThreshold},
+		},
+		{
+			name:  "http",
+			value: `{"type": "http", "port": 8080, "interval": "5s", "timeout": "2s", "healthyThreshold": 2, "unhealthyThreshold": 4}`,
+			want: &Spec{Type: HTTP, Port: 8080, Path: "/", Interval: 5 * time.Second, Timeout: 2 * time.Second,
+				HealthyThreshold: 2, UnhealthyThreshold: 4},
+		},
+		{
+			name:  "grpc",
+			value: `{"type": "GRPC", "service": "foo"}`,
+			want: &Spec{Type: GRPC, Service: "foo", Interval: defaultInterval, Timeout: defaultTimeout,
+				HealthyThreshold: defaultHealthyThreshold, UnhealthyThreshold: defaultUnhealthyThreshold},
+		},
+		{name: "invalid json", value: `{`, err: true},
+		{name: "invalid type", value: `{"type": "UDP"}`, err: true},
+		{name: "invalid interval", value: `{"interval": "soon"}`, err: true},
+		{name: "timeout longer than interval", value: `{"interval": "1s", "timeout": "2s"}`, err: true},
+		{name: "negative threshold", value: `{"unhealthyThreshold": -1}`, err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSpec(tt.value)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != *tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProberThresholds(t *testing.T) {
+	target := Target{Address: "1.1.1.1", Port: 80}
+	spec := &Spec{Type: TCP, Interval: time.Hour, Timeout: time.Second, HealthyThreshold: 2, UnhealthyThreshold: 3}
+	p := newProber(nil, nil)
+	p.probes[target] = &probe{spec: spec, healthy: true}
+
+	steps := []struct {
+		success bool
+		changed bool
+		status  model.HealthStatus
+	}{
+		{false, false, model.Healthy},
+		{false, false, model.Healthy},
+		{true, false, model.Healthy}, // resets the failure count
+		{false, false, model.Healthy},
+		{false, false, model.Healthy},
+		{false, true, model.UnHealthy},
+		{false, false, model.UnHealthy},
+		{true, false, model.UnHealthy},
+		{true, true, model.Healthy},
+	}
+	for i, s := range steps {
+		if changed := p.record(target, s.success); changed != s.changed {
+			t.Fatalf("step %d: got changed %v, want %v", i, changed, s.changed)
+		}
+		if status := p.Status(target); status != s.status {
+			t.Fatalf("step %d: got status %v, want %v", i, status, s.status)
+		}
+	}
+}
+
+func TestProberUpdate(t *testing.T) {
+	var mu sync.Mutex
+	failing := map[Target]bool{}
+	check := func(_ context.Context, target Target, _ *Spec) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing[target] {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	changes := make(chan Target, 10)
+	p := newProber(check, func(t Target) { changes <- t })
+	defer p.Stop()
+
+	a := Target{Address: "1.1.1.1", Port: 80}
+	b := Target{Address: "2.2.2.2", Port: 80}
+	spec := &Spec{Type: TCP, Interval: 10 * time.Millisecond, Timeout: 5 * time.Millisecond,
+		HealthyThreshold: 1, UnhealthyThreshold: 1}
+	mu.Lock()
+	failing[b] = true
+	mu.Unlock()
+
+	p.Update("ns/se1", map[Target]*Spec{a: spec})
+	p.Update("ns/se2", map[Target]*Spec{a: spec, b: spec})
+
+	select {
+	case got := <-changes:
+		if got != b {
+			t.Fatalf("got change for %v, want %v", got, b)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for health status change")
+	}
+	if p.Status(a) != model.Healthy || p.Status(b) != model.UnHealthy {
+		t.Fatalf("unexpected status a=%v b=%v", p.Status(a), p.Status(b))
+	}
+
+	// Removing the only owner of b stops probing it; a is still owned by se1.
+	p.Update("ns/se2", nil)
+	if p.Status(b) != model.Healthy {
+		t.Fatalf("expected unknown target to be healthy, got %v", p.Status(b))
+	}
+	p.mu.RLock()
+	_, probingA := p.probes[a]
+	_, probingB := p.probes[b]
+	p.mu.RUnlock()
+	if !probingA || probingB {
+		t.Fatalf("unexpected probes: a=%v b=%v", probingA, probingB)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	target := Target{Address: host, Port: uint32(p)}
+
+	cases := []struct {
+		name string
+		spec *Spec
+		err  bool
+	}{
+		{"tcp", &Spec{Type: TCP}, false},
+		{"http", &Spec{Type: HTTP, Path: "/healthz"}, false},
+		{"http failing", &Spec{Type: HTTP, Path: "/"}, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := Check(ctx, target, tt.spec)
+			if (err != nil) != tt.err {
+				t.Fatalf("got err %v, want error: %v", err, tt.err)
+			}
+		})
+	}
+}
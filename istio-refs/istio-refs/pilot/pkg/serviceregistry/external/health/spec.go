@@ -0,0 +1,137 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package health implements active health checking of ServiceEntry endpoints.
+// Pilot probes the endpoints centrally and publishes their health status in EDS.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Annotation is the ServiceEntry annotation holding the JSON encoded health check Spec.
+const Annotation = "networking.istio.io/healthCheck"
+
+// Type is the kind of probe sent to an endpoint.
+type Type string
+
+const (
+	// TCP checks succeed if a connection can be established.
+	TCP Type = "TCP"
+	// HTTP checks succeed if a GET request returns a 2xx or 3xx status.
+	HTTP Type = "HTTP"
+	// GRPC checks succeed if the grpc.health.v1.Health service reports SERVING.
+	GRPC Type = "GRPC"
+)
+
+const (
+	defaultInterval           = 10 * time.Second
+	defaultTimeout            = time.Second
+	defaultHealthyThreshold   = 1
+	defaultUnhealthyThreshold = 3
+)
+
+// Spec describes how the endpoints of a ServiceEntry are health checked.
+type Spec struct {
+	// Type of the probe. Defaults to TCP.
+	Type Type
+	// Port to probe. If unset, each endpoint is probed on its own port.
+	Port uint32
+	// Path of the HTTP request. Defaults to "/".
+	Path string
+	// Service name sent in gRPC health check requests.
+	Service string
+	// Interval between two probes of the same endpoint.
+	Interval time.Duration
+	// Timeout of a single probe.
+	Timeout time.Duration
+	// HealthyThreshold is the number of consecutive successes marking an unhealthy endpoint healthy.
+	HealthyThreshold int
+	// UnhealthyThreshold is the number of consecutive failures marking a healthy endpoint unhealthy.
+	UnhealthyThreshold int
+}
+
+// rawSpec is the annotation format, with durations as strings (e.g. "5s").
+type rawSpec struct {
+	Type               string `json:"type,omitempty"`
+	Port               uint32 `json:"port,omitempty"`
+	Path               string `json:"path,omitempty"`
+	Service            string `json:"service,omitempty"`
+	Interval           string `json:"interval,omitempty"`
+	Timeout            string `json:"timeout,omitempty"`
+	HealthyThreshold   int    `json:"healthyThreshold,omitempty"`
+	UnhealthyThreshold int    `json:"unhealthyThreshold,omitempty"`
+}
+
+// ParseSpec parses the value of the health check annotation, applying defaults for unset fields.
+func ParseSpec(value string) (*Spec, error) {
+	raw := rawSpec{}
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("invalid health check %q: %v", value, err)
+	}
+
+	spec := &Spec{
+		Type:               Type(strings.ToUpper(raw.Type)),
+		Port:               raw.Port,
+		Path:               raw.Path,
+		Service:            raw.Service,
+		Interval:           defaultInterval,
+		Timeout:            defaultTimeout,
+		HealthyThreshold:   raw.HealthyThreshold,
+		UnhealthyThreshold: raw.UnhealthyThreshold,
+	}
+	switch spec.Type {
+	case "":
+		spec.Type = TCP
+	case TCP, HTTP, GRPC:
+	default:
+		return nil, fmt.Errorf("invalid health check type %q", raw.Type)
+	}
+	if spec.Type == HTTP && spec.Path == "" {
+		spec.Path = "/"
+	}
+	if spec.Port > 65535 {
+		return nil, fmt.Errorf("invalid health check port %d", spec.Port)
+	}
+
+	var err error
+	if raw.Interval != "" {
+		if spec.Interval, err = time.ParseDuration(raw.Interval); err != nil || spec.Interval <= 0 {
+			return nil, fmt.Errorf("invalid health check interval %q", raw.Interval)
+		}
+	}
+	if raw.Timeout != "" {
+		if spec.Timeout, err = time.ParseDuration(raw.Timeout); err != nil || spec.Timeout <= 0 {
+			return nil, fmt.Errorf("invalid health check timeout %q", raw.Timeout)
+		}
+	}
+	if spec.Timeout > spec.Interval {
+		return nil, fmt.Errorf("health check timeout %v is longer than the interval %v", spec.Timeout, spec.Interval)
+	}
+
+	if spec.HealthyThreshold == 0 {
+		spec.HealthyThreshold = defaultHealthyThreshold
+	}
+	if spec.UnhealthyThreshold == 0 {
+		spec.UnhealthyThreshold = defaultUnhealthyThreshold
+	}
+	if spec.HealthyThreshold < 0 || spec.UnhealthyThreshold < 0 {
+		return nil, fmt.Errorf("health check thresholds must be positive")
+	}
+
+	return spec, nil
+}
@@ -21,8 +21,10 @@ import (
 
 	"istio.io/pkg/log"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/serviceregistry/external/health"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -51,6 +53,10 @@ type ServiceEntryStore struct {
 	changeMutex    sync.RWMutex
 	lastChange     time.Time
 	refreshIndexes bool
+
+	// healthProber probes endpoints of ServiceEntries with a health check annotation.
+	// It is nil if health checking is disabled.
+	healthProber *health.Prober
 }
 
 // NewServiceDiscovery creates a new ServiceEntry discovery service
@@ -62,10 +68,14 @@ func NewServiceDiscovery(configController model.ConfigStoreCache, store model.Is
 		instances:      map[host.Name]map[string][]*model.ServiceInstance{},
 		refreshIndexes: true,
 	}
+	if features.EnableServiceEntryHealthChecks {
+		c.healthProber = health.NewProber(c.onHealthChange)
+	}
 	if configController != nil {
 		configController.RegisterEventHandler(serviceEntryKind,
 			func(old, curr model.Config, event model.Event) {
 				cs := convertServices(curr)
+				c.updateHealthTargets(curr, event)
 
 				// If it is add/delete event we should always do a full push. If it is update event, we should do full push,
 				// only when services have changed - otherwise, just push endpoint updates.
@@ -83,7 +93,7 @@ func NewServiceDiscovery(configController model.ConfigStoreCache, store model.Is
 				// If service entry is deleted, cleanup endpoint shards for services.
 				if event == model.EventDelete {
 					for _, svc := range cs {
-						c.XdsUpdater.SvcUpdate(c.Cluster(), string(svc.Hostname), svc.Attributes.Namespace, event)
+						c.XdsUpdater.SvcUpdate(serviceregistry.ShardKey(c.Provider(), c.Cluster()), string(svc.Hostname), svc.Attributes.Namespace, event)
 					}
 				}
 
@@ -103,31 +113,10 @@ func NewServiceDiscovery(configController model.ConfigStoreCache, store model.Is
 					c.XdsUpdater.ConfigUpdate(pushReq)
 				} else {
 					instances := convertInstances(curr, cs)
+					c.applyHealthStatus(curr, instances)
 					// If only instances have changed, just update the indexes for the changed instances.
 					c.updateExistingInstances(instances)
-					endpointsByHostname := make(map[string][]*model.IstioEndpoint)
-					for _, instance := range instances {
-						for _, port := range instance.Service.Ports {
-							endpointsByHostname[string(instance.Service.Hostname)] = append(endpointsByHostname[string(instance.Service.Hostname)],
-								&model.IstioEndpoint{
-									Address:         instance.Endpoint.Address,
-									EndpointPort:    uint32(port.Port),
-									ServicePortName: port.Name,
-									Labels:          instance.Endpoint.Labels,
-									UID:             instance.Endpoint.UID,
-									ServiceAccount:  instance.Endpoint.ServiceAccount,
-									Network:         instance.Endpoint.Network,
-									Locality:        instance.Endpoint.Locality,
-									LbWeight:        instance.Endpoint.LbWeight,
-									TLSMode:         instance.Endpoint.TLSMode,
-								})
-						}
-					}
-
-					for host, eps := range endpointsByHostname {
-						_ = c.XdsUpdater.EDSUpdate(c.Cluster(), host, curr.Namespace, eps)
-					}
-
+					c.edsUpdate(instances)
 				}
 			})
 	}
@@ -153,7 +142,12 @@ func (d *ServiceEntryStore) AppendInstanceHandler(_ func(*model.ServiceInstance,
 }
 
 // Run is used by some controllers to execute background jobs after init is done.
-func (d *ServiceEntryStore) Run(_ <-chan struct{}) {}
+func (d *ServiceEntryStore) Run(stop <-chan struct{}) {
+	if d.healthProber != nil {
+		<-stop
+		d.healthProber.Stop()
+	}
+}
 
 // Services list declarations of all services in the system
 func (d *ServiceEntryStore) Services() ([]*model.Service, error) {
@@ -240,7 +234,9 @@ func (d *ServiceEntryStore) maybeRefreshIndexes() {
 	dip := map[string][]*model.ServiceInstance{}
 
 	for _, cfg := range d.store.ServiceEntries() {
-		updateInstances(convertInstances(cfg, nil), di, dip)
+		instances := convertInstances(cfg, nil)
+		d.applyHealthStatus(cfg, instances)
+		updateInstances(instances, di, dip)
 	}
 
 	d.storeMutex.Lock()
@@ -255,6 +251,130 @@ func (d *ServiceEntryStore) maybeRefreshIndexes() {
 	d.changeMutex.Unlock()
 }
 
+// edsUpdate pushes the endpoints of the passed in instances, grouped by hostname and namespace.
+func (d *ServiceEntryStore) edsUpdate(instances []*model.ServiceInstance) {
+	type hostKey struct {
+		hostname  string
+		namespace string
+	}
+	endpointsByHost := make(map[hostKey][]*model.IstioEndpoint)
+	for _, instance := range instances {
+		k := hostKey{string(instance.Service.Hostname), instance.Service.Attributes.Namespace}
+		for _, port := range instance.Service.Ports {
+			endpointsByHost[k] = append(endpointsByHost[k],
+				&model.IstioEndpoint{
+					Address:         instance.Endpoint.Address,
+					EndpointPort:    uint32(port.Port),
+					ServicePortName: port.Name,
+					Labels:          instance.Endpoint.Labels,
+					UID:             instance.Endpoint.UID,
+					ServiceAccount:  instance.Endpoint.ServiceAccount,
+					Network:         instance.Endpoint.Network,
+					AddressFamily:   instance.Endpoint.AddressFamily,
+					Locality:        instance.Endpoint.Locality,
+					LbWeight:        instance.Endpoint.LbWeight,
+					TLSMode:         instance.Endpoint.TLSMode,
+					HealthStatus:    instance.Endpoint.HealthStatus,
+				})
+		}
+	}
+
+	for k, eps := range endpointsByHost {
+		_ = d.XdsUpdater.EDSUpdate(serviceregistry.ShardKey(d.Provider(), d.Cluster()), k.hostname, k.namespace, eps)
+	}
+}
+
+// healthCheckSpec returns the health check spec of a ServiceEntry, or nil if it has none.
+func healthCheckSpec(cfg model.Config) *health.Spec {
+	value, f := cfg.Annotations[health.Annotation]
+	if !f {
+		return nil
+	}
+	spec, err := health.ParseSpec(value)
+	if err != nil {
+		log.Warnf("ignoring health check of service entry %s/%s: %v", cfg.Namespace, cfg.Name, err)
+		return nil
+	}
+	return spec
+}
+
+// healthTarget returns the address and port probed for an instance.
+func healthTarget(spec *health.Spec, instance *model.ServiceInstance) (health.Target, bool) {
+	// Unix domain socket endpoints have no port and can not be probed.
+	if instance.Endpoint.EndpointPort == 0 {
+		return health.Target{}, false
+	}
+	port := spec.Port
+	if port == 0 {
+		port = instance.Endpoint.EndpointPort
+	}
+	return health.Target{Address: instance.Endpoint.Address, Port: port}, true
+}
+
+// updateHealthTargets registers the endpoints of a ServiceEntry with the health prober.
+func (d *ServiceEntryStore) updateHealthTargets(cfg model.Config, event model.Event) {
+	if d.healthProber == nil {
+		return
+	}
+	owner := cfg.Namespace + "/" + cfg.Name
+	spec := healthCheckSpec(cfg)
+	if event == model.EventDelete || spec == nil {
+		d.healthProber.Update(owner, nil)
+		return
+	}
+	targets := map[health.Target]*health.Spec{}
+	for _, instance := range convertInstances(cfg, nil) {
+		if t, ok := healthTarget(spec, instance); ok {
+			targets[t] = spec
+		}
+	}
+	d.healthProber.Update(owner, targets)
+}
+
+// applyHealthStatus sets the health status of the instances of a ServiceEntry, as last seen by the prober.
+func (d *ServiceEntryStore) applyHealthStatus(cfg model.Config, instances []*model.ServiceInstance) {
+	if d.healthProber == nil {
+		return
+	}
+	spec := healthCheckSpec(cfg)
+	if spec == nil {
+		return
+	}
+	for _, instance := range instances {
+		if t, ok := healthTarget(spec, instance); ok {
+			instance.Endpoint.HealthStatus = d.healthProber.Status(t)
+		}
+	}
+}
+
+// onHealthChange is called by the prober when a target changes health status. The indexes are
+// rebuilt, and endpoints of all services the target address belongs to are pushed.
+func (d *ServiceEntryStore) onHealthChange(target health.Target) {
+	d.changeMutex.Lock()
+	d.lastChange = time.Now()
+	d.refreshIndexes = true
+	d.changeMutex.Unlock()
+	d.maybeRefreshIndexes()
+
+	d.storeMutex.RLock()
+	var instances []*model.ServiceInstance
+	seen := map[host.Name]map[string]bool{}
+	for _, instance := range d.ip2instance[target.Address] {
+		hostname, ns := instance.Service.Hostname, instance.Service.Attributes.Namespace
+		if seen[hostname][ns] {
+			continue
+		}
+		if seen[hostname] == nil {
+			seen[hostname] = map[string]bool{}
+		}
+		seen[hostname][ns] = true
+		instances = append(instances, d.instances[hostname][ns]...)
+	}
+	d.storeMutex.RUnlock()
+
+	d.edsUpdate(instances)
+}
+
 // updateExistingInstances updates the indexes (by host, byip maps) for the passed in instances.
 func (d *ServiceEntryStore) updateExistingInstances(instances []*model.ServiceInstance) {
 	d.storeMutex.Lock()
@@ -23,6 +23,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/config/memory"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/external/health"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
@@ -244,6 +245,76 @@ func TestNonServiceConfig(t *testing.T) {
 	}
 }
 
+func TestHealthTargets(t *testing.T) {
+	cases := []struct {
+		name       string
+		cfg        *model.Config
+		annotation string
+		want       []health.Target
+	}{
+		{
+			name: "no annotation",
+			cfg:  httpStatic,
+		},
+		{
+			name:       "invalid annotation",
+			cfg:        httpStatic,
+			annotation: `{"type": "UDP"}`,
+		},
+		{
+			name:       "endpoint ports",
+			cfg:        httpStatic,
+			annotation: `{"type": "TCP"}`,
+			want: []health.Target{
+				{Address: "2.2.2.2", Port: 7080}, {Address: "2.2.2.2", Port: 18080},
+				{Address: "3.3.3.3", Port: 1080}, {Address: "3.3.3.3", Port: 8080},
+				{Address: "4.4.4.4", Port: 1080}, {Address: "4.4.4.4", Port: 8080},
+			},
+		},
+		{
+			name:       "health check port",
+			cfg:        httpStatic,
+			annotation: `{"type": "HTTP", "port": 9000, "path": "/healthz"}`,
+			want: []health.Target{
+				{Address: "2.2.2.2", Port: 9000}, {Address: "3.3.3.3", Port: 9000}, {Address: "4.4.4.4", Port: 9000},
+			},
+		},
+		{
+			name:       "unix domain socket",
+			cfg:        udsLocal,
+			annotation: `{"type": "GRPC"}`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *tt.cfg
+			if tt.annotation != "" {
+				cfg.Annotations = map[string]string{health.Annotation: tt.annotation}
+			}
+			got := map[health.Target]bool{}
+			if spec := healthCheckSpec(cfg); spec != nil {
+				for _, instance := range convertInstances(cfg, nil) {
+					if target, ok := healthTarget(spec, instance); ok {
+						got[target] = true
+					}
+				}
+			}
+			want := map[health.Target]bool{}
+			for _, target := range tt.want {
+				want[target] = true
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got targets %v, want %v", got, want)
+			}
+			for target := range want {
+				if !got[target] {
+					t.Fatalf("got targets %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
 func TestServicesChanged(t *testing.T) {
 
 	var updatedHTTPDNS = &model.Config{
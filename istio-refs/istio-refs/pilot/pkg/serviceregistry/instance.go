@@ -49,3 +49,13 @@ func (r Simple) Provider() ProviderID {
 func (r Simple) Cluster() string {
 	return r.ClusterID
 }
+
+// ShardKey returns the key of the EDS endpoint shard holding the endpoints of a registry. Kubernetes registries
+// are keyed by cluster ID. Other registries are keyed by provider too, as several of them use the same cluster
+// ID (e.g. ServiceEntry and Consul registries both use "").
+func ShardKey(provider ProviderID, cluster string) string {
+	if provider == Kubernetes {
+		return cluster
+	}
+	return string(provider) + "/" + cluster
+}
@@ -119,6 +119,12 @@ type Options struct {
 
 	// EndpointMode decides what source to use to get endpoint information
 	EndpointMode EndpointMode
+
+	// DiscoverySelectors restrict the namespaces and services the controller exposes.
+	DiscoverySelectors DiscoverySelectors
+
+	// SendUnhealthyEndpoints includes not ready endpoints in EDS, marked unhealthy or draining.
+	SendUnhealthyEndpoints bool
 }
 
 // EndpointMode decides what source to use to get endpoint information
@@ -155,6 +161,9 @@ type Controller struct {
 	services  cache.SharedIndexInformer
 	endpoints kubeEndpointsController
 
+	namespaces      cache.SharedIndexInformer
+	discoveryFilter discoveryFilter
+
 	// TODO we can disable this when we only have EndpointSlice enabled
 	nodes           cache.SharedIndexInformer
 	pods            *PodCache
@@ -180,6 +189,9 @@ type Controller struct {
 
 	// Network name for the registry as specified by the MeshNetworks configmap
 	networkForRegistry string
+
+	// sendUnhealthyEndpoints includes not ready endpoints in EDS, marked unhealthy or draining
+	sendUnhealthyEndpoints bool
 }
 
 // NewController creates a new Kubernetes controller
@@ -199,13 +211,18 @@ func NewController(client kubernetes.Interface, options Options) *Controller {
 		externalNameSvcInstanceMap: make(map[host.Name][]*model.ServiceInstance),
 		networksWatcher:            options.NetworksWatcher,
 		metrics:                    options.Metrics,
+		sendUnhealthyEndpoints:     options.SendUnhealthyEndpoints,
 	}
+	c.discoveryFilter.set(options.DiscoverySelectors)
 
 	sharedInformers := informers.NewSharedInformerFactoryWithOptions(client, options.ResyncPeriod, informers.WithNamespace(options.WatchedNamespace))
 
 	c.services = sharedInformers.Core().V1().Services().Informer()
 	registerHandlers(c.services, c.queue, "Services", c.onServiceEvent)
 
+	c.namespaces = sharedInformers.Core().V1().Namespaces().Informer()
+	registerHandlers(c.namespaces, c.queue, "Namespaces", c.onNamespaceEvent)
+
 	switch options.EndpointMode {
 	case EndpointsOnly:
 		c.endpoints = newEndpointsController(c, sharedInformers)
@@ -260,6 +277,18 @@ func (c *Controller) onServiceEvent(curr interface{}, event model.Event) error {
 	log.Debugf("Handle event %s for service %s in namespace %s", event, svc.Name, svc.Namespace)
 
 	svcConv := kube.ConvertService(*svc, c.domainSuffix, c.clusterID)
+	c.RLock()
+	_, known := c.servicesMap[svcConv.Hostname]
+	c.RUnlock()
+	if event != model.EventDelete && !c.isSelected(svc) {
+		if !known {
+			return nil
+		}
+		// The service no longer matches the discovery selectors.
+		event = model.EventDelete
+	} else if event == model.EventDelete && !known && !c.isSelected(svc) {
+		return nil
+	}
 	switch event {
 	case model.EventDelete:
 		c.Lock()
@@ -267,7 +296,7 @@ func (c *Controller) onServiceEvent(curr interface{}, event model.Event) error {
 		delete(c.externalNameSvcInstanceMap, svcConv.Hostname)
 		c.Unlock()
 		// EDS needs to just know when service is deleted.
-		c.xdsUpdater.SvcUpdate(c.clusterID, svc.Name, svc.Namespace, event)
+		c.xdsUpdater.SvcUpdate(c.clusterID, string(svcConv.Hostname), svc.Namespace, event)
 	default:
 		// instance conversion is only required when service is added/updated.
 		instances := kube.ExternalNameServiceInstances(*svc, svcConv)
@@ -279,7 +308,7 @@ func (c *Controller) onServiceEvent(curr interface{}, event model.Event) error {
 			c.externalNameSvcInstanceMap[svcConv.Hostname] = instances
 		}
 		c.Unlock()
-		c.xdsUpdater.SvcUpdate(c.clusterID, svc.Name, svc.Namespace, event)
+		c.xdsUpdater.SvcUpdate(c.clusterID, string(svcConv.Hostname), svc.Namespace, event)
 	}
 
 	// Notify service handlers.
@@ -326,8 +355,8 @@ func registerHandlers(informer cache.SharedIndexInformer, q queue.Instance, otyp
 }
 
 // compareEndpoints returns true if the two endpoints are the same in aspects Pilot cares about
-// This currently means only looking at "Ready" endpoints
-func compareEndpoints(a, b *v1.Endpoints) bool {
+// This currently means only looking at "Ready" endpoints, and "NotReady" ones if includeNotReady is set
+func compareEndpoints(a, b *v1.Endpoints, includeNotReady bool) bool {
 	if len(a.Subsets) != len(b.Subsets) {
 		return false
 	}
@@ -338,6 +367,9 @@ func compareEndpoints(a, b *v1.Endpoints) bool {
 		if !reflect.DeepEqual(a.Subsets[i].Addresses, b.Subsets[i].Addresses) {
 			return false
 		}
+		if includeNotReady && !reflect.DeepEqual(a.Subsets[i].NotReadyAddresses, b.Subsets[i].NotReadyAddresses) {
+			return false
+		}
 	}
 	return true
 }
@@ -345,6 +377,7 @@ func compareEndpoints(a, b *v1.Endpoints) bool {
 // HasSynced returns true after the initial state synchronization
 func (c *Controller) HasSynced() bool {
 	if !c.services.HasSynced() ||
+		!c.namespaces.HasSynced() ||
 		!c.endpoints.HasSynced() ||
 		!c.pods.informer.HasSynced() ||
 		!c.nodes.HasSynced() {
@@ -365,13 +398,14 @@ func (c *Controller) Run(stop <-chan struct{}) {
 		c.queue.Run(stop)
 	}()
 
+	go c.namespaces.Run(stop)
 	go c.services.Run(stop)
 	go c.pods.informer.Run(stop)
 	go c.nodes.Run(stop)
 
 	// To avoid endpoints without labels or ports, wait for sync.
 	cache.WaitForCacheSync(stop, c.nodes.HasSynced, c.pods.informer.HasSynced,
-		c.services.HasSynced)
+		c.services.HasSynced, c.namespaces.HasSynced)
 
 	go c.endpoints.Run(stop)
 
@@ -658,6 +692,7 @@ func (c *Controller) getProxyServiceInstancesFromMetadata(proxy *model.Proxy) ([
 						Labels:         proxy.Metadata.Labels,
 						ServiceAccount: svcAccount,
 						Network:        c.endpointNetwork(ip),
+						AddressFamily:  model.AddressFamilyOf(ip),
 						Locality: model.Locality{
 							Label:     util.LocalityToString(proxy.Locality),
 							ClusterID: c.clusterID,
@@ -814,33 +849,9 @@ func (c *Controller) updateEDS(ep *v1.Endpoints, event model.Event) {
 	endpoints := make([]*model.IstioEndpoint, 0)
 	if event != model.EventDelete {
 		for _, ss := range ep.Subsets {
-			for _, ea := range ss.Addresses {
-				pod := c.pods.getPodByIP(ea.IP)
-				if pod == nil {
-					// This means, the endpoint event has arrived before pod event. This might happen because
-					// PodCache is eventually consistent. We should try to get the pod from kube-api server.
-					if ea.TargetRef != nil && ea.TargetRef.Kind == "Pod" {
-						pod = c.pods.getPod(ea.TargetRef.Name, ea.TargetRef.Namespace)
-						if pod == nil {
-							// If pod is still not available, this an unusual case.
-							endpointsWithNoPods.Increment()
-							log.Errorf("Endpoint without pod %s %s.%s", ea.IP, ep.Name, ep.Namespace)
-							if c.metrics != nil {
-								c.metrics.AddMetric(model.EndpointNoPod, string(hostname), nil, ea.IP)
-							}
-							continue
-						}
-					}
-				}
-
-				builder := NewEndpointBuilder(c, pod)
-
-				// EDS and ServiceEntry use name for service port - ADS will need to
-				// map to numbers.
-				for _, port := range ss.Ports {
-					istioEndpoint := builder.buildIstioEndpoint(ea.IP, port.Port, port.Name)
-					endpoints = append(endpoints, istioEndpoint)
-				}
+			endpoints = append(endpoints, c.buildIstioEndpoints(ep, hostname, ss.Addresses, ss.Ports, true)...)
+			if c.sendUnhealthyEndpoints {
+				endpoints = append(endpoints, c.buildIstioEndpoints(ep, hostname, ss.NotReadyAddresses, ss.Ports, false)...)
 			}
 		}
 	}
@@ -850,6 +861,51 @@ func (c *Controller) updateEDS(ep *v1.Endpoints, event model.Event) {
 	_ = c.xdsUpdater.EDSUpdate(c.clusterID, string(hostname), ep.Namespace, endpoints)
 }
 
+// buildIstioEndpoints converts the addresses of an endpoints subset. Not ready addresses are marked
+// draining if their pod is terminating, and unhealthy otherwise.
+func (c *Controller) buildIstioEndpoints(ep *v1.Endpoints, hostname host.Name, addresses []v1.EndpointAddress,
+	ports []v1.EndpointPort, ready bool) []*model.IstioEndpoint {
+	endpoints := make([]*model.IstioEndpoint, 0, len(addresses)*len(ports))
+	for _, ea := range addresses {
+		pod := c.pods.getPodByIP(ea.IP)
+		if pod == nil && !ready && ea.TargetRef != nil && ea.TargetRef.Kind == "Pod" {
+			// Terminating pods are removed from the IP index.
+			pod = c.pods.getCachedPod(ea.TargetRef.Name, ea.TargetRef.Namespace)
+		}
+		if pod == nil {
+			// This means, the endpoint event has arrived before pod event. This might happen because
+			// PodCache is eventually consistent. We should try to get the pod from kube-api server.
+			if ea.TargetRef != nil && ea.TargetRef.Kind == "Pod" {
+				pod = c.pods.getPod(ea.TargetRef.Name, ea.TargetRef.Namespace)
+				if pod == nil {
+					// If pod is still not available, this an unusual case.
+					endpointsWithNoPods.Increment()
+					log.Errorf("Endpoint without pod %s %s.%s", ea.IP, ep.Name, ep.Namespace)
+					if c.metrics != nil {
+						c.metrics.AddMetric(model.EndpointNoPod, string(hostname), nil, ea.IP)
+					}
+					continue
+				}
+			}
+		}
+
+		builder := NewEndpointBuilder(c, pod)
+		healthStatus := model.Healthy
+		if !ready {
+			healthStatus = notReadyHealthStatus(pod)
+		}
+
+		// EDS and ServiceEntry use name for service port - ADS will need to
+		// map to numbers.
+		for _, port := range ports {
+			istioEndpoint := builder.buildIstioEndpoint(ea.IP, port.Port, port.Name)
+			istioEndpoint.HealthStatus = healthStatus
+			endpoints = append(endpoints, istioEndpoint)
+		}
+	}
+	return endpoints
+}
+
 // namedRangerEntry for holding network's CIDR and name
 type namedRangerEntry struct {
 	name    string
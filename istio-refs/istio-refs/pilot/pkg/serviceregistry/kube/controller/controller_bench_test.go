@@ -0,0 +1,107 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	benchNamespaces         = 100
+	benchServicesPerNs      = 20
+	benchSelectedNamespaces = 10
+)
+
+// BenchmarkServiceDiscoveryScale measures how long the registry takes to process a cluster with
+// many namespaces, with and without discovery selectors restricting it to a few of them.
+func BenchmarkServiceDiscoveryScale(b *testing.B) {
+	selectors, _ := ParseDiscoverySelectors("istio-discovery=enabled")
+	cases := []struct {
+		name      string
+		selectors DiscoverySelectors
+		expected  int
+	}{
+		{"all namespaces", DiscoverySelectors{}, benchNamespaces * benchServicesPerNs},
+		{"namespace selectors", DiscoverySelectors{Namespaces: selectors}, benchSelectedNamespaces * benchServicesPerNs},
+	}
+	for _, bc := range cases {
+		b.Run(bc.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				controller, _ := newFakeControllerWithOptions(fakeControllerOptions{
+					mode:               EndpointsOnly,
+					discoverySelectors: bc.selectors,
+				})
+				b.StartTimer()
+
+				populateCluster(b, controller)
+				if err := wait.Poll(5*time.Millisecond, time.Minute, func() (bool, error) {
+					svcs, _ := controller.Services()
+					return len(svcs) == bc.expected, nil
+				}); err != nil {
+					b.Fatalf("registry did not converge to %d services", bc.expected)
+				}
+
+				b.StopTimer()
+				controller.Stop()
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func populateCluster(b *testing.B, controller *Controller) {
+	for i := 0; i < benchNamespaces; i++ {
+		ns := fmt.Sprintf("ns-%d", i)
+		nsLabels := map[string]string{}
+		if i < benchSelectedNamespaces {
+			nsLabels["istio-discovery"] = "enabled"
+		}
+		if _, err := controller.client.CoreV1().Namespaces().Create(&coreV1.Namespace{
+			ObjectMeta: metaV1.ObjectMeta{Name: ns, Labels: nsLabels},
+		}); err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < benchServicesPerNs; j++ {
+			name := fmt.Sprintf("svc-%d", j)
+			if _, err := controller.client.CoreV1().Services(ns).Create(&coreV1.Service{
+				ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: ns},
+				Spec: coreV1.ServiceSpec{
+					ClusterIP: "10.0.0.1",
+					Ports:     []coreV1.ServicePort{{Name: "http", Port: 80}},
+					Selector:  map[string]string{"app": name},
+					Type:      coreV1.ServiceTypeClusterIP,
+				},
+			}); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := controller.client.CoreV1().Endpoints(ns).Create(&coreV1.Endpoints{
+				ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: ns},
+				Subsets: []coreV1.EndpointSubset{{
+					Addresses: []coreV1.EndpointAddress{{IP: fmt.Sprintf("10.%d.%d.1", i, j)}},
+					Ports:     []coreV1.EndpointPort{{Name: "http", Port: 8080}},
+				}},
+			}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
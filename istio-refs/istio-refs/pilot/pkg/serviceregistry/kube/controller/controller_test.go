@@ -28,6 +28,7 @@ import (
 	discoveryv1alpha1 "k8s.io/api/discovery/v1alpha1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
@@ -79,6 +80,9 @@ type XdsEvent struct {
 
 	// The id of the event
 	ID string
+
+	// The endpoints of an eds event
+	Endpoints []*model.IstioEndpoint
 }
 
 // NewFakeXDS creates a XdsUpdater reporting events via a channel.
@@ -91,7 +95,7 @@ func NewFakeXDS() *FakeXdsUpdater {
 func (fx *FakeXdsUpdater) EDSUpdate(_, hostname string, _ string, entry []*model.IstioEndpoint) error {
 	if len(entry) > 0 {
 		select {
-		case fx.Events <- XdsEvent{Type: "eds", ID: hostname}:
+		case fx.Events <- XdsEvent{Type: "eds", ID: hostname, Endpoints: entry}:
 		default:
 		}
 
@@ -137,11 +141,14 @@ func (fx *FakeXdsUpdater) Clear() {
 }
 
 type fakeControllerOptions struct {
-	networksWatcher mesh.NetworksWatcher
-	serviceHandler  func(service *model.Service, event model.Event)
-	instanceHandler func(instance *model.ServiceInstance, event model.Event)
-	mode            EndpointMode
-	clusterID       string
+	networksWatcher    mesh.NetworksWatcher
+	serviceHandler     func(service *model.Service, event model.Event)
+	instanceHandler    func(instance *model.ServiceInstance, event model.Event)
+	mode               EndpointMode
+	clusterID          string
+	discoverySelectors DiscoverySelectors
+	// sendUnhealthyEndpoints includes not ready endpoints in EDS
+	sendUnhealthyEndpoints bool
 }
 
 func newFakeControllerWithOptions(opts fakeControllerOptions) (*Controller, *FakeXdsUpdater) {
@@ -149,14 +156,17 @@ func newFakeControllerWithOptions(opts fakeControllerOptions) (*Controller, *Fak
 
 	clientSet := fake.NewSimpleClientset()
 	c := NewController(clientSet, Options{
-		WatchedNamespace: "", // tests create resources in multiple ns
-		ResyncPeriod:     resync,
-		DomainSuffix:     domainSuffix,
-		XDSUpdater:       fx,
-		Metrics:          &model.Environment{},
-		NetworksWatcher:  opts.networksWatcher,
-		EndpointMode:     opts.mode,
-		ClusterID:        opts.clusterID,
+		WatchedNamespace:   "", // tests create resources in multiple ns
+		ResyncPeriod:       resync,
+		DomainSuffix:       domainSuffix,
+		XDSUpdater:         fx,
+		Metrics:            &model.Environment{},
+		NetworksWatcher:    opts.networksWatcher,
+		EndpointMode:       opts.mode,
+		ClusterID:          opts.clusterID,
+		DiscoverySelectors: opts.discoverySelectors,
+
+		SendUnhealthyEndpoints: opts.sendUnhealthyEndpoints,
 	})
 
 	if opts.instanceHandler != nil {
@@ -538,6 +548,7 @@ func TestGetProxyServiceInstances(t *testing.T) {
 				Endpoint: &model.IstioEndpoint{Labels: labels.Instance{"app": "prod-app"},
 					ServiceAccount:  "account",
 					Address:         "1.1.1.1",
+					AddressFamily:   model.AddressFamilyIPv4,
 					EndpointPort:    0,
 					ServicePortName: "tcp-port",
 					Locality: model.Locality{
@@ -597,6 +608,7 @@ func TestGetProxyServiceInstances(t *testing.T) {
 				ServicePort: &model.Port{Name: "tcp-port", Port: 8080, Protocol: protocol.TCP},
 				Endpoint: &model.IstioEndpoint{
 					Address:         "129.0.0.1",
+					AddressFamily:   model.AddressFamilyIPv4,
 					EndpointPort:    0,
 					ServicePortName: "tcp-port",
 					Locality: model.Locality{
@@ -654,6 +666,7 @@ func TestGetProxyServiceInstances(t *testing.T) {
 				ServicePort: &model.Port{Name: "tcp-port", Port: 8080, Protocol: protocol.TCP},
 				Endpoint: &model.IstioEndpoint{
 					Address:         "129.0.0.2",
+					AddressFamily:   model.AddressFamilyIPv4,
 					EndpointPort:    0,
 					ServicePortName: "tcp-port",
 					Locality: model.Locality{
@@ -1282,12 +1295,13 @@ func TestCompareEndpoints(t *testing.T) {
 	portA := v1.EndpointPort{Name: "a"}
 	portB := v1.EndpointPort{Name: "b"}
 	cases := []struct {
-		name string
-		a    *v1.Endpoints
-		b    *v1.Endpoints
-		want bool
+		name            string
+		a               *v1.Endpoints
+		b               *v1.Endpoints
+		includeNotReady bool
+		want            bool
 	}{
-		{"both empty", &v1.Endpoints{}, &v1.Endpoints{}, true},
+		{"both empty", &v1.Endpoints{}, &v1.Endpoints{}, false, true},
 		{
 			"just not ready endpoints",
 			&v1.Endpoints{Subsets: []v1.EndpointSubset{
@@ -1295,6 +1309,7 @@ func TestCompareEndpoints(t *testing.T) {
 			}},
 			&v1.Endpoints{},
 			false,
+			false,
 		},
 		{
 			"not ready to ready",
@@ -1305,6 +1320,7 @@ func TestCompareEndpoints(t *testing.T) {
 				{Addresses: []v1.EndpointAddress{addressA}},
 			}},
 			false,
+			false,
 		},
 		{
 			"ready and not ready address",
@@ -1317,7 +1333,22 @@ func TestCompareEndpoints(t *testing.T) {
 			&v1.Endpoints{Subsets: []v1.EndpointSubset{
 				{Addresses: []v1.EndpointAddress{addressA}},
 			}},
+			false,
+			true,
+		},
+		{
+			"ready and not ready address including not ready",
+			&v1.Endpoints{Subsets: []v1.EndpointSubset{
+				{
+					NotReadyAddresses: []v1.EndpointAddress{addressB},
+					Addresses:         []v1.EndpointAddress{addressA},
+				},
+			}},
+			&v1.Endpoints{Subsets: []v1.EndpointSubset{
+				{Addresses: []v1.EndpointAddress{addressA}},
+			}},
 			true,
+			false,
 		},
 		{
 			"different addresses",
@@ -1328,6 +1359,7 @@ func TestCompareEndpoints(t *testing.T) {
 				{Addresses: []v1.EndpointAddress{addressA}},
 			}},
 			false,
+			false,
 		},
 		{
 			"different ports",
@@ -1338,12 +1370,13 @@ func TestCompareEndpoints(t *testing.T) {
 				{Addresses: []v1.EndpointAddress{addressA}, Ports: []v1.EndpointPort{portB}},
 			}},
 			false,
+			false,
 		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			got := compareEndpoints(tt.a, tt.b)
-			inverse := compareEndpoints(tt.b, tt.a)
+			got := compareEndpoints(tt.a, tt.b, tt.includeNotReady)
+			inverse := compareEndpoints(tt.b, tt.a, tt.includeNotReady)
 			if got != tt.want {
 				t.Fatalf("Compare endpoints got %v, want %v", got, tt.want)
 			}
@@ -1831,3 +1864,97 @@ func TestEndpointUpdateBeforePodUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestNotReadyEndpointsHealthStatus(t *testing.T) {
+	podRef := func(name string) *v1.ObjectReference {
+		return &v1.ObjectReference{Kind: "Pod", Name: name, Namespace: "nsa"}
+	}
+	isReady := func(r bool) *bool { return &r }
+	portName, portNum := "tcp-port", int32(8080)
+	ep := &v1.Endpoints{
+		ObjectMeta: metaV1.ObjectMeta{Name: "svc1", Namespace: "nsa"},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{{IP: "128.0.0.1", TargetRef: podRef("ready")}},
+			NotReadyAddresses: []v1.EndpointAddress{
+				{IP: "128.0.0.2", TargetRef: podRef("terminating")},
+				{IP: "128.0.0.3", TargetRef: podRef("unready")},
+			},
+			Ports: []v1.EndpointPort{{Name: portName, Port: portNum}},
+		}},
+	}
+	slice := &discoveryv1alpha1.EndpointSlice{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "svc1-abc",
+			Namespace: "nsa",
+			Labels:    map[string]string{discoveryv1alpha1.LabelServiceName: "svc1"},
+		},
+		Endpoints: []discoveryv1alpha1.Endpoint{
+			{Addresses: []string{"128.0.0.1"}, TargetRef: podRef("ready"),
+				Conditions: discoveryv1alpha1.EndpointConditions{Ready: isReady(true)}},
+			{Addresses: []string{"128.0.0.2"}, TargetRef: podRef("terminating"),
+				Conditions: discoveryv1alpha1.EndpointConditions{Ready: isReady(false)}},
+			{Addresses: []string{"128.0.0.3"}, TargetRef: podRef("unready"),
+				Conditions: discoveryv1alpha1.EndpointConditions{Ready: isReady(false)}},
+		},
+		Ports: []discoveryv1alpha1.EndpointPort{{Name: &portName, Port: &portNum}},
+	}
+
+	for mode, name := range EndpointModeNames {
+		for _, sendUnhealthy := range []bool{false, true} {
+			mode, sendUnhealthy := mode, sendUnhealthy
+			t.Run(fmt.Sprintf("%s/%v", name, sendUnhealthy), func(t *testing.T) {
+				controller, fx := newFakeControllerWithOptions(fakeControllerOptions{
+					mode:                   mode,
+					sendUnhealthyEndpoints: sendUnhealthy,
+				})
+				defer controller.Stop()
+
+				ready := generatePod("128.0.0.1", "ready", "nsa", "", "", map[string]string{"app": "prod-app"}, map[string]string{})
+				terminating := generatePod("128.0.0.2", "terminating", "nsa", "", "", map[string]string{"app": "prod-app"}, map[string]string{})
+				deletionTimestamp := metaV1.Now()
+				terminating.DeletionTimestamp = &deletionTimestamp
+				unready := generatePod("128.0.0.3", "unready", "nsa", "", "", map[string]string{"app": "prod-app"}, map[string]string{})
+				addPods(t, controller, ready, terminating, unready)
+				if err := wait.Poll(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+					return controller.pods.getCachedPod("terminating", "nsa") != nil &&
+						controller.pods.getCachedPod("unready", "nsa") != nil &&
+						controller.pods.getPodByIP("128.0.0.1") != nil, nil
+				}); err != nil {
+					t.Fatalf("wait for pods err: %v", err)
+				}
+
+				createService(controller, "svc1", "nsa", nil, []int32{8080}, map[string]string{"app": "prod-app"}, t)
+				if ev := fx.Wait("service"); ev == nil {
+					t.Fatal("Timeout creating service")
+				}
+
+				if mode == EndpointSliceOnly {
+					if _, err := controller.client.DiscoveryV1alpha1().EndpointSlices("nsa").Create(slice); err != nil {
+						t.Fatal(err)
+					}
+				} else {
+					if _, err := controller.client.CoreV1().Endpoints("nsa").Create(ep); err != nil {
+						t.Fatal(err)
+					}
+				}
+				ev := fx.Wait("eds")
+				if ev == nil {
+					t.Fatal("Timeout updating endpoints")
+				}
+
+				got := map[string]model.HealthStatus{}
+				for _, ep := range ev.Endpoints {
+					got[ep.Address] = ep.HealthStatus
+				}
+				want := map[string]model.HealthStatus{"128.0.0.1": model.Healthy}
+				if sendUnhealthy {
+					want["128.0.0.2"] = model.Draining
+					want["128.0.0.3"] = model.UnHealthy
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("got health status %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/pkg/log"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube"
+)
+
+// DiscoverySelectors select the namespaces and services visible in a Kubernetes registry.
+// An object is selected if it matches any of the selectors. Empty lists select everything.
+type DiscoverySelectors struct {
+	Namespaces []klabels.Selector
+	Services   []klabels.Selector
+}
+
+// ParseDiscoverySelectors parses a semicolon separated list of Kubernetes label selectors,
+// e.g. "istio-discovery=enabled;team in (a,b)".
+func ParseDiscoverySelectors(value string) ([]klabels.Selector, error) {
+	return parseSelectors(strings.Split(value, ";"))
+}
+
+// discoverySelectorsFile is the format of the discovery selectors file, e.g.
+//
+//	namespaces:
+//	- istio-discovery=enabled
+//	services:
+//	- team in (a,b)
+type discoverySelectorsFile struct {
+	Namespaces []string `json:"namespaces,omitempty"`
+	Services   []string `json:"services,omitempty"`
+}
+
+// ReadDiscoverySelectors reads the discovery selectors from a YAML file.
+func ReadDiscoverySelectors(filename string) (DiscoverySelectors, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return DiscoverySelectors{}, err
+	}
+	var file discoverySelectorsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return DiscoverySelectors{}, fmt.Errorf("invalid discovery selectors file %s: %v", filename, err)
+	}
+	var selectors DiscoverySelectors
+	if selectors.Namespaces, err = parseSelectors(file.Namespaces); err != nil {
+		return DiscoverySelectors{}, err
+	}
+	if selectors.Services, err = parseSelectors(file.Services); err != nil {
+		return DiscoverySelectors{}, err
+	}
+	return selectors, nil
+}
+
+func parseSelectors(values []string) ([]klabels.Selector, error) {
+	var out []klabels.Selector
+	for _, s := range values {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		selector, err := klabels.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid discovery selector %q: %v", s, err)
+		}
+		out = append(out, selector)
+	}
+	return out, nil
+}
+
+func matchesAny(selectors []klabels.Selector, l map[string]string) bool {
+	if len(selectors) == 0 {
+		return true
+	}
+	for _, s := range selectors {
+		if s.Matches(klabels.Set(l)) {
+			return true
+		}
+	}
+	return false
+}
+
+// discoveryFilter holds the discovery selectors of a controller. They can be replaced at runtime.
+type discoveryFilter struct {
+	sync.RWMutex
+	selectors DiscoverySelectors
+}
+
+func (f *discoveryFilter) get() DiscoverySelectors {
+	f.RLock()
+	defer f.RUnlock()
+	return f.selectors
+}
+
+func (f *discoveryFilter) set(selectors DiscoverySelectors) {
+	f.Lock()
+	f.selectors = selectors
+	f.Unlock()
+}
+
+// isSelected returns true if the service and its namespace match the discovery selectors.
+func (c *Controller) isSelected(svc *v1.Service) bool {
+	selectors := c.discoveryFilter.get()
+	if !matchesAny(selectors.Services, svc.Labels) {
+		return false
+	}
+	if len(selectors.Namespaces) == 0 {
+		return true
+	}
+	obj, exists, err := c.namespaces.GetStore().GetByKey(svc.Namespace)
+	if err != nil || !exists {
+		return false
+	}
+	return matchesAny(selectors.Namespaces, obj.(*v1.Namespace).Labels)
+}
+
+// isServiceSelected returns true if the service with the given name is visible in the registry.
+// Endpoints of services that are not known to the informer are not filtered.
+func (c *Controller) isServiceSelected(name, namespace string) bool {
+	obj, exists, err := c.services.GetIndexer().GetByKey(kube.KeyFunc(name, namespace))
+	if err != nil || !exists {
+		return true
+	}
+	return c.isSelected(obj.(*v1.Service))
+}
+
+// SetDiscoverySelectors replaces the discovery selectors. Services that become visible are added with
+// their endpoints, services that are no longer selected are deleted.
+func (c *Controller) SetDiscoverySelectors(selectors DiscoverySelectors) {
+	c.discoveryFilter.set(selectors)
+	c.queue.Push(func() error {
+		return c.resyncServices(metav1.NamespaceAll)
+	})
+}
+
+func (c *Controller) onNamespaceEvent(curr interface{}, event model.Event) error {
+	if err := c.checkReadyForEvents(); err != nil {
+		return err
+	}
+	if len(c.discoveryFilter.get().Namespaces) == 0 {
+		return nil
+	}
+
+	ns, ok := curr.(*v1.Namespace)
+	if !ok {
+		tombstone, ok := curr.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			log.Errorf("Couldn't get object from tombstone %#v", curr)
+			return nil
+		}
+		ns, ok = tombstone.Obj.(*v1.Namespace)
+		if !ok {
+			log.Errorf("Tombstone contained object that is not a namespace %#v", curr)
+			return nil
+		}
+	}
+
+	log.Debugf("Handle event %s for namespace %s", event, ns.Name)
+	return c.resyncServices(ns.Name)
+}
+
+// resyncServices compares the services of a namespace (or all namespaces) against the discovery selectors,
+// adding the services that became visible and deleting the ones that are no longer selected.
+func (c *Controller) resyncServices(namespace string) error {
+	var objs []interface{}
+	if namespace == metav1.NamespaceAll {
+		objs = c.services.GetStore().List()
+	} else {
+		var err error
+		if objs, err = c.services.GetIndexer().ByIndex(cache.NamespaceIndex, namespace); err != nil {
+			return err
+		}
+	}
+
+	for _, obj := range objs {
+		svc := obj.(*v1.Service)
+		hostname := kube.ServiceHostname(svc.Name, svc.Namespace, c.domainSuffix)
+		c.RLock()
+		_, known := c.servicesMap[hostname]
+		c.RUnlock()
+
+		selected := c.isSelected(svc)
+		switch {
+		case selected && !known:
+			if err := c.onServiceEvent(svc, model.EventAdd); err != nil {
+				return err
+			}
+			c.endpoints.sync(svc.Name, svc.Namespace)
+		case !selected && known:
+			if err := c.onServiceEvent(svc, model.EventDelete); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
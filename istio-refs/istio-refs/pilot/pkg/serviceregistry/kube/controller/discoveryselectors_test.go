@@ -0,0 +1,216 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"istio.io/istio/pilot/pkg/serviceregistry/kube"
+)
+
+func TestParseDiscoverySelectors(t *testing.T) {
+	cases := []struct {
+		value string
+		want  []string
+		err   bool
+	}{
+		{value: "", want: nil},
+		{value: "istio-discovery=enabled", want: []string{"istio-discovery=enabled"}},
+		{value: "a=b,c!=d; team in (x,y) ;", want: []string{"a=b,c!=d", "team in (x,y)"}},
+		{value: "a==b==c", err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDiscoverySelectors(tt.value)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSelectors(t, got, tt.want)
+		})
+	}
+}
+
+func TestReadDiscoverySelectors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "discoveryselectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name       string
+		content    string
+		namespaces []string
+		services   []string
+		err        bool
+	}{
+		{name: "empty"},
+		{
+			name:       "selectors",
+			content:    "namespaces:\n- istio-discovery=enabled\nservices:\n- team in (x,y)\n- app=a\n",
+			namespaces: []string{"istio-discovery=enabled"},
+			services:   []string{"team in (x,y)", "app=a"},
+		},
+		{name: "invalid selector", content: "services:\n- a==b==c\n", err: true},
+		{name: "invalid yaml", content: "namespaces: a: b", err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, "selectors")
+			if err := ioutil.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadDiscoverySelectors(filename)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSelectors(t, got.Namespaces, tt.namespaces)
+			assertSelectors(t, got.Services, tt.services)
+		})
+	}
+
+	if _, err := ReadDiscoverySelectors(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("expected error for a missing file")
+	}
+}
+
+func assertSelectors(t *testing.T, got []klabels.Selector, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i].String() != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func createNamespace(controller *Controller, name string, labels map[string]string, t *testing.T) {
+	_, err := controller.client.CoreV1().Namespaces().Create(&coreV1.Namespace{
+		ObjectMeta: metaV1.ObjectMeta{Name: name, Labels: labels},
+	})
+	if err != nil {
+		t.Fatalf("Cannot create namespace %s (error: %v)", name, err)
+	}
+}
+
+func createServiceWithLabels(controller *Controller, name, namespace string, labels map[string]string, t *testing.T) {
+	_, err := controller.client.CoreV1().Services(namespace).Create(&coreV1.Service{
+		ObjectMeta: metaV1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: coreV1.ServiceSpec{
+			ClusterIP: "10.0.0.1",
+			Ports:     []coreV1.ServicePort{{Name: "tcp-port", Port: 8080, Protocol: "http"}},
+			Type:      coreV1.ServiceTypeClusterIP,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Cannot create service %s in namespace %s (error: %v)", name, namespace, err)
+	}
+}
+
+func waitForServiceVisibility(controller *Controller, name, namespace string, visible bool, t *testing.T) {
+	t.Helper()
+	hostname := kube.ServiceHostname(name, namespace, domainSuffix)
+	if err := wait.Poll(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		svc, _ := controller.GetService(hostname)
+		return (svc != nil) == visible, nil
+	}); err != nil {
+		t.Fatalf("service %s visible: want %v", hostname, visible)
+	}
+}
+
+func TestDiscoverySelectors(t *testing.T) {
+	for mode, name := range EndpointModeNames {
+		mode := mode
+		t.Run(name, func(t *testing.T) {
+			namespaceSelectors, _ := ParseDiscoverySelectors("istio-discovery=enabled")
+			controller, fx := newFakeControllerWithOptions(fakeControllerOptions{
+				mode:               mode,
+				discoverySelectors: DiscoverySelectors{Namespaces: namespaceSelectors},
+			})
+			defer controller.Stop()
+
+			createNamespace(controller, "selected", map[string]string{"istio-discovery": "enabled"}, t)
+			createNamespace(controller, "other", nil, t)
+			createServiceWithLabels(controller, "svc1", "selected", map[string]string{"app": "a"}, t)
+			createServiceWithLabels(controller, "svc2", "other", map[string]string{"app": "b"}, t)
+
+			waitForServiceVisibility(controller, "svc1", "selected", true, t)
+			waitForServiceVisibility(controller, "svc2", "other", false, t)
+
+			// Labeling the namespace makes its services visible.
+			ns, err := controller.client.CoreV1().Namespaces().Get("other", metaV1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns.Labels = map[string]string{"istio-discovery": "enabled"}
+			if _, err := controller.client.CoreV1().Namespaces().Update(ns); err != nil {
+				t.Fatal(err)
+			}
+			waitForServiceVisibility(controller, "svc2", "other", true, t)
+
+			// Replacing the selectors at runtime removes services no longer selected,
+			// and the EDS shards of the service are deleted.
+			fx.Clear()
+			serviceSelectors, _ := ParseDiscoverySelectors("app=a")
+			controller.SetDiscoverySelectors(DiscoverySelectors{Namespaces: namespaceSelectors, Services: serviceSelectors})
+			waitForServiceVisibility(controller, "svc1", "selected", true, t)
+			waitForServiceVisibility(controller, "svc2", "other", false, t)
+			hostname := string(kube.ServiceHostname("svc2", "other", domainSuffix))
+			for {
+				ev := fx.Wait("service")
+				if ev == nil {
+					t.Fatalf("timed out waiting for the deletion of %s", hostname)
+				}
+				if ev.ID == hostname {
+					break
+				}
+			}
+
+			// Services updated to match the selectors are added back.
+			svc, err := controller.client.CoreV1().Services("other").Get("svc2", metaV1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			svc.Labels = map[string]string{"app": "a"}
+			if _, err := controller.client.CoreV1().Services("other").Update(svc); err != nil {
+				t.Fatal(err)
+			}
+			waitForServiceVisibility(controller, "svc2", "other", true, t)
+		})
+	}
+}
@@ -56,6 +56,14 @@ func NewEndpointBuilder(c *Controller, pod *v1.Pod) *EndpointBuilder {
 	}
 }
 
+// notReadyHealthStatus returns the health status of a not ready endpoint backed by pod.
+func notReadyHealthStatus(pod *v1.Pod) model.HealthStatus {
+	if pod != nil && pod.DeletionTimestamp != nil {
+		return model.Draining
+	}
+	return model.UnHealthy
+}
+
 func (b *EndpointBuilder) buildIstioEndpoint(
 	endpointAddress string,
 	endpointPort int32,
@@ -74,5 +82,6 @@ func (b *EndpointBuilder) buildIstioEndpoint(
 		EndpointPort:    uint32(endpointPort),
 		ServicePortName: svcPortName,
 		Network:         b.controller.endpointNetwork(endpointAddress),
+		AddressFamily:   model.AddressFamilyOf(endpointAddress),
 	}
 }
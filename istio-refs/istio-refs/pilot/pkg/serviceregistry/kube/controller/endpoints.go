@@ -61,7 +61,7 @@ func (e *endpointsController) registerEndpointsHandler() {
 				oldE := old.(*v1.Endpoints)
 				curE := cur.(*v1.Endpoints)
 
-				if !compareEndpoints(oldE, curE) {
+				if !compareEndpoints(oldE, curE, e.c.sendUnhealthyEndpoints) {
 					incrementEvent("Endpoints", "update")
 					e.c.queue.Push(func() error {
 						return e.onEvent(cur, model.EventUpdate)
@@ -193,6 +193,14 @@ func (e *endpointsController) InstancesByPort(c *Controller, svc *model.Service,
 	return out, nil
 }
 
+func (e *endpointsController) sync(name, namespace string) {
+	item, exists, err := e.informer.GetStore().GetByKey(kube.KeyFunc(name, namespace))
+	if err != nil || !exists {
+		return
+	}
+	_ = e.onEvent(item, model.EventAdd)
+}
+
 func (e *endpointsController) onEvent(curr interface{}, event model.Event) error {
 	if err := e.c.checkReadyForEvents(); err != nil {
 		return err
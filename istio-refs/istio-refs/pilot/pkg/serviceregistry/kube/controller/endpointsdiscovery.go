@@ -37,6 +37,8 @@ type kubeEndpointsController interface {
 	InstancesByPort(c *Controller, svc *model.Service, reqSvcPort int,
 		labelsList labels.Collection) ([]*model.ServiceInstance, error)
 	GetProxyServiceInstances(c *Controller, proxy *model.Proxy) []*model.ServiceInstance
+	// sync pushes the current endpoints of a service, i.e. when it becomes visible in the registry.
+	sync(name, namespace string)
 }
 
 // kubeEndpoints abstracts the common behavior across endpoint and endpoint slices.
@@ -60,6 +62,10 @@ func (e *kubeEndpoints) Run(stopCh <-chan struct{}) {
 func (e *kubeEndpoints) handleEvent(name string, namespace string, event model.Event, ep interface{}, fn updateEdsFunc) error {
 	log.Debugf("Handle event %s for endpoint %s in namespace %s", event, name, namespace)
 
+	if !e.c.isServiceSelected(name, namespace) {
+		return nil
+	}
+
 	// headless service cluster discovery type is ORIGINAL_DST, we do not need update EDS.
 	if features.EnableHeadlessService.Get() {
 		if obj, _, _ := e.c.services.GetIndexer().GetByKey(kube.KeyFunc(name, namespace)); obj != nil {
@@ -69,14 +69,20 @@ func (esc *endpointSliceController) updateEDS(es interface{}, event model.Event)
 	}
 
 	endpoints := make([]*model.IstioEndpoint, 0)
-	if event != model.EventDelete {
+	if event != model.EventDelete && endpointSliceHasIPs(slice) {
 		for _, e := range slice.Endpoints {
-			if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+			ready := e.Conditions.Ready == nil || *e.Conditions.Ready
+			if !ready && !esc.c.sendUnhealthyEndpoints {
 				// Ignore not ready endpoints
 				continue
 			}
 			for _, a := range e.Addresses {
 				pod := esc.c.pods.getPodByIP(a)
+				if pod == nil && !ready && e.TargetRef != nil && e.TargetRef.Kind == "Pod" {
+					// The v1alpha1 API has no terminating condition. Terminating pods are removed from
+					// the IP index, look them up by name to tell draining endpoints from unhealthy ones.
+					pod = esc.c.pods.getCachedPod(e.TargetRef.Name, e.TargetRef.Namespace)
+				}
 				if pod == nil {
 					// This can not happen in usual case
 					if e.TargetRef != nil && e.TargetRef.Kind == "Pod" {
@@ -105,6 +111,9 @@ func (esc *endpointSliceController) updateEDS(es interface{}, event model.Event)
 					}
 
 					istioEndpoint := builder.buildIstioEndpoint(a, portNum, portName)
+					if !ready {
+						istioEndpoint.HealthStatus = notReadyHealthStatus(pod)
+					}
 					endpoints = append(endpoints, istioEndpoint)
 				}
 			}
@@ -118,6 +127,19 @@ func (esc *endpointSliceController) updateEDS(es interface{}, event model.Event)
 	_ = esc.c.xdsUpdater.EDSUpdate(esc.c.clusterID, string(hostname), slice.Namespace, esc.endpointCache.Get(hostname))
 }
 
+func (esc *endpointSliceController) sync(name, namespace string) {
+	slices, err := esc.informer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+	if err != nil {
+		log.Errorf("Get endpoint slices of %s/%s failed: %v", namespace, name, err)
+		return
+	}
+	for _, obj := range slices {
+		if obj.(*discoveryv1alpha1.EndpointSlice).Labels[discoveryv1alpha1.LabelServiceName] == name {
+			_ = esc.onEvent(obj, model.EventAdd)
+		}
+	}
+}
+
 func (esc *endpointSliceController) onEvent(curr interface{}, event model.Event) error {
 	if err := esc.c.checkReadyForEvents(); err != nil {
 		return err
@@ -150,6 +172,9 @@ func (esc *endpointSliceController) GetProxyServiceInstances(c *Controller, prox
 	}
 	out := make([]*model.ServiceInstance, 0)
 	for _, ep := range eps {
+		if !endpointSliceHasIPs(ep) {
+			continue
+		}
 		instances := esc.proxyServiceInstances(c, ep, proxy)
 		out = append(out, instances...)
 	}
@@ -226,6 +251,9 @@ func (esc *endpointSliceController) InstancesByPort(c *Controller, svc *model.Se
 
 	var out []*model.ServiceInstance
 	for _, slice := range slices {
+		if !endpointSliceHasIPs(slice) {
+			continue
+		}
 		for _, e := range slice.Endpoints {
 			for _, a := range e.Addresses {
 				var podLabels labels.Instance
@@ -263,6 +291,12 @@ func (esc *endpointSliceController) InstancesByPort(c *Controller, svc *model.Se
 	return out, nil
 }
 
+// endpointSliceHasIPs returns false for EndpointSlices that do not hold IP addresses, such as FQDN slices.
+// A dual-stack service has separate IPv4 and IPv6 slices, which are both used.
+func endpointSliceHasIPs(slice *discoveryv1alpha1.EndpointSlice) bool {
+	return slice.AddressType != discoveryv1alpha1.AddressTypeFQDN
+}
+
 func (esc *endpointSliceController) newEndpointBuilder(pod *v1.Pod, endpoint discoveryv1alpha1.Endpoint) *EndpointBuilder {
 	if pod != nil {
 		// Respect pod "istio-locality" label
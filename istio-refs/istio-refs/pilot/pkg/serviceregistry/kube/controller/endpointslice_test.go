@@ -17,6 +17,8 @@ package controller
 import (
 	"reflect"
 	"testing"
+
+	discoveryv1alpha1 "k8s.io/api/discovery/v1alpha1"
 )
 
 func TestGetLocalityFromTopology(t *testing.T) {
@@ -59,3 +61,24 @@ func TestGetLocalityFromTopology(t *testing.T) {
 		})
 	}
 }
+
+func TestEndpointSliceHasIPs(t *testing.T) {
+	cases := []struct {
+		name        string
+		addressType discoveryv1alpha1.AddressType
+		want        bool
+	}{
+		{"ip", discoveryv1alpha1.AddressTypeIP, true},
+		{"ipv4", discoveryv1alpha1.AddressTypeIPv4, true},
+		{"ipv6", discoveryv1alpha1.AddressTypeIPv6, true},
+		{"fqdn", discoveryv1alpha1.AddressTypeFQDN, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			slice := &discoveryv1alpha1.EndpointSlice{AddressType: tt.addressType}
+			if got := endpointSliceHasIPs(slice); got != tt.want {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
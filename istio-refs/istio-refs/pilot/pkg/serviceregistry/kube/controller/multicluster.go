@@ -22,6 +22,7 @@ import (
 
 	"istio.io/pkg/log"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	"istio.io/istio/pkg/config/mesh"
@@ -46,13 +47,15 @@ type Multicluster struct {
 	m                     sync.Mutex // protects remoteKubeControllers
 	remoteKubeControllers map[string]*kubeController
 	networksWatcher       mesh.NetworksWatcher
+	discoverySelectors    DiscoverySelectors
 }
 
 // NewMulticluster initializes data structure to store multicluster information
 // It also starts the secret controller
 func NewMulticluster(kc kubernetes.Interface, secretNamespace string,
 	watchedNamespace string, domainSuffix string, resyncPeriod time.Duration,
-	serviceController *aggregate.Controller, xds model.XDSUpdater, networksWatcher mesh.NetworksWatcher) (*Multicluster, error) {
+	serviceController *aggregate.Controller, xds model.XDSUpdater, networksWatcher mesh.NetworksWatcher,
+	discoverySelectors DiscoverySelectors) (*Multicluster, error) {
 
 	remoteKubeController := make(map[string]*kubeController)
 	if resyncPeriod == 0 {
@@ -68,6 +71,7 @@ func NewMulticluster(kc kubernetes.Interface, secretNamespace string,
 		XDSUpdater:            xds,
 		remoteKubeControllers: remoteKubeController,
 		networksWatcher:       networksWatcher,
+		discoverySelectors:    discoverySelectors,
 	}
 
 	err := secretcontroller.StartSecretController(kc,
@@ -88,12 +92,15 @@ func (m *Multicluster) AddMemberCluster(clientset kubernetes.Interface, clusterI
 	remoteKubeController.stopCh = stopCh
 	m.m.Lock()
 	kubectl := NewController(clientset, Options{
-		WatchedNamespace: m.WatchedNamespace,
-		ResyncPeriod:     m.ResyncPeriod,
-		DomainSuffix:     m.DomainSuffix,
-		XDSUpdater:       m.XDSUpdater,
-		ClusterID:        clusterID,
-		NetworksWatcher:  m.networksWatcher,
+		WatchedNamespace:   m.WatchedNamespace,
+		ResyncPeriod:       m.ResyncPeriod,
+		DomainSuffix:       m.DomainSuffix,
+		XDSUpdater:         m.XDSUpdater,
+		ClusterID:          clusterID,
+		NetworksWatcher:    m.networksWatcher,
+		DiscoverySelectors: m.discoverySelectors,
+
+		SendUnhealthyEndpoints: features.SendUnhealthyEndpoints,
 	})
 
 	remoteKubeController.rc = kubectl
@@ -108,6 +115,17 @@ func (m *Multicluster) AddMemberCluster(clientset kubernetes.Interface, clusterI
 	return nil
 }
 
+// SetDiscoverySelectors replaces the discovery selectors of the remote cluster controllers,
+// including the controllers of clusters added later.
+func (m *Multicluster) SetDiscoverySelectors(selectors DiscoverySelectors) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.discoverySelectors = selectors
+	for _, rc := range m.remoteKubeControllers {
+		rc.rc.SetDiscoverySelectors(selectors)
+	}
+}
+
 func (m *Multicluster) UpdateMemberCluster(clientset kubernetes.Interface, clusterID string) error {
 	if err := m.DeleteMemberCluster(clusterID); err != nil {
 		return err
@@ -38,8 +38,8 @@ type PodCache struct {
 	// This should only contain RUNNING or PENDING pods with an allocated IP.
 	podsByIP map[string]string
 	// IPByPods is a reverse map of podsByIP. This exists to allow us to prune stale entries in the
-	// pod cache if a pod changes IP.
-	IPByPods map[string]string
+	// pod cache if a pod changes IP. Dual-stack pods have one IP per family.
+	IPByPods map[string][]string
 
 	c *Controller
 }
@@ -49,7 +49,7 @@ func newPodCache(informer cache.SharedIndexInformer, c *Controller) *PodCache {
 		informer: informer,
 		c:        c,
 		podsByIP: make(map[string]string),
-		IPByPods: make(map[string]string),
+		IPByPods: make(map[string][]string),
 	}
 
 	return out
@@ -73,68 +73,107 @@ func (pc *PodCache) onEvent(curr interface{}, ev model.Event) error {
 		}
 	}
 
-	ip := pod.Status.PodIP
+	ips := podIPs(pod)
 	// PodIP will be empty when pod is just created, but before the IP is assigned
 	// via UpdateStatus.
 
-	if len(ip) > 0 {
-		log.Infof("Handling event %s for pod %s (%v) in namespace %s -> %v", ev, pod.Name, pod.Status.Phase, pod.Namespace, ip)
+	if len(ips) > 0 {
+		log.Infof("Handling event %s for pod %s (%v) in namespace %s -> %v", ev, pod.Name, pod.Status.Phase, pod.Namespace, ips)
 		key := kube.KeyFunc(pod.Name, pod.Namespace)
 		switch ev {
 		case model.EventAdd:
 			switch pod.Status.Phase {
 			case v1.PodPending, v1.PodRunning:
-				if key != pc.podsByIP[ip] {
+				if !pc.cached(ips, key) {
 					// add to cache if the pod is running or pending
-					pc.update(ip, key)
+					pc.update(ips, key)
 				}
 			}
 		case model.EventUpdate:
 			if pod.DeletionTimestamp != nil {
 				// delete only if this pod was in the cache
-				if pc.podsByIP[ip] == key {
-					pc.deleteIP(ip)
-				}
+				pc.deleteIPs(ips, key)
 				return nil
 			}
 			switch pod.Status.Phase {
 			case v1.PodPending, v1.PodRunning:
-				if key != pc.podsByIP[ip] {
+				if !pc.cached(ips, key) {
 					// add to cache if the pod is running or pending
-					pc.update(ip, key)
+					pc.update(ips, key)
 				}
 
 			default:
 				// delete if the pod switched to other states and is in the cache
-				if pc.podsByIP[ip] == key {
-					pc.deleteIP(ip)
-				}
+				pc.deleteIPs(ips, key)
 			}
 		case model.EventDelete:
 			// delete only if this pod was in the cache
-			if pc.podsByIP[ip] == key {
-				pc.deleteIP(ip)
-			}
+			pc.deleteIPs(ips, key)
 		}
 	}
 	return nil
 }
 
-func (pc *PodCache) deleteIP(ip string) {
-	pod := pc.podsByIP[ip]
-	delete(pc.podsByIP, ip)
-	delete(pc.IPByPods, pod)
+// podIPs returns all IPs of the pod. Dual-stack pods have one IP per family in PodIPs,
+// with the first one always matching PodIP.
+func podIPs(pod *v1.Pod) []string {
+	if len(pod.Status.PodIPs) == 0 {
+		if pod.Status.PodIP == "" {
+			return nil
+		}
+		return []string{pod.Status.PodIP}
+	}
+	ips := make([]string, 0, len(pod.Status.PodIPs))
+	for _, ip := range pod.Status.PodIPs {
+		ips = append(ips, ip.IP)
+	}
+	return ips
 }
 
-func (pc *PodCache) update(ip, key string) {
-	if current, f := pc.IPByPods[key]; f {
-		// The pod already exists, but with another IP Address. We need to clean up that
-		delete(pc.podsByIP, current)
+// cached returns true if all of the IPs already map to the pod key.
+func (pc *PodCache) cached(ips []string, key string) bool {
+	for _, ip := range ips {
+		if pc.podsByIP[ip] != key {
+			return false
+		}
 	}
-	pc.podsByIP[ip] = key
-	pc.IPByPods[key] = ip
+	return true
+}
 
-	pc.proxyUpdates(ip)
+// deleteIPs removes the IPs that are mapped to the pod key.
+func (pc *PodCache) deleteIPs(ips []string, key string) {
+	for _, ip := range ips {
+		if pc.podsByIP[ip] == key {
+			delete(pc.podsByIP, ip)
+		}
+	}
+	delete(pc.IPByPods, key)
+}
+
+func (pc *PodCache) update(ips []string, key string) {
+	// The pod may already exist, but with other IP addresses. We need to clean those up
+	for _, current := range pc.IPByPods[key] {
+		if pc.podsByIP[current] == key && !containsIP(ips, current) {
+			delete(pc.podsByIP, current)
+		}
+	}
+	for _, ip := range ips {
+		pc.podsByIP[ip] = key
+	}
+	pc.IPByPods[key] = ips
+
+	for _, ip := range ips {
+		pc.proxyUpdates(ip)
+	}
+}
+
+func containsIP(ips []string, ip string) bool {
+	for _, i := range ips {
+		if i == ip {
+			return true
+		}
+	}
+	return false
 }
 
 func (pc *PodCache) proxyUpdates(ip string) {
@@ -173,3 +212,13 @@ func (pc *PodCache) getPod(name string, namespace string) *v1.Pod {
 	}
 	return pod
 }
+
+// getCachedPod returns the pod from the informer cache. Unlike getPodByIP, this also finds
+// terminating pods, which are removed from the IP index.
+func (pc *PodCache) getCachedPod(name string, namespace string) *v1.Pod {
+	item, exists, err := pc.informer.GetStore().GetByKey(kube.KeyFunc(name, namespace))
+	if !exists || err != nil {
+		return nil
+	}
+	return item.(*v1.Pod)
+}
@@ -274,3 +274,46 @@ func TestPodCacheEvents(t *testing.T) {
 		t.Errorf("getPodKey => got %s, want none", pod)
 	}
 }
+
+func TestPodCacheDualStack(t *testing.T) {
+	t.Parallel()
+	c, _ := newFakeControllerWithOptions(fakeControllerOptions{mode: EndpointsOnly})
+	defer c.Stop()
+
+	tests := []struct {
+		name string
+		ips  []string
+	}{
+		{"ipv4 only", []string{"10.1.1.1"}},
+		{"ipv6 only", []string{"fd00:10::1"}},
+		{"dual stack", []string{"10.1.1.2", "fd00:10::2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			podCache := newPodCache(nil, c)
+			meta := metav1.ObjectMeta{Name: "pod", Namespace: "default"}
+			status := v1.PodStatus{PodIP: tt.ips[0], Phase: v1.PodRunning}
+			for _, ip := range tt.ips {
+				status.PodIPs = append(status.PodIPs, v1.PodIP{IP: ip})
+			}
+
+			if err := podCache.onEvent(&v1.Pod{ObjectMeta: meta, Status: status}, model.EventAdd); err != nil {
+				t.Fatal(err)
+			}
+			for _, ip := range tt.ips {
+				if pod, exists := podCache.getPodKey(ip); !exists || pod != "default/pod" {
+					t.Errorf("getPodKey(%s) => got %s, pod not found or incorrect", ip, pod)
+				}
+			}
+
+			if err := podCache.onEvent(&v1.Pod{ObjectMeta: meta, Status: status}, model.EventDelete); err != nil {
+				t.Fatal(err)
+			}
+			for _, ip := range tt.ips {
+				if pod, exists := podCache.getPodKey(ip); exists {
+					t.Errorf("getPodKey(%s) => got %s, want none", ip, pod)
+				}
+			}
+		})
+	}
+}
@@ -196,11 +196,11 @@ func ResolveHostsInNetworksConfig(config *meshconfig.MeshNetworks) {
 	}
 }
 
-// Add to the FileWatcher the provided file and execute the provided function
+// AddFileWatcher adds to the FileWatcher the provided file and executes the provided function
 // on any change event for this file.
 // Using a debouncing mechanism to avoid calling the callback multiple times
 // per event.
-func addFileWatcher(fileWatcher filewatcher.FileWatcher, file string, callback func()) {
+func AddFileWatcher(fileWatcher filewatcher.FileWatcher, file string, callback func()) {
 	_ = fileWatcher.Add(file)
 	go func() {
 		var timerC <-chan time.Time
@@ -72,7 +72,7 @@ func NewNetworksWatcher(fileWatcher filewatcher.FileWatcher, filename string) (N
 	}
 
 	// Watch the networks config file for changes and reload if it got modified
-	addFileWatcher(fileWatcher, filename, func() {
+	AddFileWatcher(fileWatcher, filename, func() {
 		// Reload the config file
 		meshNetworks, err := ReadMeshNetworks(filename)
 		if err != nil {
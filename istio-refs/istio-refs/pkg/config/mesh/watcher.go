@@ -69,7 +69,7 @@ func NewWatcher(fileWatcher filewatcher.FileWatcher, filename string) (Watcher,
 	}
 
 	// Watch the config file for changes and reload if it got modified
-	addFileWatcher(fileWatcher, filename, func() {
+	AddFileWatcher(fileWatcher, filename, func() {
 		// Reload the config file
 		meshConfig, err = ReadMeshConfig(filename)
 		if err != nil {
@@ -19,4 +19,13 @@ type Origin interface {
 	FriendlyName() string
 
 	Namespace() Namespace
+
+	// Reference to the location of the resource in its source, or nil if not known.
+	Reference() Reference
+}
+
+// Reference provides more information about an Origin, e.g. a file and line. This is also
+// source-implementation dependent.
+type Reference interface {
+	String() string
 }
@@ -406,6 +406,8 @@ snapshots:
       - "istio/authentication/v1alpha1/policies"
       - "istio/rbac/v1alpha1/servicerolebindings"
       - "istio/rbac/v1alpha1/serviceroles"
+      - "istio/security/v1beta1/authorizationpolicies"
+      - "istio/security/v1beta1/peerauthentications"
       - "istio/mesh/v1alpha1/MeshConfig"
       - "istio/networking/v1alpha3/envoyfilters"
       - "istio/networking/v1alpha3/destinationrules"
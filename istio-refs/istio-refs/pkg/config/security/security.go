@@ -134,6 +134,19 @@ func ValidateAttribute(key string, values []string) error {
 	return nil
 }
 
+// KnownAttribute returns true if key is a condition key supported by the v1beta1 AuthorizationPolicy.
+func KnownAttribute(key string) bool {
+	switch {
+	case hasPrefix(key, attrRequestHeader), hasPrefix(key, attrRequestClaims), hasPrefix(key, attrExperimental):
+		return validateMapKey(key) == nil
+	case isEqual(key, attrSrcIP, attrSrcNamespace, attrSrcPrincipal, attrRequestPrincipal, attrRequestAudiences,
+		attrRequestPresenter, attrDestIP, attrDestPort, attrConnSNI):
+		return true
+	default:
+		return false
+	}
+}
+
 func isEqual(key string, values ...string) bool {
 	for _, v := range values {
 		if key == v {
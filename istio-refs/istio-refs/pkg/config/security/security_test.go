@@ -209,3 +209,25 @@ func TestValidateCondition(t *testing.T) {
 		}
 	}
 }
+
+func TestKnownAttribute(t *testing.T) {
+	cases := map[string]bool{
+		"request.headers[User-Agent]":       true,
+		"request.headers[]":                 false,
+		"source.ip":                         true,
+		"source.principal":                  true,
+		"request.auth.claims[iss]":          true,
+		"destination.port":                  true,
+		"connection.sni":                    true,
+		"experimental.envoy.filters.a.b[c]": true,
+		"destination.labels[app]":           false,
+		"destination.name":                  false,
+		"source.user":                       false,
+		"request.host":                      false,
+	}
+	for key, want := range cases {
+		if got := security.KnownAttribute(key); got != want {
+			t.Errorf("KnownAttribute(%s): got %v, want %v", key, got, want)
+		}
+	}
+}